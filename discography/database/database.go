@@ -16,6 +16,25 @@ type Database interface {
 	FindSongByID(songID int) (general.Song, error)
 	AddArtist(artist, prefix, linkSpotify string) (general.Artist, error)
 	AddSong(song string, artists []general.Artist) (general.Song, error)
+	GetAlbumsFromArtist(artist string, offset, max int) ([]general.Album, error)
+	FindAlbumByID(albumID int) (general.Album, error)
+	AddAlbum(album string, artist general.Artist, songs []general.Song) (general.Album, error)
+	GetSongsFromGenre(genre string, offset, max int) ([]general.Song, error)
+	FindGenreByName(genre string) (general.Genre, error)
+	AddGenre(genre string) (general.Genre, error)
+	AddGenreToSong(songID int, genre general.Genre) error
+	RemoveGenreFromSong(songID int, genre general.Genre) error
+	AddGenreToAlbum(albumID int, genre general.Genre) error
+	RemoveGenreFromAlbum(albumID int, genre general.Genre) error
+	SearchArtists(query string, limit int) ([]general.Artist, error)
+	SearchSongs(query string, limit int) ([]general.Song, error)
+	GetLinkedArtists() ([]LinkedArtist, error)
+}
+
+// LinkedArtist is an artist together with the link to this artist on Spotify
+type LinkedArtist struct {
+	Artist      general.Artist
+	LinkSpotify string
 }
 
 // MusicDB is a database
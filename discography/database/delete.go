@@ -0,0 +1,37 @@
+package database
+
+import (
+	"general"
+)
+
+// RemoveGenreFromSong removes the genre from the song. It will return an error if the song isn't tagged with the genre.
+func (db *MusicDB) RemoveGenreFromSong(songID int, genre general.Genre) error {
+	info, err := db.database.Exec("DELETE FROM song_genre WHERE song_id=? AND genre_id=?;", songID, genre.ID)
+	if err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	rows, errorRows := info.RowsAffected()
+	if errorRows != nil {
+		return general.ErrorToUnknownDBError(errorRows)
+	}
+	if rows == 0 {
+		return general.GetDBError("Song isn't tagged with this genre", general.NotFoundError)
+	}
+	return nil
+}
+
+// RemoveGenreFromAlbum removes the genre from the album. It will return an error if the album isn't tagged with the genre.
+func (db *MusicDB) RemoveGenreFromAlbum(albumID int, genre general.Genre) error {
+	info, err := db.database.Exec("DELETE FROM album_genre WHERE album_id=? AND genre_id=?;", albumID, genre.ID)
+	if err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	rows, errorRows := info.RowsAffected()
+	if errorRows != nil {
+		return general.ErrorToUnknownDBError(errorRows)
+	}
+	if rows == 0 {
+		return general.GetDBError("Album isn't tagged with this genre", general.NotFoundError)
+	}
+	return nil
+}
@@ -4,12 +4,17 @@ import (
 	"general"
 )
 
-// AddArtist adds a new artist to the database
+// AddArtist adds a new artist to the database. A message to topic newArtist is added to the outbox in the same transaction.
 func (db *MusicDB) AddArtist(artist, prefix, linkSpotify string) (general.Artist, error) {
 	if len(artist) == 0 {
 		return general.Artist{}, general.GetDBError("Missing name", general.InvalidInput)
 	}
-	resultID, err := db.database.Exec("INSERT INTO artists (name_artist, prefix, linkSpotify) VALUES ( ?, ?,?)", artist, prefix, linkSpotify)
+	tx, err := db.database.Begin()
+	if err != nil {
+		return general.Artist{}, general.ErrorToUnknownDBError(err)
+	}
+	defer tx.Rollback()
+	resultID, err := tx.Exec("INSERT INTO artists (name_artist, prefix, linkSpotify) VALUES ( ?, ?,?)", artist, prefix, linkSpotify)
 	if err != nil {
 		return general.Artist{}, general.MySQLErrorToDBError(err)
 	}
@@ -17,10 +22,18 @@ func (db *MusicDB) AddArtist(artist, prefix, linkSpotify string) (general.Artist
 	if errorID != nil {
 		return general.Artist{}, general.ErrorToUnknownDBError(errorID)
 	}
-	return general.NewArtist(int(artistID), artist, prefix), nil
+	newArtist := general.NewArtist(int(artistID), artist, prefix)
+	if err = general.AddToOutbox(tx, "newArtist", newArtist); err != nil {
+		return general.Artist{}, general.ErrorToUnknownDBError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return general.Artist{}, general.ErrorToUnknownDBError(err)
+	}
+	return newArtist, nil
 }
 
 // AddSong will add a new song to the database. This function won't check if the song already exists. It will return an error if the data is incomplete or if an artist don't exist.
+// A message to topic newSong is added to the outbox in the same transaction.
 func (db *MusicDB) AddSong(song string, artists []general.Artist) (general.Song, error) {
 	if len(song) == 0 {
 		return general.Song{}, general.GetDBError("Missing name", general.InvalidInput)
@@ -28,7 +41,18 @@ func (db *MusicDB) AddSong(song string, artists []general.Artist) (general.Song,
 	if len(artists) == 0 {
 		return general.Song{}, general.GetDBError("No artists is given for adding a song", general.InvalidInput)
 	}
-	info, err := db.database.Exec("INSERT INTO songs (name_song) VALUES (?);", song)
+	for _, artist := range artists {
+		if artist.ID == 0 {
+			return general.Song{}, general.GetDBError("Invalid ID for "+artist.Name, general.InvalidInput)
+		}
+	}
+	tx, err := db.database.Begin()
+	if err != nil {
+		return general.Song{}, general.ErrorToUnknownDBError(err)
+	}
+	// Nothing is saved on failure
+	defer tx.Rollback()
+	info, err := tx.Exec("INSERT INTO songs (name_song) VALUES (?);", song)
 	if err != nil {
 		return general.Song{}, general.ErrorToUnknownDBError(err)
 	}
@@ -38,21 +62,96 @@ func (db *MusicDB) AddSong(song string, artists []general.Artist) (general.Song,
 	}
 	songID := int(lastResult)
 	for _, artist := range artists {
-		if artist.ID == 0 {
-			return general.Song{}, general.GetDBError("Invalid ID for "+artist.Name, general.InvalidInput)
-		}
-		_, err = db.database.Exec("INSERT INTO discography (artist_id, song_id) VALUES (?,?);", artist.ID, songID)
-		if err != nil {
-			// Revert changes on failure
-			db.database.Exec("DELETE FROM songs WHERE id=?;", songID)
-			artistIDs := ""
-			for _, artist := range artists {
-				artistIDs += string(artist.ID) + ","
-			}
-			artistIDs = artistIDs[:len(artistIDs)-1]
-			db.database.Exec("DELETE FROM discography WHERE song_id=? AND artist_id IN (?);", songID, artistIDs)
+		if _, err = tx.Exec("INSERT INTO discography (artist_id, song_id) VALUES (?,?);", artist.ID, songID); err != nil {
 			return general.Song{}, general.MySQLErrorToDBError(err)
 		}
 	}
-	return general.NewSong(songID, artists, song), nil
+	newSong := general.NewSong(songID, artists, song)
+	if err = general.AddToOutbox(tx, "newSong", newSong); err != nil {
+		return general.Song{}, general.ErrorToUnknownDBError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return general.Song{}, general.ErrorToUnknownDBError(err)
+	}
+	return newSong, nil
+}
+
+// AddAlbum will add a new album of the given artist to the database. The order of songs will be used as the track listing of the album.
+// It will return an error if the data is incomplete or if a song doesn't exist. A message to topic newAlbum is added to the outbox in the same transaction.
+func (db *MusicDB) AddAlbum(album string, artist general.Artist, songs []general.Song) (general.Album, error) {
+	if len(album) == 0 {
+		return general.Album{}, general.GetDBError("Missing name", general.InvalidInput)
+	}
+	if artist.ID == 0 {
+		return general.Album{}, general.GetDBError("Invalid ID for "+artist.Name, general.InvalidInput)
+	}
+	if len(songs) == 0 {
+		return general.Album{}, general.GetDBError("No songs are given for adding an album", general.InvalidInput)
+	}
+	tx, err := db.database.Begin()
+	if err != nil {
+		return general.Album{}, general.ErrorToUnknownDBError(err)
+	}
+	// Nothing is saved on failure
+	defer tx.Rollback()
+	info, err := tx.Exec("INSERT INTO albums (name_album, artist_id) VALUES (?,?);", album, artist.ID)
+	if err != nil {
+		return general.Album{}, general.MySQLErrorToDBError(err)
+	}
+	lastResult, errorID := info.LastInsertId()
+	if errorID != nil {
+		return general.Album{}, general.ErrorToUnknownDBError(errorID)
+	}
+	albumID := int(lastResult)
+	for index, song := range songs {
+		if _, err = tx.Exec("INSERT INTO album_track_listing (album_id, song_id, track_number) VALUES (?,?,?);", albumID, song.ID, index+1); err != nil {
+			return general.Album{}, general.MySQLErrorToDBError(err)
+		}
+	}
+	newAlbum := general.NewAlbum(albumID, album, artist, songs)
+	if err = general.AddToOutbox(tx, "newAlbum", newAlbum); err != nil {
+		return general.Album{}, general.ErrorToUnknownDBError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return general.Album{}, general.ErrorToUnknownDBError(err)
+	}
+	return newAlbum, nil
+}
+
+// AddGenre adds a new genre to the database
+func (db *MusicDB) AddGenre(genre string) (general.Genre, error) {
+	if len(genre) == 0 {
+		return general.Genre{}, general.GetDBError("Missing name", general.InvalidInput)
+	}
+	info, err := db.database.Exec("INSERT INTO genres (name_genre) VALUES (?);", genre)
+	if err != nil {
+		return general.Genre{}, general.MySQLErrorToDBError(err)
+	}
+	genreID, errorID := info.LastInsertId()
+	if errorID != nil {
+		return general.Genre{}, general.ErrorToUnknownDBError(errorID)
+	}
+	return general.NewGenre(int(genreID), genre), nil
+}
+
+// AddGenreToSong tags the song with the given genre. It will return an error if the song or genre doesn't exist or if the song is already tagged with the genre.
+func (db *MusicDB) AddGenreToSong(songID int, genre general.Genre) error {
+	if songID == 0 || genre.ID == 0 {
+		return general.GetDBError("Invalid ID for song or genre", general.InvalidInput)
+	}
+	if _, err := db.database.Exec("INSERT INTO song_genre (song_id, genre_id) VALUES (?,?);", songID, genre.ID); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
+// AddGenreToAlbum tags the album with the given genre. It will return an error if the album or genre doesn't exist or if the album is already tagged with the genre.
+func (db *MusicDB) AddGenreToAlbum(albumID int, genre general.Genre) error {
+	if albumID == 0 || genre.ID == 0 {
+		return general.GetDBError("Invalid ID for album or genre", general.InvalidInput)
+	}
+	if _, err := db.database.Exec("INSERT INTO album_genre (album_id, genre_id) VALUES (?,?);", albumID, genre.ID); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
 }
@@ -49,6 +49,34 @@ func scanSongs(results *sql.Rows) ([]general.Song, error) {
 	return returningResults, nil
 }
 
+func scanAlbums(results *sql.Rows) ([]general.Album, error) {
+	returningResults := make([]general.Album, 0, 20)
+	for results.Next() {
+		album := general.NewAlbum(0, "", general.Artist{}, nil)
+		err := results.Scan(&album.ID, &album.Name, &album.Artist.ID, &album.Artist.Name, &album.Artist.Prefix)
+		if err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		returningResults = append(returningResults, album)
+	}
+	return returningResults, nil
+}
+
+// scanGenresOfSongs returns a map that combines the id of a song or album with its genres
+func scanGenresOfSongs(results *sql.Rows) (map[int][]general.Genre, error) {
+	returningResults := make(map[int][]general.Genre)
+	for results.Next() {
+		var songID int
+		var genre general.Genre
+		err := results.Scan(&songID, &genre.ID, &genre.Name)
+		if err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		returningResults[songID] = append(returningResults[songID], genre)
+	}
+	return returningResults, nil
+}
+
 type artistAndSong struct {
 	artist general.Artist
 	song   general.Song
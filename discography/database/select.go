@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"general"
+	"strings"
 )
 
 // GetArtistsStartingWithLetter finds all artists that starts with a certain string
@@ -44,7 +45,11 @@ func (db *MusicDB) GetSongsFromArtist(artist string, offset, max int) ([]general
 		return nil, general.ErrorToUnknownDBError(err)
 	}
 	defer results.Close()
-	return scanSongs(results)
+	songs, scanError := scanSongs(results)
+	if scanError != nil {
+		return nil, scanError
+	}
+	return db.addGenresToSongs(songs)
 }
 
 // FindArtistByName searches the database for the artist. This function expects a name without prefix.
@@ -68,7 +73,7 @@ func (db *MusicDB) FindSongByName(artist, song string) (general.Song, error) {
 	result := db.database.QueryRow("SELECT songs.id FROM artists, discography, songs WHERE name_artist=? AND artist_id=artists.id AND songs.id=song_id AND name_song=? LIMIT 1;", artist, song)
 	var songID int
 	if err := result.Scan(&songID); err != nil {
-		if err != sql.ErrNoRows {
+		if err == sql.ErrNoRows {
 			return general.Song{}, general.GetDBError(err.Error(), general.NotFoundError)
 		}
 		return general.Song{}, general.ErrorToUnknownDBError(err)
@@ -101,5 +106,191 @@ func (db *MusicDB) FindSongByID(songID int) (general.Song, error) {
 	if scanError != nil {
 		return general.Song{}, scanError
 	}
+	if songs, err = db.addGenresToSongs(songs); err != nil {
+		return general.Song{}, err
+	}
 	return songs[0], nil
 }
+
+// GetAlbumsFromArtist finds the albums of the given artist ordered by name of the album. The tracks of the albums are not included.
+func (db *MusicDB) GetAlbumsFromArtist(artist string, offset, max int) ([]general.Album, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	results, err := db.database.Query("SELECT albums.id, name_album, artists.id, name_artist, prefix FROM albums, artists WHERE name_artist=? AND artist_id=artists.id ORDER BY name_album LIMIT ?,?;", artist, offset, max)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	return scanAlbums(results)
+}
+
+// FindAlbumByID returns the album that belongs to the given ID together with its genres and its songs ordered by track number
+func (db *MusicDB) FindAlbumByID(albumID int) (general.Album, error) {
+	album := general.NewAlbum(0, "", general.Artist{}, nil)
+	result := db.database.QueryRow("SELECT albums.id, name_album, artists.id, name_artist, prefix FROM albums, artists WHERE albums.id=? AND artist_id=artists.id LIMIT 1;", albumID)
+	err := result.Scan(&album.ID, &album.Name, &album.Artist.ID, &album.Artist.Name, &album.Artist.Prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return general.Album{}, general.GetDBError(err.Error(), general.NotFoundError)
+		}
+		return general.Album{}, general.ErrorToUnknownDBError(err)
+	}
+	genres, err := db.database.Query("SELECT album_id, genres.id, name_genre FROM album_genre, genres WHERE genres.id=genre_id AND album_id=? ORDER BY name_genre;", albumID)
+	if err != nil {
+		return general.Album{}, general.ErrorToUnknownDBError(err)
+	}
+	defer genres.Close()
+	genresOfAlbum, scanError := scanGenresOfSongs(genres)
+	if scanError != nil {
+		return general.Album{}, scanError
+	}
+	if found, ok := genresOfAlbum[album.ID]; ok {
+		album.Genres = found
+	}
+	results, err := db.database.Query("SELECT artists.id, name_artist, prefix, songs.id, name_song FROM album_track_listing, songs, discography, artists WHERE album_id=? AND album_track_listing.song_id=songs.id AND songs.id=discography.song_id AND artists.id=artist_id ORDER BY track_number;", albumID)
+	if err != nil {
+		return general.Album{}, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	songs, scanError := scanSongs(results)
+	if scanError != nil && scanError.(general.DBError).ErrorCode != general.NotFoundError {
+		return general.Album{}, scanError
+	}
+	if songs != nil {
+		if album.Songs, err = db.addGenresToSongs(songs); err != nil {
+			return general.Album{}, err
+		}
+	}
+	return album, nil
+}
+
+// GetSongsFromGenre finds songs that are tagged with the given genre ordered by name of the song.
+func (db *MusicDB) GetSongsFromGenre(genre string, offset, max int) ([]general.Song, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	// This query is a cross join between artists, discography and a subquery that selects the songs of a genre
+	results, err := db.database.Query("SELECT artists.id, name_artist, prefix, songsOfGenre.song_id, name_song FROM artists, discography CROSS JOIN (SELECT song_id, name_song FROM genres, song_genre, songs WHERE name_genre=? AND genres.id=genre_id AND songs.id=song_id ORDER BY name_song, song_id LIMIT ?,?) AS songsOfGenre ON discography.song_id=songsOfGenre.song_id WHERE artists.id=artist_id ORDER BY name_song, songsOfGenre.song_id;", genre, offset, max)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	songs, scanError := scanSongs(results)
+	if scanError != nil {
+		return nil, scanError
+	}
+	return db.addGenresToSongs(songs)
+}
+
+// FindGenreByName returns the genre with the given name
+func (db *MusicDB) FindGenreByName(genre string) (general.Genre, error) {
+	result := db.database.QueryRow("SELECT id, name_genre FROM genres WHERE name_genre=? LIMIT 1;", genre)
+	var foundGenre general.Genre
+	if err := result.Scan(&foundGenre.ID, &foundGenre.Name); err != nil {
+		if err == sql.ErrNoRows {
+			return general.Genre{}, general.GetDBError(err.Error(), general.NotFoundError)
+		}
+		return general.Genre{}, general.ErrorToUnknownDBError(err)
+	}
+	return foundGenre, nil
+}
+
+// addGenresToSongs searches the genres of all the given songs and adds them to the songs.
+func (db *MusicDB) addGenresToSongs(songs []general.Song) ([]general.Song, error) {
+	if len(songs) == 0 {
+		return songs, nil
+	}
+	songIDs := make([]interface{}, 0, len(songs))
+	for _, song := range songs {
+		songIDs = append(songIDs, song.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(songIDs)), ",")
+	results, err := db.database.Query("SELECT song_id, genres.id, name_genre FROM song_genre, genres WHERE genres.id=genre_id AND song_id IN ("+placeholders+") ORDER BY name_genre;", songIDs...)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	genres, scanError := scanGenresOfSongs(results)
+	if scanError != nil {
+		return nil, scanError
+	}
+	for index, song := range songs {
+		if genresOfSong, ok := genres[song.ID]; ok {
+			songs[index].Genres = genresOfSong
+		}
+	}
+	return songs, nil
+}
+
+// SearchArtists finds artists whose name looks like the query. The results are candidates that still need to be ranked.
+func (db *MusicDB) SearchArtists(query string, limit int) ([]general.Artist, error) {
+	if limit <= 0 {
+		return nil, general.GetDBError("Can not search with non-positive limit", general.InvalidOffsetMax)
+	}
+	conditions, args := searchConditions("name_artist", query)
+	results, err := db.database.Query("SELECT id, name_artist, prefix FROM artists WHERE "+conditions+" ORDER BY name_artist LIMIT ?;", append(args, limit)...)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	return scanArtists(results)
+}
+
+// SearchSongs finds songs whose name looks like the query. The results are candidates that still need to be ranked.
+func (db *MusicDB) SearchSongs(query string, limit int) ([]general.Song, error) {
+	if limit <= 0 {
+		return nil, general.GetDBError("Can not search with non-positive limit", general.InvalidOffsetMax)
+	}
+	conditions, args := searchConditions("name_song", query)
+	// This query is a cross join between artists, discography and a subquery that selects the songs that looks like the query
+	results, err := db.database.Query("SELECT artists.id, name_artist, prefix, foundSongs.song_id, name_song FROM artists, discography CROSS JOIN (SELECT id AS song_id, name_song FROM songs WHERE "+conditions+" ORDER BY name_song, id LIMIT ?) AS foundSongs ON discography.song_id=foundSongs.song_id WHERE artists.id=artist_id ORDER BY name_song, foundSongs.song_id;", append(args, limit)...)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	songs, scanError := scanSongs(results)
+	if scanError != nil {
+		if scanError.(general.DBError).ErrorCode == general.NotFoundError {
+			return []general.Song{}, nil
+		}
+		return nil, scanError
+	}
+	return db.addGenresToSongs(songs)
+}
+
+// searchConditions returns the WHERE clause and its arguments for finding names that looks like the query.
+// A name looks like the query if it contains the query, sounds like the query or contains the start of a word of the query.
+func searchConditions(column, query string) (string, []interface{}) {
+	query = strings.ToLower(query)
+	// Wildcards in the query should be matched literally
+	escapeLike := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	conditions := []string{"LOWER(" + column + ") LIKE ?", "SOUNDEX(" + column + ")=SOUNDEX(?)"}
+	args := []interface{}{"%" + escapeLike.Replace(query) + "%", query}
+	for _, word := range strings.Fields(query) {
+		if len([]rune(word)) < 3 {
+			continue
+		}
+		conditions = append(conditions, "LOWER("+column+") LIKE ?")
+		args = append(args, "%"+escapeLike.Replace(string([]rune(word)[:3]))+"%")
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// GetLinkedArtists returns all the artists that have a link to Spotify
+func (db *MusicDB) GetLinkedArtists() ([]LinkedArtist, error) {
+	results, err := db.database.Query("SELECT id, name_artist, prefix, linkSpotify FROM artists WHERE linkSpotify IS NOT NULL AND linkSpotify<>'' ORDER BY id;")
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	linkedArtists := make([]LinkedArtist, 0, 20)
+	for results.Next() {
+		var linkedArtist LinkedArtist
+		if err = results.Scan(&linkedArtist.Artist.ID, &linkedArtist.Artist.Name, &linkedArtist.Artist.Prefix, &linkedArtist.LinkSpotify); err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		linkedArtists = append(linkedArtists, linkedArtist)
+	}
+	return linkedArtists, nil
+}
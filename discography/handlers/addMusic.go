@@ -51,14 +51,7 @@ func (handler *MusicHandler) AddNewArtist(artist, prefix, linkSpotify string) (g
 		handler.Logger.Printf("[ERROR] Failed to add %v, %v due to: %s\n", artist, prefix, err)
 		return general.Artist{}, general.ErrorToUnknownDBError(err)
 	}
-	go func(handler *MusicHandler, newArtist general.Artist) {
-		msg, err := general.ToJSONBytes(newArtist)
-		if err != nil {
-			handler.Logger.Printf("[ERROR] Failed to convert %v, %v to bytes: %v\n", newArtist.Name, newArtist.Prefix, err)
-			return
-		}
-		handler.SendMessage("newArtist", msg)
-	}(handler, newArtist)
+	// The message to topic newArtist is send from the outbox of the database
 	handler.Logger.Printf("Succesfully added new artist %v\n", artist)
 	return newArtist, nil
 }
@@ -72,7 +65,7 @@ func (handler *MusicHandler) AddSongHandler(response http.ResponseWriter, reques
 		general.SendError(response, http.StatusBadRequest)
 		return
 	}
-	handler.Logger.Printf("Received call for adding new song %v - %v with link %v\n", newArtist.Artists, newArtist.Name)
+	handler.Logger.Printf("Received call for adding new song %v - %v\n", newArtist.Artists, newArtist.Name)
 	if _, err := handler.AddSong(newArtist.Name, newArtist.Artists...); err != nil {
 		if err.(general.DBError).ErrorCode != general.DuplicateEntry {
 			general.SendError(response, http.StatusInternalServerError)
@@ -129,28 +122,175 @@ func (handler *MusicHandler) AddSong(song string, artists ...string) (general.So
 		handler.Logger.Printf("Trying to add %v - %v but this song already exists\n", artists, song)
 		return general.Song{}, general.GetDBError("Duplicate entry", general.DuplicateEntry)
 	}
-	// Sending message to topic newSong
-	go func(handler *MusicHandler, newSong general.Song) {
-		msg, err := general.ToJSONBytes(newSong)
-		if err != nil {
-			handler.Logger.Printf("[ERROR] Failed to convert %v - %v to bytes: %v\n", newSong.Artists, newSong.Name, err)
-			return
-		}
-		handler.SendMessage("newSong", msg)
-	}(handler, newSong)
+	// The message to topic newSong is send from the outbox of the database
 
 	handler.Logger.Printf("Succesfully added new song %v - %v\n", contributingArtists, song)
 	succesNewSong.Inc()
 	return newSong, nil
 }
 
+// AddAlbumHandler is the handler used for adding a new album to the database
+func (handler *MusicHandler) AddAlbumHandler(response http.ResponseWriter, request *http.Request) {
+	var newAlbum ClientAlbum
+	if err := general.ReadFromJSON(&newAlbum, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request to add a new album: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for adding new album %v - %v with %v tracks\n", newAlbum.Artist, newAlbum.Name, len(newAlbum.Tracks))
+	if _, err := handler.AddAlbum(newAlbum.Name, newAlbum.Artist, newAlbum.Tracks...); err != nil {
+		switch err.(general.DBError).ErrorCode {
+		case general.DuplicateEntry:
+			http.Error(response, "This album already exists", http.StatusUnprocessableEntity)
+		case general.InvalidInput:
+			general.SendError(response, http.StatusBadRequest)
+		default:
+			general.SendError(response, http.StatusInternalServerError)
+		}
+		return
+	}
+	succesNewAlbum.Inc()
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// AddAlbum adds an album of the given artist to the database. The order of the tracks will be the track listing of the album.
+// Tracks without artists belong to the artist of the album. Tracks that are not yet in the database will be added by AddSong.
+func (handler *MusicHandler) AddAlbum(album, artist string, tracks ...ClientSong) (general.Album, error) {
+	if album == "" || artist == "" {
+		handler.Logger.Printf("Can't add album without a name or artist: %v - %v\n", artist, album)
+		return general.Album{}, general.GetDBError("Missing album or artist", general.InvalidInput)
+	}
+	if len(tracks) == 0 {
+		handler.Logger.Printf("Can't add album without tracks: %v - %v\n", artist, album)
+		return general.Album{}, general.GetDBError("Missing tracks", general.InvalidInput)
+	}
+	for _, track := range tracks {
+		if track.Name == "" {
+			handler.Logger.Printf("Can't add album %v - %v due to a track without a name\n", artist, album)
+			return general.Album{}, general.GetDBError("Missing song", general.InvalidInput)
+		}
+	}
+	handler.Logger.Printf("Trying to add album %v - %v\n", artist, album)
+	name, prefix := seperatePrefix(artist)
+	albumArtist, err := handler.db.FindArtistByName(name)
+	if err != nil {
+		if err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to search for artist %v due to: %s\n", name, err)
+			return general.Album{}, err
+		}
+		handler.Logger.Printf("Can't find artist %v while adding a new album\n", name)
+		albumArtist, err = handler.AddNewArtist(name, prefix, "")
+		if err != nil {
+			failedNewAlbum.Inc()
+			handler.Logger.Printf("[ERROR] Can't find or add artist %v: %v\n", name, err)
+			return general.Album{}, general.ErrorToUnknownDBError(err)
+		}
+	}
+	// Every track will be found in the DB or added to the DB
+	songs := make([]general.Song, 0, len(tracks))
+	for _, track := range tracks {
+		artists := track.Artists
+		if len(artists) == 0 {
+			artists = []string{artist}
+		}
+		nameFirstArtist, _ := seperatePrefix(artists[0])
+		song, err := handler.db.FindSongByName(nameFirstArtist, track.Name)
+		if err != nil {
+			if err.(general.DBError).ErrorCode != general.NotFoundError {
+				failedNewAlbum.Inc()
+				handler.Logger.Printf("[ERROR] Failed to search for song %v - %v due to: %s\n", artists, track.Name, err)
+				return general.Album{}, err
+			}
+			song, err = handler.AddSong(track.Name, artists...)
+			if err != nil {
+				failedNewAlbum.Inc()
+				handler.Logger.Printf("[ERROR] Can't find or add song %v - %v for album %v: %s\n", artists, track.Name, album, err)
+				return general.Album{}, err
+			}
+		}
+		songs = append(songs, song)
+	}
+	handler.Logger.Printf("Found all tracks belonging to %v - %v\n", artist, album)
+	newAlbum, err := handler.db.AddAlbum(album, albumArtist, songs)
+	if err != nil {
+		if err.(general.DBError).ErrorCode != general.DuplicateEntry {
+			handler.Logger.Printf("[ERROR] Failed to add new album %v - %v to database: %v\n", artist, album, err)
+			failedNewAlbum.Inc()
+			return general.Album{}, general.ErrorToUnknownDBError(err)
+		}
+		handler.Logger.Printf("Trying to add album %v - %v but this album already exists\n", artist, album)
+		return general.Album{}, general.GetDBError("Duplicate entry", general.DuplicateEntry)
+	}
+	// The message to topic newAlbum is send from the outbox of the database
+	handler.Logger.Printf("Succesfully added new album %v - %v\n", artist, album)
+	return newAlbum, nil
+}
+
+// AddGenreHandler is the handler used for adding a new genre to the database
+func (handler *MusicHandler) AddGenreHandler(response http.ResponseWriter, request *http.Request) {
+	var newGenre ClientGenre
+	if err := general.ReadFromJSON(&newGenre, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request to add a new genre: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for adding new genre %v\n", newGenre.Genre)
+	if _, err := handler.AddGenre(newGenre.Genre); err != nil {
+		switch err.(general.DBError).ErrorCode {
+		case general.DuplicateEntry:
+			http.Error(response, "This genre already exists", http.StatusUnprocessableEntity)
+		case general.InvalidInput:
+			general.SendError(response, http.StatusBadRequest)
+		default:
+			general.SendError(response, http.StatusInternalServerError)
+		}
+		return
+	}
+	succesNewGenre.Inc()
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// AddGenre adds a new genre to the database
+func (handler *MusicHandler) AddGenre(genre string) (general.Genre, error) {
+	if genre == "" {
+		handler.Logger.Printf("Can't add genre without a name\n")
+		return general.Genre{}, general.GetDBError("Missing genre", general.InvalidInput)
+	}
+	newGenre, err := handler.db.AddGenre(genre)
+	if err != nil {
+		if err.(general.DBError).ErrorCode == general.DuplicateEntry {
+			handler.Logger.Printf("Genre %v already exists\n", genre)
+			return general.Genre{}, general.GetDBError("This genre is already in the database", general.DuplicateEntry)
+		}
+		failedNewGenre.Inc()
+		handler.Logger.Printf("[ERROR] Failed to add genre %v due to: %s\n", genre, err)
+		return general.Genre{}, general.ErrorToUnknownDBError(err)
+	}
+	go func(handler *MusicHandler, newGenre general.Genre) {
+		msg, err := general.EncodeEvent("newGenre", newGenre)
+		if err != nil {
+			handler.Logger.Printf("[ERROR] Failed to convert genre %v to bytes: %v\n", newGenre.Name, err)
+			return
+		}
+		handler.SendMessage("newGenre", msg)
+	}(handler, newGenre)
+	handler.Logger.Printf("Succesfully added new genre %v\n", genre)
+	return newGenre, nil
+}
+
+// prefixes are the words in front of the name of an artist that are saved separately
+var prefixes = []string{"A ", "An ", "The "}
+
 func seperatePrefix(name string) (artist, prefix string) {
 	if len(name) < 4 {
 		artist = name
 		return
 	}
-	arrayPrefixes := []string{"A ", "An ", "The "}
-	for _, entry := range arrayPrefixes {
+	for _, entry := range prefixes {
 		if name[0:len(entry)] == entry {
 			prefix = strings.Trim(entry, " ")
 			artist = name[len(entry):]
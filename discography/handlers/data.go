@@ -4,11 +4,11 @@ import (
 	"discography/database"
 	"errors"
 	"general"
+	"general/config"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
-	"github.com/optiopay/kafka/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -16,17 +16,17 @@ import (
 
 const servername string = "discography"
 
-var portLikes string
+var addressLikes string
 
 // NewMusicServer returns a new server for music and a function that starts up the server
-func NewMusicServer(handler *MusicHandler, broker *kafka.Broker, servername, port string) (server *http.Server, start func()) {
-	s, channel, startServer := general.NewServer(servername, port, initRoutes(handler), broker, nil, handler.Logger)
+func NewMusicServer(handler *MusicHandler, bus general.Bus, cfg config.Config) (server *http.Server, start func()) {
+	s, channel, startServer := general.NewServer(cfg, initRoutes(handler), bus, nil, handler.Logger)
 	server = s
 	start = func() {
 		go func() {
 			for service := range channel {
 				if service.Name == "likes" {
-					portLikes = service.Address
+					addressLikes = service.Address
 				}
 			}
 		}()
@@ -45,11 +45,24 @@ func initRoutes(handler *MusicHandler) *mux.Router {
 	getR.Use(general.GetOffsetMaxMiddleware(handler.Logger))
 	getR.Path("/artists/{firstLetter}").HandlerFunc(handler.ArtistStartingWith)
 	getR.Path("/artist/{artist}").HandlerFunc(handler.SongsFromArtist)
+	getR.Path("/artist/{artist}/albums").HandlerFunc(handler.AlbumsFromArtist)
+	getR.Path("/album/{id}").HandlerFunc(handler.AlbumByID)
+	getR.Path("/genre/{genre}").HandlerFunc(handler.SongsFromGenre)
+	getR.Path("/search").HandlerFunc(handler.Search)
 
 	adminR := router.PathPrefix("/admin").Methods(http.MethodPost).Subrouter()
-	adminR.Use(general.GetIsAdminMiddleware(handler.Logger))
+	adminR.Use(general.GetIsCuratorMiddleware(handler.Logger))
 	adminR.Path("/artist").HandlerFunc(handler.AddArtistHandler)
 	adminR.Path("/song").HandlerFunc(handler.AddSongHandler)
+	adminR.Path("/album").HandlerFunc(handler.AddAlbumHandler)
+	adminR.Path("/genre").HandlerFunc(handler.AddGenreHandler)
+	adminR.Path("/tag").HandlerFunc(handler.TagSongHandler)
+	adminR.Path("/album/tag").HandlerFunc(handler.TagAlbumHandler)
+
+	adminDeleteR := router.PathPrefix("/admin").Methods(http.MethodDelete).Subrouter()
+	adminDeleteR.Use(general.GetIsCuratorMiddleware(handler.Logger))
+	adminDeleteR.Path("/tag").HandlerFunc(handler.UntagSongHandler)
+	adminDeleteR.Path("/album/tag").HandlerFunc(handler.UntagAlbumHandler)
 
 	internalR := router.PathPrefix("/intern").Methods(http.MethodGet).Subrouter()
 	internalR.Use(general.GetInternalRequestMiddleware(handler.Logger))
@@ -111,6 +124,51 @@ func NewClientSong(song string, artists ...string) ClientSong {
 	return ClientSong{Artists: artists, Name: song}
 }
 
+// ClientAlbum is the form that is used in posting a new album from the client side.
+// The order of the tracks is the track listing of the album and tracks without artists belong to the artist of the album.
+type ClientAlbum struct {
+	Artist string       `json:"artist" validate:"required"`
+	Name   string       `json:"album" validate:"required"`
+	Tracks []ClientSong `json:"tracks" validate:"required"`
+}
+
+// NewClientAlbum returns a ClientAlbum containing the given data
+func NewClientAlbum(album, artist string, tracks ...ClientSong) ClientAlbum {
+	return ClientAlbum{Artist: artist, Name: album, Tracks: tracks}
+}
+
+// ClientGenre is the form that is used in posting a new genre from the client side
+type ClientGenre struct {
+	Genre string `json:"genre" validate:"required"`
+}
+
+// NewClientGenre returns a ClientGenre containing the given data
+func NewClientGenre(genre string) ClientGenre {
+	return ClientGenre{Genre: genre}
+}
+
+// ClientTag is the form that is used in tagging a song with a genre or removing this tag from the client side
+type ClientTag struct {
+	SongID int    `json:"song" validate:"required"`
+	Genre  string `json:"genre" validate:"required"`
+}
+
+// NewClientTag returns a ClientTag containing the given data
+func NewClientTag(songID int, genre string) ClientTag {
+	return ClientTag{SongID: songID, Genre: genre}
+}
+
+// ClientAlbumTag is the form that is used in tagging an album with a genre or removing this tag from the client side
+type ClientAlbumTag struct {
+	AlbumID int    `json:"album" validate:"required"`
+	Genre   string `json:"genre" validate:"required"`
+}
+
+// NewClientAlbumTag returns a ClientAlbumTag containing the given data
+func NewClientAlbumTag(albumID int, genre string) ClientAlbumTag {
+	return ClientAlbumTag{AlbumID: albumID, Genre: genre}
+}
+
 var (
 	badRequests = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "music_badRequests_total",
@@ -151,3 +209,59 @@ var (
 		Help: "The total number of failed requests to add a new song to the database",
 	})
 )
+
+var (
+	succesNewAlbum = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "admin_new_album_total",
+		Help: "The total number of succesfull requests to add a new album to the database",
+	})
+)
+
+var (
+	failedNewAlbum = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "admin_new_album_denied_total",
+		Help: "The total number of failed requests to add a new album to the database",
+	})
+)
+
+var (
+	succesNewGenre = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "admin_new_genre_total",
+		Help: "The total number of succesfull requests to add a new genre to the database",
+	})
+)
+
+var (
+	failedNewGenre = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "admin_new_genre_denied_total",
+		Help: "The total number of failed requests to add a new genre to the database",
+	})
+)
+
+var (
+	succesChangeTag = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "admin_change_genre_tag_total",
+		Help: "The total number of succesfull requests to add or remove a genre of a song",
+	})
+)
+
+var (
+	failedChangeTag = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "admin_change_genre_tag_denied_total",
+		Help: "The total number of failed requests to add or remove a genre of a song",
+	})
+)
+
+var (
+	syncedSongs = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "discography_spotify_synced_songs_total",
+		Help: "The total number of songs that are added by synchronising with Spotify",
+	})
+)
+
+var (
+	failedSpotifySync = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "discography_spotify_sync_failed_total",
+		Help: "The total number of failures while synchronising artists with Spotify",
+	})
+)
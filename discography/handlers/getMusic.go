@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"general"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/gorilla/mux"
@@ -71,11 +72,16 @@ func (handler *MusicHandler) SongsFromArtist(response http.ResponseWriter, reque
 			return
 		}
 		userID := user.(general.Credentials).ID
-		resp, err := handler.GETRequest(fmt.Sprintf("http://localhost%v/intern/preference/%v/%v", portLikes, userID, nameArtist))
-		if err != nil || resp.StatusCode != http.StatusOK {
+		resp, err := handler.GETRequest(fmt.Sprintf("%v/intern/preference/%v/%v", addressLikes, userID, nameArtist))
+		if err != nil {
 			handler.Logger.Printf("Failed to obtain preferences of user #%v for artist %v due to: %s\n", userID, nameArtist, err)
 			return
 		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			handler.Logger.Printf("Failed to obtain preferences of user #%v for artist %v due to statuscode: %v\n", userID, nameArtist, resp.StatusCode)
+			return
+		}
 		if err = general.ReadFromJSONNoValidation(&preferences, resp.Body); err != nil {
 			handler.Logger.Printf("[ERROR] Failed to deserialze map of songsID and preferences due to: %s\n", err)
 		}
@@ -121,3 +127,115 @@ func (handler *MusicHandler) SongsFromArtist(response http.ResponseWriter, reque
 		handler.Logger.Printf("[ERROR] %s\n", err)
 	}
 }
+
+// AlbumsFromArtist returns a set of albums from the requested artist. The tracks of the albums are not included.
+func (handler *MusicHandler) AlbumsFromArtist(response http.ResponseWriter, request *http.Request) {
+	nameArtist := mux.Vars(request)["artist"]
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	handler.Logger.Printf("Received call for albums of %v and limit %v,%v\n", nameArtist, offset, max)
+	results, errorSearch := handler.db.GetAlbumsFromArtist(nameArtist, offset, max+1)
+	if errorSearch != nil {
+		if errorSearch.(general.DBError).ErrorCode == general.InvalidOffsetMax {
+			badRequests.Inc()
+			handler.Logger.Printf("Request with invalid  values for query parameters: %v,%v", offset, max)
+			general.SendError(response, http.StatusBadRequest)
+			return
+		}
+		failureSearchRequest.Inc()
+		handler.Logger.Printf("[Error] Can't find albums of %v and limit %v,%v due to: %s\n", nameArtist, offset, max, errorSearch)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	if len(results) == 0 {
+		handler.Logger.Printf("Failed to find albums of %v and limit %v,%v\n", nameArtist, offset, max)
+		failureSearchRequest.Inc()
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	handler.Logger.Printf("Succesfully found %v albums of %v and limit %v,%v\n", len(results), nameArtist, offset, max)
+	hasNext := (len(results) > max)
+	if hasNext {
+		results = results[0:max]
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err := general.WriteToJSON(&general.MultipleAlbums{Data: results, HasNext: hasNext}, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// AlbumByID returns the album that belongs to the given ID together with its track listing
+func (handler *MusicHandler) AlbumByID(response http.ResponseWriter, request *http.Request) {
+	albumIDstring := mux.Vars(request)["id"]
+	albumID, err := strconv.Atoi(albumIDstring)
+	if err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Received request with invalid id %v results in: %s\n", albumIDstring, err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	album, searchErr := handler.db.FindAlbumByID(albumID)
+	if searchErr != nil {
+		if searchErr.(general.DBError).ErrorCode != general.NotFoundError {
+			failureSearchRequest.Inc()
+			handler.Logger.Printf("[ERROR] Failed to search DB for album #%v due to: %s\n", albumID, searchErr)
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("Can't find album #%v\n", albumID)
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	handler.Logger.Printf("Succesfully found album #%v: %v - %v\n", albumID, album.Artist.Name, album.Name)
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err = general.WriteToJSON(&album, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// SongsFromGenre returns a set of songs that are tagged with the requested genre
+func (handler *MusicHandler) SongsFromGenre(response http.ResponseWriter, request *http.Request) {
+	genre := mux.Vars(request)["genre"]
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	handler.Logger.Printf("Received call for songs of genre %v and limit %v,%v\n", genre, offset, max)
+	results, errorSearch := handler.db.GetSongsFromGenre(genre, offset, max+1)
+	if errorSearch != nil {
+		switch errorSearch.(general.DBError).ErrorCode {
+		case general.InvalidOffsetMax:
+			badRequests.Inc()
+			handler.Logger.Printf("Request with invalid  values for query parameters: %v,%v", offset, max)
+			general.SendError(response, http.StatusBadRequest)
+		case general.NotFoundError:
+			handler.Logger.Printf("Failed to find songs of genre %v and limit %v,%v\n", genre, offset, max)
+			failureSearchRequest.Inc()
+			general.SendError(response, http.StatusNotFound)
+		default:
+			failureSearchRequest.Inc()
+			handler.Logger.Printf("[Error] Can't find songs of genre %v and limit %v,%v due to: %s\n", genre, offset, max, errorSearch)
+			general.SendError(response, http.StatusInternalServerError)
+		}
+		return
+	}
+	if len(results) == 0 {
+		handler.Logger.Printf("Failed to find songs of genre %v and limit %v,%v\n", genre, offset, max)
+		failureSearchRequest.Inc()
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	handler.Logger.Printf("Succesfully found %v songs of genre %v and limit %v,%v\n", len(results), genre, offset, max)
+	hasNext := (len(results) > max)
+	if hasNext {
+		results = results[0:max]
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err := general.WriteToJSON(&general.MultipleSongs{Data: results, HasNext: hasNext}, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
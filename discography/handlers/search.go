@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"general"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// searchCandidates is the maximum amount of artists and songs that will be requested from the database for ranking
+const searchCandidates int = 100
+
+// minimalSearchScore is the minimal similarity between query and name that is needed to be a search result
+const minimalSearchScore float64 = 0.6
+
+// Search returns artists and songs that looks like the query. The results are ordered by how well they match the query.
+func (handler *MusicHandler) Search(response http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query().Get("q")
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	handler.Logger.Printf("Received call for searching %v and limit %v,%v\n", query, offset, max)
+	results, err := handler.SearchMusic(query)
+	if err != nil {
+		if err.(general.DBError).ErrorCode == general.InvalidInput {
+			badRequests.Inc()
+			handler.Logger.Printf("Received search request without query\n")
+			general.SendError(response, http.StatusBadRequest)
+			return
+		}
+		failureSearchRequest.Inc()
+		handler.Logger.Printf("[Error] Can't search for %v due to: %s\n", query, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	if len(results) <= offset {
+		handler.Logger.Printf("Failed to find music for %v and limit %v,%v\n", query, offset, max)
+		failureSearchRequest.Inc()
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	handler.Logger.Printf("Succesfully found %v results for %v\n", len(results), query)
+	hasNext := (len(results) > offset+max)
+	results = results[offset:int(math.Min(float64(offset+max), float64(len(results))))]
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err = general.WriteToJSON(&general.Music{Data: results, HasNext: hasNext}, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// SearchMusic returns all artists and songs that looks like the query, ordered by how well they match the query.
+// The results are of type general.Artist or general.Song. Matching is case-insensitive and tolerates typos.
+func (handler *MusicHandler) SearchMusic(query string) ([]interface{}, error) {
+	fullQuery, queryWithoutPrefix := normalizeQuery(query)
+	if queryWithoutPrefix == "" {
+		return nil, general.GetDBError("Missing query", general.InvalidInput)
+	}
+	artists, err := handler.db.SearchArtists(queryWithoutPrefix, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	songs, err := handler.db.SearchSongs(queryWithoutPrefix, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	ranking := make([]rankedResult, 0, len(artists)+len(songs))
+	for _, artist := range artists {
+		nameWithPrefix := strings.TrimSpace(artist.Prefix + " " + artist.Name)
+		score := math.Max(similarity(queryWithoutPrefix, artist.Name), similarity(fullQuery, nameWithPrefix))
+		if score >= minimalSearchScore {
+			ranking = append(ranking, rankedResult{score: score, name: artist.Name, music: artist})
+		}
+	}
+	for _, song := range songs {
+		score := math.Max(similarity(queryWithoutPrefix, song.Name), similarity(fullQuery, song.Name))
+		if score >= minimalSearchScore {
+			ranking = append(ranking, rankedResult{score: score, name: song.Name, music: song})
+		}
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		if ranking[i].score != ranking[j].score {
+			return ranking[i].score > ranking[j].score
+		}
+		return ranking[i].name < ranking[j].name
+	})
+	results := make([]interface{}, 0, len(ranking))
+	for _, result := range ranking {
+		results = append(results, result.music)
+	}
+	return results, nil
+}
+
+type rankedResult struct {
+	score float64
+	name  string
+	music interface{}
+}
+
+// normalizeQuery lowercases the query and removes redundant whitespace.
+// It also returns the query without the prefix in the same way as seperatePrefix does for names of artists.
+func normalizeQuery(query string) (fullQuery, queryWithoutPrefix string) {
+	fullQuery = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	queryWithoutPrefix = fullQuery
+	for _, entry := range prefixes {
+		entry = strings.ToLower(entry)
+		if strings.HasPrefix(fullQuery, entry) && len(fullQuery) > len(entry) {
+			queryWithoutPrefix = fullQuery[len(entry):]
+			return
+		}
+	}
+	return
+}
+
+// similarity returns a score between 0 and 1 that shows how much the name looks like the (lowercase) query.
+// Names that contain the query score at least 0.8. Otherwise the score depends on the edit distance between
+// the query and the name or between the query and a part of the name with the same amount of words.
+func similarity(query, name string) float64 {
+	name = strings.ToLower(name)
+	switch {
+	case name == query:
+		return 1
+	case strings.HasPrefix(name, query):
+		return 0.9
+	case strings.Contains(name, query):
+		return 0.8
+	}
+	score := 1 - editRatio(query, name)
+	wordsQuery, wordsName := len(strings.Fields(query)), strings.Fields(name)
+	for start := 0; start+wordsQuery <= len(wordsName); start++ {
+		part := strings.Join(wordsName[start:start+wordsQuery], " ")
+		score = math.Max(score, 0.8*(1-editRatio(query, part)))
+	}
+	return score
+}
+
+// editRatio returns the Levenshtein distance between both strings relative to the length of the longest string
+func editRatio(first, second string) float64 {
+	a, b := []rune(first), []rune(second)
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return float64(previous[len(b)]) / math.Max(float64(len(a)), float64(len(b)))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
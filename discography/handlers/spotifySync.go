@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"discography/database"
+	"discography/spotify"
+	"general"
+	"time"
+)
+
+// StartSpotifySync synchronises the database with Spotify right away and after that every interval until stop is called
+func (handler *MusicHandler) StartSpotifySync(client spotify.Client, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			handler.SyncSpotify(client)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// SyncSpotify adds the missing songs of every artist with a link to Spotify to the database. It returns the amount of added songs.
+func (handler *MusicHandler) SyncSpotify(client spotify.Client) int {
+	linkedArtists, err := handler.db.GetLinkedArtists()
+	if err != nil {
+		failedSpotifySync.Inc()
+		handler.Logger.Printf("[ERROR] Can't synchronise with Spotify due to failure of finding linked artists: %s\n", err)
+		return 0
+	}
+	handler.Logger.Printf("Start synchronising %v artists with Spotify\n", len(linkedArtists))
+	added := 0
+	for _, linkedArtist := range linkedArtists {
+		addedSongsArtist, err := handler.syncArtist(client, linkedArtist)
+		added += addedSongsArtist
+		if err != nil {
+			failedSpotifySync.Inc()
+			handler.Logger.Printf("[ERROR] Failed to synchronise artist %v with Spotify: %s\n", linkedArtist.Artist.Name, err)
+			continue
+		}
+	}
+	handler.Logger.Printf("Succesfully synchronised with Spotify: %v songs are added\n", added)
+	return added
+}
+
+// syncArtist adds the songs of the albums of the given artist that are missing in the database.
+// Only the tracks to which the artist contributes are added.
+func (handler *MusicHandler) syncArtist(client spotify.Client, linkedArtist database.LinkedArtist) (int, error) {
+	spotifyID, err := spotify.ArtistID(linkedArtist.LinkSpotify)
+	if err != nil {
+		return 0, err
+	}
+	albums, err := client.GetAlbums(spotifyID)
+	if err != nil {
+		return 0, err
+	}
+	nameArtist := linkedArtist.Artist.Name
+	if linkedArtist.Artist.Prefix != "" {
+		nameArtist = linkedArtist.Artist.Prefix + " " + nameArtist
+	}
+	added := 0
+	for _, album := range albums {
+		tracks, err := client.GetTracks(album.ID)
+		if err != nil {
+			return added, err
+		}
+		for _, track := range tracks {
+			artists := make([]string, 0, len(track.Artists))
+			contributes := false
+			for _, artist := range track.Artists {
+				// The name of the linked artist in the database is leading
+				if artist.ID == spotifyID {
+					contributes = true
+					artists = append(artists, nameArtist)
+					continue
+				}
+				artists = append(artists, artist.Name)
+			}
+			if !contributes {
+				continue
+			}
+			nameFirstArtist, _ := seperatePrefix(artists[0])
+			_, err := handler.db.FindSongByName(nameFirstArtist, track.Name)
+			if err == nil {
+				continue
+			}
+			if err.(general.DBError).ErrorCode != general.NotFoundError {
+				return added, err
+			}
+			if _, err = handler.AddSong(track.Name, artists...); err != nil {
+				return added, err
+			}
+			syncedSongs.Inc()
+			added++
+		}
+	}
+	return added, nil
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"general"
+	"net/http"
+)
+
+// TagSongHandler is the handler used for tagging a song with a genre
+func (handler *MusicHandler) TagSongHandler(response http.ResponseWriter, request *http.Request) {
+	var tag ClientTag
+	if err := general.ReadFromJSON(&tag, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request to tag a song: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for tagging song #%v with genre %v\n", tag.SongID, tag.Genre)
+	if _, err := handler.TagSong(tag.SongID, tag.Genre); err != nil {
+		sendTagError(response, "song", err)
+		return
+	}
+	succesChangeTag.Inc()
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// UntagSongHandler is the handler used for removing a genre from a song
+func (handler *MusicHandler) UntagSongHandler(response http.ResponseWriter, request *http.Request) {
+	var tag ClientTag
+	if err := general.ReadFromJSON(&tag, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request to remove a tag of a song: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for removing genre %v from song #%v\n", tag.Genre, tag.SongID)
+	if _, err := handler.UntagSong(tag.SongID, tag.Genre); err != nil {
+		sendTagError(response, "song", err)
+		return
+	}
+	succesChangeTag.Inc()
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// TagAlbumHandler is the handler used for tagging an album with a genre
+func (handler *MusicHandler) TagAlbumHandler(response http.ResponseWriter, request *http.Request) {
+	var tag ClientAlbumTag
+	if err := general.ReadFromJSON(&tag, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request to tag an album: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for tagging album #%v with genre %v\n", tag.AlbumID, tag.Genre)
+	if _, err := handler.TagAlbum(tag.AlbumID, tag.Genre); err != nil {
+		sendTagError(response, "album", err)
+		return
+	}
+	succesChangeTag.Inc()
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// UntagAlbumHandler is the handler used for removing a genre from an album
+func (handler *MusicHandler) UntagAlbumHandler(response http.ResponseWriter, request *http.Request) {
+	var tag ClientAlbumTag
+	if err := general.ReadFromJSON(&tag, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request to remove a tag of an album: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for removing genre %v from album #%v\n", tag.Genre, tag.AlbumID)
+	if _, err := handler.UntagAlbum(tag.AlbumID, tag.Genre); err != nil {
+		sendTagError(response, "album", err)
+		return
+	}
+	succesChangeTag.Inc()
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// sendTagError sends the response for an error of tagging a song or album
+func sendTagError(response http.ResponseWriter, music string, err error) {
+	switch err.(general.DBError).ErrorCode {
+	case general.NotFoundError:
+		general.SendError(response, http.StatusNotFound)
+	case general.DuplicateEntry:
+		http.Error(response, "This "+music+" is already tagged with this genre", http.StatusUnprocessableEntity)
+	case general.InvalidInput:
+		general.SendError(response, http.StatusBadRequest)
+	default:
+		general.SendError(response, http.StatusInternalServerError)
+	}
+}
+
+// TagSong tags the song with the given genre. The genre has to exist in the database.
+// It returns a NotFoundError if the song or the genre doesn't exist.
+func (handler *MusicHandler) TagSong(songID int, genre string) (general.GenreTag, error) {
+	foundGenre, err := handler.findGenreForTag("song", songID, genre)
+	if err != nil {
+		return general.GenreTag{}, err
+	}
+	if err = handler.db.AddGenreToSong(songID, foundGenre); err != nil {
+		switch err.(general.DBError).ErrorCode {
+		case general.MissingForeignKey:
+			handler.Logger.Printf("Can't tag song #%v with genre %v because the song doesn't exist\n", songID, genre)
+			return general.GenreTag{}, general.GetDBError("Song doesn't exist", general.NotFoundError)
+		case general.DuplicateEntry:
+			handler.Logger.Printf("Song #%v is already tagged with genre %v\n", songID, genre)
+			return general.GenreTag{}, general.GetDBError("Duplicate entry", general.DuplicateEntry)
+		default:
+			failedChangeTag.Inc()
+			handler.Logger.Printf("[ERROR] Failed to tag song #%v with genre %v due to: %s\n", songID, genre, err)
+			return general.GenreTag{}, general.ErrorToUnknownDBError(err)
+		}
+	}
+	tag := general.NewGenreTag(songID, foundGenre)
+	handler.sendTag("tagSong", tag)
+	handler.Logger.Printf("Succesfully tagged song #%v with genre %v\n", songID, genre)
+	return tag, nil
+}
+
+// TagAlbum tags the album with the given genre. The genre has to exist in the database.
+// It returns a NotFoundError if the album or the genre doesn't exist.
+func (handler *MusicHandler) TagAlbum(albumID int, genre string) (general.AlbumGenreTag, error) {
+	foundGenre, err := handler.findGenreForTag("album", albumID, genre)
+	if err != nil {
+		return general.AlbumGenreTag{}, err
+	}
+	if err = handler.db.AddGenreToAlbum(albumID, foundGenre); err != nil {
+		switch err.(general.DBError).ErrorCode {
+		case general.MissingForeignKey:
+			handler.Logger.Printf("Can't tag album #%v with genre %v because the album doesn't exist\n", albumID, genre)
+			return general.AlbumGenreTag{}, general.GetDBError("Album doesn't exist", general.NotFoundError)
+		case general.DuplicateEntry:
+			handler.Logger.Printf("Album #%v is already tagged with genre %v\n", albumID, genre)
+			return general.AlbumGenreTag{}, general.GetDBError("Duplicate entry", general.DuplicateEntry)
+		default:
+			failedChangeTag.Inc()
+			handler.Logger.Printf("[ERROR] Failed to tag album #%v with genre %v due to: %s\n", albumID, genre, err)
+			return general.AlbumGenreTag{}, general.ErrorToUnknownDBError(err)
+		}
+	}
+	tag := general.NewAlbumGenreTag(albumID, foundGenre)
+	handler.sendTag("tagAlbum", tag)
+	handler.Logger.Printf("Succesfully tagged album #%v with genre %v\n", albumID, genre)
+	return tag, nil
+}
+
+// UntagAlbum removes the given genre from the album.
+// It returns a NotFoundError if the genre doesn't exist or if the album isn't tagged with this genre.
+func (handler *MusicHandler) UntagAlbum(albumID int, genre string) (general.AlbumGenreTag, error) {
+	foundGenre, err := handler.findGenreForTag("album", albumID, genre)
+	if err != nil {
+		return general.AlbumGenreTag{}, err
+	}
+	if err = handler.db.RemoveGenreFromAlbum(albumID, foundGenre); err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			handler.Logger.Printf("Album #%v isn't tagged with genre %v\n", albumID, genre)
+			return general.AlbumGenreTag{}, err
+		}
+		failedChangeTag.Inc()
+		handler.Logger.Printf("[ERROR] Failed to remove genre %v from album #%v due to: %s\n", genre, albumID, err)
+		return general.AlbumGenreTag{}, general.ErrorToUnknownDBError(err)
+	}
+	tag := general.NewAlbumGenreTag(albumID, foundGenre)
+	handler.sendTag("untagAlbum", tag)
+	handler.Logger.Printf("Succesfully removed genre %v from album #%v\n", genre, albumID)
+	return tag, nil
+}
+
+// UntagSong removes the given genre from the song.
+// It returns a NotFoundError if the genre doesn't exist or if the song isn't tagged with this genre.
+func (handler *MusicHandler) UntagSong(songID int, genre string) (general.GenreTag, error) {
+	foundGenre, err := handler.findGenreForTag("song", songID, genre)
+	if err != nil {
+		return general.GenreTag{}, err
+	}
+	if err = handler.db.RemoveGenreFromSong(songID, foundGenre); err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			handler.Logger.Printf("Song #%v isn't tagged with genre %v\n", songID, genre)
+			return general.GenreTag{}, err
+		}
+		failedChangeTag.Inc()
+		handler.Logger.Printf("[ERROR] Failed to remove genre %v from song #%v due to: %s\n", genre, songID, err)
+		return general.GenreTag{}, general.ErrorToUnknownDBError(err)
+	}
+	tag := general.NewGenreTag(songID, foundGenre)
+	handler.sendTag("untagSong", tag)
+	handler.Logger.Printf("Succesfully removed genre %v from song #%v\n", genre, songID)
+	return tag, nil
+}
+
+func (handler *MusicHandler) findGenreForTag(music string, id int, genre string) (general.Genre, error) {
+	if id <= 0 || genre == "" {
+		handler.Logger.Printf("Can't change tags with invalid %v #%v or genre %v\n", music, id, genre)
+		return general.Genre{}, general.GetDBError("Missing "+music+" or genre", general.InvalidInput)
+	}
+	foundGenre, err := handler.db.FindGenreByName(genre)
+	if err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			handler.Logger.Printf("Can't find genre %v\n", genre)
+			return general.Genre{}, err
+		}
+		failedChangeTag.Inc()
+		handler.Logger.Printf("[ERROR] Failed to search for genre %v due to: %s\n", genre, err)
+		return general.Genre{}, general.ErrorToUnknownDBError(err)
+	}
+	return foundGenre, nil
+}
+
+// sendTag sends the GenreTag or AlbumGenreTag to the topic
+func (handler *MusicHandler) sendTag(topic string, tag interface{}) {
+	go func(handler *MusicHandler, tag interface{}) {
+		msg, err := general.EncodeEvent(topic, tag)
+		if err != nil {
+			handler.Logger.Printf("[ERROR] Failed to convert tag %v of topic %v to bytes: %v\n", tag, topic, err)
+			return
+		}
+		handler.SendMessage(topic, msg)
+	}(handler, tag)
+}
@@ -1,40 +1,58 @@
 package main
 
 import (
+	"flag"
 	"general"
+	"general/config"
 	"log"
 	"os"
+	"time"
 
 	"discography/database"
 	"discography/handlers"
-
-	"github.com/optiopay/kafka/v2"
+	"discography/spotify"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-const port string = ":9002"
-const servername string = "discography"
+var configPath = flag.String("config", "config.yaml", "Path to the configuration file of this service")
 
 func main() {
-	logger := log.New(os.Stdout, servername, log.LstdFlags|log.Lshortfile)
-	db, err := general.ConnectToMYSQL(logger, servername, "adminMusicApp:admin@tcp(127.0.0.1:3306)/discography")
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[ERROR] Can't load configuration due to: %s\n", err)
+	}
+	logger := log.New(os.Stdout, cfg.Servername, log.LstdFlags|log.Lshortfile)
+	if err = general.ConfigureKeys(logger, cfg.Keys); err != nil {
+		logger.Fatalf("[ERROR] Can't load keys due to: %s\n", err)
+	}
+	go general.WatchKeys(logger, *configPath)
+	db, err := general.ConnectToMYSQL(logger, cfg)
 	if err != nil {
 		logger.Printf("Stop starting server")
 		return
 	}
 	defer db.Close()
-	broker, closeBroker := general.ConnectToKafka(logger, servername)
-	defer closeBroker()
-	if topicErr := general.CreateTopics(broker, logger, "newArtist", "newSong"); topicErr != nil {
+	bus, closeBus := general.ConnectToBus(logger, cfg)
+	defer closeBus()
+	if topicErr := bus.CreateTopics("newArtist", "newSong", "newAlbum", "newGenre", "tagSong", "untagSong", "tagAlbum", "untagAlbum"); topicErr != nil {
 		logger.Fatalf("[ERROR] Failed to create topics due to: %s\n", topicErr)
 	}
 	logger.Printf("Handler is ready for sending get requests")
-	producer := broker.Producer(kafka.NewProducerConf())
-	handler, err := handlers.NewMusicHandler(logger, database.NewMusicDB(db), general.GetSendMessage(producer), nil)
+	// New artists, songs and albums are send from the outbox that is written together with the artist, song or album
+	stopRelay := general.NewOutboxRelay(logger, db, bus).Start()
+	defer stopRelay()
+	handler, err := handlers.NewMusicHandler(logger, database.NewMusicDB(db), bus.Publish, nil)
 	if err != nil {
 		logger.Fatalf("[ERROR] Can't create handler due to: %s\n", err)
 	}
-	_, startServer := handlers.NewMusicServer(handler, broker, servername, port)
+	// The synchronisation with Spotify is only started if the credentials of a Spotify app are given
+	if cfg.Spotify.ClientID != "" {
+		client := spotify.NewHTTPClient("https://api.spotify.com", "https://accounts.spotify.com/api/token", cfg.Spotify.ClientID, cfg.Spotify.ClientSecret, nil)
+		stopSync := handler.StartSpotifySync(client, 24*time.Hour)
+		defer stopSync()
+	}
+	_, startServer := handlers.NewMusicServer(handler, bus, cfg)
 	startServer()
 }
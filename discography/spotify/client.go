@@ -0,0 +1,133 @@
+package spotify
+
+import (
+	"fmt"
+	"general"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPClient obtains the catalog from the Spotify Web API. It authenticates with the client credentials flow.
+type HTTPClient struct {
+	apiURL, tokenURL       string
+	clientID, clientSecret string
+	client                 *http.Client
+	lock                   sync.Mutex
+	accessToken            string
+	accessTokenExpiresAt   time.Time
+}
+
+// NewHTTPClient returns a HTTPClient that sends requests to the api on apiURL and obtains access tokens from tokenURL.
+// If client is nil, then a client with a timeout of 10 seconds will be used
+func NewHTTPClient(apiURL, tokenURL, clientID, clientSecret string, client *http.Client) *HTTPClient {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPClient{apiURL: strings.TrimSuffix(apiURL, "/"), tokenURL: tokenURL, clientID: clientID, clientSecret: clientSecret, client: client}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type albumsPage struct {
+	Items []Album `json:"items"`
+	Next  string  `json:"next"`
+}
+
+type tracksPage struct {
+	Items []Track `json:"items"`
+	Next  string  `json:"next"`
+}
+
+// GetAlbums returns all the albums and singles of the artist with the given Spotify id
+func (spotify *HTTPClient) GetAlbums(artistID string) ([]Album, error) {
+	albums := make([]Album, 0, 20)
+	next := fmt.Sprintf("%v/v1/artists/%v/albums?include_groups=album,single&limit=50", spotify.apiURL, url.PathEscape(artistID))
+	for next != "" {
+		var page albumsPage
+		if err := spotify.get(next, &page); err != nil {
+			return nil, err
+		}
+		albums = append(albums, page.Items...)
+		next = page.Next
+	}
+	return albums, nil
+}
+
+// GetTracks returns all the tracks of the album with the given Spotify id
+func (spotify *HTTPClient) GetTracks(albumID string) ([]Track, error) {
+	tracks := make([]Track, 0, 20)
+	next := fmt.Sprintf("%v/v1/albums/%v/tracks?limit=50", spotify.apiURL, url.PathEscape(albumID))
+	for next != "" {
+		var page tracksPage
+		if err := spotify.get(next, &page); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, page.Items...)
+		next = page.Next
+	}
+	return tracks, nil
+}
+
+// get sends an authorized get request to the given address and reads the response into result
+func (spotify *HTTPClient) get(address string, result interface{}) error {
+	token, err := spotify.token()
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := spotify.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		spotify.lock.Lock()
+		spotify.accessToken = ""
+		spotify.lock.Unlock()
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Spotify responded to %v with statuscode %v", address, response.StatusCode)
+	}
+	return general.ReadFromJSONNoValidation(result, response.Body)
+}
+
+// token returns an access token. A new access token will be requested if there is no valid access token
+func (spotify *HTTPClient) token() (string, error) {
+	spotify.lock.Lock()
+	defer spotify.lock.Unlock()
+	if spotify.accessToken != "" && time.Now().Before(spotify.accessTokenExpiresAt) {
+		return spotify.accessToken, nil
+	}
+	request, err := http.NewRequest(http.MethodPost, spotify.tokenURL, strings.NewReader(url.Values{"grant_type": {"client_credentials"}}.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(spotify.clientID, spotify.clientSecret)
+	response, err := spotify.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Spotify refused to give an access token with statuscode %v", response.StatusCode)
+	}
+	var token tokenResponse
+	if err = general.ReadFromJSONNoValidation(&token, response.Body); err != nil {
+		return "", err
+	}
+	// The token is renewed a minute before it expires
+	spotify.accessToken = token.AccessToken
+	spotify.accessTokenExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return spotify.accessToken, nil
+}
@@ -0,0 +1,52 @@
+package spotify
+
+import (
+	"errors"
+	"strings"
+)
+
+// Album is an album or single of an artist on Spotify
+type Album struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Track is a track of an album on Spotify
+type Track struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	TrackNumber int           `json:"track_number"`
+	Artists     []TrackArtist `json:"artists"`
+}
+
+// TrackArtist is an artist that contributes to a track on Spotify
+type TrackArtist struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Client is an interface for obtaining the catalog of artists from Spotify
+type Client interface {
+	GetAlbums(artistID string) ([]Album, error)
+	GetTracks(albumID string) ([]Track, error)
+}
+
+// ArtistID returns the Spotify id of an artist from a link to this artist.
+// The link can be an url (https://open.spotify.com/artist/{id}), an uri (spotify:artist:{id}) or the id itself.
+func ArtistID(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if index := strings.Index(link, "?"); index != -1 {
+		link = link[:index]
+	}
+	link = strings.TrimSuffix(link, "/")
+	switch {
+	case strings.HasPrefix(link, "spotify:artist:"):
+		link = strings.TrimPrefix(link, "spotify:artist:")
+	case strings.Contains(link, "/artist/"):
+		link = link[strings.LastIndex(link, "/artist/")+len("/artist/"):]
+	}
+	if link == "" || strings.ContainsAny(link, "/: ") {
+		return "", errors.New("Invalid link to a Spotify artist")
+	}
+	return link, nil
+}
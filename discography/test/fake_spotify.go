@@ -0,0 +1,142 @@
+package test
+
+import (
+	"discography/spotify"
+	"fmt"
+	"general"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// fakeSpotify is a local stand-in for the Spotify endpoints that are used by the spotify.HTTPClient.
+// It can be started with httptest.NewServer in order to test the synchronisation offline.
+type fakeSpotify struct {
+	clientID, clientSecret string
+	pageSize               int
+	router                 *mux.Router
+	lock                   sync.RWMutex
+	tokens                 map[string]bool
+	albums                 map[string][]spotify.Album
+	tracks                 map[string][]spotify.Track
+}
+
+type fakeTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type fakeAlbumsPage struct {
+	Items []spotify.Album `json:"items"`
+	Next  string          `json:"next"`
+}
+
+type fakeTracksPage struct {
+	Items []spotify.Track `json:"items"`
+	Next  string          `json:"next"`
+}
+
+// newFakeSpotify returns a fakeSpotify that only gives access tokens for the given client credentials.
+// Results are send in pages of at most pageSize items, so that the pagination of clients will be used.
+func newFakeSpotify(clientID, clientSecret string, pageSize int) *fakeSpotify {
+	fake := &fakeSpotify{clientID: clientID, clientSecret: clientSecret, pageSize: pageSize, tokens: make(map[string]bool), albums: make(map[string][]spotify.Album), tracks: make(map[string][]spotify.Track)}
+	router := mux.NewRouter()
+	router.Path("/api/token").Methods(http.MethodPost).HandlerFunc(fake.token)
+	router.Path("/v1/artists/{id}/albums").Methods(http.MethodGet).HandlerFunc(fake.getAlbums)
+	router.Path("/v1/albums/{id}/tracks").Methods(http.MethodGet).HandlerFunc(fake.getTracks)
+	fake.router = router
+	return fake
+}
+
+// AddAlbum adds an album with the given tracks to the catalog of the artist with the given Spotify id
+func (fake *fakeSpotify) AddAlbum(artistID string, album spotify.Album, tracks ...spotify.Track) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.albums[artistID] = append(fake.albums[artistID], album)
+	fake.tracks[album.ID] = append(fake.tracks[album.ID], tracks...)
+}
+
+// ServeHTTP handles the requests to the fake Spotify endpoints
+func (fake *fakeSpotify) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	fake.router.ServeHTTP(response, request)
+}
+
+func (fake *fakeSpotify) token(response http.ResponseWriter, request *http.Request) {
+	clientID, clientSecret, ok := request.BasicAuth()
+	if !ok || clientID != fake.clientID || clientSecret != fake.clientSecret || request.FormValue("grant_type") != "client_credentials" {
+		general.SendError(response, http.StatusUnauthorized)
+		return
+	}
+	fake.lock.Lock()
+	accessToken := fmt.Sprintf("fake-token-%v", len(fake.tokens)+1)
+	fake.tokens[accessToken] = true
+	fake.lock.Unlock()
+	response.Header().Set("Content-Type", "application/json")
+	general.WriteToJSON(&fakeTokenResponse{AccessToken: accessToken, ExpiresIn: 3600}, response)
+}
+
+func (fake *fakeSpotify) authorized(request *http.Request) bool {
+	authorization := request.Header.Get("Authorization")
+	if len(authorization) <= len("Bearer ") {
+		return false
+	}
+	fake.lock.RLock()
+	defer fake.lock.RUnlock()
+	return fake.tokens[authorization[len("Bearer "):]]
+}
+
+// page returns the bounds of the requested page and the url of the next page
+func (fake *fakeSpotify) page(request *http.Request, total int) (start, end int, next string) {
+	start, _ = strconv.Atoi(request.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(request.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > fake.pageSize {
+		limit = fake.pageSize
+	}
+	if start > total {
+		start = total
+	}
+	end = start + limit
+	if end >= total {
+		return start, total, ""
+	}
+	query := request.URL.Query()
+	query.Set("offset", strconv.Itoa(end))
+	query.Set("limit", strconv.Itoa(limit))
+	return start, end, fmt.Sprintf("http://%v%v?%v", request.Host, request.URL.Path, query.Encode())
+}
+
+func (fake *fakeSpotify) getAlbums(response http.ResponseWriter, request *http.Request) {
+	if !fake.authorized(request) {
+		general.SendError(response, http.StatusUnauthorized)
+		return
+	}
+	fake.lock.RLock()
+	albums, ok := fake.albums[mux.Vars(request)["id"]]
+	fake.lock.RUnlock()
+	if !ok {
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	start, end, next := fake.page(request, len(albums))
+	response.Header().Set("Content-Type", "application/json")
+	general.WriteToJSON(&fakeAlbumsPage{Items: albums[start:end], Next: next}, response)
+}
+
+func (fake *fakeSpotify) getTracks(response http.ResponseWriter, request *http.Request) {
+	if !fake.authorized(request) {
+		general.SendError(response, http.StatusUnauthorized)
+		return
+	}
+	fake.lock.RLock()
+	tracks, ok := fake.tracks[mux.Vars(request)["id"]]
+	fake.lock.RUnlock()
+	if !ok {
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	start, end, next := fake.page(request, len(tracks))
+	response.Header().Set("Content-Type", "application/json")
+	general.WriteToJSON(&fakeTracksPage{Items: tracks[start:end], Next: next}, response)
+}
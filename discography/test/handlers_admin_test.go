@@ -25,6 +25,7 @@ func TestAdminHandlers_response(t *testing.T) {
 		"AddArtist: Empty artist name":                        {"/admin/artist", "admin", handlers.NewClientArtist("", "link"), http.StatusBadRequest},
 		"AddArtist: No Spotify link":                          {"/admin/artist", "admin", handlers.NewClientArtist("Blur", ""), http.StatusBadRequest},
 		"AddArtist: Non-admin":                                {"/admin/artist", "user", handlers.NewClientArtist("Blur", "link"), http.StatusUnauthorized},
+		"AddArtist: Curator":                                  {"/admin/artist", "curator", handlers.NewClientArtist("Blur", "link"), http.StatusOK},
 		"AddArtist: Duplicate entry":                          {"/admin/artist", "admin", handlers.NewClientArtist(artist.Prefix+" "+artist.Name, linkArtist), http.StatusUnprocessableEntity},
 		"AddArtist: Duplicate artist with different link":     {"/admin/artist", "admin", handlers.NewClientArtist(artist.Prefix+" "+artist.Name, "link"), http.StatusUnprocessableEntity},
 		"AddArtist: Artist with same name but without prefix": {"/admin/artist", "admin", handlers.NewClientArtist(artist.Name, linkArtist), http.StatusUnprocessableEntity},
@@ -37,7 +38,18 @@ func TestAdminHandlers_response(t *testing.T) {
 		"AddSong: Song without a name":                        {"/admin/song", "admin", handlers.NewClientSong("", "The Prodigy"), http.StatusBadRequest},
 		"AddSong: Song without an artist":                     {"/admin/song", "admin", handlers.NewClientSong("House of the Rising Sun"), http.StatusBadRequest},
 		"AddSong: Non-admin":                                  {"/admin/song", "user", handlers.NewClientSong("House of the Rising Sun", "The Animals"), http.StatusUnauthorized},
+		"AddSong: Curator":                                    {"/admin/song", "curator", handlers.NewClientSong("House of the Rising Sun", "The Animals"), http.StatusOK},
 		"AddSong: Duplicate entry":                            {"/admin/song", "admin", handlers.NewClientSong(song, artist.Name), http.StatusUnprocessableEntity},
+		"AddAlbum: Request without body":                      {"/admin/album", "admin", nil, http.StatusBadRequest},
+		"AddAlbum: Existing artist with existing song":        {"/admin/album", "admin", handlers.NewClientAlbum("The Fat of the Land", artist.Name, handlers.NewClientSong(song)), http.StatusOK},
+		"AddAlbum: Existing artist with new songs":            {"/admin/album", "admin", handlers.NewClientAlbum("The Fat of the Land", artist.Name, handlers.NewClientSong("Breathe"), handlers.NewClientSong("Narayan")), http.StatusOK},
+		"AddAlbum: New artist with new songs":                 {"/admin/album", "admin", handlers.NewClientAlbum("Chuck", "Sum 41", handlers.NewClientSong("No Reason")), http.StatusOK},
+		"AddAlbum: Track with collaborating artists":          {"/admin/album", "admin", handlers.NewClientAlbum("Less Is More", "Lost Frequencies", handlers.NewClientSong("Crazy", "Lost Frequencies", "Zonderling")), http.StatusOK},
+		"AddAlbum: Album without a name":                      {"/admin/album", "admin", handlers.NewClientAlbum("", artist.Name, handlers.NewClientSong(song)), http.StatusBadRequest},
+		"AddAlbum: Album without an artist":                   {"/admin/album", "admin", handlers.NewClientAlbum("The Fat of the Land", "", handlers.NewClientSong(song)), http.StatusBadRequest},
+		"AddAlbum: Album without tracks":                      {"/admin/album", "admin", handlers.NewClientAlbum("The Fat of the Land", artist.Name), http.StatusBadRequest},
+		"AddAlbum: Track without a name":                      {"/admin/album", "admin", handlers.NewClientAlbum("The Fat of the Land", artist.Name, handlers.NewClientSong("")), http.StatusBadRequest},
+		"AddAlbum: Non-admin":                                 {"/admin/album", "user", handlers.NewClientAlbum("The Fat of the Land", artist.Name, handlers.NewClientSong(song)), http.StatusUnauthorized},
 	}
 	for name, test := range cases {
 		db := newTestDB()
@@ -187,21 +199,18 @@ func TestAddArtist_sendMessage(t *testing.T) {
 			t.Errorf("%v: Failed to run test due to failing adding existing artist: %s\n", name, err)
 			continue
 		}
+		db.clearOutbox()
 		handler, channel := testMusicHandlerNoRequest(t, db)
 		handler.AddNewArtist(test.artist, test.prefix, test.link)
-		go func() {
-			time.Sleep(time.Millisecond)
-			close(channel)
-		}()
 		foundTopic := false
-		for message := range channel {
+		for _, message := range testMessages(db, channel) {
 			if message.Topic != topic {
 				t.Errorf("%v: Expects no other topic than %v but got topic: %v\n", name, topic, message.Topic)
 				continue
 			}
 			foundTopic = true
 			var result general.Artist
-			if err := general.FromJSONBytes(&result, []byte(message.Message)); err != nil {
+			if _, err := general.DecodeEvent(message.Message, &result); err != nil {
 				t.Errorf("%v: Expects to send a message containing an artist but deserializing results in: %v\n", name, err)
 				continue
 			}
@@ -303,14 +312,11 @@ func TestAddSong_sendMessage(t *testing.T) {
 			t.Errorf("%v: Failed to set up test with existing song due to: %s\n", name, err)
 			continue
 		}
+		db.clearOutbox()
 		handler, channel := testMusicHandlerNoRequest(t, db)
 		handler.AddSong(test.song, test.artists...)
-		go func() {
-			time.Sleep(time.Millisecond)
-			close(channel)
-		}()
 		foundTopic := false
-		for message := range channel {
+		for _, message := range testMessages(db, channel) {
 			if message.Topic != test.topic {
 				if !test.expectedFoundOtherTopics {
 					t.Errorf("%v: Expects no other topic than %v but got topic: %v\n", name, test.topic, message.Topic)
@@ -322,7 +328,7 @@ func TestAddSong_sendMessage(t *testing.T) {
 				continue
 			}
 			var result general.Song
-			if err := general.FromJSONBytes(&result, []byte(message.Message)); err != nil {
+			if _, err := general.DecodeEvent(message.Message, &result); err != nil {
 				t.Errorf("%v: Expects to send a message containing a song but deserializing results in: %v\n", name, err)
 				continue
 			}
@@ -341,3 +347,327 @@ func TestAddSong_sendMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestAddAlbum(t *testing.T) {
+	artist := general.NewArtist(1, "Prodigy", "The")
+	song := "Firestarter"
+	album := "The Fat of the Land"
+	cases := map[string]struct {
+		album, artist      string
+		tracks             []handlers.ClientSong
+		expectedError      error
+		expectedTrackNames []string
+	}{
+		"Album with existing song":                   {"Music for the Jilted Generation", artist.Name, []handlers.ClientSong{handlers.NewClientSong(song)}, nil, []string{song}},
+		"Album with new songs keeps the track order": {"Invaders Must Die", artist.Name, []handlers.ClientSong{handlers.NewClientSong("Omen"), handlers.NewClientSong("Invaders Must Die"), handlers.NewClientSong("Take Me to the Hospital")}, nil, []string{"Omen", "Invaders Must Die", "Take Me to the Hospital"}},
+		"Album of a new artist":                      {"Underclass Hero", "Sum 41", []handlers.ClientSong{handlers.NewClientSong("Walking Disaster")}, nil, []string{"Walking Disaster"}},
+		"Album of an artist with a prefix":           {"Always Outnumbered, Never Outgunned", artist.Prefix + " " + artist.Name, []handlers.ClientSong{handlers.NewClientSong("Girls")}, nil, []string{"Girls"}},
+		"Album without tracks":                       {"Experience", artist.Name, nil, general.GetDBError("Missing input", general.InvalidInput), nil},
+		"Album without name":                         {"", artist.Name, []handlers.ClientSong{handlers.NewClientSong(song)}, general.GetDBError("Missing input", general.InvalidInput), nil},
+		"Duplicate album":                            {album, artist.Name, []handlers.ClientSong{handlers.NewClientSong(song)}, general.GetDBError("Duplicate entry", general.DuplicateEntry), nil},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		existingArtist, err := db.AddArtist(artist.Name, artist.Prefix, "link")
+		if err != nil {
+			t.Errorf("%v: Failed to set up test with existing artist due to: %s\n", name, err)
+			continue
+		}
+		existingSong, err := db.AddSong(song, []general.Artist{existingArtist})
+		if err != nil {
+			t.Errorf("%v: Failed to set up test with existing song due to: %s\n", name, err)
+			continue
+		}
+		if _, err = db.AddAlbum(album, existingArtist, []general.Song{existingSong}); err != nil {
+			t.Errorf("%v: Failed to set up test with existing album due to: %s\n", name, err)
+			continue
+		}
+		handler, _ := testMusicHandlerNoRequest(t, db)
+		result, err := handler.AddAlbum(test.album, test.artist, test.tracks...)
+		if err == nil && test.expectedError != nil {
+			t.Errorf("%v: Expects error with code %v but got no error\n", name, test.expectedError.(general.DBError).ErrorCode)
+		}
+		if err != nil && test.expectedError == nil {
+			t.Errorf("%v: Expects no error but got error with code %v\n", name, err.(general.DBError).ErrorCode)
+		}
+		if err != nil && test.expectedError != nil && err.(general.DBError).ErrorCode != test.expectedError.(general.DBError).ErrorCode {
+			t.Errorf("%v: Expects error with code %v but got error with code %v\n", name, test.expectedError.(general.DBError).ErrorCode, err.(general.DBError).ErrorCode)
+		}
+		if err != nil {
+			continue
+		}
+		saved, ok := db.albumsDB[result.ID]
+		if !ok {
+			t.Errorf("%v: Expects album to be saved in db\n", name)
+			continue
+		}
+		if len(saved.Songs) != len(test.expectedTrackNames) {
+			t.Errorf("%v: Expects %v tracks but got: %v\n", name, len(test.expectedTrackNames), len(saved.Songs))
+			continue
+		}
+		for index, track := range saved.Songs {
+			if track.Name != test.expectedTrackNames[index] {
+				t.Errorf("%v: Expects track %v at position %v but got: %v\n", name, test.expectedTrackNames[index], index+1, track.Name)
+			}
+		}
+	}
+}
+
+func TestAddAlbum_sendMessage(t *testing.T) {
+	artist := general.NewArtist(1, "Queen", "")
+	song := "Bohemian Rhapsody"
+	topicAlbum := "newAlbum"
+	cases := map[string]struct {
+		album                    string
+		tracks                   []handlers.ClientSong
+		topic                    string
+		expectedFoundTopic       bool
+		expectedFoundOtherTopics bool
+	}{
+		"Album with existing song":               {"A Night at the Opera", []handlers.ClientSong{handlers.NewClientSong(song)}, topicAlbum, true, false},
+		"Album with new song":                    {"News of the World", []handlers.ClientSong{handlers.NewClientSong("We Will Rock You")}, topicAlbum, true, true},
+		"Album with new song also sends newSong": {"News of the World", []handlers.ClientSong{handlers.NewClientSong("We Will Rock You")}, "newSong", true, true},
+		"Album without tracks":                   {"Jazz", nil, topicAlbum, false, false},
+		"Album without name":                     {"", []handlers.ClientSong{handlers.NewClientSong(song)}, topicAlbum, false, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		newArtist, err := db.AddArtist(artist.Name, artist.Prefix, "link")
+		if err != nil {
+			t.Errorf("%v: Failed to set up test with existing artist due to: %s\n", name, err)
+			continue
+		}
+		if _, err = db.AddSong(song, []general.Artist{newArtist}); err != nil {
+			t.Errorf("%v: Failed to set up test with existing song due to: %s\n", name, err)
+			continue
+		}
+		db.clearOutbox()
+		handler, channel := testMusicHandlerNoRequest(t, db)
+		handler.AddAlbum(test.album, artist.Name, test.tracks...)
+		foundTopic := false
+		for _, message := range testMessages(db, channel) {
+			if message.Topic != test.topic {
+				if !test.expectedFoundOtherTopics {
+					t.Errorf("%v: Expects no other topic than %v but got topic: %v\n", name, test.topic, message.Topic)
+				}
+				continue
+			}
+			foundTopic = true
+			if message.Topic != topicAlbum {
+				continue
+			}
+			var result general.Album
+			if _, err := general.DecodeEvent([]byte(message.Message), &result); err != nil {
+				t.Errorf("%v: Expects to send a message containing an album but deserializing results in: %v\n", name, err)
+				continue
+			}
+			if result.ID == 0 {
+				t.Errorf("%v: Expects message with id but got id=0\n", name)
+			}
+			if result.Name != test.album {
+				t.Errorf("%v: Expects message with album %v but got: %v\n", name, test.album, result.Name)
+			}
+			if len(result.Songs) != len(test.tracks) {
+				t.Errorf("%v: Expects message with %v songs but got: %v\n", name, len(test.tracks), len(result.Songs))
+			}
+		}
+		if foundTopic != test.expectedFoundTopic {
+			t.Errorf("%v: Expects to found topic %v but got: %v\n", name, test.expectedFoundTopic, foundTopic)
+		}
+	}
+}
+
+func TestGenreHandlers_response(t *testing.T) {
+	artist := general.NewArtist(1, "Prodigy", "The")
+	song := "Firestarter"
+	genre, tagOfSong := "Electronic", "Big beat"
+	cases := map[string]struct {
+		method, path       string
+		roleClient         string
+		body               interface{}
+		expectedStatusCode int
+	}{
+		"AddGenre: Request without body":       {http.MethodPost, "/admin/genre", "admin", nil, http.StatusBadRequest},
+		"AddGenre: New genre":                  {http.MethodPost, "/admin/genre", "admin", handlers.NewClientGenre("Rock"), http.StatusOK},
+		"AddGenre: Empty genre":                {http.MethodPost, "/admin/genre", "admin", handlers.NewClientGenre(""), http.StatusBadRequest},
+		"AddGenre: Duplicate entry":            {http.MethodPost, "/admin/genre", "admin", handlers.NewClientGenre(genre), http.StatusUnprocessableEntity},
+		"AddGenre: Non-admin":                  {http.MethodPost, "/admin/genre", "user", handlers.NewClientGenre("Rock"), http.StatusUnauthorized},
+		"TagSong: Request without body":        {http.MethodPost, "/admin/tag", "admin", nil, http.StatusBadRequest},
+		"TagSong: Existing song and genre":     {http.MethodPost, "/admin/tag", "admin", handlers.NewClientTag(10, genre), http.StatusOK},
+		"TagSong: Song doesn't exist":          {http.MethodPost, "/admin/tag", "admin", handlers.NewClientTag(404, genre), http.StatusNotFound},
+		"TagSong: Genre doesn't exist":         {http.MethodPost, "/admin/tag", "admin", handlers.NewClientTag(10, "Rock"), http.StatusNotFound},
+		"TagSong: Song without id":             {http.MethodPost, "/admin/tag", "admin", handlers.NewClientTag(0, genre), http.StatusBadRequest},
+		"TagSong: Song is already tagged":      {http.MethodPost, "/admin/tag", "admin", handlers.NewClientTag(10, tagOfSong), http.StatusUnprocessableEntity},
+		"TagSong: Non-admin":                   {http.MethodPost, "/admin/tag", "user", handlers.NewClientTag(10, genre), http.StatusUnauthorized},
+		"UntagSong: Request without body":      {http.MethodDelete, "/admin/tag", "admin", nil, http.StatusBadRequest},
+		"UntagSong: Song is tagged with genre": {http.MethodDelete, "/admin/tag", "admin", handlers.NewClientTag(10, tagOfSong), http.StatusOK},
+		"UntagSong: Song isn't tagged":         {http.MethodDelete, "/admin/tag", "admin", handlers.NewClientTag(10, genre), http.StatusNotFound},
+		"UntagSong: Genre doesn't exist":       {http.MethodDelete, "/admin/tag", "admin", handlers.NewClientTag(10, "Rock"), http.StatusNotFound},
+		"UntagSong: Non-admin":                 {http.MethodDelete, "/admin/tag", "user", handlers.NewClientTag(10, tagOfSong), http.StatusUnauthorized},
+		"TagAlbum: Existing album and genre":   {http.MethodPost, "/admin/album/tag", "admin", handlers.NewClientAlbumTag(1, genre), http.StatusOK},
+		"TagAlbum: Album doesn't exist":        {http.MethodPost, "/admin/album/tag", "admin", handlers.NewClientAlbumTag(404, genre), http.StatusNotFound},
+		"TagAlbum: Album without id":           {http.MethodPost, "/admin/album/tag", "admin", handlers.NewClientAlbumTag(0, genre), http.StatusBadRequest},
+		"TagAlbum: Album is already tagged":    {http.MethodPost, "/admin/album/tag", "admin", handlers.NewClientAlbumTag(1, tagOfSong), http.StatusUnprocessableEntity},
+		"TagAlbum: Non-admin":                  {http.MethodPost, "/admin/album/tag", "user", handlers.NewClientAlbumTag(1, genre), http.StatusUnauthorized},
+		"UntagAlbum: Album is tagged":          {http.MethodDelete, "/admin/album/tag", "admin", handlers.NewClientAlbumTag(1, tagOfSong), http.StatusOK},
+		"UntagAlbum: Album isn't tagged":       {http.MethodDelete, "/admin/album/tag", "admin", handlers.NewClientAlbumTag(1, genre), http.StatusNotFound},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if _, err := db.AddArtist(artist.Name, artist.Prefix, "link"); err != nil {
+			t.Fatalf("%v: Failed to add artist for test TestGenreHandlers_response due to: %s\n", name, err)
+		}
+		if _, err := db.AddSong(song, []general.Artist{artist}); err != nil {
+			t.Fatalf("%v: Failed to add song for test TestGenreHandlers_response due to: %s\n", name, err)
+		}
+		for _, newGenre := range []string{genre, tagOfSong} {
+			if _, err := db.AddGenre(newGenre); err != nil {
+				t.Fatalf("%v: Failed to add genre for test TestGenreHandlers_response due to: %s\n", name, err)
+			}
+		}
+		if err := db.AddGenreToSong(10, db.genresDB[tagOfSong]); err != nil {
+			t.Fatalf("%v: Failed to tag song for test TestGenreHandlers_response due to: %s\n", name, err)
+		}
+		foundSong, _ := db.FindSongByID(10)
+		if _, err := db.AddAlbum("The Fat of the Land", artist, []general.Song{foundSong}); err != nil {
+			t.Fatalf("%v: Failed to add album for test TestGenreHandlers_response due to: %s\n", name, err)
+		}
+		if err := db.AddGenreToAlbum(1, db.genresDB[tagOfSong]); err != nil {
+			t.Fatalf("%v: Failed to tag album for test TestGenreHandlers_response due to: %s\n", name, err)
+		}
+		server, _ := testServerNoRequest(t, db)
+		token, err := general.CreateToken(1, "test", test.roleClient)
+		if err != nil {
+			t.Errorf("Can't start TestGenreHandlers_response due to failure making token:%s\n", err)
+			continue
+		}
+		response := general.TestRequest(t, server, test.method, test.path, token, test.body)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+	}
+}
+
+func TestTagSong_sendMessage(t *testing.T) {
+	artist := general.NewArtist(1, "Rammstein", "")
+	song := "Du Hast"
+	genre, tagOfSong := "Metal", "Industrial"
+	cases := map[string]struct {
+		changeTag          func(*handlers.MusicHandler, int, string) (general.GenreTag, error)
+		songID             int
+		genre              string
+		topic              string
+		expectedFoundTopic bool
+	}{
+		"Tag song with genre":             {(*handlers.MusicHandler).TagSong, 10, genre, "tagSong", true},
+		"Tag song that is already tagged": {(*handlers.MusicHandler).TagSong, 10, tagOfSong, "tagSong", false},
+		"Tag song with unknown genre":     {(*handlers.MusicHandler).TagSong, 10, "Schlager", "tagSong", false},
+		"Tag unknown song":                {(*handlers.MusicHandler).TagSong, 404, genre, "tagSong", false},
+		"Remove genre from song":          {(*handlers.MusicHandler).UntagSong, 10, tagOfSong, "untagSong", true},
+		"Remove genre that isn't tagged":  {(*handlers.MusicHandler).UntagSong, 10, genre, "untagSong", false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if _, err := db.AddArtist(artist.Name, artist.Prefix, "link"); err != nil {
+			t.Fatalf("%v: Failed to add artist for test TestTagSong_sendMessage due to: %s\n", name, err)
+		}
+		if _, err := db.AddSong(song, []general.Artist{artist}); err != nil {
+			t.Fatalf("%v: Failed to add song for test TestTagSong_sendMessage due to: %s\n", name, err)
+		}
+		for _, newGenre := range []string{genre, tagOfSong} {
+			if _, err := db.AddGenre(newGenre); err != nil {
+				t.Fatalf("%v: Failed to add genre for test TestTagSong_sendMessage due to: %s\n", name, err)
+			}
+		}
+		if err := db.AddGenreToSong(10, db.genresDB[tagOfSong]); err != nil {
+			t.Fatalf("%v: Failed to tag song for test TestTagSong_sendMessage due to: %s\n", name, err)
+		}
+		handler, channel := testMusicHandlerNoRequest(t, db)
+		test.changeTag(handler, test.songID, test.genre)
+		go func() {
+			time.Sleep(time.Millisecond)
+			close(channel)
+		}()
+		foundTopic := false
+		for message := range channel {
+			if message.Topic != test.topic {
+				t.Errorf("%v: Expects no other topic than %v but got topic: %v\n", name, test.topic, message.Topic)
+				continue
+			}
+			foundTopic = true
+			var result general.GenreTag
+			if _, err := general.DecodeEvent([]byte(message.Message), &result); err != nil {
+				t.Errorf("%v: Expects to send a message containing a tag but deserializing results in: %v\n", name, err)
+				continue
+			}
+			if result.SongID != test.songID || result.Genre.Name != test.genre || result.Genre.ID == 0 {
+				t.Errorf("%v: Expects message with song #%v and genre %v but got: %v\n", name, test.songID, test.genre, result)
+			}
+		}
+		if foundTopic != test.expectedFoundTopic {
+			t.Errorf("%v: Expects to find topic %v: %v but got: %v\n", name, test.topic, test.expectedFoundTopic, foundTopic)
+		}
+	}
+}
+
+func TestTagAlbum_sendMessage(t *testing.T) {
+	artist := general.NewArtist(1, "Rammstein", "")
+	genre, tagOfAlbum := "Metal", "Industrial"
+	cases := map[string]struct {
+		changeTag          func(*handlers.MusicHandler, int, string) (general.AlbumGenreTag, error)
+		albumID            int
+		genre              string
+		topic              string
+		expectedFoundTopic bool
+	}{
+		"Tag album with genre":             {(*handlers.MusicHandler).TagAlbum, 1, genre, "tagAlbum", true},
+		"Tag album that is already tagged": {(*handlers.MusicHandler).TagAlbum, 1, tagOfAlbum, "tagAlbum", false},
+		"Tag album with unknown genre":     {(*handlers.MusicHandler).TagAlbum, 1, "Schlager", "tagAlbum", false},
+		"Tag unknown album":                {(*handlers.MusicHandler).TagAlbum, 404, genre, "tagAlbum", false},
+		"Remove genre from album":          {(*handlers.MusicHandler).UntagAlbum, 1, tagOfAlbum, "untagAlbum", true},
+		"Remove genre that isn't tagged":   {(*handlers.MusicHandler).UntagAlbum, 1, genre, "untagAlbum", false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if _, err := db.AddArtist(artist.Name, artist.Prefix, "link"); err != nil {
+			t.Fatalf("%v: Failed to add artist for test TestTagAlbum_sendMessage due to: %s\n", name, err)
+		}
+		song, err := db.AddSong("Du Hast", []general.Artist{artist})
+		if err != nil {
+			t.Fatalf("%v: Failed to add song for test TestTagAlbum_sendMessage due to: %s\n", name, err)
+		}
+		if _, err := db.AddAlbum("Sehnsucht", artist, []general.Song{song}); err != nil {
+			t.Fatalf("%v: Failed to add album for test TestTagAlbum_sendMessage due to: %s\n", name, err)
+		}
+		for _, newGenre := range []string{genre, tagOfAlbum} {
+			if _, err := db.AddGenre(newGenre); err != nil {
+				t.Fatalf("%v: Failed to add genre for test TestTagAlbum_sendMessage due to: %s\n", name, err)
+			}
+		}
+		if err := db.AddGenreToAlbum(1, db.genresDB[tagOfAlbum]); err != nil {
+			t.Fatalf("%v: Failed to tag album for test TestTagAlbum_sendMessage due to: %s\n", name, err)
+		}
+		db.clearOutbox()
+		handler, channel := testMusicHandlerNoRequest(t, db)
+		test.changeTag(handler, test.albumID, test.genre)
+		foundTopic := false
+		for _, message := range testMessages(db, channel) {
+			if message.Topic != test.topic {
+				t.Errorf("%v: Expects no other topic than %v but got topic: %v\n", name, test.topic, message.Topic)
+				continue
+			}
+			foundTopic = true
+			var result general.AlbumGenreTag
+			if _, err := general.DecodeEvent([]byte(message.Message), &result); err != nil {
+				t.Errorf("%v: Expects to send a message containing a tag but deserializing results in: %v\n", name, err)
+				continue
+			}
+			if result.AlbumID != test.albumID || result.Genre.Name != test.genre || result.Genre.ID == 0 {
+				t.Errorf("%v: Expects message with album #%v and genre %v but got: %v\n", name, test.albumID, test.genre, result)
+			}
+		}
+		if foundTopic != test.expectedFoundTopic {
+			t.Errorf("%v: Expects to find topic %v: %v but got: %v\n", name, test.topic, test.expectedFoundTopic, foundTopic)
+		}
+	}
+}
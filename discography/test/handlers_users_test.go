@@ -4,6 +4,7 @@ import (
 	"discography/handlers"
 	"general"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -173,3 +174,202 @@ func TestSongsFromArtist_orderResults(t *testing.T) {
 		}
 	}
 }
+
+func TestAlbumHandlers_response(t *testing.T) {
+	albums := []handlers.ClientAlbum{
+		handlers.NewClientAlbum("Meteora", "Linkin Park", handlers.NewClientSong("Numb"), handlers.NewClientSong("Faint")),
+		handlers.NewClientAlbum("Hybrid Theory", "Linkin Park", handlers.NewClientSong("In the End")),
+		handlers.NewClientAlbum("Minutes to Midnight", "Linkin Park", handlers.NewClientSong("What I've Done")),
+		handlers.NewClientAlbum("Ten Thousand Fists", "Disturbed", handlers.NewClientSong("Stricken")),
+	}
+	cases := map[string]struct {
+		path                  string
+		expectedStatusCode    int
+		expectedAmountResults int
+		expectedHasNext       bool
+	}{
+		"AlbumsFromArtist: Artist has multiple albums":        {"/api/artist/Linkin%20Park/albums", http.StatusOK, 3, false},
+		"AlbumsFromArtist: Artist has one album":              {"/api/artist/Disturbed/albums", http.StatusOK, 1, false},
+		"AlbumsFromArtist: No album is found":                 {"/api/artist/Dio/albums", http.StatusNotFound, 0, false},
+		"AlbumsFromArtist: Amount of results capped by max":   {"/api/artist/Linkin%20Park/albums?max=2", http.StatusOK, 2, true},
+		"AlbumsFromArtist: Skip amount of results by offset":  {"/api/artist/Linkin%20Park/albums?offset=1", http.StatusOK, 2, false},
+		"AlbumsFromArtist: Offset is bigger than amount":      {"/api/artist/Linkin%20Park/albums?offset=100", http.StatusNotFound, 0, false},
+		"AlbumByID: Existing album returns the track listing": {"/api/album/1", http.StatusOK, 2, false},
+		"AlbumByID: Album doesn't exist":                      {"/api/album/404", http.StatusNotFound, 0, false},
+		"AlbumByID: Invalid id":                               {"/api/album/Meteora", http.StatusBadRequest, 0, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler, _ := testMusicHandlerNoRequest(t, db)
+		for _, album := range albums {
+			if _, err := handler.AddAlbum(album.Name, album.Artist, album.Tracks...); err != nil {
+				t.Fatalf("Can't add album for test TestAlbumHandlers_response due to: %s\n", err)
+			}
+		}
+		server, _ := testServerNoRequest(t, db)
+		response := general.TestRequest(t, server, http.MethodGet, test.path, "", nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var amountResults int
+		var hasNext bool
+		if strings.HasPrefix(test.path, "/api/album/") {
+			var result general.Album
+			if err := general.ReadFromJSON(&result, response.Body); err != nil {
+				t.Errorf("[ERROR] %v: Decoding response: %v\n", name, err)
+				continue
+			}
+			amountResults = len(result.Songs)
+		} else {
+			var results general.MultipleAlbums
+			if err := general.ReadFromJSON(&results, response.Body); err != nil {
+				t.Errorf("[ERROR] %v: Decoding response: %v\n", name, err)
+				continue
+			}
+			amountResults, hasNext = len(results.Data), results.HasNext
+		}
+		if amountResults != test.expectedAmountResults {
+			t.Errorf("%v: Expect to find %v results, but got: %v\n", name, test.expectedAmountResults, amountResults)
+		}
+		if hasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects HasNext is %v but got: %v\n", name, test.expectedHasNext, hasNext)
+		}
+	}
+}
+
+func TestSongsFromGenre_response(t *testing.T) {
+	tags := map[string][]handlers.ClientSong{
+		"Grunge": {handlers.NewClientSong("Smells Like Teen Spirit", "Nirvana"), handlers.NewClientSong("Black Hole Sun", "Soundgarden"), handlers.NewClientSong("Alive", "Pearl Jam")},
+		"Rock":   {handlers.NewClientSong("Smells Like Teen Spirit", "Nirvana")},
+		"Techno": {},
+	}
+	cases := map[string]struct {
+		path                  string
+		expectedStatusCode    int
+		expectedAmountResults int
+		expectedHasNext       bool
+	}{
+		"SongsFromGenre: Genre has multiple songs":         {"/api/genre/Grunge", http.StatusOK, 3, false},
+		"SongsFromGenre: Genre has one song":               {"/api/genre/Rock", http.StatusOK, 1, false},
+		"SongsFromGenre: Genre without songs":              {"/api/genre/Techno", http.StatusNotFound, 0, false},
+		"SongsFromGenre: Genre doesn't exist":              {"/api/genre/Jazz", http.StatusNotFound, 0, false},
+		"SongsFromGenre: Amount of results capped by max":  {"/api/genre/Grunge?max=2", http.StatusOK, 2, true},
+		"SongsFromGenre: Skip amount of results by offset": {"/api/genre/Grunge?offset=1", http.StatusOK, 2, false},
+		"SongsFromGenre: Offset is bigger than amount":     {"/api/genre/Grunge?offset=100", http.StatusNotFound, 0, false},
+		"SongsFromGenre: Invalid value for max":            {"/api/genre/Grunge?max=-1", http.StatusBadRequest, 0, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler, _ := testMusicHandlerNoRequest(t, db)
+		for genre, songs := range tags {
+			if _, err := handler.AddGenre(genre); err != nil {
+				t.Fatalf("Can't add genre for test TestSongsFromGenre_response due to: %s\n", err)
+			}
+			for _, song := range songs {
+				foundSong, err := db.FindSongByName(song.Artists[0], song.Name)
+				if err != nil {
+					if foundSong, err = handler.AddSong(song.Name, song.Artists...); err != nil {
+						t.Fatalf("Can't add song for test TestSongsFromGenre_response due to: %s\n", err)
+					}
+				}
+				if _, err := handler.TagSong(foundSong.ID, genre); err != nil {
+					t.Fatalf("Can't tag song for test TestSongsFromGenre_response due to: %s\n", err)
+				}
+			}
+		}
+		server, _ := testServerNoRequest(t, db)
+		response := general.TestRequest(t, server, http.MethodGet, test.path, "", nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var results general.MultipleSongs
+		if err := general.ReadFromJSON(&results, response.Body); err != nil {
+			t.Errorf("[ERROR] %v: Decoding response: %v\n", name, err)
+			continue
+		}
+		if len(results.Data) != test.expectedAmountResults {
+			t.Errorf("%v: Expect to find %v results, but got: %v\n", name, test.expectedAmountResults, len(results.Data))
+		}
+		if results.HasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects HasNext is %v but got: %v\n", name, test.expectedHasNext, results.HasNext)
+		}
+		for _, song := range results.Data {
+			if len(song.Genres) == 0 {
+				t.Errorf("%v: Expects song %v to contain its genres\n", name, song.Name)
+			}
+		}
+	}
+}
+
+func TestSearch_response(t *testing.T) {
+	discography := map[string][]handlers.ClientSong{
+		"The Prodigy": {handlers.NewClientSong("Firestarter", "The Prodigy"), handlers.NewClientSong("Breathe", "The Prodigy")},
+		"Metallica":   {handlers.NewClientSong("Enter Sandman", "Metallica"), handlers.NewClientSong("Master of Puppets", "Metallica")},
+		"Nirvana":     {handlers.NewClientSong("Smells Like Teen Spirit", "Nirvana")},
+		"The Doors":   {handlers.NewClientSong("Break On Through", "The Doors")},
+		"Yellowcard":  {handlers.NewClientSong("Breathing", "Yellowcard")},
+	}
+	cases := map[string]struct {
+		path                  string
+		expectedStatusCode    int
+		expectedFirstResult   string
+		expectedAmountResults int
+		expectedHasNext       bool
+	}{
+		"Search: Exact name of artist":                {"/api/search?q=Metallica", http.StatusOK, "Metallica", 1, false},
+		"Search: Query is case-insensitive":           {"/api/search?q=mETALLICA", http.StatusOK, "Metallica", 1, false},
+		"Search: Query with typo":                     {"/api/search?q=metalica", http.StatusOK, "Metallica", 1, false},
+		"Search: Query with prefix of artist":         {"/api/search?q=the%20prodigy", http.StatusOK, "Prodigy", 1, false},
+		"Search: Query without prefix of artist":      {"/api/search?q=Prodigy", http.StatusOK, "Prodigy", 1, false},
+		"Search: Exact name of song":                  {"/api/search?q=Enter%20Sandman", http.StatusOK, "Enter Sandman", 1, false},
+		"Search: Part of the name of a song":          {"/api/search?q=teen%20spirit", http.StatusOK, "Smells Like Teen Spirit", 1, false},
+		"Search: Part of the name of a song and typo": {"/api/search?q=teen%20spirrit", http.StatusOK, "Smells Like Teen Spirit", 1, false},
+		"Search: Better matches are ranked first":     {"/api/search?q=breathe", http.StatusOK, "Breathe", 2, false},
+		"Search: Artists and songs are combined":      {"/api/search?q=br", http.StatusOK, "Break On Through", 3, false},
+		"Search: Amount of results capped by max":     {"/api/search?q=br&max=1", http.StatusOK, "Break On Through", 1, true},
+		"Search: Skip amount of results by offset":    {"/api/search?q=br&offset=1", http.StatusOK, "Breathe", 2, false},
+		"Search: Offset is bigger than amount":        {"/api/search?q=br&offset=100", http.StatusNotFound, "", 0, false},
+		"Search: Nothing looks like the query":        {"/api/search?q=Beyonce", http.StatusNotFound, "", 0, false},
+		"Search: Empty query":                         {"/api/search?q=", http.StatusBadRequest, "", 0, false},
+		"Search: Missing query":                       {"/api/search", http.StatusBadRequest, "", 0, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := testAddDiscographyToDB(t, db, discography); err != nil {
+			t.Fatalf("Failed to add discography for test TestSearch_response due to: %s\n", err)
+		}
+		server, _ := testServerNoRequest(t, db)
+		response := general.TestRequest(t, server, http.MethodGet, test.path, "", nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var results struct {
+			Data []struct {
+				Name string `json:"name"`
+			} `json:"music"`
+			HasNext bool `json:"hasNext"`
+		}
+		if err := general.ReadFromJSONNoValidation(&results, response.Body); err != nil {
+			t.Errorf("[ERROR] %v: Decoding response: %v\n", name, err)
+			continue
+		}
+		if len(results.Data) != test.expectedAmountResults {
+			t.Errorf("%v: Expect to find %v results, but got: %v\n", name, test.expectedAmountResults, results.Data)
+		}
+		if len(results.Data) > 0 && results.Data[0].Name != test.expectedFirstResult {
+			t.Errorf("%v: Expects %v as first result but got: %v\n", name, test.expectedFirstResult, results.Data[0].Name)
+		}
+		if results.HasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects HasNext is %v but got: %v\n", name, test.expectedHasNext, results.HasNext)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"discography/spotify"
+	"general"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestArtistID(t *testing.T) {
+	cases := map[string]struct {
+		link, expectedID string
+		expectedError    bool
+	}{
+		"Url":                  {"https://open.spotify.com/artist/0qu422H5MOoQxGjd4IzHbS", "0qu422H5MOoQxGjd4IzHbS", false},
+		"Url with query":       {"https://open.spotify.com/artist/0qu422H5MOoQxGjd4IzHbS?si=abc", "0qu422H5MOoQxGjd4IzHbS", false},
+		"Url with slash":       {"https://open.spotify.com/artist/0qu422H5MOoQxGjd4IzHbS/", "0qu422H5MOoQxGjd4IzHbS", false},
+		"Uri":                  {"spotify:artist:0qu422H5MOoQxGjd4IzHbS", "0qu422H5MOoQxGjd4IzHbS", false},
+		"Id":                   {"0qu422H5MOoQxGjd4IzHbS", "0qu422H5MOoQxGjd4IzHbS", false},
+		"Empty link":           {"", "", true},
+		"Link to another page": {"https://open.spotify.com/album/0qu422H5MOoQxGjd4IzHbS", "", true},
+	}
+	for name, test := range cases {
+		id, err := spotify.ArtistID(test.link)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+		if id != test.expectedID {
+			t.Errorf("%v: Expects id %v but got: %v\n", name, test.expectedID, id)
+		}
+	}
+}
+
+func testFakeSpotify() *fakeSpotify {
+	fake := newFakeSpotify("testClient", "testSecret", 1)
+	sum41 := spotify.TrackArtist{ID: "sum41", Name: "Sum 41"}
+	strokes := spotify.TrackArtist{ID: "strokes", Name: "The Strokes"}
+	fake.AddAlbum("sum41", spotify.Album{ID: "chuck", Name: "Chuck"},
+		spotify.Track{ID: "t1", Name: "No Reason", TrackNumber: 1, Artists: []spotify.TrackArtist{sum41}},
+		spotify.Track{ID: "t2", Name: "We're All to Blame", TrackNumber: 2, Artists: []spotify.TrackArtist{sum41}},
+		spotify.Track{ID: "t3", Name: "Pieces", TrackNumber: 3, Artists: []spotify.TrackArtist{sum41}})
+	fake.AddAlbum("sum41", spotify.Album{ID: "collab", Name: "Collab"},
+		spotify.Track{ID: "t4", Name: "Makes No Difference", TrackNumber: 1, Artists: []spotify.TrackArtist{sum41, {ID: "tommy", Name: "Tommy Lee"}}},
+		spotify.Track{ID: "t5", Name: "Without Sum 41", TrackNumber: 2, Artists: []spotify.TrackArtist{{ID: "tommy", Name: "Tommy Lee"}}})
+	fake.AddAlbum("strokes", spotify.Album{ID: "isthisit", Name: "Is This It"},
+		spotify.Track{ID: "t6", Name: "Last Nite", TrackNumber: 1, Artists: []spotify.TrackArtist{strokes}})
+	fake.AddAlbum("slipknot", spotify.Album{ID: "iowa", Name: "Iowa"},
+		spotify.Track{ID: "t7", Name: "Left Behind", TrackNumber: 1, Artists: []spotify.TrackArtist{{ID: "slipknot", Name: "Slipknot"}}})
+	return fake
+}
+
+func TestSyncSpotify_addSongs(t *testing.T) {
+	server := httptest.NewServer(testFakeSpotify())
+	defer server.Close()
+	cases := map[string]struct {
+		clientSecret    string
+		expectedAdded   int
+		expectedSongs   map[string][]string
+		unexpectedSongs map[string][]string
+	}{
+		"Missing songs of linked artists are added": {"testSecret", 4, map[string][]string{"Sum 41": {"No Reason", "We're All to Blame", "Pieces", "Makes No Difference"}, "Tommy Lee": {"Makes No Difference"}, "Strokes": {"Last Nite"}}, map[string][]string{"Tommy Lee": {"Without Sum 41"}, "Slipknot": {"Left Behind"}}},
+		"Nothing is added with invalid credentials": {"wrongSecret", 0, map[string][]string{"Sum 41": {"We're All to Blame"}}, map[string][]string{"Sum 41": {"No Reason"}, "Strokes": {"Last Nite"}}},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler, channel := testMusicHandlerNoRequest(t, db)
+		go func() {
+			for range channel {
+			}
+		}()
+		handler.AddNewArtist("Sum 41", "", "https://open.spotify.com/artist/sum41?si=test")
+		handler.AddNewArtist("Strokes", "The", "spotify:artist:strokes")
+		handler.AddNewArtist("Slipknot", "", "")
+		handler.AddNewArtist("Unknown", "", "https://open.spotify.com/artist/unknown")
+		handler.AddNewArtist("Invalid", "", "not a link")
+		if _, err := handler.AddSong("We're All to Blame", "Sum 41"); err != nil {
+			t.Fatalf("%v: Failed to add existing song due to: %s\n", name, err)
+		}
+		client := spotify.NewHTTPClient(server.URL, server.URL+"/api/token", "testClient", test.clientSecret, nil)
+		if added := handler.SyncSpotify(client); added != test.expectedAdded {
+			t.Errorf("%v: Expects %v added songs but got: %v\n", name, test.expectedAdded, added)
+		}
+		for artist, songs := range test.expectedSongs {
+			for _, song := range songs {
+				if _, err := db.FindSongByName(artist, song); err != nil {
+					t.Errorf("%v: Expects song %v - %v to be in the database but got: %s\n", name, artist, song, err)
+				}
+			}
+		}
+		for artist, songs := range test.unexpectedSongs {
+			for _, song := range songs {
+				if _, err := db.FindSongByName(artist, song); err == nil {
+					t.Errorf("%v: Expects song %v - %v not to be in the database\n", name, artist, song)
+				}
+			}
+		}
+		if _, err := db.FindArtistByName("The Strokes"); err == nil {
+			t.Errorf("%v: Expects the linked artist to be used instead of adding The Strokes as a new artist\n", name)
+		}
+		if added := handler.SyncSpotify(client); added != 0 {
+			t.Errorf("%v: Expects no added songs after synchronising twice but got: %v\n", name, added)
+		}
+	}
+}
+
+func TestSyncSpotify_sendMessage(t *testing.T) {
+	server := httptest.NewServer(testFakeSpotify())
+	defer server.Close()
+	db := newTestDB()
+	handler, channel := testMusicHandlerNoRequest(t, db)
+	handler.AddNewArtist("Strokes", "The", "spotify:artist:strokes")
+	handler.SyncSpotify(spotify.NewHTTPClient(server.URL, server.URL+"/api/token", "testClient", "testSecret", nil))
+	for _, message := range testMessages(db, channel) {
+		if message.Topic != "newSong" {
+			continue
+		}
+		var song general.Song
+		if _, err := general.DecodeEvent(message.Message, &song); err != nil {
+			t.Fatalf("Failed to deserialize message: %s\n", err)
+		}
+		if song.Name != "Last Nite" {
+			t.Errorf("Expects a message about Last Nite but got: %v\n", song.Name)
+		}
+		return
+	}
+	t.Fatalf("Expects a message to topic newSong for the added song\n")
+}
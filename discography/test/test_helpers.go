@@ -4,16 +4,18 @@ import (
 	"discography/database"
 	"discography/handlers"
 	"general"
+	"general/config"
 	"math"
 	"net/http"
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func testServerNoRequest(t *testing.T, db database.Database) (*http.Server, chan general.Message) {
 	handler, channel := testMusicHandlerNoRequest(t, db)
-	server, _ := handlers.NewMusicServer(handler, nil, "music_test", "")
+	server, _ := handlers.NewMusicServer(handler, nil, config.Config{Servername: "music_test"})
 	return server, channel
 }
 
@@ -46,11 +48,38 @@ func testAddDiscographyToDB(t *testing.T, db database.Database, discography map[
 type testDB struct {
 	artistsDB map[string]testArtist
 	songsDB   map[string]map[string]general.Song
+	albumsDB  map[int]general.Album
+	genresDB  map[string]general.Genre
 	lastID    int
+	outbox    *[]general.Message
 }
 
 func newTestDB() testDB {
-	return testDB{artistsDB: make(map[string]testArtist), songsDB: make(map[string]map[string]general.Song)}
+	return testDB{artistsDB: make(map[string]testArtist), songsDB: make(map[string]map[string]general.Song), albumsDB: make(map[int]general.Album), genresDB: make(map[string]general.Genre), outbox: &[]general.Message{}}
+}
+
+// addToOutbox stores an event like the outbox table of the database, the messages are read with testMessages
+func (fake testDB) addToOutbox(topic string, payload interface{}) {
+	msg, _ := general.EncodeEvent(topic, payload)
+	*fake.outbox = append(*fake.outbox, general.Message{Topic: topic, Message: msg})
+}
+
+func (fake testDB) clearOutbox() {
+	*fake.outbox = (*fake.outbox)[:0]
+}
+
+// testMessages returns the messages that the handler sends within a millisecond followed by the messages in the outbox of the database
+func testMessages(db testDB, channel chan general.Message) []general.Message {
+	messages := make([]general.Message, 0)
+	timeout := time.After(time.Millisecond)
+	for {
+		select {
+		case message := <-channel:
+			messages = append(messages, message)
+		case <-timeout:
+			return append(messages, *db.outbox...)
+		}
+	}
 }
 
 type testArtist struct {
@@ -164,7 +193,9 @@ func (fake testDB) AddArtist(artist, prefix, linkSpotify string) (general.Artist
 	newArtist := testArtist{id: len(fake.artistsDB) + 1, name: artist, prefix: prefix, linkSpotify: linkSpotify}
 	fake.artistsDB[artist] = newArtist
 	fake.songsDB[artist] = make(map[string]general.Song)
-	return general.NewArtist(newArtist.id, newArtist.name, newArtist.prefix), nil
+	result := general.NewArtist(newArtist.id, newArtist.name, newArtist.prefix)
+	fake.addToOutbox("newArtist", result)
+	return result, nil
 }
 
 func (fake testDB) AddSong(song string, artists []general.Artist) (general.Song, error) {
@@ -183,9 +214,220 @@ func (fake testDB) AddSong(song string, artists []general.Artist) (general.Song,
 		}
 	}
 	songID := artists[0].ID*10 + len(fake.songsDB[artists[0].Name])
-	newSong := general.Song{ID: songID, Name: song, Artists: artists}
+	newSong := general.NewSong(songID, artists, song)
 	for _, artist := range artists {
 		fake.songsDB[artist.Name][song] = newSong
 	}
+	fake.addToOutbox("newSong", newSong)
 	return newSong, nil
 }
+
+func (fake testDB) GetAlbumsFromArtist(artist string, offset, max int) ([]general.Album, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	albums := make([]general.Album, 0, len(fake.albumsDB))
+	for _, album := range fake.albumsDB {
+		if album.Artist.Name == artist {
+			albums = append(albums, general.NewAlbum(album.ID, album.Name, album.Artist, nil))
+		}
+	}
+	sort.Slice(albums, func(i, j int) bool {
+		return albums[i].Name < albums[j].Name
+	})
+	return albums[int(math.Min(float64(offset), float64(len(albums)))):int(math.Min(float64(offset+max), float64(len(albums))))], nil
+}
+
+func (fake testDB) FindAlbumByID(albumID int) (general.Album, error) {
+	album, ok := fake.albumsDB[albumID]
+	if !ok {
+		return general.Album{}, general.GetDBError("Not found", general.NotFoundError)
+	}
+	return album, nil
+}
+
+func (fake testDB) AddAlbum(album string, artist general.Artist, songs []general.Song) (general.Album, error) {
+	if len(album) == 0 {
+		return general.Album{}, general.GetDBError("Missing name", general.InvalidInput)
+	}
+	if len(songs) == 0 {
+		return general.Album{}, general.GetDBError("No songs are given for adding an album", general.InvalidInput)
+	}
+	if _, ok := fake.artistsDB[artist.Name]; !ok {
+		return general.Album{}, general.GetDBError("Artist doesn't exist", general.MissingForeignKey)
+	}
+	for _, existingAlbum := range fake.albumsDB {
+		if existingAlbum.Artist.ID == artist.ID && existingAlbum.Name == album {
+			return general.Album{}, general.GetDBError("Duplicate entry", general.DuplicateEntry)
+		}
+	}
+	newAlbum := general.NewAlbum(len(fake.albumsDB)+1, album, artist, songs)
+	fake.albumsDB[newAlbum.ID] = newAlbum
+	fake.addToOutbox("newAlbum", newAlbum)
+	return newAlbum, nil
+}
+
+func (fake testDB) GetSongsFromGenre(genre string, offset, max int) ([]general.Song, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	songsOfGenre := make(map[int]general.Song)
+	for _, discography := range fake.songsDB {
+		for _, song := range discography {
+			for _, genreOfSong := range song.Genres {
+				if genreOfSong.Name == genre {
+					songsOfGenre[song.ID] = song
+				}
+			}
+		}
+	}
+	songs := make([]general.Song, 0, len(songsOfGenre))
+	for _, song := range songsOfGenre {
+		songs = append(songs, song)
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		if songs[i].Name == songs[j].Name {
+			return songs[i].ID < songs[j].ID
+		}
+		return songs[i].Name < songs[j].Name
+	})
+	return songs[int(math.Min(float64(offset), float64(len(songs)))):int(math.Min(float64(offset+max), float64(len(songs))))], nil
+}
+
+func (fake testDB) FindGenreByName(genre string) (general.Genre, error) {
+	foundGenre, ok := fake.genresDB[genre]
+	if !ok {
+		return general.Genre{}, general.GetDBError("Not found", general.NotFoundError)
+	}
+	return foundGenre, nil
+}
+
+func (fake testDB) AddGenre(genre string) (general.Genre, error) {
+	if len(genre) == 0 {
+		return general.Genre{}, general.GetDBError("Missing name", general.InvalidInput)
+	}
+	if _, ok := fake.genresDB[genre]; ok {
+		return general.Genre{}, general.GetDBError("Duplicate genre", general.DuplicateEntry)
+	}
+	newGenre := general.NewGenre(len(fake.genresDB)+1, genre)
+	fake.genresDB[genre] = newGenre
+	return newGenre, nil
+}
+
+func (fake testDB) AddGenreToSong(songID int, genre general.Genre) error {
+	song, err := fake.FindSongByID(songID)
+	if err != nil {
+		return general.GetDBError("Song doesn't exist", general.MissingForeignKey)
+	}
+	for _, genreOfSong := range song.Genres {
+		if genreOfSong.ID == genre.ID {
+			return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+		}
+	}
+	song.Genres = append(append(make([]general.Genre, 0, len(song.Genres)+1), song.Genres...), genre)
+	fake.updateSong(song)
+	return nil
+}
+
+func (fake testDB) RemoveGenreFromSong(songID int, genre general.Genre) error {
+	song, err := fake.FindSongByID(songID)
+	if err != nil {
+		return general.GetDBError("Not found", general.NotFoundError)
+	}
+	genres := make([]general.Genre, 0, len(song.Genres))
+	for _, genreOfSong := range song.Genres {
+		if genreOfSong.ID != genre.ID {
+			genres = append(genres, genreOfSong)
+		}
+	}
+	if len(genres) == len(song.Genres) {
+		return general.GetDBError("Not found", general.NotFoundError)
+	}
+	song.Genres = genres
+	fake.updateSong(song)
+	return nil
+}
+
+func (fake testDB) AddGenreToAlbum(albumID int, genre general.Genre) error {
+	album, ok := fake.albumsDB[albumID]
+	if !ok {
+		return general.GetDBError("Album doesn't exist", general.MissingForeignKey)
+	}
+	for _, genreOfAlbum := range album.Genres {
+		if genreOfAlbum.ID == genre.ID {
+			return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+		}
+	}
+	album.Genres = append(append(make([]general.Genre, 0, len(album.Genres)+1), album.Genres...), genre)
+	fake.albumsDB[albumID] = album
+	return nil
+}
+
+func (fake testDB) RemoveGenreFromAlbum(albumID int, genre general.Genre) error {
+	album, ok := fake.albumsDB[albumID]
+	if !ok {
+		return general.GetDBError("Not found", general.NotFoundError)
+	}
+	genres := make([]general.Genre, 0, len(album.Genres))
+	for _, genreOfAlbum := range album.Genres {
+		if genreOfAlbum.ID != genre.ID {
+			genres = append(genres, genreOfAlbum)
+		}
+	}
+	if len(genres) == len(album.Genres) {
+		return general.GetDBError("Not found", general.NotFoundError)
+	}
+	album.Genres = genres
+	fake.albumsDB[albumID] = album
+	return nil
+}
+
+// updateSong replaces the song of every contributing artist
+func (fake testDB) updateSong(song general.Song) {
+	for _, artist := range song.Artists {
+		fake.songsDB[artist.Name][song.Name] = song
+	}
+}
+
+// SearchArtists returns all artists. The ranking of the handler decides which artists are results of the search.
+func (fake testDB) SearchArtists(query string, limit int) ([]general.Artist, error) {
+	if limit <= 0 {
+		return nil, general.GetDBError("Can not search with non-positive limit", general.InvalidOffsetMax)
+	}
+	artists := make([]general.Artist, 0, len(fake.artistsDB))
+	for _, artist := range fake.artistsDB {
+		artists = append(artists, general.NewArtist(artist.id, artist.name, artist.prefix))
+	}
+	return artists, nil
+}
+
+// SearchSongs returns all songs. The ranking of the handler decides which songs are results of the search.
+func (fake testDB) SearchSongs(query string, limit int) ([]general.Song, error) {
+	if limit <= 0 {
+		return nil, general.GetDBError("Can not search with non-positive limit", general.InvalidOffsetMax)
+	}
+	uniqueSongs := make(map[int]general.Song)
+	for _, discography := range fake.songsDB {
+		for _, song := range discography {
+			uniqueSongs[song.ID] = song
+		}
+	}
+	songs := make([]general.Song, 0, len(uniqueSongs))
+	for _, song := range uniqueSongs {
+		songs = append(songs, song)
+	}
+	return songs, nil
+}
+
+func (fake testDB) GetLinkedArtists() ([]database.LinkedArtist, error) {
+	linkedArtists := make([]database.LinkedArtist, 0, len(fake.artistsDB))
+	for _, artist := range fake.artistsDB {
+		if artist.linkSpotify != "" {
+			linkedArtists = append(linkedArtists, database.LinkedArtist{Artist: general.NewArtist(artist.id, artist.name, artist.prefix), LinkSpotify: artist.linkSpotify})
+		}
+	}
+	sort.SliceStable(linkedArtists, func(i, j int) bool {
+		return linkedArtists[i].Artist.ID < linkedArtists[j].Artist.ID
+	})
+	return linkedArtists, nil
+}
@@ -4,8 +4,10 @@ go 1.14
 
 require (
 	general v1.0.0
+	github.com/go-playground/validator v9.31.0+incompatible
 	github.com/gorilla/mux v1.7.4
 	github.com/optiopay/kafka/v2 v2.1.1
+	gopkg.in/yaml.v2 v2.2.5
 )
 
 replace general => ../general
@@ -1,44 +1,46 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"general"
-	"io"
+	"general/config"
 	"log"
 	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/mux"
-	"github.com/optiopay/kafka/v2"
 )
 
 // NewGatewayServer returns a new server that will be functioning as a API gateway and a function that starts up the server
-func NewGatewayServer(handler *GatewayHandler, broker *kafka.Broker, servername, port string) (server *http.Server, start func()) {
-	s, channel, startServer := general.NewServer(servername, port, initRoutes(handler), broker, nil, handler.logger)
+func NewGatewayServer(handler *GatewayHandler, bus general.Bus, cfg config.Config) (server *http.Server, start func()) {
+	s, channel, startServer := general.NewServer(cfg, initRoutes(handler), bus, nil, handler.logger)
 	server = s
 	start = func() {
 		go func() {
 			for service := range channel {
-				handler.services[service.Name] = service
+				if err := handler.registry.Register(service); err != nil {
+					handler.logger.Printf("[ERROR] Can't register service %v with address %v: %s\n", service.Name, service.Address, err)
+				}
 			}
 		}()
+		go handler.registry.WatchHealth(healthInterval)
 		startServer()
 	}
 	return
 }
 
-// initRoutes will returns a router with the necessary routes registered to it.
+// initRoutes will returns a router with the routes of the gateway itself registered to it.
+// The other requests are redirected with the routes from the route table.
 func initRoutes(handler *GatewayHandler) *mux.Router {
 	router := mux.NewRouter()
-	router.HandleFunc("/signup", handler.redirect("users"))
-	router.HandleFunc("/login", handler.redirect("users"))
-	router.HandleFunc("/validate/", handler.redirect("users"))
-	router.HandleFunc("/api/like", handler.redirect("likes"))
-	router.HandleFunc("/api/dislike", handler.redirect("likes"))
-	router.HandleFunc("/api/artists/{firstLetter}", handler.redirect("discography"))
-	router.HandleFunc("/api/artist/{artist}", handler.redirect("discography"))
-	router.HandleFunc("/admin/artist", handler.redirect("discography"))
-	router.HandleFunc("/admin/song", handler.redirect("discography"))
+	router.NotFoundHandler = http.HandlerFunc(handler.serveRoutes)
+
+	registryRouter := router.Path("/intern/registry").Methods(http.MethodGet).Subrouter()
+	registryRouter.Use(general.GetTokenMiddleWareForRoles(handler.logger, general.RoleAdmin, general.RoleInternal))
+	registryRouter.HandleFunc("", handler.getRegistry)
 
 	internRouter := router.PathPrefix("/intern").Methods(http.MethodGet).Subrouter()
 	internRouter.Use(general.GetInternalRequestMiddleware(handler.logger))
@@ -51,68 +53,114 @@ func initRoutes(handler *GatewayHandler) *mux.Router {
 // It will also be used for getting the addresses of the differenct services for internal requests.
 type GatewayHandler struct {
 	logger      *log.Logger
-	client      http.Client
+	proxy       *httputil.ReverseProxy
 	sendMessage func(string, []byte) error
-	services    map[string]general.Service
+	registry    *Registry
+	limiter     *RateLimiter
+	routes      atomic.Value
 }
 
-// NewGatewayHandler returns a GatewayHandler with the given data.
-// It returns an error if sendMessage is nil
-func NewGatewayHandler(logger *log.Logger, client http.Client, sendMessage func(string, []byte) error) (*GatewayHandler, error) {
+// NewGatewayHandler returns a GatewayHandler with the given data. Requests are send to the instances of services from the registry with the given transport.
+// If transport is nil, then http.DefaultTransport is used. If limiter is nil, then requests aren't rate limited. It returns an error if sendMessage or registry is nil
+func NewGatewayHandler(logger *log.Logger, registry *Registry, limiter *RateLimiter, transport http.RoundTripper, sendMessage func(string, []byte) error) (*GatewayHandler, error) {
 	if sendMessage == nil {
 		return nil, errors.New("sendMessage can't be nil")
 	}
-	return &GatewayHandler{logger: logger, client: client, sendMessage: sendMessage, services: make(map[string]general.Service)}, nil
+	if registry == nil {
+		return nil, errors.New("registry can't be nil")
+	}
+	handler := &GatewayHandler{logger: logger, sendMessage: sendMessage, registry: registry, limiter: limiter}
+	handler.proxy = &httputil.ReverseProxy{
+		Director:       handler.direct,
+		Transport:      transport,
+		FlushInterval:  -1,
+		ErrorLog:       logger,
+		ModifyResponse: handler.logResponse,
+		ErrorHandler:   handler.proxyError,
+	}
+	return handler, nil
+}
+
+// limitMiddleware returns the rate limiting middleware of the group or middleware that does nothing if there is no limiter
+func (handler *GatewayHandler) limitMiddleware(group string) mux.MiddlewareFunc {
+	if handler.limiter == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return handler.limiter.Middleware(group)
 }
+
+// instanceKey is the key of the chosen instance of a service in the context of a request that is redirected
+type instanceKey struct{}
+
+// redirect returns a handler that streams the request to an instance of the service and the response of the instance back to the client
 func (handler *GatewayHandler) redirect(serviceName string) func(http.ResponseWriter, *http.Request) {
 	return func(response http.ResponseWriter, request *http.Request) {
-		service, ok := handler.services[serviceName]
+		chosen, done, ok := handler.registry.pick(serviceName)
 		if !ok {
 			handler.logger.Printf("Failed to redirect request due to missing service: %v\n", serviceName)
-			general.SendError(response, http.StatusInternalServerError)
+			general.SendError(response, http.StatusServiceUnavailable)
 			return
 		}
-		cookie, cookieErr := request.Cookie("token")
-		target := "http://localhost" + service.Address + request.URL.Path
-		if len(request.URL.RawQuery) > 0 {
-			target += "?" + request.URL.RawQuery
-		}
-		log.Printf("Redirect request to: %s", target)
-		body := new(bytes.Buffer)
-		body.ReadFrom(request.Body)
-		bodyRequest, writer := io.Pipe()
-		go func() {
-			writer.Write(body.Bytes())
-			writer.Close()
-		}()
-		req, err := http.NewRequest(request.Method, target, bodyRequest)
-		if cookieErr == nil {
-			req.Header.Add("Token", cookie.Value)
-		}
-		if err != nil {
-			handler.logger.Printf("Failed to create request: %s\n", err)
-			general.SendError(response, http.StatusInternalServerError)
-			return
-		}
-		resp, err := handler.client.Do(req)
-		if err != nil {
-			handler.logger.Printf("Failed to redirect request: %s\n", err)
-			general.SendError(response, http.StatusInternalServerError)
-			return
+		defer done()
+		handler.logger.Printf("Redirect request %v %v to: %v\n", request.Method, request.URL.Path, chosen.target)
+		handler.proxy.ServeHTTP(response, request.WithContext(context.WithValue(request.Context(), instanceKey{}, chosen)))
+	}
+}
+
+// direct changes the request into a request for the service in the context of the request
+func (handler *GatewayHandler) direct(request *http.Request) {
+	target := request.Context().Value(instanceKey{}).(*instance).target
+	request.URL.Scheme = target.Scheme
+	request.URL.Host = target.Host
+	// A service behind a path prefix on a remote host
+	if prefix := strings.TrimSuffix(target.Path, "/"); prefix != "" {
+		request.URL.Path = prefix + request.URL.Path
+		if request.URL.RawPath != "" {
+			request.URL.RawPath = prefix + request.URL.RawPath
 		}
-		response.WriteHeader(resp.StatusCode)
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(resp.Body)
-		response.Write(buf.Bytes())
-		handler.logger.Printf("%v: Sending response: %v\n", target, resp.StatusCode)
 	}
+	// The gateway is the entrypoint, so X-Forwarded headers from the client aren't trusted.
+	// The proxy adds the address of the client to the empty X-Forwarded-For header.
+	request.Header.Del("X-Forwarded-For")
+	request.Header.Set("X-Forwarded-Host", request.Host)
+	if request.TLS != nil {
+		request.Header.Set("X-Forwarded-Proto", "https")
+	} else {
+		request.Header.Set("X-Forwarded-Proto", "http")
+	}
+	request.Host = target.Host
+}
+
+func (handler *GatewayHandler) logResponse(response *http.Response) error {
+	handler.logger.Printf("%v: Sending response: %v\n", response.Request.URL, response.StatusCode)
+	return nil
+}
+
+// proxyError marks the instance as unhealthy if it can't be reached, the instance is used again after a succesful health probe
+func (handler *GatewayHandler) proxyError(response http.ResponseWriter, request *http.Request, err error) {
+	handler.logger.Printf("Failed to redirect request to %v: %s\n", request.URL, err)
+	if request.Context().Err() == nil {
+		handler.registry.reportFailure(request.Context().Value(instanceKey{}).(*instance))
+	}
+	general.SendError(response, http.StatusBadGateway)
 }
 
 func (handler *GatewayHandler) getServices(response http.ResponseWriter, request *http.Request) {
+	services := handler.registry.Services()
 	response.Header().Set("Content-Type", "application/json")
 	response.WriteHeader(http.StatusOK)
-	err := general.WriteToJSON(&handler.services, response)
+	err := general.WriteToJSON(&services, response)
 	if err != nil {
 		handler.logger.Printf("[ERROR] %s\n", err)
 	}
 }
+
+// getRegistry responds with the state of all registered instances per service
+func (handler *GatewayHandler) getRegistry(response http.ResponseWriter, request *http.Request) {
+	status := handler.registry.Status()
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	if err := general.WriteToJSON(&status, response); err != nil {
+		handler.logger.Printf("[ERROR] %s\n", err)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"general"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBackend is an instance of a service that records the last request that isn't a health probe
+type testBackend struct {
+	server   *httptest.Server
+	requests chan *http.Request
+	bodies   chan []byte
+	handle   func(http.ResponseWriter, *http.Request)
+}
+
+func newTestBackend(handle func(http.ResponseWriter, *http.Request)) *testBackend {
+	backend := &testBackend{requests: make(chan *http.Request, 1), bodies: make(chan []byte, 1), handle: handle}
+	backend.server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if strings.HasSuffix(request.URL.Path, "/health") {
+			response.WriteHeader(http.StatusOK)
+			return
+		}
+		body, _ := ioutil.ReadAll(request.Body)
+		backend.requests <- request
+		backend.bodies <- body
+		if backend.handle != nil {
+			backend.handle(response, request)
+			return
+		}
+		response.WriteHeader(http.StatusOK)
+	}))
+	return backend
+}
+
+// testGateway returns a running gateway with the given routes and a registry in which the backend is registered as the given service on the address
+func testGateway(t *testing.T, routes []Route, service, address string) (*httptest.Server, *Registry) {
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	handler, err := NewGatewayHandler(general.TestEmptyLogger(), registry, nil, nil, general.TestSendMessageEmpty())
+	if err != nil {
+		t.Fatalf("Can't create gateway handler: %s\n", err)
+	}
+	if err = handler.SetRoutes(RouteTable{Routes: routes}); err != nil {
+		t.Fatalf("Can't set routes: %s\n", err)
+	}
+	if address != "" {
+		register(t, registry, general.Service{Name: service, Address: address})
+	}
+	return httptest.NewServer(initRoutes(handler)), registry
+}
+
+func TestRedirect_request(t *testing.T) {
+	backend := newTestBackend(nil)
+	defer backend.server.Close()
+	routes := []Route{{Path: "/api/like", Service: "likes"}, {Prefix: "/likes/", Service: "prefixed"}}
+	cases := map[string]struct {
+		path, address    string
+		header           http.Header
+		body             string
+		expectedPath     string
+		expectedHeader   http.Header
+		unexpectedHeader []string
+	}{
+		"Request with body": {"/api/like?id=1", backend.server.URL, nil, strings.Repeat("like", 10000), "/api/like", nil, nil},
+		"Forwarded headers": {"/api/like", backend.server.URL, http.Header{"X-Forwarded-For": {"198.51.100.7"}, "X-Forwarded-Proto": {"https"}}, "", "/api/like",
+			http.Header{"X-Forwarded-For": {"127.0.0.1"}, "X-Forwarded-Proto": {"http"}}, nil},
+		"Service behind a prefix on a remote host": {"/api/like", backend.server.URL + "/likes/", nil, "", "/likes/api/like", nil, nil},
+		"Token from cookies": {"/api/like", backend.server.URL, http.Header{"Cookie": {"token=abc; refreshToken=def"}}, "", "/api/like",
+			http.Header{"Token": {"abc"}, "Refresh-Token": {"def"}}, nil},
+		"Request without cookies": {"/api/like", backend.server.URL, nil, "", "/api/like", nil, []string{"Token", "Refresh-Token"}},
+	}
+	for name, test := range cases {
+		gateway, _ := testGateway(t, routes, "likes", test.address)
+		request, err := http.NewRequest(http.MethodPost, gateway.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("%v: Can't create request: %s\n", name, err)
+		}
+		for key, values := range test.header {
+			request.Header[key] = values
+		}
+		response, err := http.DefaultClient.Do(request)
+		gateway.Close()
+		if err != nil {
+			t.Errorf("%v: Request failed: %s\n", name, err)
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, http.StatusOK, response.StatusCode)
+			continue
+		}
+		received, body := <-backend.requests, <-backend.bodies
+		if received.URL.Path != test.expectedPath {
+			t.Errorf("%v: Expects path %v but got: %v\n", name, test.expectedPath, received.URL.Path)
+		}
+		if string(body) != test.body {
+			t.Errorf("%v: Expects a body of %v bytes but got %v bytes\n", name, len(test.body), len(body))
+		}
+		if strings.Contains(test.path, "?") && received.URL.RawQuery != strings.SplitN(test.path, "?", 2)[1] {
+			t.Errorf("%v: Expects query of %v but got: %v\n", name, test.path, received.URL.RawQuery)
+		}
+		if host := received.Header.Get("X-Forwarded-Host"); host != strings.TrimPrefix(gateway.URL, "http://") {
+			t.Errorf("%v: Expects X-Forwarded-Host %v but got: %v\n", name, gateway.URL, host)
+		}
+		for key, values := range test.expectedHeader {
+			if got := received.Header[key]; strings.Join(got, ",") != strings.Join(values, ",") {
+				t.Errorf("%v: Expects header %v: %v but got: %v\n", name, key, values, got)
+			}
+		}
+		for _, key := range test.unexpectedHeader {
+			if got := received.Header.Get(key); got != "" {
+				t.Errorf("%v: Expects no header %v but got: %v\n", name, key, got)
+			}
+		}
+	}
+}
+
+func TestRedirect_response(t *testing.T) {
+	backend := newTestBackend(func(response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("X-Request-Id", "42")
+		http.SetCookie(response, &http.Cookie{Name: "token", Value: "abc"})
+		response.WriteHeader(http.StatusCreated)
+		response.Write([]byte(`{"id":42}`))
+	})
+	defer backend.server.Close()
+	gateway, _ := testGateway(t, []Route{{Path: "/signup", Service: "users"}}, "users", backend.server.URL)
+	defer gateway.Close()
+	response, err := http.Post(gateway.URL+"/signup", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Request failed: %s\n", err)
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusCreated || string(body) != `{"id":42}` {
+		t.Errorf("Expects the response of the service but got %v: %s\n", response.StatusCode, body)
+	}
+	for key, expected := range map[string]string{"Content-Type": "application/json", "X-Request-Id": "42", "Set-Cookie": "token=abc"} {
+		if value := response.Header.Get(key); value != expected {
+			t.Errorf("Expects header %v: %v but got: %v\n", key, expected, value)
+		}
+	}
+}
+
+func TestRedirect_streamsResponse(t *testing.T) {
+	release := make(chan struct{})
+	backend := newTestBackend(func(response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("first"))
+		response.(http.Flusher).Flush()
+		<-release
+		response.Write([]byte("second"))
+	})
+	defer backend.server.Close()
+	gateway, _ := testGateway(t, []Route{{Prefix: "/api/notifications", Service: "notifications"}}, "notifications", backend.server.URL)
+	defer gateway.Close()
+	response, err := http.Get(gateway.URL + "/api/notifications/stream")
+	if err != nil {
+		close(release)
+		t.Fatalf("Request failed: %s\n", err)
+	}
+	defer response.Body.Close()
+	first := make(chan string, 1)
+	go func() {
+		buffer := make([]byte, len("first"))
+		io.ReadFull(response.Body, buffer)
+		first <- string(buffer)
+	}()
+	select {
+	case chunk := <-first:
+		if chunk != "first" {
+			t.Errorf("Expects the first chunk but got: %v\n", chunk)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expects the first chunk before the service finishes the response\n")
+	}
+	close(release)
+	rest, _ := ioutil.ReadAll(response.Body)
+	if string(rest) != "second" {
+		t.Errorf("Expects the rest of the response but got: %s\n", rest)
+	}
+}
+
+func TestRedirect_streamsRequest(t *testing.T) {
+	received := make(chan string, 1)
+	// The service reads the first part of the body before the client sends the rest
+	backend := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if strings.HasSuffix(request.URL.Path, "/health") {
+			response.WriteHeader(http.StatusOK)
+			return
+		}
+		buffer := make([]byte, len("first"))
+		io.ReadFull(request.Body, buffer)
+		received <- string(buffer)
+		io.Copy(ioutil.Discard, request.Body)
+		response.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	gateway, _ := testGateway(t, []Route{{Path: "/admin/import", Service: "discography"}}, "discography", backend.URL)
+	defer gateway.Close()
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		response, err := http.Post(gateway.URL+"/admin/import", "text/plain", reader)
+		if err == nil {
+			response.Body.Close()
+		}
+		done <- err
+	}()
+	writer.Write([]byte("first"))
+	select {
+	case chunk := <-received:
+		if chunk != "first" {
+			t.Errorf("Expects the first part of the body but got: %v\n", chunk)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expects the service to receive the first part of the body before the client is done\n")
+	}
+	writer.Write([]byte("second"))
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Request failed: %s\n", err)
+	}
+}
+
+func TestRedirect_unavailable(t *testing.T) {
+	backend := newTestBackend(nil)
+	routes := []Route{{Path: "/api/like", Service: "likes"}}
+	cases := map[string]struct {
+		address            string
+		stopBackend        bool
+		path               string
+		expectedStatusCode int
+	}{
+		"Unknown route":         {backend.server.URL, false, "/api/unknown", http.StatusNotFound},
+		"No instance":           {"", false, "/api/like", http.StatusServiceUnavailable},
+		"Instance that is gone": {backend.server.URL, true, "/api/like", http.StatusBadGateway},
+	}
+	for name, test := range cases {
+		gateway, _ := testGateway(t, routes, "likes", test.address)
+		if test.stopBackend {
+			backend.server.Close()
+		}
+		response, err := http.Post(gateway.URL+test.path, "application/json", bytes.NewReader(nil))
+		gateway.Close()
+		if err != nil {
+			t.Errorf("%v: Request failed: %s\n", name, err)
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.StatusCode)
+		}
+	}
+	backend.server.Close()
+}
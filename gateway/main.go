@@ -1,29 +1,43 @@
 package main
 
 import (
+	"flag"
 	"general"
+	"general/config"
 	"log"
-	"net/http"
 	"os"
-
-	"github.com/optiopay/kafka/v2"
 )
 
-// These configurations will be exported to a file
-const servername, port string = "gateway", ":9919"
+var configPath = flag.String("config", "config.yaml", "Path to the configuration file of this service")
 
 func main() {
-	logger := log.New(os.Stdout, servername, log.LstdFlags|log.Lshortfile)
-	broker, closeBroker := general.ConnectToKafka(logger, servername)
-	defer closeBroker()
-	if topicErr := general.CreateTopics(broker, logger, "newService"); topicErr != nil {
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[ERROR] Can't load configuration due to: %s\n", err)
+	}
+	logger := log.New(os.Stdout, cfg.Servername, log.LstdFlags|log.Lshortfile)
+	if err = general.ConfigureKeys(logger, cfg.Keys); err != nil {
+		logger.Fatalf("[ERROR] Can't load keys due to: %s\n", err)
+	}
+	go general.WatchKeys(logger, *configPath)
+	bus, closeBus := general.ConnectToBus(logger, cfg)
+	defer closeBus()
+	if topicErr := bus.CreateTopics("newService"); topicErr != nil {
 		logger.Fatalf("[ERROR] Failed to create topics due to: %s\n", topicErr)
 	}
-	sendMessage := general.GetSendMessage(broker.Producer(kafka.NewProducerConf()))
-	handler, err := NewGatewayHandler(logger, http.Client{}, sendMessage)
+	handler, err := NewGatewayHandler(logger, NewRegistry(logger, cfg.Gateway.Balancing, nil), NewRateLimiter(logger, cfg.Gateway.RateLimits, nil), nil, bus.Publish)
 	if err != nil {
 		logger.Fatalf("[ERROR] Can't create handler due to: %s\n", err)
 	}
-	_, startServer := NewGatewayServer(handler, broker, servername, port)
+	routes, err := LoadRoutes(cfg.Gateway.Routes)
+	if err != nil {
+		logger.Fatalf("[ERROR] Can't load routes due to: %s\n", err)
+	}
+	if err = handler.SetRoutes(routes); err != nil {
+		logger.Fatalf("[ERROR] Can't set routes due to: %s\n", err)
+	}
+	go handler.WatchRoutes(cfg.Gateway.Routes)
+	_, startServer := NewGatewayServer(handler, bus, cfg)
 	startServer()
 }
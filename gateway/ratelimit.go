@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"general"
+	"general/config"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The groups of routes that have their own rate limit
+const (
+	GroupLogin = "login"
+	GroupAPI   = "api"
+	GroupAdmin = "admin"
+)
+
+// DefaultRateLimits contains the limits for the groups that aren't configured
+var DefaultRateLimits = map[string]config.RateLimit{
+	GroupLogin: {Rate: 0.2, Burst: 5},
+	GroupAPI:   {Rate: 10, Burst: 20},
+	GroupAdmin: {Rate: 5, Burst: 10},
+}
+
+// bucketLifetime is the time after which an unused bucket is removed from the MemoryStore
+const bucketLifetime = 10 * time.Minute
+
+// RateLimitStore keeps the token buckets of the rate limiter. A store that is shared between instances of the gateway limits clients over all instances.
+type RateLimitStore interface {
+	// Take removes a token from the bucket with the given key. If the bucket is empty, it returns false and the time until a token is available.
+	Take(key string, limit config.RateLimit, now time.Time) (bool, time.Duration)
+}
+
+// MemoryStore is a RateLimitStore that keeps the buckets in the memory of a single gateway
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), lastPrune: time.Now()}
+}
+
+// Take removes a token from the bucket with the given key, a new bucket is full
+func (store *MemoryStore) Take(key string, limit config.RateLimit, now time.Time) (bool, time.Duration) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.prune(now)
+	known, ok := store.buckets[key]
+	if !ok {
+		known = &bucket{tokens: float64(limit.Burst), updated: now}
+		store.buckets[key] = known
+	}
+	known.tokens = math.Min(float64(limit.Burst), known.tokens+now.Sub(known.updated).Seconds()*limit.Rate)
+	known.updated = now
+	if known.tokens < 1 {
+		return false, time.Duration((1 - known.tokens) / limit.Rate * float64(time.Second))
+	}
+	known.tokens--
+	return true, 0
+}
+
+// prune removes the buckets that aren't used for bucketLifetime, these buckets are full again
+func (store *MemoryStore) prune(now time.Time) {
+	if now.Sub(store.lastPrune) < bucketLifetime {
+		return
+	}
+	for key, known := range store.buckets {
+		if now.Sub(known.updated) >= bucketLifetime {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastPrune = now
+}
+
+// RateLimiter limits the number of requests per user for each group of routes. Anonymous requests are limited per IP address.
+type RateLimiter struct {
+	logger *log.Logger
+	store  RateLimitStore
+	limits map[string]config.RateLimit
+	// now returns the current time, tests replace it in order to control the refill of the buckets
+	now func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with the given limits per group, groups that are missing use DefaultRateLimits.
+// The buckets are kept in the given store, if store is nil then a MemoryStore is used.
+func NewRateLimiter(logger *log.Logger, limits map[string]config.RateLimit, store RateLimitStore) *RateLimiter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	merged := make(map[string]config.RateLimit)
+	for group, limit := range DefaultRateLimits {
+		merged[group] = limit
+	}
+	for group, limit := range limits {
+		merged[group] = limit
+	}
+	return &RateLimiter{logger: logger, store: store, limits: merged, now: time.Now}
+}
+
+// Middleware returns middleware that responds with 429 if the client exceeds the limit of the group
+func (limiter *RateLimiter) Middleware(group string) func(http.Handler) http.Handler {
+	limit, ok := limiter.limits[group]
+	if !ok {
+		limiter.logger.Fatalf("[ERROR] No rate limit for group %v\n", group)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			client := limiter.clientKey(request)
+			allowed, wait := limiter.store.Take(group+"|"+client, limit, limiter.now())
+			if !allowed {
+				limiter.logger.Printf("[WARNING] Rate limit of %v is exceeded by %v\n", group, client)
+				response.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+				general.SendError(response, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+// clientKey returns the user from a valid token in the token cookie or otherwise the IP address of the client
+func (limiter *RateLimiter) clientKey(request *http.Request) string {
+	if cookie, err := request.Cookie("token"); err == nil {
+		if creds, err := general.ValidateToken(cookie.Value); err == nil {
+			return fmt.Sprintf("user:%v", creds.ID)
+		}
+	}
+	// The gateway is the entrypoint, so the address of the connection is the address of the client
+	ip, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		ip = request.RemoteAddr
+	}
+	return "ip:" + ip
+}
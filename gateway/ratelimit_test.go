@@ -0,0 +1,182 @@
+package main
+
+import (
+	"general"
+	"general/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testClock is a clock that only moves when the test advances it
+type testClock struct {
+	current time.Time
+}
+
+func (clock *testClock) now() time.Time {
+	return clock.current
+}
+
+func (clock *testClock) advance(duration time.Duration) {
+	clock.current = clock.current.Add(duration)
+}
+
+// testLimiter returns a RateLimiter with the given limit for every group that uses the clock
+func testLimiter(limit config.RateLimit, clock *testClock) *RateLimiter {
+	limiter := NewRateLimiter(general.TestEmptyLogger(), map[string]config.RateLimit{GroupLogin: limit, GroupAPI: limit, GroupAdmin: limit}, nil)
+	limiter.now = clock.now
+	return limiter
+}
+
+func TestMemoryStore_take(t *testing.T) {
+	// take is a request after the given time since the previous request that expects the given result
+	type take struct {
+		after        time.Duration
+		expectedOK   bool
+		expectedWait time.Duration
+	}
+	cases := map[string]struct {
+		limit config.RateLimit
+		takes []take
+	}{
+		"New bucket is full":             {config.RateLimit{Rate: 1, Burst: 2}, []take{{0, true, 0}, {0, true, 0}, {0, false, time.Second}}},
+		"Empty bucket is refilled":       {config.RateLimit{Rate: 1, Burst: 1}, []take{{0, true, 0}, {0, false, time.Second}, {time.Second, true, 0}}},
+		"Partly refilled bucket":         {config.RateLimit{Rate: 2, Burst: 1}, []take{{0, true, 0}, {250 * time.Millisecond, false, 250 * time.Millisecond}}},
+		"Refill stops at the burst":      {config.RateLimit{Rate: 10, Burst: 2}, []take{{0, true, 0}, {time.Hour, true, 0}, {0, true, 0}, {0, false, 100 * time.Millisecond}}},
+		"Slow rate":                      {config.RateLimit{Rate: 0.2, Burst: 1}, []take{{0, true, 0}, {time.Second, false, 4 * time.Second}, {4 * time.Second, true, 0}}},
+		"Rejected request uses no token": {config.RateLimit{Rate: 1, Burst: 1}, []take{{0, true, 0}, {0, false, time.Second}, {0, false, time.Second}, {time.Second, true, 0}}},
+	}
+	for name, test := range cases {
+		store := NewMemoryStore()
+		now := time.Now()
+		for index, take := range test.takes {
+			now = now.Add(take.after)
+			ok, wait := store.Take("login|ip:127.0.0.1", test.limit, now)
+			if ok != take.expectedOK {
+				t.Errorf("%v: Expects request #%v to be allowed: %v but got: %v\n", name, index, take.expectedOK, ok)
+			}
+			if wait != take.expectedWait {
+				t.Errorf("%v: Expects request #%v to wait %v but got: %v\n", name, index, take.expectedWait, wait)
+			}
+		}
+	}
+}
+
+func TestMemoryStore_keys(t *testing.T) {
+	store := NewMemoryStore()
+	limit := config.RateLimit{Rate: 1, Burst: 1}
+	now := time.Now()
+	if ok, _ := store.Take("login|ip:127.0.0.1", limit, now); !ok {
+		t.Errorf("Expects the first request to be allowed\n")
+	}
+	// Every client has its own bucket in every group
+	for _, key := range []string{"login|ip:127.0.0.2", "api|ip:127.0.0.1", "login|user:1"} {
+		if ok, _ := store.Take(key, limit, now); !ok {
+			t.Errorf("Expects the first request of %v to be allowed\n", key)
+		}
+	}
+}
+
+func TestMemoryStore_prune(t *testing.T) {
+	store := NewMemoryStore()
+	limit := config.RateLimit{Rate: 1, Burst: 1}
+	now := time.Now()
+	store.Take("login|ip:127.0.0.1", limit, now)
+	store.Take("login|ip:127.0.0.2", limit, now.Add(bucketLifetime/2))
+	store.Take("login|ip:127.0.0.3", limit, now.Add(bucketLifetime))
+	if _, ok := store.buckets["login|ip:127.0.0.1"]; ok {
+		t.Errorf("Expects the bucket that isn't used for %v to be removed\n", bucketLifetime)
+	}
+	if len(store.buckets) != 2 {
+		t.Errorf("Expects 2 buckets after pruning but got: %v\n", len(store.buckets))
+	}
+}
+
+func TestRateLimiter_middleware(t *testing.T) {
+	clock := &testClock{current: time.Now()}
+	limiter := testLimiter(config.RateLimit{Rate: 0.5, Burst: 2}, clock)
+	handler := limiter.Middleware(GroupLogin)(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusOK)
+	}))
+	cases := []struct {
+		after              time.Duration
+		remoteAddr         string
+		expectedStatusCode int
+		expectedRetryAfter string
+	}{
+		{0, "198.51.100.7:1234", http.StatusOK, ""},
+		{0, "198.51.100.7:1235", http.StatusOK, ""},
+		{0, "198.51.100.7:1236", http.StatusTooManyRequests, "2"},
+		{0, "198.51.100.8:1234", http.StatusOK, ""},
+		{500 * time.Millisecond, "198.51.100.7:1234", http.StatusTooManyRequests, "2"},
+		{500 * time.Millisecond, "198.51.100.7:1234", http.StatusTooManyRequests, "1"},
+		{time.Second, "198.51.100.7:1234", http.StatusOK, ""},
+	}
+	for index, test := range cases {
+		clock.advance(test.after)
+		request := httptest.NewRequest(http.MethodPost, "/login", nil)
+		request.RemoteAddr = test.remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != test.expectedStatusCode {
+			t.Errorf("Request #%v: Expects statuscode: %v but got: %v\n", index, test.expectedStatusCode, recorder.Code)
+		}
+		if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != test.expectedRetryAfter {
+			t.Errorf("Request #%v: Expects Retry-After %q but got: %q\n", index, test.expectedRetryAfter, retryAfter)
+		}
+	}
+}
+
+func TestRateLimiter_clientKey(t *testing.T) {
+	token, err := general.CreateToken(7, "test", "user")
+	if err != nil {
+		t.Fatalf("Can't create token: %s\n", err)
+	}
+	limiter := testLimiter(config.RateLimit{Rate: 1, Burst: 1}, &testClock{current: time.Now()})
+	cases := map[string]struct {
+		remoteAddr  string
+		token       string
+		expectedKey string
+	}{
+		"Anonymous client":          {"198.51.100.7:1234", "", "ip:198.51.100.7"},
+		"Client with valid token":   {"198.51.100.7:1234", token, "user:7"},
+		"Client with invalid token": {"198.51.100.7:1234", "invalid", "ip:198.51.100.7"},
+		"Client on IPv6":            {"[2001:db8::1]:1234", "", "ip:2001:db8::1"},
+		"Address without port":      {"198.51.100.7", "", "ip:198.51.100.7"},
+	}
+	for name, test := range cases {
+		request := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+		request.RemoteAddr = test.remoteAddr
+		if test.token != "" {
+			request.AddCookie(&http.Cookie{Name: "token", Value: test.token})
+		}
+		if key := limiter.clientKey(request); key != test.expectedKey {
+			t.Errorf("%v: Expects key %v but got: %v\n", name, test.expectedKey, key)
+		}
+	}
+}
+
+func TestRateLimiter_beforeAuthentication(t *testing.T) {
+	backend := newTestBackend(nil)
+	defer backend.server.Close()
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	register(t, registry, general.Service{Name: "users", Address: backend.server.URL})
+	limiter := testLimiter(config.RateLimit{Rate: 1, Burst: 2}, &testClock{current: time.Now()})
+	handler, err := NewGatewayHandler(general.TestEmptyLogger(), registry, limiter, nil, general.TestSendMessageEmpty())
+	if err != nil {
+		t.Fatalf("Can't create gateway handler: %s\n", err)
+	}
+	if err = handler.SetRoutes(RouteTable{Routes: []Route{{Prefix: "/account", Auth: AuthToken, RateLimit: GroupLogin, Service: "users"}}}); err != nil {
+		t.Fatalf("Can't set routes: %s\n", err)
+	}
+	// Requests without a token are rejected by the authentication, but they use the tokens of the client anyway
+	for index, expected := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		request := httptest.NewRequest(http.MethodPut, "/account/password", nil)
+		recorder := httptest.NewRecorder()
+		handler.serveRoutes(recorder, request)
+		if recorder.Code != expected {
+			t.Errorf("Request #%v: Expects statuscode: %v but got: %v\n", index, expected, recorder.Code)
+		}
+	}
+}
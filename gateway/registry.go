@@ -0,0 +1,257 @@
+package main
+
+import (
+	"general"
+	"general/config"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The strategies for choosing an instance of a service
+const (
+	RoundRobin       = "round-robin"
+	LeastConnections = "least-connections"
+)
+
+const (
+	// healthInterval is the time between two health probes of an instance
+	healthInterval = 10 * time.Second
+	// healthTimeout is the time an instance gets for responding to a health probe
+	healthTimeout = 2 * time.Second
+	// maxHealthFailures is the number of consecutive failed health probes after which an instance is evicted
+	maxHealthFailures = 3
+)
+
+// InstanceStatus shows the state of an instance of a service in the registry
+type InstanceStatus struct {
+	Address     string    `json:"address"`
+	Healthy     bool      `json:"healthy"`
+	Failures    int       `json:"failures"`
+	Connections int64     `json:"connections"`
+	LastCheck   time.Time `json:"lastCheck"`
+}
+
+// instance is a registered instance of a service. An instance only gets requests after it responded to a health probe,
+// so instances that are registered by old messages don't get requests if they are gone.
+// A suspect instance failed a request, it only gets requests if there are no other healthy instances until it responds to a health probe.
+type instance struct {
+	service     general.Service
+	target      *url.URL
+	connections int64
+	verified    bool
+	suspect     bool
+	failures    int
+	lastCheck   time.Time
+}
+
+func (known *instance) healthy() bool {
+	return known.verified && known.failures == 0
+}
+
+// Registry keeps track of the instances of all services. Instances are probed periodically and evicted after too many failed probes.
+// Services register themselves periodically, so an evicted instance that is running again is added again.
+type Registry struct {
+	logger    *log.Logger
+	client    *http.Client
+	balancing string
+	mutex     sync.Mutex
+	instances map[string][]*instance
+	next      map[string]int
+}
+
+// NewRegistry returns an empty Registry that chooses instances with the given strategy.
+// Health probes are send with the given client, if client is nil then a client with a timeout of healthTimeout is used.
+func NewRegistry(logger *log.Logger, balancing string, client *http.Client) *Registry {
+	if balancing == "" {
+		balancing = RoundRobin
+	}
+	if client == nil {
+		client = &http.Client{Timeout: healthTimeout}
+	}
+	return &Registry{logger: logger, client: client, balancing: balancing, instances: make(map[string][]*instance), next: make(map[string]int)}
+}
+
+// Register adds an instance of a service. The new instance is probed in the background and gets requests as soon as it responds.
+// Nothing changes for a known instance that registers again.
+func (registry *Registry) Register(service general.Service) error {
+	target, err := config.ServiceURL(service.Address)
+	if err != nil {
+		return err
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for _, known := range registry.instances[service.Name] {
+		if known.service.Address == service.Address {
+			return nil
+		}
+	}
+	added := &instance{service: service, target: target}
+	registry.instances[service.Name] = append(registry.instances[service.Name], added)
+	registry.logger.Printf("Registered instance %v of service %v\n", service.Address, service.Name)
+	go registry.check([]*instance{added})
+	return nil
+}
+
+// pick chooses a healthy instance of the service and returns a function that has to be called when the request to the instance is done.
+// Suspect instances are only chosen if all healthy instances are suspect.
+func (registry *Registry) pick(name string) (*instance, func(), bool) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	healthy := make([]*instance, 0, len(registry.instances[name]))
+	for _, candidate := range registry.instances[name] {
+		if candidate.healthy() && !candidate.suspect {
+			healthy = append(healthy, candidate)
+		}
+	}
+	if len(healthy) == 0 {
+		for _, candidate := range registry.instances[name] {
+			if candidate.healthy() {
+				healthy = append(healthy, candidate)
+			}
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, nil, false
+	}
+	chosen := healthy[registry.next[name]%len(healthy)]
+	registry.next[name]++
+	if registry.balancing == LeastConnections {
+		for _, candidate := range healthy {
+			if atomic.LoadInt64(&candidate.connections) < atomic.LoadInt64(&chosen.connections) {
+				chosen = candidate
+			}
+		}
+	}
+	atomic.AddInt64(&chosen.connections, 1)
+	return chosen, func() { atomic.AddInt64(&chosen.connections, -1) }, true
+}
+
+// reportFailure marks the instance as suspect until it responds to a health probe
+func (registry *Registry) reportFailure(failed *instance) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	failed.suspect = true
+}
+
+// Services returns a healthy instance of every service
+func (registry *Registry) Services() map[string]general.Service {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	services := make(map[string]general.Service)
+	for name, instances := range registry.instances {
+		for _, candidate := range instances {
+			if candidate.healthy() {
+				services[name] = candidate.service
+				break
+			}
+		}
+	}
+	return services
+}
+
+// Status returns the state of all the instances per service
+func (registry *Registry) Status() map[string][]InstanceStatus {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	status := make(map[string][]InstanceStatus)
+	for name, instances := range registry.instances {
+		for _, known := range instances {
+			status[name] = append(status[name], InstanceStatus{
+				Address:     known.service.Address,
+				Healthy:     known.healthy() && !known.suspect,
+				Failures:    known.failures,
+				Connections: atomic.LoadInt64(&known.connections),
+				LastCheck:   known.lastCheck,
+			})
+		}
+		sort.Slice(status[name], func(i, j int) bool { return status[name][i].Address < status[name][j].Address })
+	}
+	return status
+}
+
+// WatchHealth probes all instances every interval. It never returns.
+func (registry *Registry) WatchHealth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		registry.CheckHealth()
+	}
+}
+
+// CheckHealth probes all instances at once and evicts the instances that failed maxHealthFailures probes in a row
+func (registry *Registry) CheckHealth() {
+	registry.mutex.Lock()
+	all := make([]*instance, 0)
+	for _, instances := range registry.instances {
+		all = append(all, instances...)
+	}
+	registry.mutex.Unlock()
+	registry.check(all)
+}
+
+// check probes the given instances at once
+func (registry *Registry) check(all []*instance) {
+	results := make([]bool, len(all))
+	var wg sync.WaitGroup
+	for index, known := range all {
+		wg.Add(1)
+		go func(index int, known *instance) {
+			defer wg.Done()
+			results[index] = registry.probe(known)
+		}(index, known)
+	}
+	wg.Wait()
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	now := time.Now()
+	for index, known := range all {
+		known.lastCheck = now
+		if results[index] {
+			known.verified, known.suspect, known.failures = true, false, 0
+			continue
+		}
+		known.failures++
+		registry.logger.Printf("[WARNING] Instance %v of service %v failed %v health probes\n", known.service.Address, known.service.Name, known.failures)
+		if known.failures >= maxHealthFailures {
+			registry.evict(known)
+		}
+	}
+}
+
+// evict removes the instance from the registry, the caller has to hold the mutex
+func (registry *Registry) evict(evicted *instance) {
+	name := evicted.service.Name
+	instances := registry.instances[name]
+	found := false
+	for index, known := range instances {
+		if known == evicted {
+			registry.instances[name] = append(instances[:index:index], instances[index+1:]...)
+			found = true
+			break
+		}
+	}
+	// The instance is already evicted by an earlier check
+	if !found {
+		return
+	}
+	if len(registry.instances[name]) == 0 {
+		delete(registry.instances, name)
+		delete(registry.next, name)
+	}
+	registry.logger.Printf("Evicted instance %v of service %v\n", evicted.service.Address, name)
+}
+
+func (registry *Registry) probe(known *instance) bool {
+	response, err := registry.client.Get(strings.TrimSuffix(known.target.String(), "/") + "/health")
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode == http.StatusOK
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"general"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testInstance is a service instance whose health probes fail while it is down
+type testInstance struct {
+	server *httptest.Server
+	down   int32
+}
+
+func newTestInstance() *testInstance {
+	instance := &testInstance{}
+	instance.server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if atomic.LoadInt32(&instance.down) == 1 {
+			general.SendError(response, http.StatusServiceUnavailable)
+			return
+		}
+		response.WriteHeader(http.StatusOK)
+	}))
+	return instance
+}
+
+func (instance *testInstance) setDown(down bool) {
+	if down {
+		atomic.StoreInt32(&instance.down, 1)
+	} else {
+		atomic.StoreInt32(&instance.down, 0)
+	}
+}
+
+func (instance *testInstance) service(name string) general.Service {
+	return general.Service{Name: name, Address: instance.server.URL}
+}
+
+// register registers the service and waits until the registry probed it
+func register(t *testing.T, registry *Registry, service general.Service) {
+	if err := registry.Register(service); err != nil {
+		t.Fatalf("Can't register %v: %s\n", service.Address, err)
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		for _, status := range registry.Status()[service.Name] {
+			if status.Address == service.Address && !status.LastCheck.IsZero() {
+				return
+			}
+		}
+	}
+	t.Fatalf("Expects instance %v to be probed after registering\n", service.Address)
+}
+
+// pickAddress returns the address of the chosen instance or an empty string if no instance is chosen
+func pickAddress(registry *Registry, name string) string {
+	chosen, done, ok := registry.pick(name)
+	if !ok {
+		return ""
+	}
+	done()
+	return chosen.service.Address
+}
+
+func TestRegistry_register(t *testing.T) {
+	healthy, down := newTestInstance(), newTestInstance()
+	defer healthy.server.Close()
+	defer down.server.Close()
+	down.setDown(true)
+	cases := map[string]struct {
+		services        []general.Service
+		expectedError   bool
+		expectedPicked  string
+		expectedEntries int
+	}{
+		"Healthy instance":             {[]general.Service{healthy.service("likes")}, false, healthy.server.URL, 1},
+		"Instance that is down":        {[]general.Service{down.service("likes")}, false, "", 1},
+		"Instance registered twice":    {[]general.Service{healthy.service("likes"), healthy.service("likes")}, false, healthy.server.URL, 1},
+		"Instance with invalid scheme": {[]general.Service{{Name: "likes", Address: "ftp://likes:9002"}}, true, "", 0},
+	}
+	for name, test := range cases {
+		registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+		for _, service := range test.services {
+			if test.expectedError {
+				if err := registry.Register(service); err == nil {
+					t.Errorf("%v: Expects an error for address %v\n", name, service.Address)
+				}
+				continue
+			}
+			register(t, registry, service)
+		}
+		if picked := pickAddress(registry, "likes"); picked != test.expectedPicked {
+			t.Errorf("%v: Expects to pick %q but got: %q\n", name, test.expectedPicked, picked)
+		}
+		if entries := len(registry.Status()["likes"]); entries != test.expectedEntries {
+			t.Errorf("%v: Expects %v instances but got: %v\n", name, test.expectedEntries, entries)
+		}
+	}
+}
+
+func TestRegistry_pickRoundRobin(t *testing.T) {
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	counts := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		instance := newTestInstance()
+		defer instance.server.Close()
+		register(t, registry, instance.service("likes"))
+		counts[instance.server.URL] = 0
+	}
+	for i := 0; i < 9; i++ {
+		counts[pickAddress(registry, "likes")]++
+	}
+	for address, count := range counts {
+		if count != 3 {
+			t.Errorf("Expects instance %v to be picked 3 times but got: %v\n", address, count)
+		}
+	}
+	if picked := pickAddress(registry, "discography"); picked != "" {
+		t.Errorf("Expects no instance of an unknown service but got: %v\n", picked)
+	}
+}
+
+func TestRegistry_pickLeastConnections(t *testing.T) {
+	registry := NewRegistry(general.TestEmptyLogger(), LeastConnections, nil)
+	first, second := newTestInstance(), newTestInstance()
+	defer first.server.Close()
+	defer second.server.Close()
+	register(t, registry, first.service("likes"))
+	register(t, registry, second.service("likes"))
+	busy, doneBusy, _ := registry.pick("likes")
+	// Every request goes to the other instance as long as the first request isn't done
+	for i := 0; i < 3; i++ {
+		chosen, done, ok := registry.pick("likes")
+		if !ok || chosen == busy {
+			t.Errorf("Pick %v: Expects the instance without connections but got: %v\n", i, chosen.service.Address)
+		}
+		done()
+	}
+	other, doneOther, _ := registry.pick("likes")
+	if other == busy {
+		t.Errorf("Expects the instance without connections but got the busy instance\n")
+	}
+	doneBusy()
+	// The busy instance has no connections anymore, so it is chosen while the other instance has a connection
+	if chosen, done, _ := registry.pick("likes"); chosen != busy {
+		t.Errorf("Expects the instance that is done to be chosen but got: %v\n", chosen.service.Address)
+	} else {
+		done()
+	}
+	doneOther()
+	for _, status := range registry.Status()["likes"] {
+		if status.Connections != 0 {
+			t.Errorf("Expects no open connections to %v but got: %v\n", status.Address, status.Connections)
+		}
+	}
+}
+
+func TestRegistry_checkHealth(t *testing.T) {
+	cases := map[string]struct {
+		failedChecks     int
+		recovers         bool
+		expectedPicked   bool
+		expectedFailures int
+		expectedEvicted  bool
+	}{
+		"Healthy instance":                  {0, false, true, 0, false},
+		"Instance failed a probe":           {1, false, false, 1, false},
+		"Instance recovers after a probe":   {maxHealthFailures - 1, true, true, 0, false},
+		"Instance is evicted":               {maxHealthFailures, false, false, 0, true},
+		"Evicted instance stays evicted":    {maxHealthFailures, true, false, 0, true},
+		"Instance that is down for a while": {maxHealthFailures - 1, false, false, maxHealthFailures - 1, false},
+	}
+	for name, test := range cases {
+		instance := newTestInstance()
+		registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+		register(t, registry, instance.service("likes"))
+		instance.setDown(true)
+		for i := 0; i < test.failedChecks; i++ {
+			registry.CheckHealth()
+		}
+		if test.recovers {
+			instance.setDown(false)
+			registry.CheckHealth()
+		}
+		if picked := pickAddress(registry, "likes") != ""; picked != test.expectedPicked {
+			t.Errorf("%v: Expects the instance to be picked: %v but got: %v\n", name, test.expectedPicked, picked)
+		}
+		status := registry.Status()["likes"]
+		if evicted := len(status) == 0; evicted != test.expectedEvicted {
+			t.Errorf("%v: Expects the instance to be evicted: %v but got: %v\n", name, test.expectedEvicted, evicted)
+		} else if !evicted && status[0].Failures != test.expectedFailures {
+			t.Errorf("%v: Expects %v failures but got: %v\n", name, test.expectedFailures, status[0].Failures)
+		}
+		instance.server.Close()
+	}
+}
+
+func TestRegistry_registerAfterEviction(t *testing.T) {
+	instance := newTestInstance()
+	defer instance.server.Close()
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	register(t, registry, instance.service("likes"))
+	instance.setDown(true)
+	for i := 0; i < maxHealthFailures; i++ {
+		registry.CheckHealth()
+	}
+	if _, ok := registry.Services()["likes"]; ok {
+		t.Fatalf("Expects the instance to be evicted\n")
+	}
+	// A service registers itself periodically, so the instance comes back when it is running again
+	instance.setDown(false)
+	register(t, registry, instance.service("likes"))
+	if picked := pickAddress(registry, "likes"); picked != instance.server.URL {
+		t.Errorf("Expects the instance to be picked after registering again but got: %q\n", picked)
+	}
+	if service, ok := registry.Services()["likes"]; !ok || service.Address != instance.server.URL {
+		t.Errorf("Expects the instance in the list of services but got: %v\n", registry.Services())
+	}
+}
+
+func TestRegistry_reportFailure(t *testing.T) {
+	first, second := newTestInstance(), newTestInstance()
+	defer first.server.Close()
+	defer second.server.Close()
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	register(t, registry, first.service("likes"))
+	failed, done, _ := registry.pick("likes")
+	done()
+	registry.reportFailure(failed)
+	// The only instance is still used after a failed request
+	if picked := pickAddress(registry, "likes"); picked != failed.service.Address {
+		t.Errorf("Expects the only instance to be picked after a failed request but got: %q\n", picked)
+	}
+	register(t, registry, second.service("likes"))
+	for i := 0; i < 4; i++ {
+		if picked := pickAddress(registry, "likes"); picked != second.server.URL {
+			t.Errorf("Pick %v: Expects the instance without failed requests to be picked but got: %v\n", i, picked)
+		}
+	}
+	// A succesful health probe clears the failed request
+	registry.CheckHealth()
+	picks := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		picks[pickAddress(registry, "likes")] = true
+	}
+	if !picks[first.server.URL] || !picks[second.server.URL] {
+		t.Errorf("Expects both instances to be picked after a health probe but got: %v\n", picks)
+	}
+}
+
+func TestRegistry_probePrefix(t *testing.T) {
+	probed := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		select {
+		case probed <- request.URL.Path:
+		default:
+		}
+		response.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	register(t, registry, general.Service{Name: "likes", Address: server.URL + "/likes/"})
+	if path := <-probed; !strings.HasSuffix(path, "/likes/health") {
+		t.Errorf("Expects the health probe on the prefix of the service but got: %v\n", path)
+	}
+}
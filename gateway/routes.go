@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"general"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v2"
+)
+
+// The authentication that a route can require before a request is redirected
+const (
+	AuthToken    = "token"
+	AuthCurator  = "curator"
+	AuthAdmin    = "admin"
+	AuthInternal = "internal"
+)
+
+// Route redirects the requests that match the path template Path, or every path that starts with Prefix, to Service.
+// Only the given methods are redirected, all methods if Methods is empty. Auth is the authentication that is required and RateLimit is the group of the route for rate limiting.
+// StripPrefix is removed from the path before the request is redirected, so routes can tell apart services that have the same paths.
+type Route struct {
+	Path        string   `yaml:"path"`
+	Prefix      string   `yaml:"prefix"`
+	StripPrefix string   `yaml:"stripPrefix"`
+	Methods     []string `yaml:"methods" validate:"dive,oneof=GET POST PUT PATCH DELETE HEAD OPTIONS"`
+	Auth        string   `yaml:"auth" validate:"omitempty,oneof=token curator admin internal"`
+	RateLimit   string   `yaml:"rateLimit" validate:"omitempty,oneof=login api admin"`
+	Service     string   `yaml:"service" validate:"required"`
+}
+
+// RouteTable contains the routes of the gateway. A request is redirected by the first route that matches.
+type RouteTable struct {
+	Routes []Route `yaml:"routes" validate:"required,min=1,dive"`
+}
+
+// LoadRoutes reads the route table from the YAML file on the given path and validates it
+func LoadRoutes(path string) (RouteTable, error) {
+	var table RouteTable
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RouteTable{}, fmt.Errorf("Can't read route table %v: %s", path, err)
+	}
+	if err = yaml.UnmarshalStrict(content, &table); err != nil {
+		return RouteTable{}, fmt.Errorf("Can't parse route table %v: %s", path, err)
+	}
+	if err = table.Validate(); err != nil {
+		return RouteTable{}, fmt.Errorf("Invalid route table in %v: %s", path, err)
+	}
+	return table, nil
+}
+
+// Validate returns an error if a route misses a required value, if a route doesn't have exactly one of a path and a prefix
+// or if the prefix to strip isn't the first part of the path or prefix of the route
+func (table RouteTable) Validate() error {
+	if err := validator.New().Struct(table); err != nil {
+		return err
+	}
+	for index, route := range table.Routes {
+		if (route.Path == "") == (route.Prefix == "") {
+			return fmt.Errorf("Route #%v to %v should have either a path or a prefix", index, route.Service)
+		}
+		if route.StripPrefix == "" {
+			continue
+		}
+		if !strings.HasPrefix(route.StripPrefix, "/") || strings.HasSuffix(route.StripPrefix, "/") || !strings.HasPrefix(route.Path+route.Prefix, route.StripPrefix+"/") {
+			return fmt.Errorf("Route #%v to %v should strip a prefix like /%v that is followed by the rest of its path or prefix", index, route.Service, route.Service)
+		}
+	}
+	return nil
+}
+
+// SetRoutes replaces the routes of the gateway by the routes in the table. Requests that are already redirected aren't affected.
+func (handler *GatewayHandler) SetRoutes(table RouteTable) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	router := mux.NewRouter()
+	router.Use(translateCookies)
+	for _, route := range table.Routes {
+		var registered *mux.Route
+		if route.Path != "" {
+			registered = router.Path(route.Path)
+		} else {
+			registered = router.PathPrefix(route.Prefix)
+		}
+		if len(route.Methods) != 0 {
+			registered.Methods(route.Methods...)
+		}
+		var next http.Handler = http.HandlerFunc(handler.redirect(route.Service))
+		if route.StripPrefix != "" {
+			next = http.StripPrefix(route.StripPrefix, next)
+		}
+		if route.Auth != "" {
+			next = authMiddleware(handler.logger, route.Auth)(next)
+		}
+		// The rate limit is checked before the authentication, so requests that fail the authentication use tokens as well
+		if route.RateLimit != "" {
+			next = handler.limitMiddleware(route.RateLimit)(next)
+		}
+		registered.Handler(next)
+	}
+	handler.routes.Store(router)
+	handler.logger.Printf("Loaded %v routes\n", len(table.Routes))
+	return nil
+}
+
+// serveRoutes redirects the request with the current routes
+func (handler *GatewayHandler) serveRoutes(response http.ResponseWriter, request *http.Request) {
+	router, ok := handler.routes.Load().(*mux.Router)
+	if !ok {
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	router.ServeHTTP(response, request)
+}
+
+// WatchRoutes reloads the route table from the given path every time that the process receives a SIGHUP signal.
+// The current routes stay in use if the route table is invalid.
+func (handler *GatewayHandler) WatchRoutes(path string) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		if err := handler.reloadRoutes(path); err != nil {
+			handler.logger.Printf("[ERROR] Can't reload routes: %s\n", err)
+			continue
+		}
+		handler.logger.Printf("Succesfully reloaded routes\n")
+	}
+}
+
+// reloadRoutes replaces the routes by the route table from the given path, the current routes stay in use if it returns an error
+func (handler *GatewayHandler) reloadRoutes(path string) error {
+	table, err := LoadRoutes(path)
+	if err != nil {
+		return err
+	}
+	return handler.SetRoutes(table)
+}
+
+func authMiddleware(logger *log.Logger, auth string) func(http.Handler) http.Handler {
+	switch auth {
+	case AuthCurator:
+		return general.GetIsCuratorMiddleware(logger)
+	case AuthAdmin:
+		return general.GetIsAdminMiddleware(logger)
+	case AuthInternal:
+		return general.GetInternalRequestMiddleware(logger)
+	default:
+		return general.GetValidateTokenMiddleWare(logger)
+	}
+}
+
+// translateCookies sends the token and refresh token from the cookies in the Token and Refresh-Token headers
+func translateCookies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if cookie, err := request.Cookie("token"); err == nil {
+			request.Header.Set("Token", cookie.Value)
+		}
+		if refreshToken, err := request.Cookie("refreshToken"); err == nil {
+			request.Header.Set("Refresh-Token", refreshToken.Value)
+		}
+		next.ServeHTTP(response, request)
+	})
+}
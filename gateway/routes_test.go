@@ -0,0 +1,218 @@
+package main
+
+import (
+	"general"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const testRoutesFile = `routes:
+  - path: /login
+    methods: [POST]
+    rateLimit: login
+    service: users
+  - prefix: /likes/admin/consumers/
+    stripPrefix: /likes
+    auth: admin
+    service: likes
+`
+
+// writeRoutes writes the content to a route table in a temporary directory and returns the path and a function that removes the directory
+func writeRoutes(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatalf("Can't create directory for routes: %s\n", err)
+	}
+	path := filepath.Join(dir, "routes.yaml")
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Can't write routes: %s\n", err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadRoutes(t *testing.T) {
+	cases := map[string]struct {
+		content       string
+		expectedError bool
+		expectedTable RouteTable
+	}{
+		"Valid file": {testRoutesFile, false, RouteTable{Routes: []Route{
+			{Path: "/login", Methods: []string{http.MethodPost}, RateLimit: GroupLogin, Service: "users"},
+			{Prefix: "/likes/admin/consumers/", StripPrefix: "/likes", Auth: AuthAdmin, Service: "likes"},
+		}}},
+		"Unknown field":  {testRoutesFile + "    timeout: 5s\n", true, RouteTable{}},
+		"Invalid YAML":   {"routes: [path: /login\n", true, RouteTable{}},
+		"Empty file":     {"", true, RouteTable{}},
+		"Invalid route":  {"routes:\n  - path: /login\n    prefix: /login\n    service: users\n", true, RouteTable{}},
+		"Invalid method": {"routes:\n  - path: /login\n    methods: [post]\n    service: users\n", true, RouteTable{}},
+	}
+	for name, test := range cases {
+		path, remove := writeRoutes(t, test.content)
+		table, err := LoadRoutes(path)
+		remove()
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+			continue
+		}
+		if !reflect.DeepEqual(table, test.expectedTable) {
+			t.Errorf("%v: Expects routes %+v but got: %+v\n", name, test.expectedTable, table)
+		}
+	}
+	if _, err := LoadRoutes(filepath.Join(os.TempDir(), "missing", "routes.yaml")); err == nil {
+		t.Errorf("Expects an error for a missing route table\n")
+	}
+	// The route table of the repository has to stay valid
+	if _, err := LoadRoutes("routes.yaml"); err != nil {
+		t.Errorf("Expects the route table of the gateway to be valid but got: %s\n", err)
+	}
+}
+
+func TestRouteTable_validate(t *testing.T) {
+	cases := map[string]struct {
+		route         Route
+		expectedError bool
+	}{
+		"Route with path":                    {Route{Path: "/api/album/{id}", Service: "discography"}, false},
+		"Route with prefix":                  {Route{Prefix: "/account", Service: "users"}, false},
+		"Route with path and prefix":         {Route{Path: "/account", Prefix: "/account", Service: "users"}, true},
+		"Route without path and prefix":      {Route{Service: "users"}, true},
+		"Route without service":              {Route{Path: "/login"}, true},
+		"Route with methods":                 {Route{Path: "/login", Methods: []string{http.MethodPost, http.MethodOptions}, Service: "users"}, false},
+		"Route with unknown method":          {Route{Path: "/login", Methods: []string{"CONNECT"}, Service: "users"}, true},
+		"Route with lowercase method":        {Route{Path: "/login", Methods: []string{"get"}, Service: "users"}, true},
+		"Route with unknown authentication":  {Route{Path: "/login", Auth: "basic", Service: "users"}, true},
+		"Route with unknown rate limit":      {Route{Path: "/login", RateLimit: "search", Service: "users"}, true},
+		"Route that strips a prefix":         {Route{Prefix: "/likes/admin/consumers/", StripPrefix: "/likes", Service: "likes"}, false},
+		"Route that strips part of a path":   {Route{Path: "/likes/admin/consumers/replay", StripPrefix: "/likes", Service: "likes"}, false},
+		"Route that strips other prefix":     {Route{Prefix: "/likes/admin/consumers/", StripPrefix: "/notifications", Service: "likes"}, true},
+		"Route that strips part of a name":   {Route{Prefix: "/likes/admin/consumers/", StripPrefix: "/lik", Service: "likes"}, true},
+		"Route that strips the whole prefix": {Route{Prefix: "/likes/", StripPrefix: "/likes/", Service: "likes"}, true},
+		"Route that strips without slash":    {Route{Prefix: "/likes/admin/consumers/", StripPrefix: "likes", Service: "likes"}, true},
+	}
+	for name, test := range cases {
+		if err := (RouteTable{Routes: []Route{test.route}}).Validate(); (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+	}
+	if err := (RouteTable{}).Validate(); err == nil {
+		t.Errorf("Expects an error for a route table without routes\n")
+	}
+}
+
+func TestSetRoutes_stripPrefix(t *testing.T) {
+	admin, err := general.CreateToken(1, "test", "admin")
+	if err != nil {
+		t.Fatalf("Can't create token: %s\n", err)
+	}
+	backend := newTestBackend(nil)
+	defer backend.server.Close()
+	gateway, _ := testGateway(t, []Route{{Prefix: "/likes/admin/consumers/", StripPrefix: "/likes", Auth: AuthAdmin, Service: "likes"}}, "likes", backend.server.URL)
+	defer gateway.Close()
+	request, err := http.NewRequest(http.MethodGet, gateway.URL+"/likes/admin/consumers/dlq/newSong", nil)
+	if err != nil {
+		t.Fatalf("Can't create request: %s\n", err)
+	}
+	request.Header.Set("Token", admin)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Request failed: %s\n", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expects statuscode: %v but got: %v\n", http.StatusOK, response.StatusCode)
+	}
+	if received := <-backend.requests; received.URL.Path != "/admin/consumers/dlq/newSong" {
+		t.Errorf("Expects the service to receive the path without prefix but got: %v\n", received.URL.Path)
+	}
+	<-backend.bodies
+}
+
+func TestSetRoutes_reload(t *testing.T) {
+	users, likes := newTestBackend(nil), newTestBackend(nil)
+	defer users.server.Close()
+	defer likes.server.Close()
+	registry := NewRegistry(general.TestEmptyLogger(), RoundRobin, nil)
+	register(t, registry, general.Service{Name: "users", Address: users.server.URL})
+	register(t, registry, general.Service{Name: "likes", Address: likes.server.URL})
+	handler, err := NewGatewayHandler(general.TestEmptyLogger(), registry, nil, nil, general.TestSendMessageEmpty())
+	if err != nil {
+		t.Fatalf("Can't create gateway handler: %s\n", err)
+	}
+	if err = handler.SetRoutes(RouteTable{Routes: []Route{{Path: "/api/like", Service: "users"}}}); err != nil {
+		t.Fatalf("Can't set routes: %s\n", err)
+	}
+	cases := []struct {
+		content       string
+		expectedError bool
+		expected      *testBackend
+	}{
+		{"routes:\n  - path: /api/like\n    service: likes\n", false, likes},
+		{"routes:\n  - path: /api/like\n    methods: [FETCH]\n    service: users\n", true, likes},
+		{"routes:\n  - path: /api/like\n    service: users\n", false, users},
+		{"routes: [\n", true, users},
+	}
+	for index, test := range cases {
+		path, remove := writeRoutes(t, test.content)
+		err := handler.reloadRoutes(path)
+		remove()
+		if (err != nil) != test.expectedError {
+			t.Errorf("Reload #%v: Expects error: %v but got: %v\n", index, test.expectedError, err)
+		}
+		// The current routes stay in use if the route table is invalid
+		recorder := httptest.NewRecorder()
+		handler.serveRoutes(recorder, httptest.NewRequest(http.MethodPost, "/api/like", nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Reload #%v: Expects statuscode: %v but got: %v\n", index, http.StatusOK, recorder.Code)
+			continue
+		}
+		select {
+		case <-test.expected.requests:
+			<-test.expected.bodies
+		default:
+			t.Errorf("Reload #%v: Expects the request to be redirected to %v\n", index, test.expected.server.URL)
+		}
+	}
+}
+
+func TestSetRoutes_concurrentRequests(t *testing.T) {
+	handler, err := NewGatewayHandler(general.TestEmptyLogger(), NewRegistry(general.TestEmptyLogger(), RoundRobin, nil), nil, nil, general.TestSendMessageEmpty())
+	if err != nil {
+		t.Fatalf("Can't create gateway handler: %s\n", err)
+	}
+	tables := []RouteTable{
+		{Routes: []Route{{Path: "/api/like", Service: "likes"}}},
+		{Routes: []Route{{Prefix: "/api/", Service: "likes"}}},
+	}
+	if err = handler.SetRoutes(tables[0]); err != nil {
+		t.Fatalf("Can't set routes: %s\n", err)
+	}
+	// Requests that are redirected while the routes are replaced always see a complete route table
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				recorder := httptest.NewRecorder()
+				handler.serveRoutes(recorder, httptest.NewRequest(http.MethodPost, "/api/like", nil))
+				if recorder.Code != http.StatusServiceUnavailable {
+					t.Errorf("Expects statuscode: %v but got: %v\n", http.StatusServiceUnavailable, recorder.Code)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		if err := handler.SetRoutes(tables[i%2]); err != nil {
+			t.Errorf("Can't set routes: %s\n", err)
+		}
+	}
+	wg.Wait()
+}
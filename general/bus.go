@@ -0,0 +1,126 @@
+package general
+
+import (
+	"errors"
+	"general/config"
+	"hash/fnv"
+	"log"
+)
+
+// The offsets at which a Subscription can start besides the offset of a message
+const (
+	OffsetNewest int64 = -1
+	OffsetOldest int64 = -2
+)
+
+var (
+	// ErrNoMessage is returned by a Subscription when no new message arrived for a while
+	ErrNoMessage = errors.New("no new message")
+	// ErrOffsetOutOfRange is returned by a Subscription that starts at an offset that doesn't exist
+	ErrOffsetOutOfRange = errors.New("offset is out of range")
+	// ErrSubscriptionClosed is returned by a Subscription that is closed
+	ErrSubscriptionClosed = errors.New("subscription is closed")
+)
+
+// BusMessage is a message in a partition of a topic
+type BusMessage struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Value     []byte
+}
+
+// Bus publishes messages to topics. A topic consists of partitions in which the messages are ordered by their offset.
+type Bus interface {
+	// Publish adds the message to the topic. Messages are spread over the partitions, so their order is only kept within a partition.
+	Publish(topic string, message []byte) error
+	// PublishWithKey adds the message to the partition of the key, so the messages with the same key stay in order
+	PublishWithKey(topic, key string, message []byte) error
+	// CreateTopics creates the topics that don't exist yet
+	CreateTopics(topics ...string) error
+	// CreateCompactedTopics creates the topics that don't exist yet as compacted topics, which eventually only keep the newest message of every key.
+	// Only messages with a key can be published to a compacted topic.
+	CreateCompactedTopics(topics ...string) error
+	// Partitions returns the number of partitions of the topic, the topic is created if it doesn't exist
+	Partitions(topic string) (int32, error)
+	// Subscribe returns a Subscription on the partition of the topic that starts at the given offset, OffsetOldest or OffsetNewest
+	Subscribe(topic string, partition int32, offset int64) (Subscription, error)
+	// Offsets returns the offsets that are committed by the consumer group with the given name
+	Offsets(group string) (OffsetStore, error)
+}
+
+// Subscription reads the messages of a partition in order
+type Subscription interface {
+	// Next returns the next message. It returns ErrNoMessage if no message arrives for a while.
+	Next() (BusMessage, error)
+	// Close stops the Subscription, Next returns ErrSubscriptionClosed afterwards
+	Close() error
+}
+
+// OffsetStore keeps the offsets of the first messages that a consumer group didn't consume yet
+type OffsetStore interface {
+	// Commit stores the offset of the partition of the topic
+	Commit(topic string, partition int32, offset int64) error
+	// Offset returns the committed offset of the partition of the topic or a negative offset if nothing is committed
+	Offset(topic string, partition int32) (int64, error)
+}
+
+// keyPartition returns the partition of the messages with the given key
+func keyPartition(key string, partitions int32) int32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int32(hash.Sum32() % uint32(partitions))
+}
+
+// ConnectToBus connects to the configured message bus and returns the bus and a closing function.
+// The service publishes its events on the bus, see EventBus.
+func ConnectToBus(logger *log.Logger, cfg config.Config) (Bus, func()) {
+	return ConnectToSharedBus(logger, cfg, NewMemoryBus())
+}
+
+// ConnectToSharedBus works like ConnectToBus, but a service that is configured with the in-memory bus uses the shared MemoryBus.
+// Services that run in one process, like in integration tests, deliver their messages to each other if they share the MemoryBus.
+func ConnectToSharedBus(logger *log.Logger, cfg config.Config, shared *MemoryBus) (Bus, func()) {
+	if cfg.Bus == config.BusMemory {
+		logger.Printf("[WARNING] Using the in-memory bus, messages are only delivered within this process\n")
+		return NewEventBus(shared, cfg.Servername), func() {}
+	}
+	broker, closeBroker := ConnectToKafka(logger, cfg)
+	return NewEventBus(NewKafkaBus(broker, logger), cfg.Servername), closeBroker
+}
+
+// EventBus is a Bus on which a service publishes its events. Published events without producer get the service as producer,
+// like the events that are encoded with EncodeEvent or that are written to the outbox.
+type EventBus struct {
+	Bus
+	producer string
+}
+
+// NewEventBus returns an EventBus that publishes the events on the bus on behalf of the given service
+func NewEventBus(bus Bus, producer string) *EventBus {
+	return &EventBus{Bus: bus, producer: producer}
+}
+
+// Publish adds the message to the topic, the service of the bus becomes the producer of an event without producer
+func (bus *EventBus) Publish(topic string, message []byte) error {
+	return bus.Bus.Publish(topic, bus.withProducer(message))
+}
+
+// PublishWithKey adds the message to the partition of the key, the service of the bus becomes the producer of an event without producer
+func (bus *EventBus) PublishWithKey(topic, key string, message []byte) error {
+	return bus.Bus.PublishWithKey(topic, key, bus.withProducer(message))
+}
+
+// withProducer returns the message with the service of the bus as producer if the message is an event without producer
+func (bus *EventBus) withProducer(message []byte) []byte {
+	event, ok := asEvent(message)
+	if !ok || event.Producer != "" {
+		return message
+	}
+	event.Producer = bus.producer
+	encoded, err := ToJSONBytes(event)
+	if err != nil {
+		return message
+	}
+	return encoded
+}
@@ -0,0 +1,298 @@
+package general
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// DefaultRequestTimeout is the deadline of an internal request including its retries
+	DefaultRequestTimeout = 5 * time.Second
+	// DefaultRetries is the number of times an idempotent internal request is retried after a failure
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the base delay before retrying, the delay doubles for every retry and is randomized
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// DefaultBreakerThreshold is the number of consecutive failures after which the circuit breaker of a target opens
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is the time an open circuit breaker rejects requests before it lets a trial request through
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for requests to a target whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+var (
+	internalRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "internal_requests_total",
+		Help: "The total number of attempts of requests to other services by target and result",
+	}, []string{"target", "result"})
+	internalRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "internal_request_duration_seconds",
+		Help:    "The duration of attempts of requests to other services by target",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"target"})
+	internalRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "internal_request_retries_total",
+		Help: "The total number of retried requests to other services by target",
+	}, []string{"target"})
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "internal_circuit_breaker_open",
+		Help: "Whether the circuit breaker of a target is open (1) or closed (0)",
+	}, []string{"target"})
+)
+
+// InternalClient sends requests with an internal token to other services.
+// Every request has a deadline, idempotent requests are retried and targets that keep failing are temporarily skipped by a circuit breaker.
+type InternalClient struct {
+	Timeout          time.Duration
+	Retries          int
+	RetryBackoff     time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	servername       string
+	client           *http.Client
+	mutex            sync.Mutex
+	token            string
+	breakers         map[string]*breaker
+}
+
+// NewInternalClient returns an InternalClient with the default settings that sends requests on behalf of the given service.
+// Requests are send with the given client, if client is nil then http.DefaultClient is used.
+func NewInternalClient(servername string, client *http.Client) (*InternalClient, error) {
+	token, err := CreateTokenInternalRequests(servername)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &InternalClient{
+		Timeout:          DefaultRequestTimeout,
+		Retries:          DefaultRetries,
+		RetryBackoff:     DefaultRetryBackoff,
+		BreakerThreshold: DefaultBreakerThreshold,
+		BreakerCooldown:  DefaultBreakerCooldown,
+		servername:       servername,
+		client:           client,
+		token:            token,
+		breakers:         make(map[string]*breaker),
+	}, nil
+}
+
+// Get sends a get request to the url with a deadline of Timeout
+func (client *InternalClient) Get(url string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout)
+	resp, err := client.Do(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// Do sends a request with the given body to the url. A failed attempt is retried if the method is idempotent and the context isn't done.
+// Attempts fail on errors of the connection and on responses with statuscode 502, 503 or 504.
+// It returns ErrCircuitOpen without sending the request if the target failed too often.
+func (client *InternalClient) Do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	target, err := requestTarget(url)
+	if err != nil {
+		return nil, err
+	}
+	retries := 0
+	if idempotent(method) {
+		retries = client.Retries
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := client.attempt(ctx, target, method, url, body)
+		if !retryable(resp, err) || attempt >= retries || err == ErrCircuitOpen {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		internalRetries.WithLabelValues(target).Inc()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(client.backoff(attempt)):
+		}
+	}
+}
+
+// attempt sends the request once. The token is renewed and the request is send again if the target rejects the token.
+func (client *InternalClient) attempt(ctx context.Context, target, method, url string, body []byte) (*http.Response, error) {
+	circuit := client.breaker(target)
+	if !circuit.allow() {
+		internalRequests.WithLabelValues(target, "rejected").Inc()
+		return nil, ErrCircuitOpen
+	}
+	start := time.Now()
+	resp, err := client.send(ctx, method, url, body, client.currentToken())
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		token, errToken := client.renewToken()
+		if errToken != nil {
+			circuit.release()
+			return nil, errToken
+		}
+		resp, err = client.send(ctx, method, url, body, token)
+	}
+	internalRequestDuration.WithLabelValues(target).Observe(time.Since(start).Seconds())
+	// Requests that are canceled by the caller say nothing about the health of the target
+	if ctx.Err() != context.Canceled {
+		circuit.done(!retryable(resp, err))
+	} else {
+		circuit.release()
+	}
+	if err != nil {
+		internalRequests.WithLabelValues(target, "error").Inc()
+	} else {
+		internalRequests.WithLabelValues(target, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+func (client *InternalClient) send(ctx context.Context, method, url string, body []byte, token string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("Token", token)
+	return client.client.Do(request)
+}
+
+func (client *InternalClient) currentToken() string {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.token
+}
+
+func (client *InternalClient) renewToken() (string, error) {
+	token, err := CreateTokenInternalRequests(client.servername)
+	if err != nil {
+		return "", err
+	}
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.token = token
+	return token, nil
+}
+
+// backoff returns a random delay of at most RetryBackoff * 2^attempt
+func (client *InternalClient) backoff(attempt int) time.Duration {
+	max := int64(client.RetryBackoff) << uint(attempt)
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(max) + 1)
+}
+
+func (client *InternalClient) breaker(target string) *breaker {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if known, ok := client.breakers[target]; ok {
+		return known
+	}
+	created := &breaker{target: target, threshold: client.BreakerThreshold, cooldown: client.BreakerCooldown}
+	client.breakers[target] = created
+	return created
+}
+
+// breaker is a circuit breaker for a single target. It opens after threshold consecutive failures.
+// After the cooldown a single trial request is let through, which closes the breaker if it succeeds.
+type breaker struct {
+	target    string
+	threshold int
+	cooldown  time.Duration
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (breaker *breaker) allow() bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	if breaker.failures < breaker.threshold {
+		return true
+	}
+	if time.Now().Before(breaker.openUntil) || breaker.trial {
+		return false
+	}
+	breaker.trial = true
+	return true
+}
+
+// release lets another trial request through without changing the state of the breaker
+func (breaker *breaker) release() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	breaker.trial = false
+}
+
+func (breaker *breaker) done(success bool) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	breaker.trial = false
+	if success {
+		breaker.failures = 0
+		breakerState.WithLabelValues(breaker.target).Set(0)
+		return
+	}
+	breaker.failures++
+	if breaker.failures >= breaker.threshold {
+		breaker.openUntil = time.Now().Add(breaker.cooldown)
+		breakerState.WithLabelValues(breaker.target).Set(1)
+	}
+}
+
+// cancelBody releases the context of a request when the body of its response is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+func requestTarget(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Host, nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"gopkg.in/yaml.v2"
+)
+
+// Config contains the configuration of a service. The server listens on Port, which is of the form :port or host:port.
+// Address is the address under which the other services reach this service: :port, host:port or a url like https://host:port/prefix.
+// Services without an address are reached on Port. TrustedProxies are the IP addresses and networks (CIDR) of the proxies, like the gateway,
+// whose X-Forwarded-For header is trusted by the service.
+type Config struct {
+	Servername     string    `yaml:"servername" validate:"required"`
+	Port           string    `yaml:"port" validate:"required"`
+	Address        string    `yaml:"address"`
+	MySQL          MySQL     `yaml:"mysql"`
+	Bus            string    `yaml:"bus" validate:"omitempty,oneof=kafka memory"`
+	Kafka          Kafka     `yaml:"kafka"`
+	Gateway        Gateway   `yaml:"gateway"`
+	Keys           Keys      `yaml:"keys"`
+	Signup         Signup    `yaml:"signup"`
+	Passwords      Passwords `yaml:"passwords"`
+	Argon2         Argon2    `yaml:"argon2"`
+	Spotify        Spotify   `yaml:"spotify"`
+	TrustedProxies []string  `yaml:"trustedProxies" validate:"dive,cidr|ip"`
+}
+
+// MySQL contains the configuration for connecting to a MySQL database. Services without a database can leave this empty
+type MySQL struct {
+	DSN string `yaml:"dsn"`
+}
+
+// The message buses that a service can use. The in-memory bus only delivers messages within the process of the service.
+const (
+	BusKafka  = "kafka"
+	BusMemory = "memory"
+)
+
+// Kafka contains the addresses of the brokers of the Kafka cluster. The addresses are required unless the in-memory bus is used.
+type Kafka struct {
+	Addresses []string `yaml:"addresses" validate:"dive,required"`
+}
+
+// Gateway contains the name and the address of the gateway that is used for obtaining the addresses of other services.
+// Balancing is the strategy of the gateway for choosing an instance of a service: round-robin (default) or least-connections.
+// RateLimits contains the limits of the gateway per group of routes: login, api or admin. Groups that are left out use the defaults of the gateway.
+// Routes is the path to the route table of the gateway. The values other than the name and the address are only used by the gateway itself.
+type Gateway struct {
+	Name       string               `yaml:"name" validate:"required"`
+	Address    string               `yaml:"address" validate:"required"`
+	Balancing  string               `yaml:"balancing" validate:"omitempty,oneof=round-robin least-connections"`
+	RateLimits map[string]RateLimit `yaml:"rateLimits" validate:"dive,keys,oneof=login api admin,endkeys,required"`
+	Routes     string               `yaml:"routes"`
+}
+
+// RateLimit is a token bucket that is refilled with Rate requests per second and holds at most Burst requests
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" validate:"gt=0"`
+	Burst int     `yaml:"burst" validate:"min=1"`
+}
+
+// Keys contains the paths to the keys that are used for signing and validating tokens.
+// The signing key is a PEM encoded RSA or P-256 private key. Verification keys are PEM encoded public keys of keys that are rotated out
+// or of the keys of other services, tokens signed by them stay valid as long as they are listed. JWKS is the url of a JWKS endpoint that is used for obtaining unknown keys.
+// Roles are the roles of the tokens that a key can sign, a key without roles can sign tokens of every role.
+// Only the users service signs tokens of users, the other services only sign tokens of the role MusicApp for internal requests.
+type Keys struct {
+	ID               string            `yaml:"id" validate:"required_with=SigningKey"`
+	SigningKey       string            `yaml:"signingKey"`
+	Roles            []string          `yaml:"roles" validate:"dive,oneof=MusicApp admin curator user"`
+	VerificationKeys []VerificationKey `yaml:"verificationKeys" validate:"dive"`
+	JWKS             string            `yaml:"jwks"`
+}
+
+// VerificationKey contains the id, the path and the roles of a public key
+type VerificationKey struct {
+	ID    string   `yaml:"id" validate:"required"`
+	Path  string   `yaml:"path" validate:"required"`
+	Roles []string `yaml:"roles" validate:"dive,oneof=MusicApp admin curator user"`
+}
+
+// Signup contains the policy for usernames and passwords of new users. Services without users can leave this empty.
+// Values that are left empty are replaced by the defaults of the users service. BreachedPasswords is the path to a file with one blocked password per line.
+type Signup struct {
+	UsernameMinLength  int    `yaml:"usernameMinLength" validate:"min=0"`
+	UsernameMaxLength  int    `yaml:"usernameMaxLength" validate:"min=0,max=64"`
+	UsernamePattern    string `yaml:"usernamePattern"`
+	PasswordMinLength  int    `yaml:"passwordMinLength" validate:"min=0"`
+	PasswordMaxLength  int    `yaml:"passwordMaxLength" validate:"min=0"`
+	PasswordMinClasses int    `yaml:"passwordMinClasses" validate:"min=0,max=4"`
+	BreachedPasswords  string `yaml:"breachedPasswords"`
+}
+
+// Passwords contains the pepper, the secret key of the legacy HMAC-SHA512 hashes of passwords. Services without users can leave this empty.
+// Users with a legacy hash can't log in without the pepper.
+type Passwords struct {
+	Pepper string `yaml:"pepper"`
+}
+
+// Argon2 contains the cost parameters of argon2id that are used for hashing passwords, Memory is given in KiB.
+// Values that are left empty are replaced by the defaults of the users service. Services without users can leave this empty.
+type Argon2 struct {
+	Time    uint32 `yaml:"time"`
+	Memory  uint32 `yaml:"memory"`
+	Threads uint8  `yaml:"threads"`
+}
+
+// Spotify contains the credentials of the Spotify app that is used for synchronising the discography with Spotify.
+// The synchronisation is only started if both are given. Services other than the discography service can leave this empty.
+type Spotify struct {
+	ClientID     string `yaml:"clientId" validate:"required_with=ClientSecret"`
+	ClientSecret string `yaml:"clientSecret" validate:"required_with=ClientID"`
+}
+
+// The environment variables that override the values from the configuration file
+const (
+	EnvServername        = "MUSICAPP_SERVERNAME"
+	EnvPort              = "MUSICAPP_PORT"
+	EnvAddress           = "MUSICAPP_ADDRESS"
+	EnvMySQLDSN          = "MUSICAPP_MYSQL_DSN"
+	EnvBus               = "MUSICAPP_BUS"
+	EnvKafkaAddresses    = "MUSICAPP_KAFKA_ADDRESSES"
+	EnvGatewayName       = "MUSICAPP_GATEWAY_NAME"
+	EnvGatewayAddress    = "MUSICAPP_GATEWAY_ADDRESS"
+	EnvKeyID             = "MUSICAPP_KEYS_ID"
+	EnvSigningKey        = "MUSICAPP_KEYS_SIGNING_KEY"
+	EnvKeyRoles          = "MUSICAPP_KEYS_ROLES"
+	EnvJWKS              = "MUSICAPP_KEYS_JWKS"
+	EnvBreachedPasswords = "MUSICAPP_SIGNUP_BREACHED_PASSWORDS"
+	EnvPepper            = "MUSICAPP_PASSWORDS_PEPPER"
+	EnvTrustedProxies    = "MUSICAPP_TRUSTED_PROXIES"
+	EnvArgon2Time        = "MUSICAPP_ARGON2_TIME"
+	EnvArgon2Memory      = "MUSICAPP_ARGON2_MEMORY"
+	EnvArgon2Threads     = "MUSICAPP_ARGON2_THREADS"
+	EnvSpotifyID         = "MUSICAPP_SPOTIFY_CLIENT_ID"
+	EnvSpotifySecret     = "MUSICAPP_SPOTIFY_CLIENT_SECRET"
+)
+
+// Load reads the YAML file on the given path, applies the overrides from the environment variables and validates the result
+func Load(path string) (Config, error) {
+	var config Config
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("Can't read configuration file %v: %s", path, err)
+	}
+	if err = yaml.UnmarshalStrict(content, &config); err != nil {
+		return Config{}, fmt.Errorf("Can't parse configuration file %v: %s", path, err)
+	}
+	if err = config.applyEnvironment(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err = config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("Invalid configuration in %v: %s", path, err)
+	}
+	return config, nil
+}
+
+// applyEnvironment overrides the values of the configuration with the environment variables that are set.
+// It returns an error if a numeric value isn't a number.
+func (config *Config) applyEnvironment(lookup func(string) (string, bool)) error {
+	if value, ok := lookup(EnvServername); ok {
+		config.Servername = value
+	}
+	if value, ok := lookup(EnvPort); ok {
+		config.Port = value
+	}
+	if value, ok := lookup(EnvAddress); ok {
+		config.Address = value
+	}
+	if value, ok := lookup(EnvMySQLDSN); ok {
+		config.MySQL.DSN = value
+	}
+	if value, ok := lookup(EnvBus); ok {
+		config.Bus = value
+	}
+	if value, ok := lookup(EnvKafkaAddresses); ok {
+		config.Kafka.Addresses = splitList(value)
+	}
+	if value, ok := lookup(EnvGatewayName); ok {
+		config.Gateway.Name = value
+	}
+	if value, ok := lookup(EnvGatewayAddress); ok {
+		config.Gateway.Address = value
+	}
+	if value, ok := lookup(EnvKeyID); ok {
+		config.Keys.ID = value
+	}
+	if value, ok := lookup(EnvSigningKey); ok {
+		config.Keys.SigningKey = value
+	}
+	if value, ok := lookup(EnvKeyRoles); ok {
+		config.Keys.Roles = splitList(value)
+	}
+	if value, ok := lookup(EnvJWKS); ok {
+		config.Keys.JWKS = value
+	}
+	if value, ok := lookup(EnvBreachedPasswords); ok {
+		config.Signup.BreachedPasswords = value
+	}
+	if value, ok := lookup(EnvPepper); ok {
+		config.Passwords.Pepper = value
+	}
+	if value, ok := lookup(EnvTrustedProxies); ok {
+		config.TrustedProxies = splitList(value)
+	}
+	if value, ok := lookup(EnvSpotifyID); ok {
+		config.Spotify.ClientID = value
+	}
+	if value, ok := lookup(EnvSpotifySecret); ok {
+		config.Spotify.ClientSecret = value
+	}
+	for variable, field := range map[string]*uint32{EnvArgon2Time: &config.Argon2.Time, EnvArgon2Memory: &config.Argon2.Memory} {
+		if value, ok := lookup(variable); ok {
+			number, err := parseUint(variable, value, 32)
+			if err != nil {
+				return err
+			}
+			*field = uint32(number)
+		}
+	}
+	if value, ok := lookup(EnvArgon2Threads); ok {
+		number, err := parseUint(EnvArgon2Threads, value, 8)
+		if err != nil {
+			return err
+		}
+		config.Argon2.Threads = uint8(number)
+	}
+	return nil
+}
+
+// parseUint parses the value of the environment variable as an unsigned number of the given size
+func parseUint(variable, value string, bits int) (uint64, error) {
+	number, err := strconv.ParseUint(strings.TrimSpace(value), 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("%v should be a number of at most %v bits but got: %v", variable, bits, value)
+	}
+	return number, nil
+}
+
+// splitList returns the non-empty values of a comma separated list
+func splitList(list string) []string {
+	values := make([]string, 0, 2)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Validate returns an error if a required value is missing, if the port isn't of the form :port or host:port or if an address isn't valid
+func (config Config) Validate() error {
+	if err := validator.New().Struct(config); err != nil {
+		return err
+	}
+	if _, port, err := net.SplitHostPort(config.Port); err != nil || !validPort(port) {
+		return fmt.Errorf("Port %v should be of the form :port or host:port", config.Port)
+	}
+	if config.Address != "" {
+		if _, err := ServiceURL(config.Address); err != nil {
+			return fmt.Errorf("Invalid address %v: %s", config.Address, err)
+		}
+	}
+	if _, err := ServiceURL(config.Gateway.Address); err != nil {
+		return fmt.Errorf("Invalid address of the gateway %v: %s", config.Gateway.Address, err)
+	}
+	if config.Bus != BusMemory && len(config.Kafka.Addresses) == 0 {
+		return fmt.Errorf("No addresses of Kafka brokers are configured")
+	}
+	return nil
+}
+
+// AdvertisedAddress returns the address under which the other services reach this service.
+// This is the configured address or the port if no address is configured. A port on all interfaces is advertised as :port.
+func (config Config) AdvertisedAddress() string {
+	if config.Address != "" {
+		return config.Address
+	}
+	host, port, err := net.SplitHostPort(config.Port)
+	if err != nil || host == "" || host == "0.0.0.0" || host == "::" {
+		return ":" + port
+	}
+	return config.Port
+}
+
+// ServiceURL returns the url of a service with the given address. An address of the form :port is a service on localhost,
+// a service on a remote host has an address of the form host:port or a complete url like https://host:port/prefix
+func ServiceURL(address string) (*url.URL, error) {
+	switch {
+	case strings.Contains(address, "://"):
+	case strings.HasPrefix(address, ":"):
+		address = "http://localhost" + address
+	default:
+		address = "http://" + address
+	}
+	target, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("Address %v should use http or https", address)
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("Address %v doesn't contain a host", address)
+	}
+	if port := target.Port(); port != "" && !validPort(port) {
+		return nil, fmt.Errorf("Address %v contains an invalid port", address)
+	}
+	return target, nil
+}
+
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number > 0 && number <= 65535
+}
@@ -0,0 +1,639 @@
+package general
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// consumerMinBackoff is the delay before reconnecting after the first failure of a consumer
+	consumerMinBackoff = time.Second
+	// consumerMaxBackoff is the maximum delay before reconnecting a consumer, the delay doubles after every failure
+	consumerMaxBackoff = 30 * time.Second
+	// DefaultHeartbeatInterval is the time between two heartbeats of a member of a consumer group
+	DefaultHeartbeatInterval = 10 * time.Second
+	// memberTimeoutHeartbeats is the number of heartbeat intervals without a heartbeat after which a member is removed from its group
+	memberTimeoutHeartbeats = 3
+	// memberJoinWait is the time a new member of a consumer group waits for the answers of the other members before it takes partitions
+	memberJoinWait = 500 * time.Millisecond
+	// joinHeartbeatInterval is the time between two heartbeats of a new member until it receives its own heartbeat
+	joinHeartbeatInterval = 100 * time.Millisecond
+)
+
+// MembersTopic is the topic on which the members of consumer groups send their heartbeats and replays
+const MembersTopic = "consumerGroupMembers"
+
+// ReplayRequest contains the topic and partition that has to be consumed again starting from the given offset
+type ReplayRequest struct {
+	Topic     string `json:"topic" validate:"required"`
+	Partition int32  `json:"partition" validate:"min=0"`
+	Offset    int64  `json:"offset" validate:"min=0"`
+}
+
+// NewReplayRequest returns a ReplayRequest with the given data
+func NewReplayRequest(topic string, partition int32, offset int64) ReplayRequest {
+	return ReplayRequest{Topic: topic, Partition: partition, Offset: offset}
+}
+
+// GroupMessage is send by a member of a consumer group to the other members of the group on MembersTopic.
+// It is a heartbeat of the member, unless the member leaves the group or asks the member that consumes a partition to replay it.
+type GroupMessage struct {
+	Group   string         `json:"group"`
+	Member  string         `json:"member"`
+	Joining bool           `json:"joining,omitempty"`
+	Leave   bool           `json:"leave,omitempty"`
+	Replay  *ReplayRequest `json:"replay,omitempty"`
+}
+
+// ConsumerGroup consumes topics on behalf of a service. Every instance of the service is a member of the group and the partitions of topics
+// with committed offsets are divided among the members that send heartbeats. The offsets of consumed messages are committed to the bus
+// under the name of the group, so a member that takes over a partition, for example after a restart, continues with the first message that isn't consumed.
+// A partition can be consumed by two members for a short time while members join or leave, so a message can be processed more than once.
+// Messages that fail to be processed are handled by the ErrorPolicy.
+type ConsumerGroup struct {
+	ErrorPolicy ErrorPolicy
+	// HeartbeatInterval is the time between two heartbeats of the member and between two lookups of the number of partitions of the topics.
+	// It has to be set before the first topic is consumed.
+	HeartbeatInterval time.Duration
+	name              string
+	member            string
+	logger            *log.Logger
+	bus               Bus
+	mutex             sync.Mutex
+	redriveMutex      sync.Mutex
+	joinOnce          sync.Once
+	seenOnce          sync.Once
+	closeOnce         sync.Once
+	running           sync.WaitGroup
+	offsets           OffsetStore
+	topics            map[string]*topicConsumer
+	handlers          map[string]func([]byte) error
+	// members contains the time of the last heartbeat of the other members
+	members map[string]time.Time
+	joined  bool
+	// changed is closed and replaced when members join or leave the group
+	changed chan struct{}
+	// seen is closed when the member receives its own heartbeat
+	seen chan struct{}
+	// done is closed when the group is closed
+	done chan struct{}
+}
+
+// topicConsumer consumes the partitions of a topic that belong to the member
+type topicConsumer struct {
+	topic          string
+	commit         bool
+	initial        int64
+	processMessage func([]byte) error
+	count          int32
+	partitions     map[int32]*partitionConsumer
+}
+
+type partitionConsumer struct {
+	topic     string
+	partition int32
+	commit    bool
+	initial   int64
+	replay    chan int64
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewConsumerGroup returns a ConsumerGroup with the given name that consumes messages from the bus as a new member of the group.
+// Messages that keep failing are send to the dead letter topic of their topic by a DeadLetterPolicy with the default settings.
+func NewConsumerGroup(bus Bus, logger *log.Logger, name string) *ConsumerGroup {
+	group := &ConsumerGroup{
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		name:              name,
+		member:            newMemberID(name),
+		logger:            logger,
+		bus:               bus,
+		topics:            make(map[string]*topicConsumer),
+		handlers:          make(map[string]func([]byte) error),
+		members:           make(map[string]time.Time),
+		changed:           make(chan struct{}),
+		seen:              make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+	// A server without a bus doesn't consume messages
+	if bus != nil {
+		group.ErrorPolicy = NewDeadLetterPolicy(logger, bus.Publish)
+	}
+	return group
+}
+
+// newMemberID returns a random id for a member of the group
+func newMemberID(group string) string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%v-%v", group, time.Now().UnixNano())
+	}
+	return group + "-" + hex.EncodeToString(id)
+}
+
+// Consume calls processMessage for every message of the partitions of the topic that belong to the member in the background. It starts at the committed offsets
+// of the group, or at the oldest messages if nothing is committed yet. The offset of every consumed message is committed.
+// A message for which processMessage returns an error is handled by the ErrorPolicy of the group.
+func (group *ConsumerGroup) Consume(topic string, processMessage func([]byte) error) {
+	group.start(topic, true, OffsetOldest, processMessage)
+}
+
+// ConsumeNew works like Consume, but it starts at the newest messages if nothing is committed yet.
+// This is meant for messages that only have to be handled shortly after they are send, like messages that notify users.
+func (group *ConsumerGroup) ConsumeNew(topic string, processMessage func([]byte) error) {
+	group.start(topic, true, OffsetNewest, processMessage)
+}
+
+// ConsumeAll calls processMessage for every message of all partitions of the topic in the background starting at the oldest messages.
+// Nothing is committed, this is meant for topics that describe state that every instance of a service needs.
+func (group *ConsumerGroup) ConsumeAll(topic string, processMessage func([]byte) error) {
+	group.start(topic, false, OffsetOldest, processMessage)
+}
+
+// ConsumeAllNew works like ConsumeAll, but it starts at the newest messages.
+// This is meant for topics on which the state is send again regularly, so an instance doesn't need the old messages.
+func (group *ConsumerGroup) ConsumeAllNew(topic string, processMessage func([]byte) error) {
+	group.start(topic, false, OffsetNewest, processMessage)
+}
+
+// Replay consumes the partition of the topic again starting from the given offset. The offset is committed and the member
+// that consumes the partition is asked to replay it, so it doesn't matter which member receives the request.
+// It returns an error if the partition isn't consumed with committed offsets by this group.
+func (group *ConsumerGroup) Replay(request ReplayRequest) error {
+	group.mutex.Lock()
+	consumer, ok := group.topics[request.Topic]
+	consumed := ok && consumer.commit && request.Partition < consumer.count
+	group.mutex.Unlock()
+	if !consumed {
+		return fmt.Errorf("Partition %v of topic %v isn't consumed by %v", request.Partition, request.Topic, group.name)
+	}
+	// A member that takes the partition before it receives the replay starts at the committed offset
+	group.commit(request.Topic, request.Partition, request.Offset)
+	return SendEvent(group.bus.Publish, MembersTopic, GroupMessage{Group: group.name, Member: group.member, Replay: &request})
+}
+
+// Partitions returns the partitions of the topic that are consumed by this member of the group
+func (group *ConsumerGroup) Partitions(topic string) []int32 {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	partitions := make([]int32, 0)
+	if consumer, ok := group.topics[topic]; ok {
+		for partition := range consumer.partitions {
+			partitions = append(partitions, partition)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
+
+// ReplayHandler handles requests with a ReplayRequest in the body
+func (group *ConsumerGroup) ReplayHandler(response http.ResponseWriter, request *http.Request) {
+	var replay ReplayRequest
+	if err := ReadFromJSON(&replay, request.Body); err != nil {
+		group.logger.Printf("Got invalid request for a replay: %v\n", err)
+		SendError(response, http.StatusBadRequest)
+		return
+	}
+	if err := group.Replay(replay); err != nil {
+		group.logger.Printf("Can't replay: %s\n", err)
+		SendError(response, http.StatusNotFound)
+		return
+	}
+	group.logger.Printf("Succesfully requested a replay of partition %v of topic %v from offset %v\n", replay.Partition, replay.Topic, replay.Offset)
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+func (group *ConsumerGroup) start(topic string, commit bool, initial int64, processMessage func([]byte) error) {
+	consumer := &topicConsumer{topic: topic, commit: commit, initial: initial, processMessage: processMessage, partitions: make(map[int32]*partitionConsumer)}
+	group.mutex.Lock()
+	group.topics[topic] = consumer
+	group.handlers[topic] = processMessage
+	group.mutex.Unlock()
+	// The partitions of topics with committed offsets are divided among the members of the group
+	if commit {
+		group.joinOnce.Do(func() {
+			group.running.Add(1)
+			go group.join()
+		})
+	}
+	group.running.Add(1)
+	go group.watch(consumer)
+}
+
+// Close stops consuming and leaves the group, so the partitions of the member are divided among the other members right away.
+// It waits until the messages that are processed are done and their offsets are committed.
+func (group *ConsumerGroup) Close() {
+	group.closeOnce.Do(func() {
+		close(group.done)
+		group.running.Wait()
+		group.mutex.Lock()
+		joined := group.joined
+		group.mutex.Unlock()
+		if !joined {
+			return
+		}
+		if err := SendEvent(group.bus.Publish, MembersTopic, GroupMessage{Group: group.name, Member: group.member, Leave: true}); err != nil {
+			group.logger.Printf("[WARNING] Failed to leave consumer group %v: %s\n", group.name, err)
+		}
+		group.logger.Printf("Left consumer group %v\n", group.name)
+	})
+}
+
+// watch consumes the partitions of the topic that belong to the member. The partitions are divided again every time that members join or leave the group
+// and the number of partitions is looked up every HeartbeatInterval, so partitions that are added to the topic are consumed as well.
+func (group *ConsumerGroup) watch(consumer *topicConsumer) {
+	defer group.running.Done()
+	count, ok := group.partitionCount(consumer.topic)
+	if !ok {
+		return
+	}
+	group.logger.Printf("Starting to consume messages from %v partitions of topic %v\n", count, consumer.topic)
+	ticker := time.NewTicker(group.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		group.mutex.Lock()
+		changed := group.changed
+		group.mutex.Unlock()
+		group.assign(consumer, count)
+		select {
+		case <-group.done:
+			group.assign(consumer, 0)
+			return
+		case <-changed:
+		case <-ticker.C:
+			latest, err := group.bus.Partitions(consumer.topic)
+			if err != nil {
+				group.logger.Printf("[WARNING] Cannot look up partitions of topic %v: %s\n", consumer.topic, err)
+				continue
+			}
+			if latest > count {
+				group.logger.Printf("Topic %v has %v new partitions\n", consumer.topic, latest-count)
+				count = latest
+			}
+		}
+	}
+}
+
+// assign starts consumers for the partitions of the topic that belong to the member and stops the consumers of the partitions that belong to other members.
+// A partition belongs to a single member of the sorted members of the group, a topic without committed offsets is consumed completely by every member.
+func (group *ConsumerGroup) assign(consumer *topicConsumer, count int32) {
+	group.mutex.Lock()
+	consumer.count = count
+	members := group.currentMembers()
+	owned := make(map[int32]bool)
+	for partition := int32(0); partition < count; partition++ {
+		if !consumer.commit || (group.joined && members[int(partition)%len(members)] == group.member) {
+			owned[partition] = true
+		}
+	}
+	stopping := make([]*partitionConsumer, 0)
+	for partition, running := range consumer.partitions {
+		if !owned[partition] {
+			close(running.stop)
+			stopping = append(stopping, running)
+			delete(consumer.partitions, partition)
+		}
+	}
+	started := make([]*partitionConsumer, 0)
+	for partition := range owned {
+		if _, ok := consumer.partitions[partition]; ok {
+			continue
+		}
+		created := &partitionConsumer{topic: consumer.topic, partition: partition, commit: consumer.commit, initial: consumer.initial,
+			replay: make(chan int64, 1), stop: make(chan struct{}), stopped: make(chan struct{})}
+		consumer.partitions[partition] = created
+		started = append(started, created)
+	}
+	group.mutex.Unlock()
+	// The consumers finish the messages that they are processing, so their offsets are committed before the partitions are handed over
+	for _, running := range stopping {
+		<-running.stopped
+		group.logger.Printf("Stopped consuming partition %v of topic %v\n", running.partition, running.topic)
+	}
+	for _, created := range started {
+		go group.consumePartition(created, consumer.processMessage)
+	}
+}
+
+// currentMembers returns the sorted ids of the members of the group including this member, the caller has to hold the mutex
+func (group *ConsumerGroup) currentMembers() []string {
+	members := []string{group.member}
+	for member := range group.members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// join makes the member known to the other members of the group. The subscription on MembersTopic starts at the newest message,
+// so the member sends heartbeats until it receives its own heartbeat. Then it waits memberJoinWait for the answers of the other members
+// before it takes partitions. Afterwards it sends a heartbeat every HeartbeatInterval and removes the members that stopped sending heartbeats.
+func (group *ConsumerGroup) join() {
+	defer group.running.Done()
+	group.start(MembersTopic, false, OffsetNewest, group.receive)
+	for group.heartbeat(true); !waitFor(group.seen, joinHeartbeatInterval); group.heartbeat(true) {
+		if group.closed() {
+			return
+		}
+	}
+	if waitFor(group.done, memberJoinWait) {
+		return
+	}
+	group.mutex.Lock()
+	group.joined = true
+	members := len(group.members) + 1
+	group.notifyChanged()
+	group.mutex.Unlock()
+	group.logger.Printf("Joined consumer group %v with %v members as %v\n", group.name, members, group.member)
+	ticker := time.NewTicker(group.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-group.done:
+			return
+		case <-ticker.C:
+			group.heartbeat(false)
+			group.removeSilentMembers()
+		}
+	}
+}
+
+// heartbeat sends a heartbeat of the member, the other members answer the heartbeats of a member that is joining
+func (group *ConsumerGroup) heartbeat(joining bool) {
+	if err := SendEvent(group.bus.Publish, MembersTopic, GroupMessage{Group: group.name, Member: group.member, Joining: joining}); err != nil {
+		group.logger.Printf("[WARNING] Failed to send heartbeat of consumer group %v: %s\n", group.name, err)
+	}
+}
+
+// receive handles the heartbeats and replays of the members of the group
+func (group *ConsumerGroup) receive(message []byte) error {
+	var received GroupMessage
+	if _, err := DecodeEvent(message, &received); err != nil {
+		group.logger.Printf("Failed to deserialize message of consumer group: %v due to: %s\n", string(message), err)
+		return PermanentError(err)
+	}
+	if received.Group != group.name || received.Member == "" {
+		return nil
+	}
+	if received.Replay != nil {
+		group.replayPartition(*received.Replay)
+		return nil
+	}
+	if received.Member == group.member {
+		group.seenOnce.Do(func() { close(group.seen) })
+		return nil
+	}
+	if received.Leave {
+		group.removeMember(received.Member)
+		return nil
+	}
+	group.mutex.Lock()
+	_, known := group.members[received.Member]
+	group.members[received.Member] = time.Now()
+	if !known {
+		group.notifyChanged()
+	}
+	group.mutex.Unlock()
+	if !known {
+		group.logger.Printf("Member %v joined consumer group %v\n", received.Member, group.name)
+	}
+	// The new member learns about this member from the answer. A heartbeat that is send before the new member subscribed
+	// to MembersTopic isn't received by the new member, so every heartbeat of a joining member is answered.
+	if received.Joining {
+		group.heartbeat(false)
+	}
+	return nil
+}
+
+// removeSilentMembers removes the members that didn't send a heartbeat for memberTimeoutHeartbeats intervals, their partitions are divided among the other members
+func (group *ConsumerGroup) removeSilentMembers() {
+	timeout := memberTimeoutHeartbeats * group.HeartbeatInterval
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	for member, last := range group.members {
+		if time.Since(last) < timeout {
+			continue
+		}
+		delete(group.members, member)
+		group.logger.Printf("[WARNING] Member %v left consumer group %v without heartbeat for %v\n", member, group.name, timeout)
+		group.notifyChanged()
+	}
+}
+
+// removeMember removes the member that left the group, its partitions are divided among the other members
+func (group *ConsumerGroup) removeMember(member string) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	if _, ok := group.members[member]; !ok {
+		return
+	}
+	delete(group.members, member)
+	group.logger.Printf("Member %v left consumer group %v\n", member, group.name)
+	group.notifyChanged()
+}
+
+// notifyChanged wakes up the consumers of the topics in order to divide the partitions again, the caller has to hold the mutex
+func (group *ConsumerGroup) notifyChanged() {
+	close(group.changed)
+	group.changed = make(chan struct{})
+}
+
+// replayPartition replays the partition if it is consumed by this member
+func (group *ConsumerGroup) replayPartition(request ReplayRequest) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	consumer, ok := group.topics[request.Topic]
+	if !ok || !consumer.commit {
+		return
+	}
+	running, ok := consumer.partitions[request.Partition]
+	if !ok {
+		return
+	}
+	// A newer replay replaces a replay that isn't started yet
+	select {
+	case <-running.replay:
+	default:
+	}
+	running.replay <- request.Offset
+}
+
+// partitionCount returns the number of partitions of the topic. The topic is created if it doesn't exist.
+// It returns false if the group is closed before the partitions are found.
+func (group *ConsumerGroup) partitionCount(topic string) (int32, bool) {
+	for backoff := consumerMinBackoff; ; backoff = nextBackoff(backoff) {
+		count, err := group.bus.Partitions(topic)
+		if err == nil && count > 0 {
+			return count, true
+		}
+		group.logger.Printf("[WARNING] Cannot find or create topic %v, retrying in %v: %v\n", topic, backoff, err)
+		if waitFor(group.done, backoff) {
+			return 0, false
+		}
+	}
+}
+
+// sleep waits for the duration and returns false if the partition is stopped in the meantime
+func (partition *partitionConsumer) sleep(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-partition.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// consumePartition consumes the partition until it is stopped. A subscription is closed when it is replaced or when the partition is stopped.
+func (group *ConsumerGroup) consumePartition(partition *partitionConsumer, processMessage func([]byte) error) {
+	defer close(partition.stopped)
+	offset := partition.initial
+	if partition.commit {
+		offset = group.committedOffset(partition.topic, partition.partition, partition.initial)
+	}
+	var subscription Subscription
+	defer func() {
+		if subscription != nil {
+			subscription.Close()
+		}
+	}()
+	backoff := consumerMinBackoff
+	for {
+		select {
+		case <-partition.stop:
+			return
+		case replayOffset := <-partition.replay:
+			group.logger.Printf("Replaying partition %v of topic %v from offset %v\n", partition.partition, partition.topic, replayOffset)
+			if subscription != nil {
+				subscription.Close()
+			}
+			offset, subscription = replayOffset, nil
+			group.commit(partition.topic, partition.partition, offset)
+		default:
+		}
+		if subscription == nil {
+			created, err := group.bus.Subscribe(partition.topic, partition.partition, offset)
+			if err == ErrOffsetOutOfRange {
+				group.logger.Printf("[WARNING] Offset %v of partition %v of topic %v doesn't exist anymore, consuming from the oldest message\n", offset, partition.partition, partition.topic)
+				offset = OffsetOldest
+				continue
+			}
+			if err != nil {
+				group.logger.Printf("[ERROR] Cannot subscribe to partition %v of topic %v: %s\n", partition.partition, partition.topic, err)
+				if !partition.sleep(backoff) {
+					return
+				}
+				backoff = nextBackoff(backoff)
+				continue
+			}
+			subscription = created
+		}
+		msg, err := subscription.Next()
+		if err == ErrNoMessage {
+			continue
+		}
+		if err == ErrOffsetOutOfRange {
+			group.logger.Printf("[WARNING] Offset %v of partition %v of topic %v doesn't exist anymore, consuming from the oldest message\n", offset, partition.partition, partition.topic)
+			subscription.Close()
+			offset, subscription = OffsetOldest, nil
+			continue
+		}
+		if err != nil {
+			group.logger.Printf("[ERROR] Cannot consume message of partition %v of topic %v, reconnecting in %v: %s\n", partition.partition, partition.topic, backoff, err)
+			subscription.Close()
+			subscription = nil
+			if !partition.sleep(backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = consumerMinBackoff
+		// The message isn't committed, so the member that takes over the partition processes it
+		select {
+		case <-partition.stop:
+			return
+		default:
+		}
+		if !group.process(partition, msg, processMessage) {
+			return
+		}
+		offset = msg.Offset + 1
+		if partition.commit {
+			group.commit(partition.topic, partition.partition, offset)
+		}
+	}
+}
+
+// committedOffset returns the offset of the first message of the partition that isn't consumed by the group or initial if nothing is committed
+func (group *ConsumerGroup) committedOffset(topic string, partition int32, initial int64) int64 {
+	offsets, err := group.offsetStore()
+	if err != nil {
+		group.logger.Printf("[WARNING] Can't obtain committed offset of partition %v of topic %v: %s\n", partition, topic, err)
+		return initial
+	}
+	offset, err := offsets.Offset(topic, partition)
+	if err != nil || offset < 0 {
+		return initial
+	}
+	return offset
+}
+
+func (group *ConsumerGroup) commit(topic string, partition int32, offset int64) {
+	offsets, err := group.offsetStore()
+	if err == nil {
+		err = offsets.Commit(topic, partition, offset)
+	}
+	if err != nil {
+		group.logger.Printf("[WARNING] Failed to commit offset %v of partition %v of topic %v: %s\n", offset, partition, topic, err)
+	}
+}
+
+func (group *ConsumerGroup) offsetStore() (OffsetStore, error) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	if group.offsets != nil {
+		return group.offsets, nil
+	}
+	offsets, err := group.bus.Offsets(group.name)
+	if err != nil {
+		return nil, err
+	}
+	group.offsets = offsets
+	return offsets, nil
+}
+
+// closed returns true if the group is closed
+func (group *ConsumerGroup) closed() bool {
+	select {
+	case <-group.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitFor returns true if done is closed within the timeout
+func waitFor(done <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff *= 2; backoff > consumerMaxBackoff {
+		return consumerMaxBackoff
+	}
+	return backoff
+}
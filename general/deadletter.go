@@ -0,0 +1,293 @@
+package general
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// DefaultConsumerRetries is the number of times a message that fails is processed again before it is dead-lettered
+	DefaultConsumerRetries = 3
+	// DefaultConsumerRetryBackoff is the delay before processing a failed message again, the delay doubles for every retry
+	DefaultConsumerRetryBackoff = time.Second
+)
+
+var (
+	consumerFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "consumer_failures_total",
+		Help: "The total number of failed attempts to process a message by topic",
+	}, []string{"topic"})
+	deadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "consumer_dead_letters_total",
+		Help: "The total number of messages that are given up on by topic",
+	}, []string{"topic"})
+)
+
+type permanentError struct {
+	err error
+}
+
+func (err permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err permanentError) Unwrap() error {
+	return err.err
+}
+
+// PermanentError marks the error of processing a message as permanent, such a message is dead-lettered without retrying
+func PermanentError(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent returns true if the error is marked as permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// DeadLetterTopic returns the topic that contains the messages of the given topic that couldn't be processed
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// DeadLetter is a message that couldn't be processed by a consumer group together with the reason of the failure
+type DeadLetter struct {
+	Group     string    `json:"group"`
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Message   []byte    `json:"message"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	Failed    time.Time `json:"failed"`
+}
+
+// NewDeadLetter returns a DeadLetter for the message that failed the given number of times
+func NewDeadLetter(group string, message BusMessage, attempts int, err error) DeadLetter {
+	return DeadLetter{Group: group, Topic: message.Topic, Partition: message.Partition, Offset: message.Offset, Message: message.Value, Error: err.Error(), Attempts: attempts, Failed: time.Now()}
+}
+
+// DeadLetterEntry is a DeadLetter together with its position in the dead letter topic
+type DeadLetterEntry struct {
+	Partition int32      `json:"partition"`
+	Offset    int64      `json:"offset"`
+	Letter    DeadLetter `json:"letter"`
+}
+
+// RedriveResult contains the number of dead letters that are processed succesfully and the number that failed again
+type RedriveResult struct {
+	Redriven int `json:"redriven"`
+	Failed   int `json:"failed"`
+}
+
+// ErrorPolicy decides what happens with a message that a consumer fails to process
+type ErrorPolicy interface {
+	// Retry returns the delay before the message is processed again after it failed the given number of times, or false if it isn't processed again
+	Retry(attempts int, err error) (time.Duration, bool)
+	// GiveUp is called with a message that isn't processed again
+	GiveUp(letter DeadLetter)
+}
+
+// DeadLetterPolicy retries a message with an increasing delay and sends the message to the dead letter topic if it keeps failing.
+// Messages with a permanent error aren't retried.
+type DeadLetterPolicy struct {
+	Retries     int
+	Backoff     time.Duration
+	logger      *log.Logger
+	sendMessage func(topic string, message []byte) error
+}
+
+// NewDeadLetterPolicy returns a DeadLetterPolicy with the default settings that sends dead letters with sendMessage
+func NewDeadLetterPolicy(logger *log.Logger, sendMessage func(topic string, message []byte) error) *DeadLetterPolicy {
+	return &DeadLetterPolicy{Retries: DefaultConsumerRetries, Backoff: DefaultConsumerRetryBackoff, logger: logger, sendMessage: sendMessage}
+}
+
+// Retry returns Backoff * 2^(attempts-1) as delay until the message failed Retries times
+func (policy *DeadLetterPolicy) Retry(attempts int, err error) (time.Duration, bool) {
+	if IsPermanent(err) || attempts > policy.Retries {
+		return 0, false
+	}
+	delay := policy.Backoff << uint(attempts-1)
+	if delay <= 0 || delay > consumerMaxBackoff {
+		delay = consumerMaxBackoff
+	}
+	return delay, true
+}
+
+// GiveUp sends the letter to the dead letter topic of its topic
+func (policy *DeadLetterPolicy) GiveUp(letter DeadLetter) {
+	msg, err := ToJSONBytes(letter)
+	if err == nil {
+		err = policy.sendMessage(DeadLetterTopic(letter.Topic), msg)
+	}
+	if err != nil {
+		policy.logger.Printf("[ERROR] Failed to dead-letter message %v of partition %v of topic %v, the message is lost: %v: %s\n", letter.Offset, letter.Partition, letter.Topic, string(letter.Message), err)
+		return
+	}
+	deadLetters.WithLabelValues(letter.Topic).Inc()
+}
+
+// process calls processMessage until it succeeds or until the error policy gives up on the message.
+// It returns false if the partition is stopped while it waits for the next attempt, the message isn't processed then.
+func (group *ConsumerGroup) process(partition *partitionConsumer, msg BusMessage, processMessage func([]byte) error) bool {
+	for attempts := 1; ; attempts++ {
+		err := processMessage(msg.Value)
+		if err == nil {
+			return true
+		}
+		consumerFailures.WithLabelValues(msg.Topic).Inc()
+		delay, retry := group.ErrorPolicy.Retry(attempts, err)
+		if !retry {
+			group.logger.Printf("[ERROR] Giving up on message %v of partition %v of topic %v after %v attempts: %s\n", msg.Offset, msg.Partition, msg.Topic, attempts, err)
+			group.ErrorPolicy.GiveUp(NewDeadLetter(group.name, msg, attempts, err))
+			return true
+		}
+		group.logger.Printf("[WARNING] Failed to process message %v of partition %v of topic %v, retrying in %v: %s\n", msg.Offset, msg.Partition, msg.Topic, delay, err)
+		if !partition.sleep(delay) {
+			// The member that takes over the partition processes the message, since it isn't committed
+			return false
+		}
+	}
+}
+
+// DeadLetters returns the dead letters of the topic that the group gave up on and that aren't redriven yet
+func (group *ConsumerGroup) DeadLetters(topic string) ([]DeadLetterEntry, error) {
+	dlq := DeadLetterTopic(topic)
+	count, err := group.bus.Partitions(dlq)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]DeadLetterEntry, 0)
+	for partition := int32(0); partition < count; partition++ {
+		found, err := group.readDeadLetters(dlq, partition)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+	return entries, nil
+}
+
+// readDeadLetters reads the dead letters of the group in the partition starting at the committed offset of the group
+func (group *ConsumerGroup) readDeadLetters(dlq string, partition int32) ([]DeadLetterEntry, error) {
+	offset := group.committedOffset(dlq, partition, OffsetOldest)
+	subscription, err := group.bus.Subscribe(dlq, partition, offset)
+	if err == ErrOffsetOutOfRange {
+		// The committed offset is removed by the retention of the topic
+		subscription, err = group.bus.Subscribe(dlq, partition, OffsetOldest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if subscription != nil {
+			subscription.Close()
+		}
+	}()
+	entries := make([]DeadLetterEntry, 0)
+	for {
+		msg, err := subscription.Next()
+		if err == ErrNoMessage {
+			return entries, nil
+		}
+		if err == ErrOffsetOutOfRange && offset != OffsetOldest {
+			offset = OffsetOldest
+			subscription.Close()
+			if subscription, err = group.bus.Subscribe(dlq, partition, offset); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var letter DeadLetter
+		if err := FromJSONBytes(&letter, msg.Value); err != nil {
+			group.logger.Printf("[WARNING] Skipping invalid dead letter %v of partition %v of topic %v: %s\n", msg.Offset, partition, dlq, err)
+			continue
+		}
+		if letter.Group == group.name {
+			entries = append(entries, DeadLetterEntry{Partition: partition, Offset: msg.Offset, Letter: letter})
+		}
+	}
+}
+
+// Redrive processes the dead letters of the topic again. A dead letter that is processed succesfully is removed from the dead letters of the group.
+// The dead letters of a partition after a dead letter that fails again stay as well, so they are redriven in order next time.
+func (group *ConsumerGroup) Redrive(topic string) (RedriveResult, error) {
+	group.mutex.Lock()
+	processMessage, ok := group.handlers[topic]
+	group.mutex.Unlock()
+	if !ok {
+		return RedriveResult{}, fmt.Errorf("Topic %v isn't consumed by %v", topic, group.name)
+	}
+	group.redriveMutex.Lock()
+	defer group.redriveMutex.Unlock()
+	entries, err := group.DeadLetters(topic)
+	if err != nil {
+		return RedriveResult{}, err
+	}
+	var result RedriveResult
+	blocked := make(map[int32]bool)
+	for _, entry := range entries {
+		if blocked[entry.Partition] {
+			continue
+		}
+		if err := processMessage(entry.Letter.Message); err != nil {
+			group.logger.Printf("[ERROR] Redriving message %v of partition %v of topic %v failed again: %s\n", entry.Letter.Offset, entry.Letter.Partition, topic, err)
+			blocked[entry.Partition] = true
+			result.Failed++
+			continue
+		}
+		group.commit(DeadLetterTopic(topic), entry.Partition, entry.Offset+1)
+		result.Redriven++
+	}
+	return result, nil
+}
+
+// DeadLettersHandler responds with the dead letters of the topic in the path
+func (group *ConsumerGroup) DeadLettersHandler(response http.ResponseWriter, request *http.Request) {
+	topic := mux.Vars(request)["topic"]
+	entries, err := group.DeadLetters(topic)
+	if err != nil {
+		group.logger.Printf("[ERROR] Can't read dead letters of topic %v: %s\n", topic, err)
+		SendError(response, http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	if err := WriteToJSON(&entries, response); err != nil {
+		group.logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// RedriveHandler redrives the dead letters of the topic in the path and responds with a RedriveResult
+func (group *ConsumerGroup) RedriveHandler(response http.ResponseWriter, request *http.Request) {
+	topic := mux.Vars(request)["topic"]
+	group.mutex.Lock()
+	_, ok := group.handlers[topic]
+	group.mutex.Unlock()
+	if !ok {
+		group.logger.Printf("Can't redrive topic %v that isn't consumed by %v\n", topic, group.name)
+		SendError(response, http.StatusNotFound)
+		return
+	}
+	result, err := group.Redrive(topic)
+	if err != nil {
+		group.logger.Printf("[ERROR] Can't redrive dead letters of topic %v: %s\n", topic, err)
+		SendError(response, http.StatusInternalServerError)
+		return
+	}
+	group.logger.Printf("Succesfully redrove %v dead letters of topic %v, %v failed again\n", result.Redriven, topic, result.Failed)
+	response.Header().Set("Content-Type", "application/json")
+	if err := WriteToJSON(&result, response); err != nil {
+		group.logger.Printf("[ERROR] %s\n", err)
+	}
+}
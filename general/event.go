@@ -0,0 +1,151 @@
+package general
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventSchemaVersion is the version of the envelope and the payloads that this version of the services publishes and understands.
+// Messages without an envelope, that are published before events were introduced, have version 0.
+const EventSchemaVersion = 1
+
+// DefaultDeduplicationWindow is the number of event ids that an EventDeduplicator remembers
+const DefaultDeduplicationWindow = 10000
+
+// Event is the envelope of a message to Kafka. The payload contains the data of the event, like a new artist or a new user.
+type Event struct {
+	ID         string          `json:"id" validate:"required"`
+	Type       string          `json:"type" validate:"required"`
+	Version    int             `json:"version" validate:"min=1"`
+	Producer   string          `json:"producer"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Payload    json.RawMessage `json:"payload" validate:"required"`
+}
+
+// NewEvent returns an event of the given type with a new id that contains the payload.
+// The event doesn't have a producer yet, the EventBus that publishes it sets its service as producer.
+func NewEvent(eventType string, payload interface{}) (Event, error) {
+	data, err := ToJSONBytes(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return Event{}, err
+	}
+	return Event{ID: hex.EncodeToString(id), Type: eventType, Version: EventSchemaVersion, OccurredAt: time.Now(), Payload: data}, nil
+}
+
+// EncodeEvent returns the serialized event of the given type that contains the payload, the EventBus that publishes it sets the producer
+func EncodeEvent(eventType string, payload interface{}) ([]byte, error) {
+	event, err := NewEvent(eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+	return ToJSONBytes(event)
+}
+
+// SendEvent sends an event with the payload to the topic with sendMessage, the type of the event is the name of the topic
+func SendEvent(sendMessage func(topic string, message []byte) error, topic string, payload interface{}) error {
+	msg, err := EncodeEvent(topic, payload)
+	if err != nil {
+		return err
+	}
+	return sendMessage(topic, msg)
+}
+
+// DecodeEvent deserializes the payload of the event in the message into payload and returns the envelope.
+// A message without envelope is seen as the payload of an event with version 0 and without id.
+// It returns an error for events with a version that is newer than EventSchemaVersion.
+func DecodeEvent(message []byte, payload interface{}) (Event, error) {
+	event, ok := asEvent(message)
+	if !ok {
+		// Messages from before the envelope only contain the payload
+		return Event{}, FromJSONBytes(payload, message)
+	}
+	if event.Version > EventSchemaVersion {
+		return Event{}, fmt.Errorf("Event %v of type %v has unknown version %v", event.ID, event.Type, event.Version)
+	}
+	if err := Validate(&event); err != nil {
+		return Event{}, err
+	}
+	return event, FromJSONBytes(payload, event.Payload)
+}
+
+// asEvent returns the event in the message or false if the message doesn't have an envelope
+func asEvent(message []byte) (Event, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return Event{}, false
+	}
+	if _, ok := fields["type"]; !ok {
+		return Event{}, false
+	}
+	if _, ok := fields["payload"]; !ok {
+		return Event{}, false
+	}
+	var event Event
+	if err := json.Unmarshal(message, &event); err != nil {
+		return Event{}, false
+	}
+	return event, true
+}
+
+// EventDeduplicator remembers the ids of the last processed events, so events that are delivered more than once are only processed once.
+// The ids are kept in memory, so an event that is delivered again after a restart is processed again.
+type EventDeduplicator struct {
+	mutex sync.Mutex
+	seen  map[string]bool
+	ids   []string
+	next  int
+}
+
+// NewEventDeduplicator returns an EventDeduplicator that remembers the given number of ids
+func NewEventDeduplicator(size int) *EventDeduplicator {
+	if size <= 0 {
+		size = DefaultDeduplicationWindow
+	}
+	return &EventDeduplicator{seen: make(map[string]bool), ids: make([]string, size)}
+}
+
+// Deduplicate returns a function that only calls processMessage for events that aren't processed succesfully yet.
+// Messages without envelope are always processed.
+func (deduplicator *EventDeduplicator) Deduplicate(processMessage func([]byte) error) func([]byte) error {
+	return func(message []byte) error {
+		event, ok := asEvent(message)
+		if !ok || event.ID == "" {
+			return processMessage(message)
+		}
+		if deduplicator.contains(event.ID) {
+			return nil
+		}
+		if err := processMessage(message); err != nil {
+			return err
+		}
+		deduplicator.add(event.ID)
+		return nil
+	}
+}
+
+func (deduplicator *EventDeduplicator) contains(id string) bool {
+	deduplicator.mutex.Lock()
+	defer deduplicator.mutex.Unlock()
+	return deduplicator.seen[id]
+}
+
+// add remembers the id and forgets the oldest id if the deduplicator is full
+func (deduplicator *EventDeduplicator) add(id string) {
+	deduplicator.mutex.Lock()
+	defer deduplicator.mutex.Unlock()
+	if deduplicator.seen[id] {
+		return
+	}
+	delete(deduplicator.seen, deduplicator.ids[deduplicator.next])
+	deduplicator.ids[deduplicator.next] = id
+	deduplicator.seen[id] = true
+	deduplicator.next = (deduplicator.next + 1) % len(deduplicator.ids)
+}
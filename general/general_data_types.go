@@ -1,5 +1,7 @@
 package general
 
+import "time"
+
 // Service contains the name and address of a microservce. This type will be used for sharing this data with the services
 type Service struct {
 	Name    string `json:"name" validate:"required"`
@@ -30,11 +32,45 @@ func NewArtist(id int, name, prefix string) Artist {
 	return Artist{ID: id, Name: name, Prefix: prefix}
 }
 
-// Song contains the id, contributing artists and name of the song
+// Genre contains the id and name of a genre
+type Genre struct {
+	ID   int    `json:"id" validate:"required"`
+	Name string `json:"name" validate:"required"`
+}
+
+// NewGenre returns a Genre with the given data
+func NewGenre(id int, name string) Genre {
+	return Genre{ID: id, Name: name}
+}
+
+// GenreTag represents a genre that is added to or removed from a song
+type GenreTag struct {
+	SongID int   `json:"songID" validate:"required"`
+	Genre  Genre `json:"genre"`
+}
+
+// NewGenreTag returns a GenreTag with the given data
+func NewGenreTag(songID int, genre Genre) GenreTag {
+	return GenreTag{SongID: songID, Genre: genre}
+}
+
+// AlbumGenreTag represents a genre that is added to or removed from an album
+type AlbumGenreTag struct {
+	AlbumID int   `json:"albumID" validate:"required"`
+	Genre   Genre `json:"genre"`
+}
+
+// NewAlbumGenreTag returns an AlbumGenreTag with the given data
+func NewAlbumGenreTag(albumID int, genre Genre) AlbumGenreTag {
+	return AlbumGenreTag{AlbumID: albumID, Genre: genre}
+}
+
+// Song contains the id, contributing artists, name and genres of the song
 type Song struct {
 	ID         int      `json:"id" validate:"required"`
 	Artists    []Artist `json:"artists" validate:"required"`
 	Name       string   `json:"name" validate:"required"`
+	Genres     []Genre  `json:"genres"`
 	Preference string   `json:"preference"`
 }
 
@@ -43,7 +79,24 @@ func NewSong(id int, artists []Artist, song string) Song {
 	if artists == nil {
 		artists = make([]Artist, 0, 1)
 	}
-	return Song{ID: id, Name: song, Artists: artists}
+	return Song{ID: id, Name: song, Artists: artists, Genres: make([]Genre, 0)}
+}
+
+// Album contains the id, name, main artist and genres of an album together with its tracks in the order of the track listing
+type Album struct {
+	ID     int     `json:"id" validate:"required"`
+	Name   string  `json:"name" validate:"required"`
+	Artist Artist  `json:"artist"`
+	Songs  []Song  `json:"songs"`
+	Genres []Genre `json:"genres"`
+}
+
+// NewAlbum returns an Album containing the given data
+func NewAlbum(id int, album string, artist Artist, songs []Song) Album {
+	if songs == nil {
+		songs = make([]Song, 0, 1)
+	}
+	return Album{ID: id, Name: album, Artist: artist, Songs: songs, Genres: make([]Genre, 0)}
 }
 
 // Preference represents a preference of an user with the id of the song or artist and the page where the request came from.
@@ -58,6 +111,102 @@ func NewPreference(id int, page string) Preference {
 	return Preference{ID: id, Page: page}
 }
 
+// RoleChange represents a new role of an user. Tokens of the user with a version lower than the given version contain the old role.
+type RoleChange struct {
+	UserID  int    `json:"userID" validate:"required"`
+	Role    string `json:"role" validate:"required"`
+	Version int    `json:"version" validate:"required"`
+}
+
+// NewRoleChange returns a RoleChange with the given data
+func NewRoleChange(userID int, role string, version int) RoleChange {
+	return RoleChange{UserID: userID, Role: role, Version: version}
+}
+
+// LoginFailure represents a failed login attempt for a username from an IP address. Locked is true if the attempt results in a lockout.
+type LoginFailure struct {
+	Username string    `json:"username" validate:"required"`
+	IP       string    `json:"ip"`
+	Attempts int       `json:"attempts" validate:"required"`
+	Locked   bool      `json:"locked"`
+	Time     time.Time `json:"time"`
+}
+
+// NewLoginFailure returns a LoginFailure with the given data that happened now
+func NewLoginFailure(username, ip string, attempts int, locked bool) LoginFailure {
+	return LoginFailure{Username: username, IP: ip, Attempts: attempts, Locked: locked, Time: time.Now().UTC()}
+}
+
+// PreferenceChange represents a like or dislike of an user for a song that is added or removed
+type PreferenceChange struct {
+	UserID     int    `json:"userID" validate:"required"`
+	SongID     int    `json:"songID" validate:"required"`
+	Preference string `json:"preference" validate:"required"`
+	Removed    bool   `json:"removed"`
+}
+
+// NewPreferenceChange returns a PreferenceChange with the given data
+func NewPreferenceChange(userID, songID int, preference string, removed bool) PreferenceChange {
+	return PreferenceChange{UserID: userID, SongID: songID, Preference: preference, Removed: removed}
+}
+
+// UserPreferences contains all the likes, dislikes and followed artists of an user
+type UserPreferences struct {
+	Likes           []Song   `json:"likes"`
+	Dislikes        []Song   `json:"dislikes"`
+	FollowedArtists []Artist `json:"followedArtists"`
+}
+
+// NewUserPreferences returns UserPreferences with the given data
+func NewUserPreferences(likes, dislikes []Song, followedArtists []Artist) UserPreferences {
+	if likes == nil {
+		likes = make([]Song, 0)
+	}
+	if dislikes == nil {
+		dislikes = make([]Song, 0)
+	}
+	if followedArtists == nil {
+		followedArtists = make([]Artist, 0)
+	}
+	return UserPreferences{Likes: likes, Dislikes: dislikes, FollowedArtists: followedArtists}
+}
+
+// Suggestion contains a song that is suggested to an user together with the score of this suggestion
+type Suggestion struct {
+	Song  Song `json:"song"`
+	Score int  `json:"score"`
+}
+
+// NewSuggestion returns a Suggestion with the given data
+func NewSuggestion(song Song, score int) Suggestion {
+	return Suggestion{Song: song, Score: score}
+}
+
+// Notification represents a notification for an user about a new song or album of a followed artist
+type Notification struct {
+	ID      int       `json:"id"`
+	Type    string    `json:"type"`
+	MusicID int       `json:"musicID"`
+	Name    string    `json:"name"`
+	Artists []Artist  `json:"artists"`
+	Read    bool      `json:"read"`
+	Created time.Time `json:"created"`
+}
+
+// NewNotification returns an unread Notification containing the given data
+func NewNotification(id int, notificationType string, musicID int, name string, artists []Artist) Notification {
+	if artists == nil {
+		artists = make([]Artist, 0, 1)
+	}
+	return Notification{ID: id, Type: notificationType, MusicID: musicID, Name: name, Artists: artists}
+}
+
+// MultipleCredentials represents the results of a request in a form containing the found users and a boolean that shows if there are more results
+type MultipleCredentials struct {
+	Data    []Credentials `json:"users"`
+	HasNext bool          `json:"hasNext"`
+}
+
 // MultipleArtists represents the results of a request in a form containing the found artists and a boolean that shows if there are more results
 type MultipleArtists struct {
 	Data    []Artist `json:"music"`
@@ -70,6 +219,24 @@ type MultipleSongs struct {
 	HasNext bool   `json:"hasNext"`
 }
 
+// MultipleAlbums represents the results of a request in a form containing the found albums and a boolean that shows if there are more results
+type MultipleAlbums struct {
+	Data    []Album `json:"music"`
+	HasNext bool    `json:"hasNext"`
+}
+
+// MultipleSuggestions represents the results of a request in a form containing the suggested songs and a boolean that shows if there are more results
+type MultipleSuggestions struct {
+	Data    []Suggestion `json:"music"`
+	HasNext bool         `json:"hasNext"`
+}
+
+// MultipleNotifications represents the results of a request in a form containing the found notifications and a boolean that shows if there are more results
+type MultipleNotifications struct {
+	Data    []Notification `json:"notifications"`
+	HasNext bool           `json:"hasNext"`
+}
+
 // Music represents the results of a request in a form containing the found artists and songs and a boolean that shows if there are more results
 type Music struct {
 	Data    []interface{} `json:"music"`
@@ -9,4 +9,6 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/leodido/go-urn v1.2.0 // indirect
 	github.com/optiopay/kafka/v2 v2.1.1
+	github.com/prometheus/client_golang v1.5.1
+	gopkg.in/yaml.v2 v2.2.5
 )
@@ -0,0 +1,197 @@
+package general
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/optiopay/kafka/v2"
+	"github.com/optiopay/kafka/v2/proto"
+)
+
+// consumerPollRetries is the number of empty fetches after which a Subscription returns ErrNoMessage, a fetch waits 50ms
+const consumerPollRetries = 20
+
+// publishPartitionsInterval is the time after which a KafkaBus looks up the number of partitions of a topic again before publishing
+const publishPartitionsInterval = 30 * time.Second
+
+// KafkaBus is a Bus on a Kafka cluster
+type KafkaBus struct {
+	broker   *kafka.Broker
+	logger   *log.Logger
+	producer kafka.Producer
+	mutex    sync.Mutex
+	targets  map[string]*publishTarget
+}
+
+// publishTarget is the number of partitions of a topic and the partition of the next message that is published to it
+type publishTarget struct {
+	partitions int32
+	next       int32
+	updated    time.Time
+}
+
+// NewKafkaBus returns a KafkaBus that uses the given broker
+func NewKafkaBus(broker *kafka.Broker, logger *log.Logger) *KafkaBus {
+	return &KafkaBus{broker: broker, logger: logger, producer: broker.Producer(kafka.NewProducerConf()), targets: make(map[string]*publishTarget)}
+}
+
+// Publish sends the message to the partitions of the topic in turn. Partitions that are added to the topic are used after publishPartitionsInterval.
+func (bus *KafkaBus) Publish(topic string, message []byte) error {
+	_, err := bus.producer.Produce(topic, bus.nextPartition(topic), &proto.Message{Value: message})
+	return err
+}
+
+// PublishWithKey sends the message with the key to the partition of the key.
+// The partition of a key changes when partitions are added to the topic, like with the default partitioner of Kafka.
+func (bus *KafkaBus) PublishWithKey(topic, key string, message []byte) error {
+	target := bus.target(topic)
+	bus.mutex.Lock()
+	partition := keyPartition(key, target.partitions)
+	bus.mutex.Unlock()
+	_, err := bus.producer.Produce(topic, partition, &proto.Message{Key: []byte(key), Value: message})
+	return err
+}
+
+// nextPartition returns the partition to which the next message of the topic is send, the first partition if the topic is unknown
+func (bus *KafkaBus) nextPartition(topic string) int32 {
+	target := bus.target(topic)
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	partition := target.next % target.partitions
+	target.next = partition + 1
+	return partition
+}
+
+// target returns the publishTarget of the topic, its number of partitions is looked up again after publishPartitionsInterval
+func (bus *KafkaBus) target(topic string) *publishTarget {
+	bus.mutex.Lock()
+	target, ok := bus.targets[topic]
+	if !ok {
+		target = &publishTarget{partitions: 1}
+		bus.targets[topic] = target
+	}
+	refresh := time.Since(target.updated) >= publishPartitionsInterval
+	if refresh {
+		// Other messages use the known partitions while the partitions are looked up
+		target.updated = time.Now()
+	}
+	bus.mutex.Unlock()
+	if refresh {
+		if count, found, err := bus.partitionsFromMetadata(topic); err == nil && found && count > 0 {
+			bus.mutex.Lock()
+			target.partitions = count
+			bus.mutex.Unlock()
+		}
+	}
+	return target
+}
+
+// CreateTopics creates the topics with a single partition
+func (bus *KafkaBus) CreateTopics(topics ...string) error {
+	return CreateTopics(bus.broker, bus.logger, topics...)
+}
+
+// CreateCompactedTopics creates the topics with a single partition and the cleanup policy compact
+func (bus *KafkaBus) CreateCompactedTopics(topics ...string) error {
+	return CreateCompactedTopics(bus.broker, bus.logger, topics...)
+}
+
+// Partitions returns the number of partitions of the topic from the current metadata of the cluster
+func (bus *KafkaBus) Partitions(topic string) (int32, error) {
+	count, found, err := bus.partitionsFromMetadata(topic)
+	if err != nil || found {
+		return count, err
+	}
+	if err = bus.CreateTopics(topic); err != nil {
+		return 0, err
+	}
+	count, found, err = bus.partitionsFromMetadata(topic)
+	if err == nil && !found {
+		err = fmt.Errorf("Topic %v isn't found after creating it", topic)
+	}
+	return count, err
+}
+
+// partitionsFromMetadata requests the metadata of the cluster and returns the number of partitions of the topic and whether the topic exists
+func (bus *KafkaBus) partitionsFromMetadata(topic string) (int32, bool, error) {
+	metadata, err := bus.broker.Metadata()
+	if err != nil {
+		return 0, false, err
+	}
+	for _, known := range metadata.Topics {
+		if known.Name == topic && known.Err == nil {
+			return int32(len(known.Partitions)), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// Subscribe returns a Subscription that waits consumerPollRetries fetches for a new message
+func (bus *KafkaBus) Subscribe(topic string, partition int32, offset int64) (Subscription, error) {
+	conf := kafka.NewConsumerConf(topic, partition)
+	switch offset {
+	case OffsetOldest:
+		conf.StartOffset = kafka.StartOffsetOldest
+	case OffsetNewest:
+		conf.StartOffset = kafka.StartOffsetNewest
+	default:
+		conf.StartOffset = offset
+	}
+	conf.RetryLimit = consumerPollRetries
+	consumer, err := bus.broker.Consumer(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaSubscription{consumer: consumer}, nil
+}
+
+// Offsets returns the offset coordinator of the group
+func (bus *KafkaBus) Offsets(group string) (OffsetStore, error) {
+	coordinator, err := bus.broker.OffsetCoordinator(kafka.NewOffsetCoordinatorConf(group))
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaOffsets{coordinator: coordinator}, nil
+}
+
+type kafkaSubscription struct {
+	consumer kafka.Consumer
+}
+
+func (subscription *kafkaSubscription) Next() (BusMessage, error) {
+	if subscription.consumer == nil {
+		return BusMessage{}, ErrSubscriptionClosed
+	}
+	msg, err := subscription.consumer.Consume()
+	switch err {
+	case nil:
+		return BusMessage{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, Value: msg.Value}, nil
+	case kafka.ErrNoData:
+		return BusMessage{}, ErrNoMessage
+	case proto.ErrOffsetOutOfRange:
+		return BusMessage{}, ErrOffsetOutOfRange
+	default:
+		return BusMessage{}, err
+	}
+}
+
+// Close drops the consumer with the messages that it fetched, the connections belong to the broker and stay open
+func (subscription *kafkaSubscription) Close() error {
+	subscription.consumer = nil
+	return nil
+}
+
+type kafkaOffsets struct {
+	coordinator kafka.OffsetCoordinator
+}
+
+func (offsets *kafkaOffsets) Commit(topic string, partition int32, offset int64) error {
+	return offsets.coordinator.Commit(topic, partition, offset)
+}
+
+func (offsets *kafkaOffsets) Offset(topic string, partition int32) (int64, error) {
+	offset, _, err := offsets.coordinator.Offset(topic, partition)
+	return offset, err
+}
@@ -0,0 +1,525 @@
+package general
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"general/config"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// tokenLifetime is the duration that a token is valid
+const tokenLifetime = 8 * time.Hour
+
+// jwksRefreshInterval is the minimal duration between two requests to the JWKS endpoint
+const jwksRefreshInterval = 10 * time.Second
+
+// tokenKeys contains the keys that are used for creating and validating tokens
+var tokenKeys = NewKeySet()
+
+// ConfigureKeys loads the keys from the configuration into the key set that is used for creating and validating tokens.
+// Calling it again with a new signing key rotates the keys: the previous signing key stays valid until the tokens signed by it are expired.
+func ConfigureKeys(logger *log.Logger, cfg config.Keys) error {
+	if cfg.SigningKey == "" {
+		logger.Printf("[WARNING] No signing key is configured, tokens will be signed by an ephemeral key\n")
+	} else {
+		signingKey, err := LoadSigningKey(cfg.ID, cfg.SigningKey)
+		if err != nil {
+			return err
+		}
+		signingKey.Roles = cfg.Roles
+		tokenKeys.SetSigningKey(signingKey)
+		logger.Printf("Tokens will be signed by key %v\n", signingKey.ID)
+	}
+	verificationKeys := make(map[string]TrustedKey)
+	for _, key := range cfg.VerificationKeys {
+		public, err := LoadVerificationKey(key.Path)
+		if err != nil {
+			return err
+		}
+		verificationKeys[key.ID] = TrustedKey{Public: public, Roles: key.Roles}
+	}
+	if err := tokenKeys.SetVerificationKeys(verificationKeys); err != nil {
+		return err
+	}
+	if cfg.JWKS != "" {
+		tokenKeys.UseJWKS(cfg.JWKS, &http.Client{Timeout: 5 * time.Second})
+		logger.Printf("Unknown keys will be obtained from %v\n", cfg.JWKS)
+	}
+	return nil
+}
+
+// WatchKeys reloads the keys from the configuration file every time that the process receives a SIGHUP signal
+func WatchKeys(logger *log.Logger, path string) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		cfg, err := config.Load(path)
+		if err != nil {
+			logger.Printf("[ERROR] Can't reload keys: %s\n", err)
+			continue
+		}
+		if err = ConfigureKeys(logger, cfg.Keys); err != nil {
+			logger.Printf("[ERROR] Can't reload keys: %s\n", err)
+			continue
+		}
+		logger.Printf("Succesfully reloaded keys\n")
+	}
+}
+
+// GetJWKS returns the public keys that can be used for validating the tokens of this service
+func GetJWKS() (JWKS, error) {
+	return tokenKeys.JWKS()
+}
+
+// SigningKey is a private key together with the id that is added as kid header to the tokens that are signed by it.
+// Roles are the roles of the tokens that the key can sign, a key without roles can sign tokens of every role.
+type SigningKey struct {
+	ID      string
+	Roles   []string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+// TrustedKey is a public key that is used for validating tokens of the given roles, a key without roles is trusted for every role
+type TrustedKey struct {
+	Public crypto.PublicKey
+	Roles  []string
+}
+
+// NewSigningKey returns a SigningKey. Only RSA keys (RS256) and ECDSA keys on the P-256 curve (ES256) are supported.
+func NewSigningKey(id string, private crypto.Signer) (SigningKey, error) {
+	if id == "" {
+		return SigningKey{}, errors.New("A signing key needs an id")
+	}
+	method, err := signingMethod(private.Public())
+	if err != nil {
+		return SigningKey{}, err
+	}
+	return SigningKey{ID: id, method: method, private: private}, nil
+}
+
+// LoadSigningKey reads a PEM encoded private key from the given file
+func LoadSigningKey(id, path string) (SigningKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	var private interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("Can't parse private key %v: %s", path, err)
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return SigningKey{}, fmt.Errorf("Unsupported type of private key %v: %T", path, private)
+	}
+	return NewSigningKey(id, signer)
+}
+
+// LoadVerificationKey reads a PEM encoded public key from the given file
+func LoadVerificationKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	var public interface{}
+	if block.Type == "RSA PUBLIC KEY" {
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	} else {
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse public key %v: %s", path, err)
+	}
+	if _, err = signingMethod(public); err != nil {
+		return nil, fmt.Errorf("Can't use public key %v: %s", path, err)
+	}
+	return public, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read key %v: %s", path, err)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("Key %v is not PEM encoded", path)
+	}
+	return block, nil
+}
+
+func signingMethod(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("Unsupported curve %v", key.Curve.Params().Name)
+		}
+		return jwt.SigningMethodES256, nil
+	}
+	return nil, fmt.Errorf("Unsupported type of key %T", public)
+}
+
+type verificationKey struct {
+	public  crypto.PublicKey
+	method  jwt.SigningMethod
+	roles   []string
+	expires time.Time
+}
+
+func newVerificationKey(public crypto.PublicKey, roles []string) (verificationKey, error) {
+	method, err := signingMethod(public)
+	if err != nil {
+		return verificationKey{}, err
+	}
+	return verificationKey{public: public, method: method, roles: roles}, nil
+}
+
+func (key verificationKey) expired() bool {
+	return !key.expires.IsZero() && time.Now().After(key.expires)
+}
+
+// allowsRole returns true if one of the roles is the given role or if there are no roles
+func allowsRole(roles []string, role string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, allowed := range roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// KeySet contains the key for signing tokens and all keys for validating tokens.
+// Keys that are rotated out are kept until all tokens that are signed by them are expired.
+type KeySet struct {
+	mutex      sync.RWMutex
+	signing    *SigningKey
+	configured map[string]verificationKey
+	retired    map[string]verificationKey
+	fetched    map[string]verificationKey
+	jwksURL    string
+	client     *http.Client
+	lastFetch  time.Time
+}
+
+// NewKeySet returns an empty KeySet
+func NewKeySet() *KeySet {
+	return &KeySet{configured: make(map[string]verificationKey), retired: make(map[string]verificationKey), fetched: make(map[string]verificationKey)}
+}
+
+// SetSigningKey replaces the signing key. The previous signing key stays valid for validating tokens until the tokens signed by it are expired.
+func (keys *KeySet) SetSigningKey(key SigningKey) {
+	keys.mutex.Lock()
+	defer keys.mutex.Unlock()
+	if previous := keys.signing; previous != nil && previous.ID != key.ID {
+		keys.retired[previous.ID] = verificationKey{public: previous.private.Public(), method: previous.method, roles: previous.Roles, expires: time.Now().Add(tokenLifetime)}
+	}
+	delete(keys.retired, key.ID)
+	keys.signing = &key
+	for id, retired := range keys.retired {
+		if retired.expired() {
+			delete(keys.retired, id)
+		}
+	}
+}
+
+// SetVerificationKeys replaces the keys that are used for validating tokens besides the signing key
+func (keys *KeySet) SetVerificationKeys(trustedKeys map[string]TrustedKey) error {
+	configured := make(map[string]verificationKey)
+	for id, trusted := range trustedKeys {
+		key, err := newVerificationKey(trusted.Public, trusted.Roles)
+		if err != nil {
+			return fmt.Errorf("Can't use verification key %v: %s", id, err)
+		}
+		configured[id] = key
+	}
+	keys.mutex.Lock()
+	keys.configured = configured
+	keys.mutex.Unlock()
+	return nil
+}
+
+// UseJWKS sets the url of a JWKS endpoint. Keys that are unknown will be obtained from this endpoint.
+func (keys *KeySet) UseJWKS(url string, client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	keys.mutex.Lock()
+	keys.jwksURL, keys.client = url, client
+	keys.lastFetch = time.Time{}
+	keys.mutex.Unlock()
+}
+
+// JWKS returns the public keys of the signing key and all keys that are still valid for validating tokens. Keys that are obtained from another JWKS endpoint are not included.
+func (keys *KeySet) JWKS() (JWKS, error) {
+	keys.mutex.RLock()
+	defer keys.mutex.RUnlock()
+	jwks := JWKS{Keys: make([]JWK, 0, 1+len(keys.configured)+len(keys.retired))}
+	if keys.signing != nil {
+		jwk, err := NewJWK(keys.signing.ID, keys.signing.private.Public())
+		if err != nil {
+			return JWKS{}, err
+		}
+		jwk.Roles = keys.signing.Roles
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	for _, verificationKeys := range []map[string]verificationKey{keys.configured, keys.retired} {
+		for id, key := range verificationKeys {
+			if key.expired() || (keys.signing != nil && id == keys.signing.ID) {
+				continue
+			}
+			jwk, err := NewJWK(id, key.public)
+			if err != nil {
+				return JWKS{}, err
+			}
+			jwk.Roles = key.roles
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks, nil
+}
+
+// sign returns a token with the given claims that is signed by the signing key. An ephemeral key that can sign tokens of every role
+// is generated if there is no signing key. It returns an error if the signing key can't sign tokens of the role of the claims.
+func (keys *KeySet) sign(claims *tokenClaims) (string, error) {
+	keys.mutex.RLock()
+	signing := keys.signing
+	keys.mutex.RUnlock()
+	if signing == nil {
+		key, err := keys.generateSigningKey()
+		if err != nil {
+			return "", err
+		}
+		signing = &key
+	}
+	if !allowsRole(signing.Roles, claims.Audience) {
+		return "", fmt.Errorf("Key %v can't sign tokens of role %v", signing.ID, claims.Audience)
+	}
+	token := jwt.NewWithClaims(signing.method, claims)
+	token.Header["kid"] = signing.ID
+	return token.SignedString(signing.private)
+}
+
+func (keys *KeySet) generateSigningKey() (SigningKey, error) {
+	keys.mutex.Lock()
+	defer keys.mutex.Unlock()
+	if keys.signing != nil {
+		return *keys.signing, nil
+	}
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	randomID := make([]byte, 8)
+	if _, err = rand.Read(randomID); err != nil {
+		return SigningKey{}, err
+	}
+	key, err := NewSigningKey("ephemeral-"+hex.EncodeToString(randomID), private)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	keys.signing = &key
+	return key, nil
+}
+
+// keyFunc returns the public key that belongs to the kid header of the token if the key is trusted for the role of the token
+func (keys *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	if id == "" {
+		return nil, errors.New("token without kid")
+	}
+	key, ok := keys.find(id)
+	if !ok {
+		if err := keys.fetchJWKS(); err != nil {
+			return nil, fmt.Errorf("unknown key %v: %s", id, err)
+		}
+		if key, ok = keys.find(id); !ok {
+			return nil, fmt.Errorf("unknown key %v", id)
+		}
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("invalid signing method %v for key %v", token.Method.Alg(), id)
+	}
+	claims, ok := token.Claims.(*tokenClaims)
+	if !ok {
+		return nil, errors.New("token without claims")
+	}
+	if !allowsRole(key.roles, claims.Audience) {
+		return nil, fmt.Errorf("key %v isn't trusted for tokens of role %v", id, claims.Audience)
+	}
+	return key.public, nil
+}
+
+func (keys *KeySet) find(id string) (verificationKey, bool) {
+	keys.mutex.RLock()
+	defer keys.mutex.RUnlock()
+	if keys.signing != nil && keys.signing.ID == id {
+		return verificationKey{public: keys.signing.private.Public(), method: keys.signing.method, roles: keys.signing.Roles}, true
+	}
+	for _, verificationKeys := range []map[string]verificationKey{keys.configured, keys.retired, keys.fetched} {
+		if key, ok := verificationKeys[id]; ok && !key.expired() {
+			return key, true
+		}
+	}
+	return verificationKey{}, false
+}
+
+// fetchJWKS replaces the keys that are obtained from the JWKS endpoint. The endpoint is requested at most once every jwksRefreshInterval.
+func (keys *KeySet) fetchJWKS() error {
+	keys.mutex.Lock()
+	url, client := keys.jwksURL, keys.client
+	if url == "" {
+		keys.mutex.Unlock()
+		return errors.New("no JWKS endpoint is configured")
+	}
+	if time.Since(keys.lastFetch) < jwksRefreshInterval {
+		keys.mutex.Unlock()
+		return errors.New("JWKS endpoint is recently requested")
+	}
+	keys.lastFetch = time.Now()
+	keys.mutex.Unlock()
+	response, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint responded with statuscode %v", response.StatusCode)
+	}
+	var jwks JWKS
+	if err = ReadFromJSONNoValidation(&jwks, response.Body); err != nil {
+		return err
+	}
+	fetched := make(map[string]verificationKey)
+	for _, jwk := range jwks.Keys {
+		public, err := jwk.PublicKey()
+		if err != nil {
+			return fmt.Errorf("Can't use key %v from JWKS endpoint: %s", jwk.Kid, err)
+		}
+		if fetched[jwk.Kid], err = newVerificationKey(public, jwk.Roles); err != nil {
+			return fmt.Errorf("Can't use key %v from JWKS endpoint: %s", jwk.Kid, err)
+		}
+	}
+	keys.mutex.Lock()
+	keys.fetched = fetched
+	keys.mutex.Unlock()
+	return nil
+}
+
+// JWK is a public key in the JSON Web Key format. Roles is an additional member with the roles of the tokens that the key can sign.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// Roles is empty for keys that can sign tokens of every role
+	Roles []string `json:"roles,omitempty"`
+}
+
+// JWKS is a set of JSON Web Keys
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK returns the JWK of the given public key
+func NewJWK(id string, public crypto.PublicKey) (JWK, error) {
+	method, err := signingMethod(public)
+	if err != nil {
+		return JWK{}, err
+	}
+	jwk := JWK{Kid: id, Use: "sig", Alg: method.Alg()}
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(key.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty, jwk.Crv = "EC", key.Curve.Params().Name
+		jwk.X = encodeBase64URL(padBytes(key.X.Bytes(), size))
+		jwk.Y = encodeBase64URL(padBytes(key.Y.Bytes(), size))
+	}
+	return jwk, nil
+}
+
+// PublicKey returns the public key that is described by the JWK
+func (jwk JWK) PublicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := decodeBase64URL(jwk.N)
+		e, errE := decodeBase64URL(jwk.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return nil, errors.New("invalid modulus or exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != elliptic.P256().Params().Name {
+			return nil, fmt.Errorf("unsupported curve %v", jwk.Crv)
+		}
+		x, errX := decodeBase64URL(jwk.X)
+		y, errY := decodeBase64URL(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %v", jwk.Kty)
+}
+
+func encodeBase64URL(bytes []byte) string {
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+func padBytes(bytes []byte, size int) []byte {
+	if len(bytes) >= size {
+		return bytes
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(bytes):], bytes)
+	return padded
+}
@@ -0,0 +1,229 @@
+package general
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// memoryPollTimeout is the time a Subscription of a MemoryBus waits for a new message
+const memoryPollTimeout = 100 * time.Millisecond
+
+// MemoryBus is a Bus that keeps the messages in memory. A topic has a single partition unless more partitions are added with SetPartitions,
+// messages without key are spread over the partitions of their topic. Messages are never removed, except from compacted topics.
+// Services that share a MemoryBus can run in one process without Kafka, for example in tests.
+type MemoryBus struct {
+	mutex   sync.Mutex
+	topics  map[string]*memoryTopic
+	offsets map[string]map[memoryPartition]int64
+}
+
+type memoryTopic struct {
+	partitions [][]memoryMessage
+	// compacted topics only keep the newest message of every key
+	compacted bool
+	// next is the partition of the next message that is published
+	next int
+	// published is closed and replaced when a message is published
+	published chan struct{}
+}
+
+// memoryMessage is a message in a partition, a message that is removed by the compaction keeps its offset without value
+type memoryMessage struct {
+	key     string
+	value   []byte
+	removed bool
+}
+
+type memoryPartition struct {
+	topic     string
+	partition int32
+}
+
+// NewMemoryBus returns a MemoryBus without topics
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{topics: make(map[string]*memoryTopic), offsets: make(map[string]map[memoryPartition]int64)}
+}
+
+// Publish adds the message to the next partition of the topic, the topic is created if it doesn't exist
+func (bus *MemoryBus) Publish(topic string, message []byte) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	known := bus.topic(topic)
+	partition := known.next % len(known.partitions)
+	known.next = partition + 1
+	known.append(partition, memoryMessage{value: message})
+	return nil
+}
+
+// PublishWithKey adds the message to the partition of the key, the topic is created if it doesn't exist.
+// The older messages with the key are removed right away if the topic is compacted.
+func (bus *MemoryBus) PublishWithKey(topic, key string, message []byte) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	known := bus.topic(topic)
+	partition := int(keyPartition(key, int32(len(known.partitions))))
+	if known.compacted {
+		for index, older := range known.partitions[partition] {
+			if !older.removed && older.key == key {
+				known.partitions[partition][index] = memoryMessage{removed: true}
+			}
+		}
+	}
+	known.append(partition, memoryMessage{key: key, value: message})
+	return nil
+}
+
+// CreateTopics creates the topics that don't exist yet
+func (bus *MemoryBus) CreateTopics(topics ...string) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for _, topic := range topics {
+		bus.topic(topic)
+	}
+	return nil
+}
+
+// CreateCompactedTopics creates the topics that don't exist yet as compacted topics. Like in Kafka, an existing topic keeps its configuration.
+func (bus *MemoryBus) CreateCompactedTopics(topics ...string) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for _, topic := range topics {
+		if _, ok := bus.topics[topic]; !ok {
+			bus.topic(topic).compacted = true
+		}
+	}
+	return nil
+}
+
+// SetPartitions adds partitions to the topic until it has the given number of partitions, the topic is created if it doesn't exist.
+// Like in Kafka, the number of partitions of a topic can't be reduced.
+func (bus *MemoryBus) SetPartitions(topic string, count int32) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	known := bus.topic(topic)
+	if int(count) < len(known.partitions) {
+		return fmt.Errorf("Topic %v has %v partitions, which can't be reduced to %v", topic, len(known.partitions), count)
+	}
+	for len(known.partitions) < int(count) {
+		known.partitions = append(known.partitions, make([]memoryMessage, 0))
+	}
+	return nil
+}
+
+// Partitions returns the number of partitions of the topic, the topic is created if it doesn't exist
+func (bus *MemoryBus) Partitions(topic string) (int32, error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	return int32(len(bus.topic(topic).partitions)), nil
+}
+
+// Subscribe returns a Subscription on the partition of the topic that starts at the given offset
+func (bus *MemoryBus) Subscribe(topic string, partition int32, offset int64) (Subscription, error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	known := bus.topic(topic)
+	if partition < 0 || int(partition) >= len(known.partitions) {
+		return nil, fmt.Errorf("Topic %v has no partition %v", topic, partition)
+	}
+	length := int64(len(known.partitions[partition]))
+	switch {
+	case offset == OffsetOldest:
+		offset = 0
+	case offset == OffsetNewest:
+		offset = length
+	case offset < 0 || offset > length:
+		return nil, ErrOffsetOutOfRange
+	}
+	return &memorySubscription{bus: bus, topic: topic, partition: partition, next: offset}, nil
+}
+
+// Offsets returns the offsets of the group, which are kept as long as the bus
+func (bus *MemoryBus) Offsets(group string) (OffsetStore, error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	if _, ok := bus.offsets[group]; !ok {
+		bus.offsets[group] = make(map[memoryPartition]int64)
+	}
+	return &memoryOffsets{bus: bus, group: group}, nil
+}
+
+// topic returns the topic with the given name and creates it if it doesn't exist, the caller has to hold the mutex
+func (bus *MemoryBus) topic(name string) *memoryTopic {
+	known, ok := bus.topics[name]
+	if !ok {
+		known = &memoryTopic{partitions: [][]memoryMessage{make([]memoryMessage, 0)}, published: make(chan struct{})}
+		bus.topics[name] = known
+	}
+	return known
+}
+
+// append adds the message to the partition and wakes up the subscriptions, the caller has to hold the mutex of the bus
+func (known *memoryTopic) append(partition int, message memoryMessage) {
+	known.partitions[partition] = append(known.partitions[partition], message)
+	close(known.published)
+	known.published = make(chan struct{})
+}
+
+type memorySubscription struct {
+	bus       *MemoryBus
+	topic     string
+	partition int32
+	next      int64
+	closed    bool
+}
+
+func (subscription *memorySubscription) Next() (BusMessage, error) {
+	if subscription.closed {
+		return BusMessage{}, ErrSubscriptionClosed
+	}
+	timeout := time.After(memoryPollTimeout)
+	for {
+		subscription.bus.mutex.Lock()
+		messages := subscription.bus.topic(subscription.topic).partitions[subscription.partition]
+		// Messages that are removed by the compaction are skipped
+		for subscription.next < int64(len(messages)) && messages[subscription.next].removed {
+			subscription.next++
+		}
+		if subscription.next < int64(len(messages)) {
+			msg := BusMessage{Topic: subscription.topic, Partition: subscription.partition, Offset: subscription.next, Value: messages[subscription.next].value}
+			subscription.bus.mutex.Unlock()
+			subscription.next++
+			return msg, nil
+		}
+		known := subscription.bus.topic(subscription.topic)
+		published := known.published
+		subscription.bus.mutex.Unlock()
+		select {
+		case <-published:
+		case <-timeout:
+			return BusMessage{}, ErrNoMessage
+		}
+	}
+}
+
+func (subscription *memorySubscription) Close() error {
+	subscription.closed = true
+	return nil
+}
+
+type memoryOffsets struct {
+	bus   *MemoryBus
+	group string
+}
+
+func (offsets *memoryOffsets) Commit(topic string, partition int32, offset int64) error {
+	offsets.bus.mutex.Lock()
+	defer offsets.bus.mutex.Unlock()
+	offsets.bus.offsets[offsets.group][memoryPartition{topic: topic, partition: partition}] = offset
+	return nil
+}
+
+func (offsets *memoryOffsets) Offset(topic string, partition int32) (int64, error) {
+	offsets.bus.mutex.Lock()
+	defer offsets.bus.mutex.Unlock()
+	if offset, ok := offsets.bus.offsets[offsets.group][memoryPartition{topic: topic, partition: partition}]; ok {
+		return offset, nil
+	}
+	return -1, nil
+}
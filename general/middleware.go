@@ -19,7 +19,7 @@ func toMiddlerWare(functionAsMiddleWare func(http.ResponseWriter, *http.Request,
 func GetValidateTokenMiddleWare(logger *log.Logger) func(http.Handler) http.Handler {
 	return toMiddlerWare(func(response http.ResponseWriter, request *http.Request, next http.Handler) {
 		if request.Header["Token"] == nil {
-			logger.Println("[WARNING] Unauthorized request\n")
+			logger.Println("[WARNING] Unauthorized request")
 			http.Error(response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
@@ -56,7 +56,12 @@ func GetAddTokenToContextMiddleware(logger *log.Logger) func(http.Handler) http.
 
 // GetIsAdminMiddleware returns middleware that checks if a token belongs to an admin
 func GetIsAdminMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
-	return GetTokenMiddleWareForSpecificRole(logger, "admin")
+	return GetTokenMiddleWareForSpecificRole(logger, RoleAdmin)
+}
+
+// GetIsCuratorMiddleware returns middleware that checks if a token belongs to a curator or an admin
+func GetIsCuratorMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
+	return GetTokenMiddleWareForRoles(logger, RoleCurator, RoleAdmin)
 }
 
 // GetInternalRequestMiddleware returns middleware that checks if a token belongs to a service within this application
@@ -66,16 +71,23 @@ func GetInternalRequestMiddleware(logger *log.Logger) func(http.Handler) http.Ha
 
 // GetTokenMiddleWareForSpecificRole returns middleware that checks if a token belongs to the given role
 func GetTokenMiddleWareForSpecificRole(logger *log.Logger, role string) func(http.Handler) http.Handler {
+	return GetTokenMiddleWareForRoles(logger, role)
+}
+
+// GetTokenMiddleWareForRoles returns middleware that checks if a token belongs to one of the given roles
+func GetTokenMiddleWareForRoles(logger *log.Logger, roles ...string) func(http.Handler) http.Handler {
 	tokenValidator := GetValidateTokenMiddleWare(logger)
 	return func(next http.Handler) http.Handler {
 		return tokenValidator(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
 			ctx := request.Context().Value(Credentials{}).(Credentials)
-			if ctx.Role != role {
-				logger.Printf("[WARNING] Non-%v tries to access %v content: %v\n", role, role, ctx.Username)
-				http.Error(response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
+			for _, role := range roles {
+				if ctx.Role == role {
+					next.ServeHTTP(response, request)
+					return
+				}
 			}
-			next.ServeHTTP(response, request)
+			logger.Printf("[WARNING] Non-%v tries to access %v content: %v\n", roles[0], roles[0], ctx.Username)
+			http.Error(response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		}))
 	}
 }
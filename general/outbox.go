@@ -0,0 +1,199 @@
+package general
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// DefaultOutboxInterval is the time between two runs of the relay when the outbox was empty
+	DefaultOutboxInterval = time.Second
+	// DefaultOutboxBatchSize is the maximum number of messages that are read from the outbox at once
+	DefaultOutboxBatchSize = 100
+	// DefaultOutboxRetention is the time a send message is kept in the outbox before it is removed
+	DefaultOutboxRetention = 24 * time.Hour
+)
+
+var (
+	outboxSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_messages_sent_total",
+		Help: "The total number of messages that are send from the outbox",
+	})
+	outboxFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_send_failures_total",
+		Help: "The total number of failed attempts to send a message from the outbox",
+	})
+)
+
+// AddToOutbox stores an event with the payload for the topic in the outbox table as part of the transaction.
+// The event is only send by an OutboxRelay if the transaction is committed. The id of the event stays the same when it is send again.
+func AddToOutbox(tx *sql.Tx, topic string, payload interface{}) error {
+	return addToOutbox(tx, topic, sql.NullString{}, payload)
+}
+
+// AddToOutboxWithKey works like AddToOutbox, but the event is send with the key, e.g. to a compacted topic
+func AddToOutboxWithKey(tx *sql.Tx, topic, key string, payload interface{}) error {
+	return addToOutbox(tx, topic, sql.NullString{String: key, Valid: true}, payload)
+}
+
+func addToOutbox(tx *sql.Tx, topic string, key sql.NullString, payload interface{}) error {
+	msg, err := EncodeEvent(topic, payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO outbox (topic, message_key, message) VALUES (?,?,?);", topic, key, msg)
+	return err
+}
+
+// OutboxRelay sends the messages in the outbox table in the order in which they are added.
+// A message is marked as send after it is send, a message that fails is tried again on the next run. Messages are thus send at least once.
+// Relays of multiple instances of a service can share the outbox, a batch is claimed by one relay at a time.
+type OutboxRelay struct {
+	Interval  time.Duration
+	BatchSize int
+	Retention time.Duration
+	logger    *log.Logger
+	db        *sql.DB
+	bus       Bus
+	lastPrune time.Time
+}
+
+// NewOutboxRelay returns an OutboxRelay with the default settings that publishes the messages in the outbox of the database to the bus
+func NewOutboxRelay(logger *log.Logger, db *sql.DB, bus Bus) *OutboxRelay {
+	return &OutboxRelay{
+		Interval:  DefaultOutboxInterval,
+		BatchSize: DefaultOutboxBatchSize,
+		Retention: DefaultOutboxRetention,
+		logger:    logger,
+		db:        db,
+		bus:       bus,
+	}
+}
+
+// Start sends the messages in the outbox in the background until stop is called
+func (relay *OutboxRelay) Start() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(relay.Interval)
+		defer ticker.Stop()
+		for {
+			// A full batch means that there are probably more messages waiting
+			if count, err := relay.Relay(); err == nil && count == relay.BatchSize {
+				continue
+			}
+			relay.prune()
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// Relay sends a batch of messages from the outbox and returns the number of send messages.
+// It stops at the first message that fails, so the messages stay in order.
+// The batch is locked until the run is finished. Another relay skips the locked messages and only sends messages if none of the messages before them are locked,
+// so the messages aren't send twice and the order is kept if multiple instances run a relay.
+func (relay *OutboxRelay) Relay() (int, error) {
+	tx, err := relay.db.Begin()
+	if err != nil {
+		relay.logger.Printf("[ERROR] Can't read outbox: %s\n", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	pending, err := relay.claim(tx)
+	if err != nil {
+		relay.logger.Printf("[ERROR] Can't read outbox: %s\n", err)
+		return 0, err
+	}
+	sent, err := relay.send(tx, pending)
+	if errCommit := tx.Commit(); errCommit != nil {
+		// The messages will be send again on the next run
+		relay.logger.Printf("[ERROR] Can't mark messages in outbox as send: %s\n", errCommit)
+		return 0, errCommit
+	}
+	return sent, err
+}
+
+// send sends the messages and marks them as send within the transaction. It returns the number of send messages.
+func (relay *OutboxRelay) send(tx *sql.Tx, pending []outboxMessage) (int, error) {
+	for index, message := range pending {
+		if err := relay.publish(message); err != nil {
+			outboxFailures.Inc()
+			relay.logger.Printf("[ERROR] Failed to send message %v from outbox to topic %v: %s\n", message.id, message.topic, err)
+			if _, errUpdate := tx.Exec("UPDATE outbox SET attempts=attempts+1 WHERE id=?;", message.id); errUpdate != nil {
+				relay.logger.Printf("[WARNING] Can't count failed attempt of message %v in outbox: %s\n", message.id, errUpdate)
+			}
+			return index, err
+		}
+		outboxSent.Inc()
+		if _, err := tx.Exec("UPDATE outbox SET sent=NOW() WHERE id=?;", message.id); err != nil {
+			relay.logger.Printf("[ERROR] Can't mark message %v in outbox as send: %s\n", message.id, err)
+			return index + 1, err
+		}
+	}
+	return len(pending), nil
+}
+
+// publish publishes the message with its key if it has one
+func (relay *OutboxRelay) publish(message outboxMessage) error {
+	if message.key.Valid {
+		return relay.bus.PublishWithKey(message.topic, message.key.String, message.message)
+	}
+	return relay.bus.Publish(message.topic, message.message)
+}
+
+type outboxMessage struct {
+	id      int64
+	topic   string
+	key     sql.NullString
+	message []byte
+}
+
+// claim locks the next batch of messages that aren't send yet. It returns no messages if an older message is locked by another relay.
+func (relay *OutboxRelay) claim(tx *sql.Tx) ([]outboxMessage, error) {
+	rows, err := tx.Query("SELECT id, topic, message_key, message FROM outbox WHERE sent IS NULL ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED;", relay.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pending := make([]outboxMessage, 0, relay.BatchSize)
+	for rows.Next() {
+		var message outboxMessage
+		if err := rows.Scan(&message.id, &message.topic, &message.key, &message.message); err != nil {
+			return nil, err
+		}
+		pending = append(pending, message)
+	}
+	if err = rows.Err(); err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	var locked int
+	if err = tx.QueryRow("SELECT COUNT(*) FROM outbox WHERE sent IS NULL AND id < ?;", pending[0].id).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked != 0 {
+		// Another relay sends the older messages, the messages are send by the relay that claims them after these
+		return nil, nil
+	}
+	return pending, nil
+}
+
+// prune removes the messages that are send longer than Retention ago, it runs at most once per hour
+func (relay *OutboxRelay) prune() {
+	if time.Since(relay.lastPrune) < time.Hour {
+		return
+	}
+	relay.lastPrune = time.Now()
+	if _, err := relay.db.Exec("DELETE FROM outbox WHERE sent < NOW() - INTERVAL ? SECOND;", int64(relay.Retention/time.Second)); err != nil {
+		relay.logger.Printf("[WARNING] Can't remove send messages from outbox: %s\n", err)
+	}
+}
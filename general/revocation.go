@@ -0,0 +1,50 @@
+package general
+
+import (
+	"log"
+	"sync"
+)
+
+// TokenRevocation revokes all tokens of an user with a version lower than the given version
+type TokenRevocation struct {
+	UserID  int `json:"userID" validate:"required"`
+	Version int `json:"version" validate:"required"`
+}
+
+// NewTokenRevocation returns a TokenRevocation with the given data
+func NewTokenRevocation(userID, version int) TokenRevocation {
+	return TokenRevocation{UserID: userID, Version: version}
+}
+
+// revocations contains for every user with revoked tokens the lowest version of a token that is still valid
+var revocations = struct {
+	sync.RWMutex
+	versions map[int]int
+}{versions: make(map[int]int)}
+
+// RevokeTokens makes all tokens of the user with a version lower than the version of the revocation invalid for this service
+func RevokeTokens(revocation TokenRevocation) {
+	revocations.Lock()
+	defer revocations.Unlock()
+	if revocation.Version > revocations.versions[revocation.UserID] {
+		revocations.versions[revocation.UserID] = revocation.Version
+	}
+}
+
+func isRevoked(userID, version int) bool {
+	revocations.RLock()
+	defer revocations.RUnlock()
+	return version < revocations.versions[userID]
+}
+
+func getConsumeRevocation(logger *log.Logger) func(message []byte) error {
+	return func(message []byte) error {
+		var revocation TokenRevocation
+		if _, err := DecodeEvent(message, &revocation); err != nil {
+			logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+			return PermanentError(err)
+		}
+		RevokeTokens(revocation)
+		return nil
+	}
+}
@@ -8,20 +8,29 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
+	"general/config"
+
 	"github.com/gorilla/mux"
 	"github.com/optiopay/kafka/v2"
 	"github.com/optiopay/kafka/v2/proto"
 )
 
-var kafkaAddrs = []string{"localhost:9092", "localhost:9093"}
-var gateway, addressGateway = "gateway", ":9919"
+// registrationInterval is the time between two registrations of a service at the gateway
+const registrationInterval = time.Minute
 
-// ConnectToMYSQL connects
-func ConnectToMYSQL(logger *log.Logger, servername, dataSourceName string) (*sql.DB, error) {
+// ConnectToMYSQL connects to the MySQL database from the given configuration
+func ConnectToMYSQL(logger *log.Logger, cfg config.Config) (*sql.DB, error) {
+	servername := cfg.Servername
+	if cfg.MySQL.DSN == "" {
+		err := fmt.Errorf("No data source name is configured for %v", servername)
+		logger.Fatalf("[ERROR] Failed to open connection to %v database: %v\n", servername, err.Error())
+		return nil, err
+	}
 	// Opening the database
-	db, err := sql.Open("mysql", dataSourceName)
+	db, err := sql.Open("mysql", cfg.MySQL.DSN)
 	if err != nil {
 		logger.Fatalf("[ERROR] Failed to open connection to %v database: %v\n", servername, err.Error())
 		return nil, err
@@ -33,20 +42,42 @@ func ConnectToMYSQL(logger *log.Logger, servername, dataSourceName string) (*sql
 	return db, nil
 }
 
-// NewServer returns a server on the given port with the given router, a channel that sends addresses of other services and a start function in order to start the server
-func NewServer(servername, port string, router *mux.Router, broker *kafka.Broker, messageConsumer func(), logger *log.Logger) (server *http.Server, channelNewService chan Service, start func()) {
+// NewServer returns a server on the configured port with the given router, a channel that sends addresses of other services and a start function in order to start the server.
+// The addresses of the other services are urls without a trailing slash, the server registers itself with the advertised address of the configuration.
+// The messageConsumer is called with the consumer group of the service on start, admins can replay the topics of the group with POST /admin/consumers/replay.
+// Admins can list the dead letters of a topic with GET /admin/consumers/dlq/{topic} and process them again with POST /admin/consumers/dlq/{topic}/redrive.
+// The gateway routes these endpoints with the name of the service in front of the path, e.g. GET /likes/admin/consumers/dlq/{topic}.
+func NewServer(cfg config.Config, router *mux.Router, bus Bus, messageConsumer func(*ConsumerGroup), logger *log.Logger) (server *http.Server, channelNewService chan Service, start func()) {
+	servername := cfg.Servername
+	// The gateway sends health probes to every service
+	router.Methods(http.MethodGet).Path("/health").HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusOK)
+		response.Write([]byte(http.StatusText(http.StatusOK)))
+	})
+	consumers := NewConsumerGroup(bus, logger, servername)
+	router.Methods(http.MethodPost).Path("/admin/consumers/replay").Handler(GetIsAdminMiddleware(logger)(http.HandlerFunc(consumers.ReplayHandler)))
+	router.Methods(http.MethodGet).Path("/admin/consumers/dlq/{topic}").Handler(GetIsAdminMiddleware(logger)(http.HandlerFunc(consumers.DeadLettersHandler)))
+	router.Methods(http.MethodPost).Path("/admin/consumers/dlq/{topic}/redrive").Handler(GetIsAdminMiddleware(logger)(http.HandlerFunc(consumers.RedriveHandler)))
 	server = &http.Server{
-		Addr:     port,
+		Addr:     cfg.Port,
 		Handler:  router,
 		ErrorLog: logger,
 	}
 	channelNewService = make(chan Service)
 	start = func() {
-		go getAddressesServices(logger, channelNewService, servername)
-		go registerService(logger, broker, servername, port)
-		go StartConsumer(broker, logger, "newService", getConsumeNewService(logger, channelNewService))
+		go getAddressesServices(logger, channelNewService, servername, cfg.Gateway)
+		go registerService(logger, bus, servername, cfg.AdvertisedAddress())
+		// The services that are already running are obtained from the gateway and every service registers itself again every registrationInterval,
+		// so only new registrations are consumed
+		consumers.ConsumeAllNew("newService", getConsumeNewService(logger, channelNewService))
+		// A restarted service has to know the revocations of tokens that aren't expired yet. The topic is compacted by the id of the user,
+		// so it only keeps the newest revocation of every user.
+		if err := bus.CreateCompactedTopics("revokeTokens"); err != nil {
+			logger.Printf("[ERROR] Can't create topic revokeTokens: %s\n", err)
+		}
+		consumers.ConsumeAll("revokeTokens", getConsumeRevocation(logger))
 		if messageConsumer != nil {
-			go messageConsumer()
+			go messageConsumer(consumers)
 		}
 		go func() {
 			logger.Printf("Starting server %v on port %v\n", servername, server.Addr)
@@ -62,15 +93,18 @@ func NewServer(servername, port string, router *mux.Router, broker *kafka.Broker
 		signal.Notify(c, os.Kill)
 		sig := <-c
 		logger.Printf("Shutting down server %v due to %v signal\n", servername, sig)
-		ctx, _ := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
 		server.Shutdown(ctx)
+		// The other instances take over the partitions of this instance right away
+		consumers.Close()
 		logger.Printf("Server %v is shut down!\n", servername)
 	}
 	return
 }
 
-func getAddressesServices(logger *log.Logger, channel chan<- Service, servername string) {
-	if servername == gateway {
+func getAddressesServices(logger *log.Logger, channel chan<- Service, servername string, gateway config.Gateway) {
+	if servername == gateway.Name {
 		return
 	}
 	getRequest, err := GetInternalGETRequest(servername)
@@ -78,11 +112,17 @@ func getAddressesServices(logger *log.Logger, channel chan<- Service, servername
 		logger.Printf("[ERROR] Can't create a get request due to: %s\n", err)
 		return
 	}
-	response, err := getRequest(fmt.Sprintf("http://localhost%v/intern/service", addressGateway))
+	gatewayAddress, err := ServiceAddress(gateway.Address)
+	if err != nil {
+		logger.Printf("[ERROR] Invalid address of the gateway: %s\n", err)
+		return
+	}
+	response, err := getRequest(gatewayAddress + "/intern/service")
 	if err != nil {
 		logger.Printf("[ERROR] Failed to retrieve other services due to: %s\n", err)
 		return
 	}
+	defer response.Body.Close()
 	if response.StatusCode != 200 {
 		logger.Printf("Failed to obtain list of services due to failed request with errorcode: %v\n", response.StatusCode)
 		return
@@ -93,37 +133,61 @@ func getAddressesServices(logger *log.Logger, channel chan<- Service, servername
 		return
 	}
 	for _, service := range services {
+		if service.Address, err = ServiceAddress(service.Address); err != nil {
+			logger.Printf("[ERROR] Invalid address of service %v: %s\n", service.Name, err)
+			continue
+		}
 		channel <- service
 	}
 	logger.Printf("Obtained all addresses of services\n")
 }
 
-func registerService(logger *log.Logger, broker *kafka.Broker, servername, port string) {
-	messageService, err := ToJSONBytes(&Service{Name: servername, Address: port})
-	if err != nil {
-		logger.Fatalf("Can't register service %v due to: %s\n", servername, err)
+// registerService sends the address of the service to the topic newService every registrationInterval.
+// The gateway adds an instance that it evicted again after the next registration.
+func registerService(logger *log.Logger, bus Bus, servername, address string) {
+	ticker := time.NewTicker(registrationInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if err := SendEvent(bus.Publish, "newService", &Service{Name: servername, Address: address}); err != nil {
+			logger.Printf("[ERROR] Can't register service %v due to: %s\n", servername, err)
+		}
 	}
-	sendMessage := GetSendMessage(broker.Producer(kafka.NewProducerConf()))
-	sendMessage("newService", messageService)
 }
 
-func getConsumeNewService(logger *log.Logger, channel chan<- Service) func(message []byte) {
-	return func(message []byte) {
+func getConsumeNewService(logger *log.Logger, channel chan<- Service) func(message []byte) error {
+	return func(message []byte) error {
 		var newService Service
-		if err := FromJSONBytes(&newService, message); err != nil {
+		if _, err := DecodeEvent(message, &newService); err != nil {
 			logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
-			return
+			return PermanentError(err)
+		}
+		address, err := ServiceAddress(newService.Address)
+		if err != nil {
+			logger.Printf("Received invalid address for service %v: %s\n", newService.Name, err)
+			return PermanentError(err)
 		}
-		logger.Printf("Received address for service %v: %v\n", newService.Name, newService.Address)
+		logger.Printf("Received address for service %v: %v\n", newService.Name, address)
+		newService.Address = address
 		channel <- newService
+		return nil
+	}
+}
+
+// ServiceAddress returns the url without a trailing slash of the service with the given address, see config.ServiceURL for the forms of addresses
+func ServiceAddress(address string) (string, error) {
+	target, err := config.ServiceURL(address)
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimSuffix(target.String(), "/"), nil
 }
 
-// ConnectToKafka creates a connection to Kafka and returns a broker and a closing function
-func ConnectToKafka(logger *log.Logger, servername string) (*kafka.Broker, func()) {
+// ConnectToKafka creates a connection to the configured Kafka cluster and returns a broker and a closing function
+func ConnectToKafka(logger *log.Logger, cfg config.Config) (*kafka.Broker, func()) {
+	servername := cfg.Servername
 	conf := kafka.NewBrokerConf(servername)
 	conf.AllowTopicCreation = true
-	broker, err := kafka.Dial(kafkaAddrs, conf)
+	broker, err := kafka.Dial(cfg.Kafka.Addresses, conf)
 	if err != nil {
 		logger.Fatalf("[ERROR] Can't connect to kafka cluster: %s", err)
 	}
@@ -133,9 +197,18 @@ func ConnectToKafka(logger *log.Logger, servername string) (*kafka.Broker, func(
 
 // CreateTopics will create all given topics
 func CreateTopics(broker *kafka.Broker, logger *log.Logger, topics ...string) error {
+	return createTopics(broker, logger, nil, topics...)
+}
+
+// CreateCompactedTopics will create all given topics with the cleanup policy compact. The configuration of existing topics isn't changed.
+func CreateCompactedTopics(broker *kafka.Broker, logger *log.Logger, topics ...string) error {
+	return createTopics(broker, logger, []proto.ConfigEntry{{ConfigName: "cleanup.policy", ConfigValue: "compact"}}, topics...)
+}
+
+func createTopics(broker *kafka.Broker, logger *log.Logger, configs []proto.ConfigEntry, topics ...string) error {
 	listTopics := make([]proto.TopicInfo, 0, len(topics))
 	for _, topic := range topics {
-		listTopics = append(listTopics, proto.TopicInfo{Topic: topic, NumPartitions: 1, ReplicationFactor: 1})
+		listTopics = append(listTopics, proto.TopicInfo{Topic: topic, NumPartitions: 1, ReplicationFactor: 1, ConfigEntries: configs})
 	}
 	response, err := broker.CreateTopic(listTopics, 10*time.Second, true)
 	if err != nil {
@@ -161,55 +234,14 @@ func GetSendMessage(producer kafka.Producer) func(topic string, message []byte)
 	}
 }
 
-// GetInternalGETRequest returns a function that sends a get request with an internal token to the given url and returns the response
+// GetInternalGETRequest returns a function that sends a get request with an internal token to the given url and returns the response.
+// The requests are send by an InternalClient with the default settings.
 func GetInternalGETRequest(servername string) (func(url string) (*http.Response, error), error) {
-	tokenString, errToken := CreateTokenInternalRequests(servername)
-	token := &tokenString
-	client := http.Client{}
-	if errToken != nil {
-		return nil, errToken
-	}
-	return func(url string) (*http.Response, error) {
-		request, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		request.Header.Add("Token", *token)
-		resp, respError := client.Do(request)
-		if resp.StatusCode == http.StatusUnauthorized {
-			*token, errToken = CreateTokenInternalRequests(servername)
-			request, err = http.NewRequest("GET", url, nil)
-			request.Header.Add("Token", *token)
-			return client.Do(request)
-		}
-		return resp, respError
-	}, nil
-}
-
-// StartConsumer consumes messages from the given topic and calls the given function
-func StartConsumer(broker *kafka.Broker, logger *log.Logger, topic string, processMessage func([]byte)) {
-	conf := kafka.NewConsumerConf(topic, 0)
-	conf.StartOffset = kafka.StartOffsetNewest
-	consumer, err := broker.Consumer(conf)
+	client, err := NewInternalClient(servername, nil)
 	if err != nil {
-		logger.Printf("[Warning] Cannot create kafka consumer for %v:%s\nTrying to create topic...\n", topic, err)
-		if topicErr := CreateTopics(broker, logger, topic); topicErr != nil {
-			logger.Fatalf("[ERROR] Failed to create topics due to: %s\n", topicErr)
-			return
-		}
-	}
-	logger.Printf("Starting to consume messages from topic %v\n", topic)
-	for {
-		msg, err := consumer.Consume()
-		if err != nil {
-			if err != kafka.ErrNoData {
-				logger.Printf("Cannot consume %v topic message: %s", topic, err)
-			}
-			break
-		}
-		processMessage(msg.Value)
+		return nil, err
 	}
-	logger.Printf("Consumer %v quit!", topic)
+	return client.Get, nil
 }
 
 // SendError sends an error message corresponding to the errorcode to the response. It does not end the request
@@ -0,0 +1,291 @@
+package test
+
+import (
+	"context"
+	"general"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testTarget is a service that responds with the given statuscodes in order and with 200 after the last one
+type testTarget struct {
+	server   *httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	requests []*http.Request
+}
+
+func newTestTarget(statuses ...int) *testTarget {
+	target := &testTarget{statuses: statuses}
+	target.server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		target.mutex.Lock()
+		target.requests = append(target.requests, request)
+		status := http.StatusOK
+		if len(target.statuses) != 0 {
+			status, target.statuses = target.statuses[0], target.statuses[1:]
+		}
+		target.mutex.Unlock()
+		response.WriteHeader(status)
+	}))
+	return target
+}
+
+func (target *testTarget) attempts() int {
+	target.mutex.Lock()
+	defer target.mutex.Unlock()
+	return len(target.requests)
+}
+
+// testClient returns an InternalClient that retries without delay and whose circuit breaker doesn't open
+func testClient(t *testing.T) *general.InternalClient {
+	client, err := general.NewInternalClient("likes", nil)
+	if err != nil {
+		t.Fatalf("Can't create client: %s\n", err)
+	}
+	client.RetryBackoff = time.Millisecond
+	client.BreakerThreshold = 100
+	return client
+}
+
+func TestInternalClient_retries(t *testing.T) {
+	cases := map[string]struct {
+		method             string
+		statuses           []int
+		expectedAttempts   int
+		expectedStatusCode int
+	}{
+		"Succesful request":               {http.MethodGet, nil, 1, http.StatusOK},
+		"Retried after 503":               {http.MethodGet, []int{503, 503}, 3, http.StatusOK},
+		"Retried after 502":               {http.MethodGet, []int{502}, 2, http.StatusOK},
+		"Retried after 504":               {http.MethodGet, []int{504}, 2, http.StatusOK},
+		"Retries are used up":             {http.MethodGet, []int{503, 502, 504, 503}, 3, http.StatusGatewayTimeout},
+		"Internal server error":           {http.MethodGet, []int{500}, 1, http.StatusInternalServerError},
+		"Not found":                       {http.MethodGet, []int{404}, 1, http.StatusNotFound},
+		"Idempotent put":                  {http.MethodPut, []int{503}, 2, http.StatusOK},
+		"Idempotent delete":               {http.MethodDelete, []int{502}, 2, http.StatusOK},
+		"Post isn't retried":              {http.MethodPost, []int{503}, 1, http.StatusServiceUnavailable},
+		"Patch isn't retried":             {http.MethodPatch, []int{502}, 1, http.StatusBadGateway},
+		"Post that succeeds":              {http.MethodPost, nil, 1, http.StatusOK},
+		"Post is send once after timeout": {http.MethodPost, []int{504}, 1, http.StatusGatewayTimeout},
+	}
+	for name, test := range cases {
+		target := newTestTarget(test.statuses...)
+		client := testClient(t)
+		response, err := client.Do(context.Background(), test.method, target.server.URL+"/intern/test", []byte(`{}`))
+		if err != nil {
+			t.Errorf("%v: Request failed: %s\n", name, err)
+			target.server.Close()
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.StatusCode)
+		}
+		if attempts := target.attempts(); attempts != test.expectedAttempts {
+			t.Errorf("%v: Expects %v attempts but got: %v\n", name, test.expectedAttempts, attempts)
+		}
+		target.server.Close()
+	}
+}
+
+func TestInternalClient_retryUnreachable(t *testing.T) {
+	target := newTestTarget()
+	address := target.server.URL
+	target.server.Close()
+	client := testClient(t)
+	if _, err := client.Do(context.Background(), http.MethodGet, address, nil); err == nil {
+		t.Errorf("Expects an error for a target that can't be reached\n")
+	}
+	// A canceled request isn't retried
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Do(ctx, http.MethodGet, address, nil); err == nil {
+		t.Errorf("Expects an error for a canceled request\n")
+	}
+}
+
+func TestInternalClient_breaker(t *testing.T) {
+	var failing int32 = 1
+	var blocking int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if atomic.LoadInt32(&blocking) == 1 {
+			arrived <- struct{}{}
+			<-release
+		}
+		if atomic.LoadInt32(&failing) == 1 {
+			response.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		response.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	other := newTestTarget()
+	defer other.server.Close()
+	client := testClient(t)
+	client.Retries = 0
+	client.BreakerThreshold = 2
+	client.BreakerCooldown = 50 * time.Millisecond
+	get := func() (int, error) {
+		response, err := client.Do(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			return 0, err
+		}
+		response.Body.Close()
+		return response.StatusCode, nil
+	}
+
+	// The breaker opens after the threshold of consecutive failures
+	for i := 0; i < 2; i++ {
+		if status, err := get(); err != nil || status != http.StatusServiceUnavailable {
+			t.Fatalf("Failure %v: Expects statuscode: %v but got: %v, %v\n", i, http.StatusServiceUnavailable, status, err)
+		}
+	}
+	if _, err := get(); err != general.ErrCircuitOpen {
+		t.Errorf("Expects an open breaker after 2 failures but got: %v\n", err)
+	}
+	// The breaker belongs to a single target
+	if response, err := client.Do(context.Background(), http.MethodGet, other.server.URL, nil); err != nil {
+		t.Errorf("Expects the request to another target to be send but got: %s\n", err)
+	} else {
+		response.Body.Close()
+	}
+
+	// After the cooldown a single trial request is let through, the breaker opens again if it fails
+	time.Sleep(client.BreakerCooldown)
+	atomic.StoreInt32(&blocking, 1)
+	trial := make(chan error, 1)
+	go func() {
+		_, err := get()
+		trial <- err
+	}()
+	<-arrived
+	if _, err := get(); err != general.ErrCircuitOpen {
+		t.Errorf("Expects other requests to be rejected during the trial request but got: %v\n", err)
+	}
+	atomic.StoreInt32(&blocking, 0)
+	close(release)
+	if err := <-trial; err != nil {
+		t.Errorf("Expects the trial request to be send but got: %s\n", err)
+	}
+	if _, err := get(); err != general.ErrCircuitOpen {
+		t.Errorf("Expects the breaker to open again after a failed trial but got: %v\n", err)
+	}
+
+	// A succesful trial request closes the breaker
+	time.Sleep(client.BreakerCooldown)
+	atomic.StoreInt32(&failing, 0)
+	for i := 0; i < 3; i++ {
+		if status, err := get(); err != nil || status != http.StatusOK {
+			t.Errorf("Request %v: Expects a closed breaker after a succesful trial but got: %v, %v\n", i, status, err)
+		}
+	}
+}
+
+func TestInternalClient_renewToken(t *testing.T) {
+	var tokens []string
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		tokens = append(tokens, request.Header.Get("Token"))
+		first := len(tokens) == 1
+		mutex.Unlock()
+		// The target rejects the first token, e.g. because it is signed by a key that the target doesn't know yet
+		if first {
+			response.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := general.ValidateToken(request.Header.Get("Token")); err != nil {
+			response.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		response.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := testClient(t)
+	response, err := client.Do(context.Background(), http.MethodPost, server.URL, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Request failed: %s\n", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expects statuscode: %v after renewing the token but got: %v\n", http.StatusOK, response.StatusCode)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(tokens) != 2 {
+		t.Fatalf("Expects the request to be send again once with a new token but got %v requests\n", len(tokens))
+	}
+	for index, token := range tokens {
+		if token == "" {
+			t.Errorf("Request %v: Expects a token\n", index)
+		}
+	}
+}
+
+func TestInternalClient_getTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		select {
+		case <-release:
+		case <-request.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	client := testClient(t)
+	client.Timeout = 50 * time.Millisecond
+	client.Retries = 0
+	if _, err := client.Get(server.URL); err == nil {
+		t.Errorf("Expects an error for a request that exceeds the timeout\n")
+	}
+}
+
+func TestInternalClient_cancelBody(t *testing.T) {
+	canceled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("first"))
+		response.(http.Flusher).Flush()
+		// The response goes on until the client is done
+		<-request.Context().Done()
+		close(canceled)
+	}))
+	defer server.Close()
+	client := testClient(t)
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %s\n", err)
+	}
+	// The context of the request stays valid after Get returns, so the body can be read
+	buffer := make([]byte, len("first"))
+	if _, err = io.ReadFull(response.Body, buffer); err != nil || string(buffer) != "first" {
+		t.Errorf("Expects to read the body after Get returns but got: %q, %v\n", buffer, err)
+	}
+	select {
+	case <-canceled:
+		t.Fatalf("Expects the request to be active until the body is closed\n")
+	case <-time.After(50 * time.Millisecond):
+	}
+	response.Body.Close()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("Expects closing the body to cancel the request\n")
+	}
+	// The body of a succesful request is read completely
+	target := newTestTarget()
+	defer target.server.Close()
+	if response, err = client.Get(target.server.URL); err != nil {
+		t.Fatalf("Request failed: %s\n", err)
+	}
+	if _, err = ioutil.ReadAll(response.Body); err != nil {
+		t.Errorf("Can't read body: %s\n", err)
+	}
+	response.Body.Close()
+}
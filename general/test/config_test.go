@@ -0,0 +1,247 @@
+package test
+
+import (
+	"general/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testConfigFile = `servername: likes
+port: ":9002"
+bus: kafka
+kafka:
+  addresses:
+    - "localhost:9092"
+gateway:
+  name: gateway
+  address: ":9919"
+`
+
+func testConfig() config.Config {
+	return config.Config{
+		Servername: "likes",
+		Port:       ":9002",
+		Bus:        config.BusKafka,
+		Kafka:      config.Kafka{Addresses: []string{"localhost:9092"}},
+		Gateway:    config.Gateway{Name: "gateway", Address: ":9919"},
+	}
+}
+
+// writeConfig writes the content to a configuration file in a temporary directory and returns the path and a function that removes the directory
+func writeConfig(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("Can't create directory for configuration: %s\n", err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Can't write configuration: %s\n", err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func testRateLimits(group string, burst int) map[string]config.RateLimit {
+	return map[string]config.RateLimit{group: {Rate: 1, Burst: burst}}
+}
+
+func testVerificationKeys(roles ...string) []config.VerificationKey {
+	return []config.VerificationKey{{ID: "likes-1", Path: "likes.pub.pem", Roles: roles}}
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		change        func(*config.Config)
+		expectedError bool
+	}{
+		"Valid configuration":                 {func(cfg *config.Config) {}, false},
+		"Port with host":                      {func(cfg *config.Config) { cfg.Port = "0.0.0.0:9002" }, false},
+		"Port without colon":                  {func(cfg *config.Config) { cfg.Port = "9002" }, true},
+		"Port that isn't a number":            {func(cfg *config.Config) { cfg.Port = ":likes" }, true},
+		"Port out of range":                   {func(cfg *config.Config) { cfg.Port = ":70000" }, true},
+		"Missing port":                        {func(cfg *config.Config) { cfg.Port = "" }, true},
+		"Missing servername":                  {func(cfg *config.Config) { cfg.Servername = "" }, true},
+		"Address with host":                   {func(cfg *config.Config) { cfg.Address = "likes:9002" }, false},
+		"Address as url":                      {func(cfg *config.Config) { cfg.Address = "https://likes.example.com/likes" }, false},
+		"Address with other scheme":           {func(cfg *config.Config) { cfg.Address = "ftp://likes:9002" }, true},
+		"Address with invalid port":           {func(cfg *config.Config) { cfg.Address = "likes:0" }, true},
+		"Gateway on remote host":              {func(cfg *config.Config) { cfg.Gateway.Address = "gateway:9919" }, false},
+		"Gateway as url":                      {func(cfg *config.Config) { cfg.Gateway.Address = "http://gateway:9919/" }, false},
+		"Gateway without address":             {func(cfg *config.Config) { cfg.Gateway.Address = "" }, true},
+		"Gateway with url without host":       {func(cfg *config.Config) { cfg.Gateway.Address = "http://" }, true},
+		"Unknown bus":                         {func(cfg *config.Config) { cfg.Bus = "rabbitmq" }, true},
+		"Kafka without addresses":             {func(cfg *config.Config) { cfg.Kafka.Addresses = nil }, true},
+		"In-memory bus without addresses":     {func(cfg *config.Config) { cfg.Bus, cfg.Kafka.Addresses = config.BusMemory, nil }, false},
+		"Unknown strategy for balancing":      {func(cfg *config.Config) { cfg.Gateway.Balancing = "random" }, true},
+		"Rate limit of unknown group":         {func(cfg *config.Config) { cfg.Gateway.RateLimits = testRateLimits("search", 1) }, true},
+		"Rate limit":                          {func(cfg *config.Config) { cfg.Gateway.RateLimits = testRateLimits("login", 1) }, false},
+		"Rate limit without burst":            {func(cfg *config.Config) { cfg.Gateway.RateLimits = testRateLimits("login", 0) }, true},
+		"Signing key without id":              {func(cfg *config.Config) { cfg.Keys.SigningKey = "users.pem" }, true},
+		"Verification key without path":       {func(cfg *config.Config) { cfg.Keys.VerificationKeys = []config.VerificationKey{{ID: "users-1"}} }, true},
+		"Signing key for internal tokens":     {func(cfg *config.Config) { cfg.Keys.Roles = []string{"MusicApp"} }, false},
+		"Signing key for unknown role":        {func(cfg *config.Config) { cfg.Keys.Roles = []string{"root"} }, true},
+		"Verification key for service":        {func(cfg *config.Config) { cfg.Keys.VerificationKeys = testVerificationKeys("MusicApp") }, false},
+		"Verification key for unknown role":   {func(cfg *config.Config) { cfg.Keys.VerificationKeys = testVerificationKeys("root") }, true},
+		"Trusted proxies":                     {func(cfg *config.Config) { cfg.TrustedProxies = []string{"127.0.0.1", "10.0.0.0/8", "::1"} }, false},
+		"Trusted proxy that isn't an address": {func(cfg *config.Config) { cfg.TrustedProxies = []string{"gateway"} }, true},
+		"Password classes more than possible": {func(cfg *config.Config) { cfg.Signup.PasswordMinClasses = 5 }, true},
+	}
+	for name, test := range cases {
+		cfg := testConfig()
+		test.change(&cfg)
+		if err := cfg.Validate(); (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cases := map[string]struct {
+		content        string
+		expectedError  bool
+		expectedConfig config.Config
+	}{
+		"Valid file":            {testConfigFile, false, testConfig()},
+		"Unknown field":         {testConfigFile + "database: likes\n", true, config.Config{}},
+		"Invalid YAML":          {"servername: [likes\n", true, config.Config{}},
+		"Invalid port":          {"servername: likes\nport: \"9002\"\nbus: memory\ngateway:\n  name: gateway\n  address: \":9919\"\n", true, config.Config{}},
+		"Empty file":            {"", true, config.Config{}},
+		"In-memory bus":         {"servername: likes\nport: \":9002\"\nbus: memory\ngateway:\n  name: gateway\n  address: \":9919\"\n", false, config.Config{Servername: "likes", Port: ":9002", Bus: config.BusMemory, Gateway: config.Gateway{Name: "gateway", Address: ":9919"}}},
+		"Advertised url":        {testConfigFile + "address: \"https://likes.example.com\"\n", false, func() config.Config { cfg := testConfig(); cfg.Address = "https://likes.example.com"; return cfg }()},
+		"Gateway on other host": {"servername: likes\nport: \":9002\"\nbus: memory\ngateway:\n  name: gateway\n  address: \"gateway:9919\"\n", false, config.Config{Servername: "likes", Port: ":9002", Bus: config.BusMemory, Gateway: config.Gateway{Name: "gateway", Address: "gateway:9919"}}},
+		"Argon2": {testConfigFile + "argon2:\n  time: 4\n  memory: 32768\n  threads: 1\n", false, func() config.Config {
+			cfg := testConfig()
+			cfg.Argon2 = config.Argon2{Time: 4, Memory: 32768, Threads: 1}
+			return cfg
+		}()},
+		"Negative Argon2 time": {testConfigFile + "argon2:\n  time: -1\n", true, config.Config{}},
+		"Spotify": {testConfigFile + "spotify:\n  clientId: id\n  clientSecret: secret\n", false, func() config.Config {
+			cfg := testConfig()
+			cfg.Spotify = config.Spotify{ClientID: "id", ClientSecret: "secret"}
+			return cfg
+		}()},
+		"Spotify without secret": {testConfigFile + "spotify:\n  clientId: id\n", true, config.Config{}},
+	}
+	for name, test := range cases {
+		path, remove := writeConfig(t, test.content)
+		cfg, err := config.Load(path)
+		remove()
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg, test.expectedConfig) {
+			t.Errorf("%v: Expects configuration %+v but got: %+v\n", name, test.expectedConfig, cfg)
+		}
+	}
+	if _, err := config.Load(filepath.Join(os.TempDir(), "missing", "config.yaml")); err == nil {
+		t.Errorf("Expects an error for a missing configuration file\n")
+	}
+}
+
+func TestLoad_environment(t *testing.T) {
+	cases := map[string]struct {
+		environment   map[string]string
+		expectedError bool
+		check         func(config.Config) bool
+	}{
+		"Servername":         {map[string]string{config.EnvServername: "notifications"}, false, func(cfg config.Config) bool { return cfg.Servername == "notifications" }},
+		"Port":               {map[string]string{config.EnvPort: "0.0.0.0:9102"}, false, func(cfg config.Config) bool { return cfg.Port == "0.0.0.0:9102" }},
+		"Invalid port":       {map[string]string{config.EnvPort: "9102"}, true, nil},
+		"Advertised address": {map[string]string{config.EnvAddress: "likes-1:9002"}, false, func(cfg config.Config) bool { return cfg.Address == "likes-1:9002" }},
+		"Gateway address":    {map[string]string{config.EnvGatewayAddress: "http://gateway:9919"}, false, func(cfg config.Config) bool { return cfg.Gateway.Address == "http://gateway:9919" }},
+		"DSN":                {map[string]string{config.EnvMySQLDSN: "user:password@tcp(db:3306)/likes"}, false, func(cfg config.Config) bool { return cfg.MySQL.DSN == "user:password@tcp(db:3306)/likes" }},
+		"Kafka addresses": {map[string]string{config.EnvKafkaAddresses: " kafka-1:9092, ,kafka-2:9092 "}, false, func(cfg config.Config) bool {
+			return len(cfg.Kafka.Addresses) == 2 && cfg.Kafka.Addresses[1] == "kafka-2:9092"
+		}},
+		"Empty Kafka addresses": {map[string]string{config.EnvKafkaAddresses: ""}, true, nil},
+		"In-memory bus":         {map[string]string{config.EnvBus: config.BusMemory, config.EnvKafkaAddresses: ""}, false, func(cfg config.Config) bool { return cfg.Bus == config.BusMemory && len(cfg.Kafka.Addresses) == 0 }},
+		"Keys": {map[string]string{config.EnvKeyID: "likes-2", config.EnvSigningKey: "likes.pem", config.EnvJWKS: "http://users/jwks"}, false, func(cfg config.Config) bool {
+			return reflect.DeepEqual(cfg.Keys, config.Keys{ID: "likes-2", SigningKey: "likes.pem", JWKS: "http://users/jwks"})
+		}},
+		"Key roles": {map[string]string{config.EnvKeyRoles: "MusicApp, user"}, false, func(cfg config.Config) bool {
+			return reflect.DeepEqual(cfg.Keys.Roles, []string{"MusicApp", "user"})
+		}},
+		"Unknown key role":   {map[string]string{config.EnvKeyRoles: "root"}, true, nil},
+		"Breached passwords": {map[string]string{config.EnvBreachedPasswords: "breached.txt"}, false, func(cfg config.Config) bool { return cfg.Signup.BreachedPasswords == "breached.txt" }},
+		"Trusted proxies": {map[string]string{config.EnvTrustedProxies: "10.0.0.0/8, 127.0.0.1"}, false, func(cfg config.Config) bool {
+			return reflect.DeepEqual(cfg.TrustedProxies, []string{"10.0.0.0/8", "127.0.0.1"})
+		}},
+		"Pepper": {map[string]string{config.EnvPepper: "secret"}, false, func(cfg config.Config) bool { return cfg.Passwords.Pepper == "secret" }},
+		"Argon2": {map[string]string{config.EnvArgon2Time: "4", config.EnvArgon2Memory: "32768", config.EnvArgon2Threads: "1"}, false, func(cfg config.Config) bool {
+			return cfg.Argon2 == config.Argon2{Time: 4, Memory: 32768, Threads: 1}
+		}},
+		"Invalid Argon2 memory":   {map[string]string{config.EnvArgon2Memory: "64MiB"}, true, nil},
+		"Too many Argon2 threads": {map[string]string{config.EnvArgon2Threads: "256"}, true, nil},
+		"Spotify": {map[string]string{config.EnvSpotifyID: "id", config.EnvSpotifySecret: "secret"}, false, func(cfg config.Config) bool {
+			return cfg.Spotify == config.Spotify{ClientID: "id", ClientSecret: "secret"}
+		}},
+		"Spotify without id": {map[string]string{config.EnvSpotifySecret: "secret"}, true, nil},
+	}
+	path, remove := writeConfig(t, testConfigFile)
+	defer remove()
+	for name, test := range cases {
+		for variable, value := range test.environment {
+			os.Setenv(variable, value)
+		}
+		cfg, err := config.Load(path)
+		for variable := range test.environment {
+			os.Unsetenv(variable)
+		}
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+			continue
+		}
+		if err == nil && !test.check(cfg) {
+			t.Errorf("%v: Expects the environment %v to override the configuration but got: %+v\n", name, test.environment, cfg)
+		}
+	}
+}
+
+func TestAdvertisedAddress(t *testing.T) {
+	cases := map[string]struct {
+		port, address   string
+		expectedAddress string
+	}{
+		"Port":                   {":9002", "", ":9002"},
+		"Port on all interfaces": {"0.0.0.0:9002", "", ":9002"},
+		"Port on a host":         {"10.0.0.5:9002", "", "10.0.0.5:9002"},
+		"Configured address":     {":9002", "https://likes.example.com", "https://likes.example.com"},
+	}
+	for name, test := range cases {
+		cfg := testConfig()
+		cfg.Port, cfg.Address = test.port, test.address
+		if address := cfg.AdvertisedAddress(); address != test.expectedAddress {
+			t.Errorf("%v: Expects address %v but got: %v\n", name, test.expectedAddress, address)
+		}
+	}
+}
+
+func TestServiceURL(t *testing.T) {
+	cases := map[string]struct {
+		address       string
+		expectedURL   string
+		expectedError bool
+	}{
+		"Port":             {":9002", "http://localhost:9002", false},
+		"Host and port":    {"likes:9002", "http://likes:9002", false},
+		"Url":              {"https://likes.example.com:8443", "https://likes.example.com:8443", false},
+		"Url with prefix":  {"http://likes:9002/likes", "http://likes:9002/likes", false},
+		"Other scheme":     {"ftp://likes:9002", "", true},
+		"Url without host": {"http://", "", true},
+		"Invalid port":     {"likes:99999", "", true},
+	}
+	for name, test := range cases {
+		target, err := config.ServiceURL(test.address)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+			continue
+		}
+		if err == nil && target.String() != test.expectedURL {
+			t.Errorf("%v: Expects url %v but got: %v\n", name, test.expectedURL, target)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package test
+
+import (
+	"errors"
+	"general"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBus is a MemoryBus that counts the subscriptions of every topic and that fails the given number of subscriptions and messages of a topic
+type testBus struct {
+	*general.MemoryBus
+	mutex         sync.Mutex
+	opened        map[string]int
+	closed        map[string]int
+	failSubscribe map[string]int
+	failNext      map[string]int
+}
+
+func newTestBus() *testBus {
+	return &testBus{MemoryBus: general.NewMemoryBus(), opened: make(map[string]int), closed: make(map[string]int),
+		failSubscribe: make(map[string]int), failNext: make(map[string]int)}
+}
+
+func (bus *testBus) Subscribe(topic string, partition int32, offset int64) (general.Subscription, error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	if bus.failSubscribe[topic] > 0 {
+		bus.failSubscribe[topic]--
+		return nil, errors.New("broker is not available")
+	}
+	subscription, err := bus.MemoryBus.Subscribe(topic, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+	bus.opened[topic]++
+	return &testSubscription{Subscription: subscription, bus: bus, topic: topic}, nil
+}
+
+// subscriptions returns the number of opened and closed subscriptions of the topic
+func (bus *testBus) subscriptions(topic string) (int, int) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	return bus.opened[topic], bus.closed[topic]
+}
+
+type testSubscription struct {
+	general.Subscription
+	bus    *testBus
+	topic  string
+	closed bool
+}
+
+func (subscription *testSubscription) Next() (general.BusMessage, error) {
+	subscription.bus.mutex.Lock()
+	fail := subscription.bus.failNext[subscription.topic] > 0
+	if fail {
+		subscription.bus.failNext[subscription.topic]--
+	}
+	subscription.bus.mutex.Unlock()
+	if fail {
+		return general.BusMessage{}, errors.New("connection reset by peer")
+	}
+	return subscription.Subscription.Next()
+}
+
+func (subscription *testSubscription) Close() error {
+	subscription.bus.mutex.Lock()
+	if !subscription.closed {
+		subscription.closed = true
+		subscription.bus.closed[subscription.topic]++
+	}
+	subscription.bus.mutex.Unlock()
+	return subscription.Subscription.Close()
+}
+
+// testProcessor records the messages that it processes
+type testProcessor struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (processor *testProcessor) process(message []byte) error {
+	processor.mutex.Lock()
+	defer processor.mutex.Unlock()
+	processor.messages = append(processor.messages, string(message))
+	return nil
+}
+
+// reset forgets the messages that are processed so far
+func (processor *testProcessor) reset() {
+	processor.mutex.Lock()
+	defer processor.mutex.Unlock()
+	processor.messages = nil
+}
+
+func (processor *testProcessor) received() []string {
+	processor.mutex.Lock()
+	defer processor.mutex.Unlock()
+	return append([]string{}, processor.messages...)
+}
+
+// newTestGroup returns a member of the consumer group that sends heartbeats every 100ms
+func newTestGroup(bus general.Bus, name string) *general.ConsumerGroup {
+	group := general.NewConsumerGroup(bus, general.TestEmptyLogger(), name)
+	group.HeartbeatInterval = 100 * time.Millisecond
+	return group
+}
+
+// eventually returns true if the condition is met within three seconds.
+// A new member of a consumer group waits for the other members before it consumes, which takes up to a second.
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+// receivedExactly returns true if the processor received the expected messages in any order and nothing else
+func receivedExactly(processor *testProcessor, expected []string) bool {
+	sorted := func(messages []string) []string {
+		sort.Strings(messages)
+		return messages
+	}
+	if !eventually(func() bool { return len(processor.received()) >= len(expected) }) {
+		return false
+	}
+	// Messages that aren't expected arrive shortly after the expected ones
+	time.Sleep(100 * time.Millisecond)
+	return reflect.DeepEqual(sorted(processor.received()), sorted(append([]string{}, expected...)))
+}
+
+func publish(t *testing.T, bus general.Bus, topic string, messages ...string) {
+	for _, message := range messages {
+		if err := bus.Publish(topic, []byte(message)); err != nil {
+			t.Fatalf("Can't publish %v: %s\n", message, err)
+		}
+	}
+}
+
+func TestConsumerGroup_commitRestart(t *testing.T) {
+	cases := map[string]struct {
+		consume               func(*general.ConsumerGroup, string, func([]byte) error)
+		expectedFirst         []string
+		expectedAfterRestart  []string
+		expectedCommit        int64
+		expectedCommitRestart int64
+	}{
+		"Consume":    {(*general.ConsumerGroup).Consume, []string{"1", "2", "3"}, []string{"4"}, 3, 4},
+		"ConsumeNew": {(*general.ConsumerGroup).ConsumeNew, []string{"3"}, []string{"4"}, 3, 4},
+		"ConsumeAll": {(*general.ConsumerGroup).ConsumeAll, []string{"1", "2", "3"}, []string{"1", "2", "3", "4"}, -1, -1},
+	}
+	for name, test := range cases {
+		bus := newTestBus()
+		offsets, _ := bus.Offsets("likes")
+		publish(t, bus, "newSong", "1", "2")
+		first, processor := newTestGroup(bus, "likes"), &testProcessor{}
+		test.consume(first, "newSong", processor.process)
+		// Messages that are published after the partition is subscribed are consumed by every kind of consumer
+		if !eventually(func() bool { opened, _ := bus.subscriptions("newSong"); return opened == 1 }) {
+			t.Errorf("%v: Expects the partition to be subscribed\n", name)
+			first.Close()
+			continue
+		}
+		publish(t, bus, "newSong", "3")
+		if !receivedExactly(processor, test.expectedFirst) {
+			t.Errorf("%v: Expects messages %v but got: %v\n", name, test.expectedFirst, processor.received())
+		}
+		first.Close()
+		if offset, _ := offsets.Offset("newSong", 0); offset != test.expectedCommit {
+			t.Errorf("%v: Expects committed offset %v but got: %v\n", name, test.expectedCommit, offset)
+		}
+		for _, topic := range []string{"newSong", general.MembersTopic} {
+			if opened, closed := bus.subscriptions(topic); opened != closed {
+				t.Errorf("%v: Expects all %v subscriptions of %v to be closed but %v are closed\n", name, opened, topic, closed)
+			}
+		}
+
+		// A restarted member continues after the committed offset
+		publish(t, bus, "newSong", "4")
+		second, processor := newTestGroup(bus, "likes"), &testProcessor{}
+		test.consume(second, "newSong", processor.process)
+		if !receivedExactly(processor, test.expectedAfterRestart) {
+			t.Errorf("%v: Expects messages %v after restart but got: %v\n", name, test.expectedAfterRestart, processor.received())
+		}
+		second.Close()
+		if offset, _ := offsets.Offset("newSong", 0); offset != test.expectedCommitRestart {
+			t.Errorf("%v: Expects committed offset %v after restart but got: %v\n", name, test.expectedCommitRestart, offset)
+		}
+	}
+}
+
+func TestConsumerGroup_replay(t *testing.T) {
+	bus := newTestBus()
+	bus.SetPartitions("newSong", 2)
+	publish(t, bus, "newSong", "1", "2", "3", "4")
+	owner, processor := newTestGroup(bus, "likes"), &testProcessor{}
+	owner.Consume("newSong", processor.process)
+	owner.ConsumeAll("newUser", func([]byte) error { return nil })
+	defer owner.Close()
+	if !receivedExactly(processor, []string{"1", "2", "3", "4"}) {
+		t.Fatalf("Expects all messages to be consumed but got: %v\n", processor.received())
+	}
+	// A member that consumes no partition of the topic passes the replay on to the owner
+	other := newTestGroup(bus, "likes")
+	other.Consume("newSong", func([]byte) error {
+		t.Errorf("Expects the owner of the partitions to consume the messages\n")
+		return nil
+	})
+	defer other.Close()
+	if !eventually(func() bool { return len(owner.Partitions("newSong")) == 1 && len(other.Partitions("newSong")) == 1 }) {
+		t.Fatalf("Expects both members to consume a partition but got: %v and %v\n", owner.Partitions("newSong"), other.Partitions("newSong"))
+	}
+	// The owner consumes the even offsets of the partition with the odd messages and vice versa
+	partition := owner.Partitions("newSong")[0]
+	expected := map[int32][]string{0: {"3"}, 1: {"4"}}[partition]
+	processor.reset()
+	if err := other.Replay(general.NewReplayRequest("newSong", partition, 1)); err != nil {
+		t.Fatalf("Can't replay: %s\n", err)
+	}
+	if !receivedExactly(processor, expected) {
+		t.Errorf("Expects messages %v after replay but got: %v\n", expected, processor.received())
+	}
+
+	cases := map[string]general.ReplayRequest{
+		"Unknown topic":                   {Topic: "newArtist", Partition: 0, Offset: 0},
+		"Unknown partition":               {Topic: "newSong", Partition: 2, Offset: 0},
+		"Topic without committed offsets": {Topic: "newUser", Partition: 0, Offset: 0},
+	}
+	for name, request := range cases {
+		if err := owner.Replay(request); err == nil {
+			t.Errorf("%v: Expects an error\n", name)
+		}
+	}
+}
+
+func TestConsumerGroup_reconnect(t *testing.T) {
+	cases := map[string]struct {
+		failSubscribe  int
+		failNext       int
+		expectedClosed int
+	}{
+		"Subscription fails": {1, 0, 0},
+		"Message fails":      {0, 1, 1},
+	}
+	for name, test := range cases {
+		bus := newTestBus()
+		bus.failSubscribe["newSong"] = test.failSubscribe
+		bus.failNext["newSong"] = test.failNext
+		publish(t, bus, "newSong", "1")
+		group, processor := newTestGroup(bus, "likes"), &testProcessor{}
+		group.Consume("newSong", processor.process)
+		// The consumer reconnects after a second
+		if !receivedExactly(processor, []string{"1"}) {
+			t.Errorf("%v: Expects the message after reconnecting but got: %v\n", name, processor.received())
+		}
+		// The failed subscription is closed before the new one is opened
+		if opened, closed := bus.subscriptions("newSong"); opened != test.expectedClosed+1 || closed != test.expectedClosed {
+			t.Errorf("%v: Expects %v closed subscriptions of %v but got %v of %v\n", name, test.expectedClosed, test.expectedClosed+1, closed, opened)
+		}
+		group.Close()
+	}
+}
+
+func TestConsumerGroup_members(t *testing.T) {
+	bus := newTestBus()
+	bus.SetPartitions("newSong", 4)
+	first, firstProcessor := newTestGroup(bus, "likes"), &testProcessor{}
+	second, secondProcessor := newTestGroup(bus, "likes"), &testProcessor{}
+	first.Consume("newSong", firstProcessor.process)
+	second.Consume("newSong", secondProcessor.process)
+	defer first.Close()
+	// The partitions are divided among the members
+	divided := func() bool {
+		partitions := append(first.Partitions("newSong"), second.Partitions("newSong")...)
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+		return len(first.Partitions("newSong")) == 2 && reflect.DeepEqual(partitions, []int32{0, 1, 2, 3})
+	}
+	if !eventually(divided) {
+		t.Fatalf("Expects each member to consume 2 partitions but got: %v and %v\n", first.Partitions("newSong"), second.Partitions("newSong"))
+	}
+	messages := make([]string, 0)
+	for i := 0; i < 8; i++ {
+		messages = append(messages, strconv.Itoa(i))
+	}
+	publish(t, bus, "newSong", messages...)
+	// Every message is processed once by one of the members
+	all := &testProcessor{}
+	if !eventually(func() bool { return len(firstProcessor.received())+len(secondProcessor.received()) >= len(messages) }) {
+		t.Fatalf("Expects all messages to be consumed but got: %v and %v\n", firstProcessor.received(), secondProcessor.received())
+	}
+	all.messages = append(firstProcessor.received(), secondProcessor.received()...)
+	if !receivedExactly(all, messages) {
+		t.Errorf("Expects every message to be processed once but got: %v\n", all.received())
+	}
+
+	// The partitions of a member that leaves the group are taken over by the other members
+	second.Close()
+	if !eventually(func() bool { return len(first.Partitions("newSong")) == 4 }) {
+		t.Fatalf("Expects the remaining member to consume all partitions but got: %v\n", first.Partitions("newSong"))
+	}
+	firstProcessor.reset()
+	publish(t, bus, "newSong", "8", "9", "10", "11")
+	if !receivedExactly(firstProcessor, []string{"8", "9", "10", "11"}) {
+		t.Errorf("Expects the new messages to be consumed by the remaining member but got: %v\n", firstProcessor.received())
+	}
+}
+
+func TestConsumerGroup_newPartitions(t *testing.T) {
+	bus := newTestBus()
+	group, processor := newTestGroup(bus, "likes"), &testProcessor{}
+	group.Consume("newSong", processor.process)
+	defer group.Close()
+	if !eventually(func() bool { return reflect.DeepEqual(group.Partitions("newSong"), []int32{0}) }) {
+		t.Fatalf("Expects the member to consume the partition but got: %v\n", group.Partitions("newSong"))
+	}
+	if err := bus.SetPartitions("newSong", 3); err != nil {
+		t.Fatalf("Can't add partitions: %s\n", err)
+	}
+	if !eventually(func() bool { return reflect.DeepEqual(group.Partitions("newSong"), []int32{0, 1, 2}) }) {
+		t.Fatalf("Expects the member to consume the new partitions but got: %v\n", group.Partitions("newSong"))
+	}
+	publish(t, bus, "newSong", "1", "2", "3")
+	if !receivedExactly(processor, []string{"1", "2", "3"}) {
+		t.Errorf("Expects the messages of all partitions but got: %v\n", processor.received())
+	}
+}
+
+func TestMemoryBus_partitions(t *testing.T) {
+	bus := general.NewMemoryBus()
+	if err := bus.SetPartitions("newSong", 2); err != nil {
+		t.Fatalf("Can't add partitions: %s\n", err)
+	}
+	if err := bus.SetPartitions("newSong", 1); err == nil {
+		t.Errorf("Expects an error when the partitions are reduced\n")
+	}
+	if count, err := bus.Partitions("newSong"); err != nil || count != 2 {
+		t.Errorf("Expects 2 partitions but got: %v, %v\n", count, err)
+	}
+	publish(t, bus, "newSong", "1", "2", "3")
+	// The messages are spread over the partitions
+	for partition, expected := range map[int32][]string{0: {"1", "3"}, 1: {"2"}} {
+		subscription, err := bus.Subscribe("newSong", partition, general.OffsetOldest)
+		if err != nil {
+			t.Fatalf("Can't subscribe to partition %v: %s\n", partition, err)
+		}
+		for offset, value := range expected {
+			msg, err := subscription.Next()
+			if err != nil || string(msg.Value) != value || msg.Offset != int64(offset) || msg.Partition != partition {
+				t.Errorf("Partition %v: Expects message %v at offset %v but got: %+v, %v\n", partition, value, offset, msg, err)
+			}
+		}
+		if _, err := subscription.Next(); err != general.ErrNoMessage {
+			t.Errorf("Partition %v: Expects no more messages but got: %v\n", partition, err)
+		}
+		subscription.Close()
+		if _, err := subscription.Next(); err != general.ErrSubscriptionClosed {
+			t.Errorf("Partition %v: Expects a closed subscription but got: %v\n", partition, err)
+		}
+	}
+	if _, err := bus.Subscribe("newSong", 2, general.OffsetOldest); err == nil {
+		t.Errorf("Expects an error for an unknown partition\n")
+	}
+	if _, err := bus.Subscribe("newSong", 0, 3); err != general.ErrOffsetOutOfRange {
+		t.Errorf("Expects an offset out of range but got: %v\n", err)
+	}
+}
+
+func TestConsumerGroup_consumeAllNew(t *testing.T) {
+	bus := newTestBus()
+	publish(t, bus, "newService", "old")
+	first, second := newTestGroup(bus, "likes"), newTestGroup(bus, "likes")
+	processors := []*testProcessor{{}, {}}
+	first.ConsumeAllNew("newService", processors[0].process)
+	second.ConsumeAllNew("newService", processors[1].process)
+	defer first.Close()
+	defer second.Close()
+	if !eventually(func() bool { opened, _ := bus.subscriptions("newService"); return opened == 2 }) {
+		t.Fatalf("Expects both members to subscribe to the topic\n")
+	}
+	publish(t, bus, "newService", "new")
+	// Every member receives the new messages without the old ones
+	for member, processor := range processors {
+		if !receivedExactly(processor, []string{"new"}) {
+			t.Errorf("Member %v: Expects only the new message but got: %v\n", member, processor.received())
+		}
+	}
+}
+
+func TestMemoryBus_compaction(t *testing.T) {
+	cases := map[string]struct {
+		compacted        bool
+		expectedMessages []string
+		expectedOffsets  []int64
+	}{
+		"Compacted topic":    {true, []string{"2", "3"}, []int64{1, 2}},
+		"Topic that is kept": {false, []string{"1", "2", "3"}, []int64{0, 1, 2}},
+	}
+	for name, test := range cases {
+		bus := general.NewMemoryBus()
+		if test.compacted {
+			bus.CreateCompactedTopics("revokeTokens")
+		}
+		for _, message := range []struct{ key, value string }{{"7", "1"}, {"8", "2"}, {"7", "3"}} {
+			if err := bus.PublishWithKey("revokeTokens", message.key, []byte(message.value)); err != nil {
+				t.Fatalf("%v: Can't publish %v: %s\n", name, message.value, err)
+			}
+		}
+		subscription, err := bus.Subscribe("revokeTokens", 0, general.OffsetOldest)
+		if err != nil {
+			t.Fatalf("%v: Can't subscribe: %s\n", name, err)
+		}
+		for index, value := range test.expectedMessages {
+			msg, err := subscription.Next()
+			if err != nil || string(msg.Value) != value || msg.Offset != test.expectedOffsets[index] {
+				t.Errorf("%v: Expects message %v at offset %v but got: %+v, %v\n", name, value, test.expectedOffsets[index], msg, err)
+			}
+		}
+		if _, err := subscription.Next(); err != general.ErrNoMessage {
+			t.Errorf("%v: Expects no more messages but got: %v\n", name, err)
+		}
+	}
+
+	// Messages with the same key stay in the same partition
+	bus := general.NewMemoryBus()
+	bus.SetPartitions("revokeTokens", 4)
+	for _, value := range []string{"1", "2", "3"} {
+		bus.PublishWithKey("revokeTokens", "7", []byte(value))
+	}
+	counts := make([]int, 0)
+	for partition := int32(0); partition < 4; partition++ {
+		subscription, _ := bus.Subscribe("revokeTokens", partition, general.OffsetOldest)
+		count := 0
+		for _, err := subscription.Next(); err == nil; _, err = subscription.Next() {
+			count++
+		}
+		if count != 0 {
+			counts = append(counts, count)
+		}
+	}
+	if !reflect.DeepEqual(counts, []int{3}) {
+		t.Errorf("Expects all messages of the key in one partition but got: %v\n", counts)
+	}
+}
+
+// testRetryPolicy retries every message after an hour
+type testRetryPolicy struct{}
+
+func (testRetryPolicy) Retry(attempts int, err error) (time.Duration, bool) {
+	return time.Hour, true
+}
+
+func (testRetryPolicy) GiveUp(letter general.DeadLetter) {}
+
+func TestConsumerGroup_closeDuringRetry(t *testing.T) {
+	bus := newTestBus()
+	group := newTestGroup(bus, "likes")
+	group.ErrorPolicy = testRetryPolicy{}
+	attempted := make(chan struct{}, 1)
+	group.Consume("newSong", func(message []byte) error {
+		select {
+		case attempted <- struct{}{}:
+		default:
+		}
+		return errors.New("database is not available")
+	})
+	publish(t, bus, "newSong", "1")
+	select {
+	case <-attempted:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Expects the message to be processed within three seconds\n")
+	}
+	closed := make(chan struct{})
+	go func() {
+		group.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Expects the group to close without waiting for the next attempt\n")
+	}
+	// The message isn't committed, so it is processed again by the next member
+	if offsets, _ := bus.Offsets("likes"); offsets != nil {
+		if offset, _ := offsets.Offset("newSong", 0); offset > 0 {
+			t.Errorf("Expects the message not to be committed but got offset: %v\n", offset)
+		}
+	}
+}
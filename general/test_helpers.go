@@ -34,7 +34,7 @@ func TestRequest(t *testing.T, server *http.Server, method, path, token string,
 	go func() {
 		err := WriteToJSON(body, writer)
 		if err != nil {
-			t.Fatalf("Error in test helper: %s", err)
+			t.Errorf("Error in test helper: %s", err)
 		}
 		writer.Close()
 	}()
@@ -105,7 +105,7 @@ func TestPostRequest(t *testing.T, handler func(http.ResponseWriter, *http.Reque
 	go func() {
 		err := WriteToJSON(body, writer)
 		if err != nil {
-			t.Fatalf("Error in test helper: %s", err)
+			t.Errorf("Error in test helper: %s", err)
 		}
 		writer.Close()
 	}()
@@ -121,7 +121,7 @@ func TestPostRequestWithContext(t *testing.T, handler func(http.ResponseWriter,
 	go func() {
 		err := WriteToJSON(body, writer)
 		if err != nil {
-			t.Fatalf("Error in test helper: %s", err)
+			t.Errorf("Error in test helper: %s", err)
 		}
 		writer.Close()
 	}()
@@ -1,7 +1,6 @@
 package general
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -9,30 +8,46 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-// This key is currently hard-coded
-var key []byte = []byte("WlAye5L1uzZq61p41A6PyhpBxsnklABk6FPAOeOXUwqWuouUEvTG8Apqkqo1uloZ")
-
 // RoleInternal is the role for requests coming from a service
 const RoleInternal string = "MusicApp"
 
-func getClaims(id int, username, role string) *jwt.StandardClaims {
-	expDuration, _ := time.ParseDuration("8h")
+// The roles that can be granted to an user. Curators can manage the discography and admins can also manage the roles of users.
+const (
+	RoleAdmin   string = "admin"
+	RoleCurator string = "curator"
+	RoleUser    string = "user"
+)
+
+// tokenClaims are the claims of a token. Version is the token version of the user at the moment that the token was created.
+type tokenClaims struct {
+	jwt.StandardClaims
+	Version int `json:"ver,omitempty"`
+}
+
+func getClaims(id int, username, role string, version int) *tokenClaims {
 	nbf := time.Now()
-	exp := nbf.Add(expDuration)
-	return &jwt.StandardClaims{
-		ExpiresAt: exp.Unix(),
-		NotBefore: nbf.Unix(),
-		Id:        strconv.Itoa(id),
-		Issuer:    username,
-		Audience:  role,
+	exp := nbf.Add(tokenLifetime)
+	return &tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: exp.Unix(),
+			NotBefore: nbf.Unix(),
+			Id:        strconv.Itoa(id),
+			Issuer:    username,
+			Audience:  role,
+		},
+		Version: version,
 	}
 }
 
-// CreateToken returns a jwt signed by the key
+// CreateToken returns a jwt signed by the signing key. The id of the key is added as kid header.
 func CreateToken(id int, username, role string) (string, error) {
-	claims := getClaims(id, username, role)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(key)
+	return tokenKeys.CreateToken(id, username, role, 0)
+}
+
+// CreateTokenWithVersion returns a jwt signed by the signing key that contains the given token version.
+// The token becomes invalid as soon as the tokens of the user with this version are revoked.
+func CreateTokenWithVersion(id int, username, role string, version int) (string, error) {
+	return tokenKeys.CreateToken(id, username, role, version)
 }
 
 // CreateTokenInternalRequests creates a jwt for requests between services
@@ -40,21 +55,53 @@ func CreateTokenInternalRequests(servername string) (string, error) {
 	return CreateToken(-1, servername, RoleInternal)
 }
 
+// ValidateToken returns the credentials from the token if it is signed by a known key and it isn't revoked
+func ValidateToken(tokenString string) (Credentials, error) {
+	return validateToken(tokenString)
+}
+
 func validateToken(tokenString string) (Credentials, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return Credentials{}, errors.New("invalid signing method")
-		}
-		return key, nil
-	})
+	claims, err := tokenKeys.parse(tokenString)
+	if err != nil {
+		return Credentials{}, err
+	}
+	credentials, err := claims.credentials()
+	if err != nil {
+		return Credentials{}, err
+	}
+	if isRevoked(credentials.ID, claims.Version) {
+		return Credentials{}, fmt.Errorf("[WARNING] Received revoked token of user %v", credentials.Username)
+	}
+	return credentials, nil
+}
+
+// CreateToken returns a jwt with the given token version signed by the signing key of this set
+func (keys *KeySet) CreateToken(id int, username, role string, version int) (string, error) {
+	return keys.sign(getClaims(id, username, role, version))
+}
+
+// ValidateToken returns the credentials from the token if it is signed by one of the keys of this set
+func (keys *KeySet) ValidateToken(tokenString string) (Credentials, error) {
+	claims, err := keys.parse(tokenString)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return claims.credentials()
+}
+
+func (keys *KeySet) parse(tokenString string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
 	if err != nil || !token.Valid {
-		return Credentials{}, fmt.Errorf("[WARNING] Invalid token: %v", err)
+		return nil, fmt.Errorf("[WARNING] Invalid token: %v", err)
 	}
-	claims := token.Claims.(jwt.MapClaims)
-	id, convError := strconv.Atoi(claims["jti"].(string))
-	if convError != nil && claims["jti"].(string) != "" {
+	return claims, nil
+}
+
+func (claims *tokenClaims) credentials() (Credentials, error) {
+	id, convError := strconv.Atoi(claims.Id)
+	if convError != nil && claims.Id != "" {
 		return Credentials{}, fmt.Errorf("[WARNING] Received signed token with invalid id: %v", convError)
 	}
-	tokenContext := Credentials{ID: id, Username: claims["iss"].(string), Role: claims["aud"].(string)}
-	return tokenContext, nil
+	return Credentials{ID: id, Username: claims.Issuer, Role: claims.Audience}, nil
 }
@@ -12,6 +12,11 @@ type Database interface {
 	AddUser(user general.Credentials) error
 	AddArtist(artist general.Artist) error
 	AddSong(song general.Song) error
+	AddAlbum(album general.Album) error
+	AddGenre(genre general.Genre) error
+	AddGenreToSong(tag general.GenreTag) error
+	RemoveGenreFromSong(tag general.GenreTag) error
+	RemoveUser(userID int) error
 	AddLike(userID, songID int) error
 	AddDislike(userID, songID int) error
 	RemoveLike(userID, songID int) error
@@ -20,6 +25,10 @@ type Database interface {
 	GetDislikes(userID, offset, max int) ([]general.Song, error)
 	GetLikesIDFromArtistName(logger *log.Logger, userID int, nameArtist string, channel chan<- int, wg *sync.WaitGroup)
 	GetDislikesIDFromArtistName(logger *log.Logger, userID int, nameArtist string, channel chan<- int, wg *sync.WaitGroup)
+	FollowArtist(userID, artistID int) error
+	UnfollowArtist(userID, artistID int) error
+	GetFollowedArtists(userID, offset, max int) ([]general.Artist, error)
+	GetFollowersOfArtist(artistID int) ([]int, error)
 }
 
 // LikesDB is a database
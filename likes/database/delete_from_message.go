@@ -0,0 +1,23 @@
+package database
+
+import (
+	"general"
+)
+
+// RemoveGenreFromSong removes the genre from the song
+func (db *LikesDB) RemoveGenreFromSong(tag general.GenreTag) error {
+	_, err := db.database.Exec("DELETE FROM song_genre WHERE song_id=? AND genre_id=?;", tag.SongID, tag.Genre.ID)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
+// RemoveUser removes the user from the database. The likes, dislikes and followed artists of the user are removed by the cascading foreign keys.
+func (db *LikesDB) RemoveUser(userID int) error {
+	_, err := db.database.Exec("DELETE FROM users WHERE id=?;", userID)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
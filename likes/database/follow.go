@@ -0,0 +1,54 @@
+package database
+
+import (
+	"general"
+)
+
+// FollowArtist adds a new followed artist of the given user to the database
+func (db *LikesDB) FollowArtist(userID, artistID int) error {
+	_, err := db.database.Exec("INSERT INTO followed_artists (user_id, artist_id) VALUES (?,?);", userID, artistID)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
+// UnfollowArtist removes a followed artist of the given user from the database
+func (db *LikesDB) UnfollowArtist(userID, artistID int) error {
+	_, err := db.database.Exec("DELETE FROM followed_artists WHERE user_id=? AND artist_id=?;", userID, artistID)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
+// GetFollowedArtists finds the artists that are followed by the given user ordered by name of the artist.
+func (db *LikesDB) GetFollowedArtists(userID, offset, max int) ([]general.Artist, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	results, err := db.database.Query("SELECT artists.id, name_artist, prefix FROM followed_artists, artists WHERE user_id=? AND artist_id=artists.id ORDER BY name_artist LIMIT ?,?;", userID, offset, max)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	return scanArtists(results)
+}
+
+// GetFollowersOfArtist finds the ids of all the users that follow the given artist
+func (db *LikesDB) GetFollowersOfArtist(artistID int) ([]int, error) {
+	results, err := db.database.Query("SELECT user_id FROM followed_artists WHERE artist_id=? ORDER BY user_id;", artistID)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	followers := make([]int, 0, 20)
+	var userID int
+	for results.Next() {
+		if err = results.Scan(&userID); err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		followers = append(followers, userID)
+	}
+	return followers, nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"general"
+	"strconv"
 )
 
 // AddUser adds a new user to the database
@@ -42,7 +43,7 @@ func (db *LikesDB) AddSong(song general.Song) error {
 			db.database.Exec("DELETE FROM songs WHERE id=?;", song.ID)
 			artistIDs := ""
 			for _, artist := range song.Artists {
-				artistIDs += string(artist.ID) + ","
+				artistIDs += strconv.Itoa(artist.ID) + ","
 			}
 			artistIDs = artistIDs[:len(artistIDs)-1]
 			db.database.Exec("DELETE FROM discography WHERE song_id=? AND artist_id IN (?);", song.ID, artistIDs)
@@ -51,3 +52,41 @@ func (db *LikesDB) AddSong(song general.Song) error {
 	}
 	return nil
 }
+
+// AddAlbum adds a new album with its track listing to the database. It expects that the artist and the songs already exists
+func (db *LikesDB) AddAlbum(album general.Album) error {
+	if len(album.Songs) == 0 {
+		return general.GetDBError("No songs are given for adding an album", general.InvalidInput)
+	}
+	_, err := db.database.Exec("INSERT INTO albums (id, name_album, artist_id) VALUES (?,?,?);", album.ID, album.Name, album.Artist.ID)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	for index, song := range album.Songs {
+		_, err = db.database.Exec("INSERT INTO album_track_listing (album_id, song_id, track_number) VALUES (?,?,?);", album.ID, song.ID, index+1)
+		if err != nil {
+			// Revert changes on failure. The track listing will be deleted by the cascade.
+			db.database.Exec("DELETE FROM albums WHERE id=?;", album.ID)
+			return general.MySQLErrorToDBError(err)
+		}
+	}
+	return nil
+}
+
+// AddGenre adds a new genre to the database
+func (db *LikesDB) AddGenre(genre general.Genre) error {
+	_, err := db.database.Exec("INSERT INTO genres (id, name_genre) VALUES (?,?);", genre.ID, genre.Name)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
+// AddGenreToSong tags a song with a genre. It expects that the song and the genre already exists
+func (db *LikesDB) AddGenreToSong(tag general.GenreTag) error {
+	_, err := db.database.Exec("INSERT INTO song_genre (song_id, genre_id) VALUES (?,?);", tag.SongID, tag.Genre.ID)
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
@@ -62,3 +62,18 @@ func connectArtistsAndSongs(input <-chan artistAndSong, output chan<- general.So
 	}
 	output <- lastFoundSong
 }
+
+func scanArtists(results *sql.Rows) ([]general.Artist, error) {
+	artists := make([]general.Artist, 0, 20)
+	for results.Next() {
+		var artist general.Artist
+		if err := results.Scan(&artist.ID, &artist.Name, &artist.Prefix); err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		artists = append(artists, artist)
+	}
+	if len(artists) == 0 {
+		return nil, general.GetDBError("Not found", general.NotFoundError)
+	}
+	return artists, nil
+}
@@ -4,7 +4,6 @@ go 1.14
 
 require (
 	general v1.0.0
-	github.com/DATA-DOG/go-txdb v0.1.3
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gorilla/mux v1.7.4
 	github.com/optiopay/kafka/v2 v2.1.1
@@ -8,14 +8,20 @@ import (
 )
 
 func (handler *LikesHandler) obtainSongOrSendError(response http.ResponseWriter, songID int) bool {
-	resp, err := handler.GETRequest(fmt.Sprintf("http://localhost%v/intern/song/%v", portDiscography, songID))
+	resp, err := handler.GETRequest(fmt.Sprintf("%v/intern/song/%v", addressDiscography, songID))
+	if err != nil {
+		handler.Logger.Printf("Failed to obtain song #%v from discography: %s\n", songID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return true
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusNotFound {
 		handler.Logger.Printf("Song #%v doesn't exist!\n", songID)
 		general.SendError(response, http.StatusNotFound)
 		return true
 	}
-	if err != nil || resp.StatusCode != http.StatusOK {
-		handler.Logger.Printf("Failed to obtain song #%v from discography: %s\n", songID, err)
+	if resp.StatusCode != http.StatusOK {
+		handler.Logger.Printf("Failed to obtain song #%v from discography due to statuscode: %v\n", songID, resp.StatusCode)
 		general.SendError(response, http.StatusInternalServerError)
 		return true
 	}
@@ -49,6 +55,7 @@ func (handler *LikesHandler) AddLike(response http.ResponseWriter, request *http
 	// If no error occurs, then we can response with StatusOK
 	if err == nil {
 		handler.Logger.Printf("Succesfully added like for user #%v and song #%v\n", user.ID, newPref.ID)
+		handler.sendPreferenceChange(general.NewPreferenceChange(user.ID, newPref.ID, "like", false))
 		response.WriteHeader(http.StatusOK)
 		response.Write([]byte(http.StatusText(http.StatusOK)))
 		return
@@ -87,6 +94,7 @@ func (handler *LikesHandler) AddLike(response http.ResponseWriter, request *http
 
 	}
 	handler.Logger.Printf("Succesfully added like for user #%v and song #%v after adding missing data\n", user.ID, newPref.ID)
+	handler.sendPreferenceChange(general.NewPreferenceChange(user.ID, newPref.ID, "like", false))
 	response.WriteHeader(http.StatusOK)
 	response.Write([]byte(http.StatusText(http.StatusOK)))
 }
@@ -114,6 +122,7 @@ func (handler *LikesHandler) AddDislike(response http.ResponseWriter, request *h
 	// If no error occurs, then we can response with StatusOK
 	if err == nil {
 		handler.Logger.Printf("Succesfully added dislike for user #%v and song #%v\n", user.ID, newPref.ID)
+		handler.sendPreferenceChange(general.NewPreferenceChange(user.ID, newPref.ID, "dislike", false))
 		response.WriteHeader(http.StatusOK)
 		response.Write([]byte(http.StatusText(http.StatusOK)))
 		return
@@ -152,6 +161,19 @@ func (handler *LikesHandler) AddDislike(response http.ResponseWriter, request *h
 
 	}
 	handler.Logger.Printf("Succesfully added dislike for user #%v and song #%v after adding missing data\n", user.ID, newPref.ID)
+	handler.sendPreferenceChange(general.NewPreferenceChange(user.ID, newPref.ID, "dislike", false))
 	response.WriteHeader(http.StatusOK)
 	response.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+// sendPreferenceChange sends the change of a preference to the topic changePreference
+func (handler *LikesHandler) sendPreferenceChange(change general.PreferenceChange) {
+	go func(handler *LikesHandler, change general.PreferenceChange) {
+		msg, err := general.EncodeEvent("changePreference", change)
+		if err != nil {
+			handler.Logger.Printf("[ERROR] Failed to convert %v of user #%v and song #%v to bytes: %v\n", change.Preference, change.UserID, change.SongID, err)
+			return
+		}
+		handler.SendMessage("changePreference", msg)
+	}(handler, change)
+}
@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"general"
+	"general/config"
 	"likes/database"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
-	"github.com/optiopay/kafka/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -15,24 +15,22 @@ import (
 
 const servername string = "likes"
 
-var portDiscography string
+var addressDiscography string
 
 // NewLikesServer returns a new server for likes and dislikes and a function that starts up the server.
-// If broker is nil, then there will be no messages consumed.
-func NewLikesServer(handler *LikesHandler, broker *kafka.Broker, servername, port string) (server *http.Server, start func()) {
-	var startConsumer func()
-	if broker != nil {
-		startConsumer = func() {
-			handler.StartConsuming(broker)
-		}
+// If bus is nil, then there will be no messages consumed.
+func NewLikesServer(handler *LikesHandler, bus general.Bus, cfg config.Config) (server *http.Server, start func()) {
+	var startConsumer func(*general.ConsumerGroup)
+	if bus != nil {
+		startConsumer = handler.StartConsuming
 	}
-	s, channel, startServer := general.NewServer(servername, port, initRoutes(handler), broker, startConsumer, handler.Logger)
+	s, channel, startServer := general.NewServer(cfg, initRoutes(handler), bus, startConsumer, handler.Logger)
 	server = s
 	start = func() {
 		go func() {
 			for service := range channel {
 				if service.Name == "discography" {
-					portDiscography = service.Address
+					addressDiscography = service.Address
 				}
 			}
 		}()
@@ -53,6 +51,7 @@ func initRoutes(handler *LikesHandler) *mux.Router {
 	getR.Use(general.GetOffsetMaxMiddleware(handler.Logger))
 	getR.PathPrefix("/like").HandlerFunc(handler.GetLikes)
 	getR.PathPrefix("/dislike").HandlerFunc(handler.GetDislikes)
+	getR.PathPrefix("/follow").HandlerFunc(handler.GetFollowedArtists)
 
 	likesR := clientR.PathPrefix("/like").Subrouter()
 	likesR.Methods(http.MethodPost).HandlerFunc(handler.AddLike)
@@ -62,22 +61,32 @@ func initRoutes(handler *LikesHandler) *mux.Router {
 	dislikesR.Methods(http.MethodPost).HandlerFunc(handler.AddDislike)
 	dislikesR.Methods(http.MethodDelete).HandlerFunc(handler.RemoveDislike)
 
+	followR := clientR.PathPrefix("/follow").Subrouter()
+	followR.Methods(http.MethodPost).HandlerFunc(handler.FollowArtist)
+	followR.Methods(http.MethodDelete).HandlerFunc(handler.UnfollowArtist)
+
 	internalR := router.PathPrefix("/intern").Methods(http.MethodGet).Subrouter()
 	internalR.Use(general.GetInternalRequestMiddleware(handler.Logger))
 	internalR.Path("/preference/{user}/{artist}").HandlerFunc(handler.GetPreferencesOfArtist)
+	internalR.Path("/followers/{artist}").HandlerFunc(handler.GetFollowersOfArtist)
+	internalR.Path("/export/{user}").HandlerFunc(handler.GetPreferencesOfUser)
 	return router
 }
 
 // LikesHandler consists of a logger and a database
 type LikesHandler struct {
-	Logger     *log.Logger
-	db         database.Database
-	GETRequest func(string) (*http.Response, error)
+	Logger      *log.Logger
+	db          database.Database
+	SendMessage func(string, []byte)
+	GETRequest  func(string) (*http.Response, error)
 }
 
 //NewLikesHandler returns a MusicHandler.
 // If get is nil, then DefaultGETRequest will be used with the default servername
-func NewLikesHandler(logger *log.Logger, db database.Database, get func(string) (*http.Response, error)) *LikesHandler {
+func NewLikesHandler(logger *log.Logger, db database.Database, sendMessage func(string, []byte) error, get func(string) (*http.Response, error)) *LikesHandler {
+	if sendMessage == nil {
+		logger.Fatalf("Can't create a handler without a function for sending messages\n")
+	}
 	if get == nil {
 		var err error
 		get, err = general.GetInternalGETRequest(servername)
@@ -85,7 +94,13 @@ func NewLikesHandler(logger *log.Logger, db database.Database, get func(string)
 			logger.Fatalf("Can't create a client for sending get requests: %s\n", err)
 		}
 	}
-	return &LikesHandler{Logger: logger, db: db, GETRequest: get}
+	return &LikesHandler{Logger: logger, db: db, GETRequest: get, SendMessage: func(topic string, message []byte) {
+		if err := sendMessage(topic, message); err != nil {
+			logger.Printf("Topic %v: Can't send message %s: %v\n", topic, message, err)
+			return
+		}
+		logger.Printf("Topic %v: Send message: %s\n", topic, message)
+	}}
 }
 
 var (
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"general"
+	"net/http"
+)
+
+func (handler *LikesHandler) obtainArtistOrSendError(response http.ResponseWriter, artistID int) bool {
+	resp, err := handler.GETRequest(fmt.Sprintf("%v/intern/artist/%v", addressDiscography, artistID))
+	if err != nil {
+		handler.Logger.Printf("Failed to obtain artist #%v from discography: %s\n", artistID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return true
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		handler.Logger.Printf("Failed to obtain artist #%v from discography due to statuscode: %v\n", artistID, resp.StatusCode)
+		general.SendError(response, http.StatusInternalServerError)
+		return true
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		handler.Logger.Printf("Artist #%v doesn't exist!\n", artistID)
+		general.SendError(response, http.StatusNotFound)
+		return true
+	}
+	handler.Logger.Printf("Found missing artist #%v from discography service\n", artistID)
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(resp.Body)
+	if err = handler.ConsumeNewArtist(buf.Bytes()); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to add artist #%v from discography: %s\n", artistID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return true
+	}
+	return false
+}
+
+// FollowArtist adds a new followed artist of the user to the database.
+func (handler *LikesHandler) FollowArtist(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	var follow general.Preference
+	if err := general.ReadFromJSON(&follow, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for following artist #%v by user #%v\n", follow.ID, user.ID)
+	err := handler.db.FollowArtist(user.ID, follow.ID)
+	if err == nil {
+		handler.Logger.Printf("Succesfully added artist #%v to the followed artists of user #%v\n", follow.ID, user.ID)
+		response.WriteHeader(http.StatusOK)
+		response.Write([]byte(http.StatusText(http.StatusOK)))
+		return
+	}
+	if err.(general.DBError).ErrorCode == general.DuplicateEntry {
+		handler.Logger.Printf("User #%v already follows artist #%v\n", user.ID, follow.ID)
+		response.WriteHeader(http.StatusOK)
+		response.Write([]byte(http.StatusText(http.StatusOK)))
+		return
+	}
+	// If err gives an unexpected error, then we will send internal server error
+	if err.(general.DBError).ErrorCode != general.MissingForeignKey {
+		handler.Logger.Printf("[ERROR] Failed to follow artist #%v for user #%v: %s\n", follow.ID, user.ID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	handler.Logger.Printf("Can't follow artist #%v for user #%v. Trying to add user and artist\n", follow.ID, user.ID)
+	// The channel is buffered, so that the goroutine doesn't block if the artist can't be obtained
+	channelAddUser := make(chan error, 1)
+	go func() {
+		channelAddUser <- handler.db.AddUser(user)
+	}()
+	if handler.obtainArtistOrSendError(response, follow.ID) {
+		return
+	}
+	errAddUser := <-channelAddUser
+	if errAddUser != nil && errAddUser.(general.DBError).ErrorCode != general.DuplicateEntry {
+		handler.Logger.Printf("[ERROR] Failed to add new user %v: %s\n", user.Username, errAddUser)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	err = handler.db.FollowArtist(user.ID, follow.ID)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to follow artist #%v for user #%v after adding missing data: %s\n", follow.ID, user.ID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	handler.Logger.Printf("Succesfully added artist #%v to the followed artists of user #%v after adding missing data\n", follow.ID, user.ID)
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// UnfollowArtist removes a followed artist of the user from the database.
+func (handler *LikesHandler) UnfollowArtist(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	var follow general.Preference
+	if err := general.ReadFromJSON(&follow, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for unfollowing artist #%v by user #%v\n", follow.ID, user.ID)
+	if err := handler.db.UnfollowArtist(user.ID, follow.ID); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to unfollow artist #%v for user #%v: %s\n", follow.ID, user.ID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// GetFollowedArtists get the artists followed by an user bounded by the given offset and max in the request. The results are ordered by name of the artist
+func (handler *LikesHandler) GetFollowedArtists(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	handler.Logger.Printf("Received call for followed artists of user %v and limit %v,%v\n", user.Username, offset, max)
+	results, errorSearch := handler.db.GetFollowedArtists(user.ID, offset, max+1)
+	if errorSearch != nil {
+		errorcode := errorSearch.(general.DBError).ErrorCode
+		if errorcode == general.InvalidOffsetMax {
+			badRequests.Inc()
+			handler.Logger.Printf("Request with invalid  values for query parameters: %v,%v", offset, max)
+			general.SendError(response, http.StatusBadRequest)
+			return
+		}
+		if errorcode == general.NotFoundError {
+			handler.Logger.Printf("Request with no results for user %v: %v,%v", user.Username, offset, max)
+			general.SendError(response, http.StatusNotFound)
+			return
+		}
+		failureGetRequest.Inc()
+		handler.Logger.Printf("[Error] Can't find followed artists of user %v and limit %v,%v due to: %s\n", user.Username, offset, max, errorSearch)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	if len(results) == 0 {
+		handler.Logger.Printf("Failed to find followed artists of user %v and limit %v,%v\n", user.Username, offset, max)
+		failureGetRequest.Inc()
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	handler.Logger.Printf("Succesfully found %v followed artists of user %v and limit %v,%v\n", len(results), user.Username, offset, max)
+	hasNext := (len(results) > max)
+	if hasNext {
+		results = results[0:max]
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err := general.WriteToJSON(&general.MultipleArtists{Data: results, HasNext: hasNext}, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
@@ -22,28 +22,29 @@ func (handler *LikesHandler) GetPreferencesOfArtist(response http.ResponseWriter
 	results := make(map[int]string)
 	likesChan := make(chan int, 20)
 	dislikesChan := make(chan int, 20)
-	doneChan := make(chan bool)
 	var wg sync.WaitGroup
 	wg.Add(2)
-	go func(wg *sync.WaitGroup) {
-		wg.Wait()
-		handler.Logger.Printf("Found all preferences of user #%v\n", userID)
-		doneChan <- true
-	}(&wg)
 	go handler.db.GetLikesIDFromArtistName(handler.Logger, userID, nameArtist, likesChan, &wg)
 	go handler.db.GetDislikesIDFromArtistName(handler.Logger, userID, nameArtist, dislikesChan, &wg)
-LOOP:
-	for {
+	// Both channels will be closed when all results are send, so we keep reading until both are closed.
+	for likesChan != nil || dislikesChan != nil {
 		select {
-		case like := <-likesChan:
+		case like, ok := <-likesChan:
+			if !ok {
+				likesChan = nil
+				continue
+			}
 			results[like] = "like"
-		case dislike := <-dislikesChan:
+		case dislike, ok := <-dislikesChan:
+			if !ok {
+				dislikesChan = nil
+				continue
+			}
 			results[dislike] = "dislike"
-		case <-doneChan:
-			handler.Logger.Printf("Stop waiting for more results for user #%v\n", userID)
-			break LOOP
 		}
 	}
+	wg.Wait()
+	handler.Logger.Printf("Found all preferences of user #%v\n", userID)
 	handler.Logger.Printf("User #%v has %v preferences of songs of artist %v\n", userID, len(results), nameArtist)
 	if len(results) == 0 {
 		general.SendError(response, http.StatusNotFound)
@@ -55,3 +56,96 @@ LOOP:
 		handler.Logger.Printf("[ERROR] %s\n", err)
 	}
 }
+
+// GetFollowersOfArtist responds with the ids of all the users that follow the given artist
+func (handler *LikesHandler) GetFollowersOfArtist(response http.ResponseWriter, request *http.Request) {
+	artist := mux.Vars(request)["artist"]
+	artistID, err := strconv.Atoi(artist)
+	if err != nil {
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received internal call for followers of artist #%v\n", artistID)
+	followers, err := handler.db.GetFollowersOfArtist(artistID)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to find followers of artist #%v: %s\n", artistID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	handler.Logger.Printf("Artist #%v has %v followers\n", artistID, len(followers))
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	if err = general.WriteToJSON(&followers, response); err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// exportBatchSize is the number of preferences that are obtained from the database at once when exporting the preferences of an user
+const exportBatchSize int = 100
+
+// GetPreferencesOfUser responds with all the likes, dislikes and followed artists of the given user
+func (handler *LikesHandler) GetPreferencesOfUser(response http.ResponseWriter, request *http.Request) {
+	user := mux.Vars(request)["user"]
+	userID, err := strconv.Atoi(user)
+	if err != nil {
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received internal call for all preferences of user #%v\n", userID)
+	likes, err := collectSongs(func(offset, max int) ([]general.Song, error) {
+		return handler.db.GetLikes(userID, offset, max)
+	})
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to find likes of user #%v: %s\n", userID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	dislikes, err := collectSongs(func(offset, max int) ([]general.Song, error) {
+		return handler.db.GetDislikes(userID, offset, max)
+	})
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to find dislikes of user #%v: %s\n", userID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	followedArtists := make([]general.Artist, 0)
+	for offset := 0; ; offset += exportBatchSize {
+		artists, err := handler.db.GetFollowedArtists(userID, offset, exportBatchSize)
+		if err != nil {
+			if err.(general.DBError).ErrorCode == general.NotFoundError {
+				break
+			}
+			handler.Logger.Printf("[ERROR] Failed to find followed artists of user #%v: %s\n", userID, err)
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		followedArtists = append(followedArtists, artists...)
+		if len(artists) < exportBatchSize {
+			break
+		}
+	}
+	handler.Logger.Printf("User #%v has %v likes, %v dislikes and %v followed artists\n", userID, len(likes), len(dislikes), len(followedArtists))
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	if err = general.WriteToJSON(general.NewUserPreferences(likes, dislikes, followedArtists), response); err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// collectSongs keeps obtaining songs in batches until all songs are found
+func collectSongs(getSongs func(offset, max int) ([]general.Song, error)) ([]general.Song, error) {
+	songs := make([]general.Song, 0)
+	for offset := 0; ; offset += exportBatchSize {
+		batch, err := getSongs(offset, exportBatchSize)
+		if err != nil {
+			if err.(general.DBError).ErrorCode == general.NotFoundError {
+				return songs, nil
+			}
+			return nil, err
+		}
+		songs = append(songs, batch...)
+		if len(batch) < exportBatchSize {
+			return songs, nil
+		}
+	}
+}
@@ -2,61 +2,82 @@ package handlers
 
 import (
 	"general"
-
-	"github.com/optiopay/kafka/v2"
 )
 
-// StartConsuming will start all the consumers that belongs to the likes service
-func (handler *LikesHandler) StartConsuming(broker *kafka.Broker) {
-	go general.StartConsumer(broker, handler.Logger, "newUser", handler.ConsumeNewUser)
-	go general.StartConsumer(broker, handler.Logger, "newArtist", handler.ConsumeNewArtist)
-	go general.StartConsumer(broker, handler.Logger, "newSong", handler.ConsumeNewSong)
+// StartConsuming will start all the consumers that belongs to the likes service. Events that are delivered more than once are only processed once.
+func (handler *LikesHandler) StartConsuming(consumers *general.ConsumerGroup) {
+	deduplicator := general.NewEventDeduplicator(general.DefaultDeduplicationWindow)
+	consumers.Consume("newUser", deduplicator.Deduplicate(handler.ConsumeNewUser))
+	consumers.Consume("userDeleted", deduplicator.Deduplicate(handler.ConsumeDeletedUser))
+	consumers.Consume("newArtist", deduplicator.Deduplicate(handler.ConsumeNewArtist))
+	consumers.Consume("newSong", deduplicator.Deduplicate(handler.ConsumeNewSong))
+	consumers.Consume("newAlbum", deduplicator.Deduplicate(handler.ConsumeNewAlbum))
+	consumers.Consume("newGenre", deduplicator.Deduplicate(handler.ConsumeNewGenre))
+	consumers.Consume("tagSong", deduplicator.Deduplicate(handler.ConsumeTagSong))
+	consumers.Consume("untagSong", deduplicator.Deduplicate(handler.ConsumeUntagSong))
 }
 
 // ConsumeNewUser consumes a message and adds a new user to the database
-func (handler *LikesHandler) ConsumeNewUser(message []byte) {
+func (handler *LikesHandler) ConsumeNewUser(message []byte) error {
 	var newUser general.Credentials
-	if err := general.FromJSONBytes(&newUser, message); err != nil {
+	if _, err := general.DecodeEvent(message, &newUser); err != nil {
 		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
-		return
+		return general.PermanentError(err)
 	}
 	if err := handler.db.AddUser(newUser); err != nil {
 		if err.(general.DBError).ErrorCode != general.DuplicateEntry {
 			handler.Logger.Printf("[ERROR] Failed to add new user %v to DB: %s\n", newUser.Username, err)
-			return
+			return err
 		}
 		handler.Logger.Printf("Adding user %v results in duplicate error.\n", newUser.Username)
 	}
 	handler.Logger.Printf("Succesfully added new user %v\n", newUser.Username)
+	return nil
+}
+
+// ConsumeDeletedUser consumes a message and removes the user together with all its preferences from the database
+func (handler *LikesHandler) ConsumeDeletedUser(message []byte) error {
+	var user general.Credentials
+	if _, err := general.DecodeEvent(message, &user); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if err := handler.db.RemoveUser(user.ID); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to remove user %v from DB: %s\n", user.Username, err)
+		return err
+	}
+	handler.Logger.Printf("Succesfully removed user %v\n", user.Username)
+	return nil
 }
 
 // ConsumeNewArtist consumes a message and adds a new artist to the database
-func (handler *LikesHandler) ConsumeNewArtist(message []byte) {
+func (handler *LikesHandler) ConsumeNewArtist(message []byte) error {
 	var artist general.Artist
-	if err := general.FromJSONBytes(&artist, message); err != nil {
+	if _, err := general.DecodeEvent(message, &artist); err != nil {
 		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
-		return
+		return general.PermanentError(err)
 	}
 	if err := handler.db.AddArtist(artist); err != nil {
 		if err.(general.DBError).ErrorCode != general.DuplicateEntry {
 			handler.Logger.Printf("[ERROR] Failed to add new artist %v to DB: %s\n", artist.Name, err)
-			return
+			return err
 		}
 		handler.Logger.Printf("Adding artist %v results in duplicate error.\n", artist.Name)
 	}
 	handler.Logger.Printf("Succesfully added new artist %v\n", artist.Name)
+	return nil
 }
 
 // ConsumeNewSong consumes a message and adds a new song to the database. It expects that the collaborating artists already exists
-func (handler *LikesHandler) ConsumeNewSong(message []byte) {
+func (handler *LikesHandler) ConsumeNewSong(message []byte) error {
 	var song general.Song
-	if err := general.FromJSONBytes(&song, message); err != nil {
+	if _, err := general.DecodeEvent(message, &song); err != nil {
 		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
-		return
+		return general.PermanentError(err)
 	}
 	if len(song.Artists) == 0 {
 		handler.Logger.Printf("Received new song #%v without artist\n", song.ID)
-		return
+		return general.PermanentError(general.GetDBError("Missing artists", general.InvalidInput))
 	}
 	if err := handler.db.AddSong(song); err != nil {
 		switch err.(general.DBError).ErrorCode {
@@ -65,7 +86,7 @@ func (handler *LikesHandler) ConsumeNewSong(message []byte) {
 				addArtistErr := handler.db.AddArtist(artist)
 				if addArtistErr != nil && addArtistErr.(general.DBError).ErrorCode != general.DuplicateEntry {
 					handler.Logger.Printf("Failed to add new song %v -%v due to failure of adding artist %v: %s\n", song.Artists[0].Name, song.Name, artist.Name, addArtistErr)
-					return
+					return addArtistErr
 				}
 				if addArtistErr == nil {
 					handler.Logger.Printf("Artist %v was missing from DB but is now succesfully added.\n", artist.Name)
@@ -73,15 +94,125 @@ func (handler *LikesHandler) ConsumeNewSong(message []byte) {
 			}
 			if errSecondTry := handler.db.AddSong(song); errSecondTry != nil {
 				handler.Logger.Printf("Failed to add new song %v -%v due to second failure: %s\n", song.Artists[0].Name, song.Name, errSecondTry)
-				return
+				return errSecondTry
 			}
 		case general.DuplicateEntry:
 			handler.Logger.Printf("Adding song %v -%v results in duplicate error.\n", song.Artists[0].Name, song.Name)
-			return
+			return nil
 		default:
 			handler.Logger.Printf("[ERROR] Failed to add new song %v - %v to DB: %s\n", song.Artists[0].Name, song.Name, err)
-			return
+			return err
 		}
 	}
 	handler.Logger.Printf("Succesfully added new song %v -%v\n", song.Artists[0].Name, song.Name)
+	return nil
+}
+
+// ConsumeNewAlbum consumes a message and adds a new album to the database. Missing artists and songs of the album will be added as well
+func (handler *LikesHandler) ConsumeNewAlbum(message []byte) error {
+	var album general.Album
+	if _, err := general.DecodeEvent(message, &album); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if len(album.Songs) == 0 {
+		handler.Logger.Printf("Received new album #%v without songs\n", album.ID)
+		return general.PermanentError(general.GetDBError("Missing songs", general.InvalidInput))
+	}
+	if err := handler.db.AddAlbum(album); err != nil {
+		switch err.(general.DBError).ErrorCode {
+		case general.MissingForeignKey:
+			addArtistErr := handler.db.AddArtist(album.Artist)
+			if addArtistErr != nil && addArtistErr.(general.DBError).ErrorCode != general.DuplicateEntry {
+				handler.Logger.Printf("Failed to add new album %v - %v due to failure of adding artist: %s\n", album.Artist.Name, album.Name, addArtistErr)
+				return addArtistErr
+			}
+			for _, song := range album.Songs {
+				songMessage, err := general.EncodeEvent("newSong", song)
+				if err != nil {
+					handler.Logger.Printf("[ERROR] Failed to convert song #%v of album %v to bytes: %s\n", song.ID, album.Name, err)
+					return general.PermanentError(err)
+				}
+				if err := handler.ConsumeNewSong(songMessage); err != nil {
+					return err
+				}
+			}
+			if errSecondTry := handler.db.AddAlbum(album); errSecondTry != nil {
+				handler.Logger.Printf("Failed to add new album %v - %v due to second failure: %s\n", album.Artist.Name, album.Name, errSecondTry)
+				return errSecondTry
+			}
+		case general.DuplicateEntry:
+			handler.Logger.Printf("Adding album %v - %v results in duplicate error.\n", album.Artist.Name, album.Name)
+			return nil
+		default:
+			handler.Logger.Printf("[ERROR] Failed to add new album %v - %v to DB: %s\n", album.Artist.Name, album.Name, err)
+			return err
+		}
+	}
+	handler.Logger.Printf("Succesfully added new album %v - %v\n", album.Artist.Name, album.Name)
+	return nil
+}
+
+// ConsumeNewGenre consumes a message and adds a new genre to the database
+func (handler *LikesHandler) ConsumeNewGenre(message []byte) error {
+	var genre general.Genre
+	if _, err := general.DecodeEvent(message, &genre); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if err := handler.db.AddGenre(genre); err != nil {
+		if err.(general.DBError).ErrorCode != general.DuplicateEntry {
+			handler.Logger.Printf("[ERROR] Failed to add new genre %v to DB: %s\n", genre.Name, err)
+			return err
+		}
+		handler.Logger.Printf("Adding genre %v results in duplicate error.\n", genre.Name)
+	}
+	handler.Logger.Printf("Succesfully added new genre %v\n", genre.Name)
+	return nil
+}
+
+// ConsumeTagSong consumes a message and tags a song with a genre. A missing genre will be added as well
+func (handler *LikesHandler) ConsumeTagSong(message []byte) error {
+	var tag general.GenreTag
+	if _, err := general.DecodeEvent(message, &tag); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if err := handler.db.AddGenreToSong(tag); err != nil {
+		switch err.(general.DBError).ErrorCode {
+		case general.MissingForeignKey:
+			addGenreErr := handler.db.AddGenre(tag.Genre)
+			if addGenreErr != nil && addGenreErr.(general.DBError).ErrorCode != general.DuplicateEntry {
+				handler.Logger.Printf("Failed to tag song #%v with genre %v due to failure of adding genre: %s\n", tag.SongID, tag.Genre.Name, addGenreErr)
+				return addGenreErr
+			}
+			if errSecondTry := handler.db.AddGenreToSong(tag); errSecondTry != nil {
+				handler.Logger.Printf("Failed to tag song #%v with genre %v due to second failure: %s\n", tag.SongID, tag.Genre.Name, errSecondTry)
+				return errSecondTry
+			}
+		case general.DuplicateEntry:
+			handler.Logger.Printf("Tagging song #%v with genre %v results in duplicate error.\n", tag.SongID, tag.Genre.Name)
+			return nil
+		default:
+			handler.Logger.Printf("[ERROR] Failed to tag song #%v with genre %v: %s\n", tag.SongID, tag.Genre.Name, err)
+			return err
+		}
+	}
+	handler.Logger.Printf("Succesfully tagged song #%v with genre %v\n", tag.SongID, tag.Genre.Name)
+	return nil
+}
+
+// ConsumeUntagSong consumes a message and removes a genre from a song
+func (handler *LikesHandler) ConsumeUntagSong(message []byte) error {
+	var tag general.GenreTag
+	if _, err := general.DecodeEvent(message, &tag); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if err := handler.db.RemoveGenreFromSong(tag); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to remove genre %v from song #%v: %s\n", tag.Genre.Name, tag.SongID, err)
+		return err
+	}
+	handler.Logger.Printf("Succesfully removed genre %v from song #%v\n", tag.Genre.Name, tag.SongID)
+	return nil
 }
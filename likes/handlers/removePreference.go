@@ -22,6 +22,7 @@ func (handler *LikesHandler) RemoveLike(response http.ResponseWriter, request *h
 		general.SendError(response, http.StatusInternalServerError)
 		return
 	}
+	handler.sendPreferenceChange(general.NewPreferenceChange(user.ID, preference.ID, "like", true))
 	response.WriteHeader(http.StatusOK)
 	response.Write([]byte(http.StatusText(http.StatusOK)))
 }
@@ -43,6 +44,7 @@ func (handler *LikesHandler) RemoveDislike(response http.ResponseWriter, request
 		general.SendError(response, http.StatusInternalServerError)
 		return
 	}
+	handler.sendPreferenceChange(general.NewPreferenceChange(user.ID, preference.ID, "dislike", true))
 	response.WriteHeader(http.StatusOK)
 	response.Write([]byte(http.StatusText(http.StatusOK)))
 }
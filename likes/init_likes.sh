@@ -10,13 +10,26 @@ CREATE TABLE IF NOT EXISTS users (id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, us
 CREATE TABLE IF NOT EXISTS artists (id INT NOT NULL PRIMARY KEY, name_artist VARCHAR(64) NOT NULL, prefix VARCHAR(7), UNIQUE(name_artist));
 CREATE TABLE IF NOT EXISTS songs (id INT NOT NULL PRIMARY KEY, name_song VARCHAR(64) NOT NULL);
 CREATE TABLE IF NOT EXISTS discography (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, artist_id INT NOT NULL, FOREIGN KEY (artist_id) REFERENCES artists (id) ON UPDATE CASCADE ON DELETE CASCADE, song_id INT NOT NULL, FOREIGN KEY (song_id) REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE);
+CREATE TABLE IF NOT EXISTS albums (id INT NOT NULL PRIMARY KEY, name_album VARCHAR(64) NOT NULL, artist_id INT NOT NULL, FOREIGN KEY (artist_id) REFERENCES artists (id) ON UPDATE CASCADE ON DELETE CASCADE);
+CREATE TABLE IF NOT EXISTS album_track_listing (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, album_id INT NOT NULL, FOREIGN KEY (album_id) REFERENCES albums (id) ON UPDATE CASCADE ON DELETE CASCADE, song_id INT NOT NULL, FOREIGN KEY (song_id) REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE, track_number INT NOT NULL, UNIQUE(album_id, song_id), UNIQUE(album_id, track_number));
+CREATE TABLE IF NOT EXISTS genres (id INT NOT NULL PRIMARY KEY, name_genre VARCHAR(64) NOT NULL, UNIQUE(name_genre));
+CREATE TABLE IF NOT EXISTS song_genre (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, song_id INT NOT NULL, FOREIGN KEY (song_id) REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE, genre_id INT NOT NULL, FOREIGN KEY (genre_id) REFERENCES genres (id) ON UPDATE CASCADE ON DELETE CASCADE, UNIQUE(song_id, genre_id));
 CREATE TABLE IF NOT EXISTS liked_songs (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, user_id INT NOT NULL, FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE, song_id INT NOT NULL, FOREIGN KEY (song_id) REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE, UNIQUE(user_id, song_id));
 CREATE TABLE IF NOT EXISTS disliked_songs (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, user_id INT NOT NULL, FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE, song_id INT NOT NULL, FOREIGN KEY (song_id) REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE, UNIQUE(user_id, song_id));
+CREATE TABLE IF NOT EXISTS followed_artists (id INT NOT NULL AUTO_INCREMENT PRIMARY KEY, user_id INT NOT NULL, FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE, artist_id INT NOT NULL, FOREIGN KEY (artist_id) REFERENCES artists (id) ON UPDATE CASCADE ON DELETE CASCADE, UNIQUE(user_id, artist_id));
+-- Migration of databases that were created before track numbers were unique per album, it is skipped if the schema is up to date
+SET @migration = IF(EXISTS(SELECT * FROM information_schema.statistics WHERE table_schema=DATABASE() AND table_name='album_track_listing' AND column_name='track_number' AND non_unique=0), 'DO 0', 'ALTER TABLE album_track_listing ADD UNIQUE(album_id, track_number)');
+PREPARE migration FROM @migration; EXECUTE migration; DEALLOCATE PREPARE migration;
 CREATE USER IF NOT EXISTS likesMusicApp IDENTIFIED BY 'likelikes';
-GRANT SELECT, INSERT ON pref_likes.users TO likesMusicApp;
+GRANT SELECT, INSERT, DELETE ON pref_likes.users TO likesMusicApp;
 GRANT SELECT, INSERT ON pref_likes.artists TO likesMusicApp;
 GRANT SELECT, INSERT ON pref_likes.songs TO likesMusicApp;
 GRANT SELECT, INSERT ON pref_likes.discography TO likesMusicApp;
+GRANT SELECT, INSERT, DELETE ON pref_likes.albums TO likesMusicApp;
+GRANT SELECT, INSERT ON pref_likes.album_track_listing TO likesMusicApp;
+GRANT SELECT, INSERT ON pref_likes.genres TO likesMusicApp;
+GRANT SELECT, INSERT, DELETE ON pref_likes.song_genre TO likesMusicApp;
 GRANT SELECT, INSERT, DELETE ON pref_likes.liked_songs TO likesMusicApp;
 GRANT SELECT, INSERT, DELETE ON pref_likes.disliked_songs TO likesMusicApp;
+GRANT SELECT, INSERT, DELETE ON pref_likes.followed_artists TO likesMusicApp;
 EOF
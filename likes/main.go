@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"general"
+	"general/config"
 	"likes/database"
 	"likes/handlers"
 	"log"
@@ -13,22 +15,33 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-const port string = ":9004"
-const servername string = "likes"
+var configPath = flag.String("config", "config.yaml", "Path to the configuration file of this service")
 
 func main() {
-	logger := log.New(os.Stdout, servername, log.LstdFlags|log.Lshortfile)
-	db, err := general.ConnectToMYSQL(logger, servername, "likesMusicApp:likelikes@tcp(127.0.0.1:3306)/pref_likes")
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[ERROR] Can't load configuration due to: %s\n", err)
+	}
+	logger := log.New(os.Stdout, cfg.Servername, log.LstdFlags|log.Lshortfile)
+	if err = general.ConfigureKeys(logger, cfg.Keys); err != nil {
+		logger.Fatalf("[ERROR] Can't load keys due to: %s\n", err)
+	}
+	go general.WatchKeys(logger, *configPath)
+	db, err := general.ConnectToMYSQL(logger, cfg)
 	if err != nil {
 		logger.Printf("Stop starting server")
 		return
 	}
 	defer db.Close()
-	broker, closeBroker := general.ConnectToKafka(logger, servername)
-	defer closeBroker()
+	bus, closeBus := general.ConnectToBus(logger, cfg)
+	defer closeBus()
+	if topicErr := bus.CreateTopics("changePreference"); topicErr != nil {
+		logger.Fatalf("[ERROR] Failed to create topics due to: %s\n", topicErr)
+	}
 	logger.Printf("Handler is ready for sending get requests")
-	handler := handlers.NewLikesHandler(logger, database.NewLikesDB(db), nil)
-	_, startServer := handlers.NewLikesServer(handler, broker, servername, port)
+	handler := handlers.NewLikesHandler(logger, database.NewLikesDB(db), bus.Publish, nil)
+	_, startServer := handlers.NewLikesServer(handler, bus, cfg)
 	startServer()
 }
 
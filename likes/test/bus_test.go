@@ -0,0 +1,153 @@
+package test
+
+import (
+	"general"
+	"general/config"
+	"likes/handlers"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestConsumers_memoryBus(t *testing.T) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	cases := map[string]struct {
+		topic               string
+		payload             interface{}
+		expectedUser        int
+		expectedSong        int
+		expectedDeadLetters int
+	}{
+		"New user is saved":                    {"newUser", general.NewCredentials(1, "Test", "user"), 1, 0, 0},
+		"New song is saved":                    {"newSong", general.NewSong(11, []general.Artist{artist}, "In Too Deep"), 0, 11, 0},
+		"Song without artist is dead-lettered": {"newSong", general.NewSong(12, []general.Artist{}, "Fat Lip"), 0, 0, 1},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler := testLikesHandler(db, nil)
+		bus := general.NewMemoryBus()
+		consumers := general.NewConsumerGroup(bus, general.TestEmptyLogger(), "likes_test")
+		handler.StartConsuming(consumers)
+		if err := general.SendEvent(bus.Publish, test.topic, test.payload); err != nil {
+			t.Errorf("%v: Can't publish %v due to: %s\n", name, test.payload, err)
+			continue
+		}
+		if !waitForCommit(bus, "likes_test", test.topic, 1) {
+			t.Errorf("%v: Expects the message to be consumed within three seconds\n", name)
+			continue
+		}
+		// The consumers don't touch the database after the message is committed
+		if _, ok := db.users[test.expectedUser]; test.expectedUser != 0 && !ok {
+			t.Errorf("%v: Expects user #%v to be saved\n", name, test.expectedUser)
+		}
+		if _, ok := db.songs[test.expectedSong]; test.expectedSong != 0 && !ok {
+			t.Errorf("%v: Expects song #%v to be saved\n", name, test.expectedSong)
+		}
+		letters, err := consumers.DeadLetters(test.topic)
+		if err != nil {
+			t.Errorf("%v: Can't read dead letters due to: %s\n", name, err)
+			continue
+		}
+		if len(letters) != test.expectedDeadLetters {
+			t.Errorf("%v: Expects %v dead letters but got %v\n", name, test.expectedDeadLetters, letters)
+		}
+	}
+}
+
+func TestServers_sharedMemoryBus(t *testing.T) {
+	shared := general.NewMemoryBus()
+	registrations, err := shared.Subscribe("newService", 0, general.OffsetOldest)
+	if err != nil {
+		t.Fatalf("Can't subscribe to registrations: %s\n", err)
+	}
+	// startService connects the service to the shared bus and starts the server that newServer returns in the background
+	startService := func(name string, newServer func(general.Bus, config.Config) func()) general.Bus {
+		cfg := config.Config{Servername: name, Port: "127.0.0.1:0", Bus: config.BusMemory}
+		bus, _ := general.ConnectToSharedBus(general.TestEmptyLogger(), cfg, shared)
+		go newServer(bus, cfg)()
+		return bus
+	}
+	// The producers need a database for their outbox, so they publish their events like their outbox relays do
+	newProducer := func(bus general.Bus, cfg config.Config) func() {
+		_, channel, start := general.NewServer(cfg, mux.NewRouter(), bus, nil, general.TestEmptyLogger())
+		go func() {
+			for range channel {
+			}
+		}()
+		return start
+	}
+	db := newTestDB()
+	startService("likes", func(bus general.Bus, cfg config.Config) func() {
+		_, start := handlers.NewLikesServer(testLikesHandler(db, nil), bus, cfg)
+		return start
+	})
+	discography := startService("discography", newProducer)
+	users := startService("users", newProducer)
+
+	if err = general.SendEvent(users.Publish, "newUser", general.NewCredentials(1, "Test", "user")); err != nil {
+		t.Fatalf("Can't publish new user: %s\n", err)
+	}
+	song := general.NewSong(11, []general.Artist{general.NewArtist(1, "Sum 41", "")}, "In Too Deep")
+	if err = general.SendEvent(discography.Publish, "newSong", song); err != nil {
+		t.Fatalf("Can't publish new song: %s\n", err)
+	}
+	for _, topic := range []string{"newUser", "newSong"} {
+		if !waitForCommit(shared, "likes", topic, 1) {
+			t.Errorf("Expects likes to consume %v within three seconds\n", topic)
+		}
+	}
+	if _, ok := db.users[1]; !ok {
+		t.Errorf("Expects the user of the users service to be saved by likes\n")
+	}
+	if _, ok := db.songs[11]; !ok {
+		t.Errorf("Expects the song of the discography service to be saved by likes\n")
+	}
+	// Every server registers itself on the shared bus
+	registered := make(map[string]bool)
+	for deadline := time.Now().Add(time.Second); len(registered) < 3 && time.Now().Before(deadline); {
+		msg, err := registrations.Next()
+		if err != nil {
+			continue
+		}
+		var service general.Service
+		if event, err := general.DecodeEvent(msg.Value, &service); err == nil {
+			registered[service.Name] = event.Producer == service.Name
+		}
+	}
+	for _, name := range []string{"likes", "discography", "users"} {
+		if !registered[name] {
+			t.Errorf("Expects %v to register itself on the shared bus as producer of the registration\n", name)
+		}
+	}
+	// Every service publishes its events on behalf of itself, although the services share the bus
+	for topic, expected := range map[string]string{"newUser": "users", "newSong": "discography"} {
+		subscription, err := shared.Subscribe(topic, 0, general.OffsetOldest)
+		if err != nil {
+			t.Fatalf("Can't subscribe to %v: %s\n", topic, err)
+		}
+		msg, err := subscription.Next()
+		if err != nil {
+			t.Errorf("Expects a message on %v but got: %s\n", topic, err)
+			continue
+		}
+		if event, err := general.DecodeEvent(msg.Value, &struct{}{}); err != nil || event.Producer != expected {
+			t.Errorf("Expects event on %v with producer %v but got: %+v, %v\n", topic, expected, event, err)
+		}
+	}
+}
+
+// waitForCommit returns true when the group committed the given offset of the topic within three seconds.
+// A new member of a consumer group waits for the other members before it consumes, which takes up to a second.
+func waitForCommit(bus general.Bus, group, topic string, offset int64) bool {
+	offsets, err := bus.Offsets(group)
+	if err != nil {
+		return false
+	}
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if committed, err := offsets.Offset(topic, 0); err == nil && committed >= offset {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,8 @@ package test
 
 import (
 	"general"
+	"general/config"
+	"likes/handlers"
 	"net/http"
 	"testing"
 	"time"
@@ -144,3 +146,89 @@ func TestChangeHandlers_savingInDB(t *testing.T) {
 		}
 	}
 }
+
+func TestChangeHandlers_sendMessage(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	artist := general.NewArtist(1, "Sum 41", "")
+	likedSong := general.NewSong(11, []general.Artist{artist}, "In Too Deep")
+	otherSong := general.NewSong(12, []general.Artist{artist}, "Fat Lip")
+	cases := map[string]struct {
+		method, path       string
+		body               interface{}
+		expectedFoundTopic bool
+		expectedChange     general.PreferenceChange
+	}{
+		"AddLike: New like sends change":            {http.MethodPost, "/api/like", general.NewPreference(otherSong.ID, "artist"), true, general.NewPreferenceChange(user.ID, otherSong.ID, "like", false)},
+		"AddLike: Existing like sends no change":    {http.MethodPost, "/api/like", general.NewPreference(likedSong.ID, "artist"), false, general.PreferenceChange{}},
+		"AddLike: Invalid request sends no change":  {http.MethodPost, "/api/like", nil, false, general.PreferenceChange{}},
+		"AddDislike: New dislike sends change":      {http.MethodPost, "/api/dislike", general.NewPreference(likedSong.ID, "artist"), true, general.NewPreferenceChange(user.ID, likedSong.ID, "dislike", false)},
+		"RemoveLike: Removing like sends change":    {http.MethodDelete, "/api/like", general.NewPreference(likedSong.ID, "artist"), true, general.NewPreferenceChange(user.ID, likedSong.ID, "like", true)},
+		"RemoveDislike: Removing sends change":      {http.MethodDelete, "/api/dislike", general.NewPreference(otherSong.ID, "artist"), true, general.NewPreferenceChange(user.ID, otherSong.ID, "dislike", true)},
+		"RemoveDislike: Invalid request sends none": {http.MethodDelete, "/api/dislike", nil, false, general.PreferenceChange{}},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(user); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", user.Username, err)
+		}
+		db.addPreferencesToTestDB(t, user.ID, []general.Song{likedSong}, db.AddLike)
+		db.addSongsToTestDB(t, []general.Song{otherSong})
+		handler, channel := testLikesHandlerWithMessages(db, nil)
+		server, _ := handlers.NewLikesServer(handler, nil, config.Config{Servername: "likes_test"})
+		token, err := general.CreateToken(user.ID, user.Username, user.Role)
+		if err != nil {
+			t.Fatalf("%v: Failed to create token: %s\n", name, err)
+		}
+		general.TestRequest(t, server, test.method, test.path, token, test.body)
+		go func() {
+			time.Sleep(time.Millisecond)
+			close(channel)
+		}()
+		foundTopic := false
+		for message := range channel {
+			if message.Topic != "changePreference" {
+				t.Errorf("%v: Expects no other topic than changePreference but got topic: %v\n", name, message.Topic)
+				continue
+			}
+			foundTopic = true
+			var result general.PreferenceChange
+			if _, err := general.DecodeEvent(message.Message, &result); err != nil {
+				t.Errorf("%v: Expects to send a message containing a change but deserializing results in: %v\n", name, err)
+				continue
+			}
+			if result != test.expectedChange {
+				t.Errorf("%v: Expects message %v but got: %v\n", name, test.expectedChange, result)
+			}
+		}
+		if foundTopic != test.expectedFoundTopic {
+			t.Errorf("%v: Expects to find topic changePreference: %v but got: %v\n", name, test.expectedFoundTopic, foundTopic)
+		}
+	}
+}
+
+func TestChangeHandlers_discographyUnavailable(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	cases := map[string]struct {
+		method, path string
+	}{
+		"AddLike: Add a like for a missing song":       {http.MethodPost, "/api/like"},
+		"AddDislike: Add a dislike for a missing song": {http.MethodPost, "/api/dislike"},
+		"FollowArtist: Follow a missing artist":        {http.MethodPost, "/api/follow"},
+	}
+	unavailable := func(string) (*http.Response, error) {
+		return nil, general.ErrCircuitOpen
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(user); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", user.Username, err)
+		}
+		sendMessage, _ := general.TestSendMessage()
+		server, _ := handlers.NewLikesServer(handlers.NewLikesHandler(general.TestEmptyLogger(), db, sendMessage, unavailable), nil, config.Config{Servername: "likes_test"})
+		token, _ := general.CreateToken(user.ID, user.Username, user.Role)
+		response := general.TestRequest(t, server, test.method, test.path, token, general.NewPreference(41, "artist"))
+		if response.Code != http.StatusInternalServerError {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, http.StatusInternalServerError, response.Code)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"general"
+	"net/http"
+	"testing"
+)
+
+func TestFollowHandlers_statusCode(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	missingUser := general.NewCredentials(2, "Missing", "user")
+	artists := map[string]general.Artist{
+		"Sum 41":    general.NewArtist(1, "Sum 41", ""),
+		"Slipknot":  general.NewArtist(2, "Slipknot", ""),
+		"Disturbed": general.NewArtist(3, "Disturbed", ""),
+		"Nameless":  general.NewArtist(4, "", ""),
+	}
+	songs := []general.Song{general.NewSong(11, []general.Artist{artists["Sum 41"]}, "In Too Deep"), general.NewSong(12, []general.Artist{artists["Slipknot"]}, "Duality")}
+	missingSongs := []general.Song{general.NewSong(41, []general.Artist{artists["Disturbed"]}, "Down with the Sickness"), general.NewSong(42, []general.Artist{artists["Nameless"]}, "Untitled")}
+	cases := map[string]struct {
+		method             string
+		credentials        *general.Credentials
+		body               interface{}
+		expectedStatusCode int
+	}{
+		"FollowArtist: Follow a new artist":                      {http.MethodPost, &user, general.NewPreference(artists["Slipknot"].ID, "artist"), http.StatusOK},
+		"FollowArtist: Follow a followed artist":                 {http.MethodPost, &user, general.NewPreference(artists["Sum 41"].ID, "artist"), http.StatusOK},
+		"FollowArtist: Follow an artist for a missing user":      {http.MethodPost, &missingUser, general.NewPreference(artists["Slipknot"].ID, "artist"), http.StatusOK},
+		"FollowArtist: Follow a missing artist":                  {http.MethodPost, &user, general.NewPreference(artists["Disturbed"].ID, "artist"), http.StatusOK},
+		"FollowArtist: Follow a non-existing artist":             {http.MethodPost, &user, general.NewPreference(404, "artist"), http.StatusNotFound},
+		"FollowArtist: Follow an invalid missing artist":         {http.MethodPost, &user, general.NewPreference(artists["Nameless"].ID, "artist"), http.StatusInternalServerError},
+		"FollowArtist: Sending no token":                         {http.MethodPost, nil, general.NewPreference(artists["Slipknot"].ID, "artist"), http.StatusUnauthorized},
+		"FollowArtist: Sending no artistID":                      {http.MethodPost, &user, general.NewPreference(0, "artist"), http.StatusBadRequest},
+		"FollowArtist: Sending no body":                          {http.MethodPost, &user, nil, http.StatusBadRequest},
+		"UnfollowArtist: Unfollow a followed artist":             {http.MethodDelete, &user, general.NewPreference(artists["Sum 41"].ID, "artist"), http.StatusOK},
+		"UnfollowArtist: Unfollow an artist that isn't followed": {http.MethodDelete, &user, general.NewPreference(artists["Slipknot"].ID, "artist"), http.StatusOK},
+		"UnfollowArtist: Unfollow for a missing user":            {http.MethodDelete, &missingUser, general.NewPreference(artists["Sum 41"].ID, "artist"), http.StatusOK},
+		"UnfollowArtist: Sending no token":                       {http.MethodDelete, nil, general.NewPreference(artists["Sum 41"].ID, "artist"), http.StatusUnauthorized},
+		"UnfollowArtist: Sending no artistID":                    {http.MethodDelete, &user, general.NewPreference(0, "artist"), http.StatusBadRequest},
+		"UnfollowArtist: Sending no body":                        {http.MethodDelete, &user, nil, http.StatusBadRequest},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(user); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", user.Username, err)
+		}
+		db.addSongsToTestDB(t, songs)
+		if err := db.FollowArtist(user.ID, artists["Sum 41"].ID); err != nil {
+			t.Fatalf("Failed to start test due to failure of following artist: %s\n", err)
+		}
+		server := testServer(db, addDBToArray(missingSongs, db))
+		token := ""
+		if test.credentials != nil {
+			var err error
+			token, err = general.CreateToken(test.credentials.ID, test.credentials.Username, test.credentials.Role)
+			if err != nil {
+				t.Errorf("%v: Failed to send token with request: %s\n", name, err)
+				continue
+			}
+		}
+		response := general.TestRequest(t, server, test.method, "/api/follow", token, test.body)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+	}
+}
+
+func TestFollowHandlers_savingInDB(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	missingUser := general.NewCredentials(2, "Missing", "user")
+	artists := map[string]general.Artist{
+		"Sum 41":    general.NewArtist(1, "Sum 41", ""),
+		"Slipknot":  general.NewArtist(2, "Slipknot", ""),
+		"Disturbed": general.NewArtist(3, "Disturbed", ""),
+	}
+	songs := []general.Song{general.NewSong(11, []general.Artist{artists["Sum 41"]}, "In Too Deep"), general.NewSong(12, []general.Artist{artists["Slipknot"]}, "Duality")}
+	missingSongs := []general.Song{general.NewSong(41, []general.Artist{artists["Disturbed"]}, "Down with the Sickness")}
+	cases := map[string]struct {
+		method           string
+		credentials      *general.Credentials
+		artistID         int
+		expectedFollowed bool
+	}{
+		"FollowArtist: Follow a new artist":                      {http.MethodPost, &user, artists["Slipknot"].ID, true},
+		"FollowArtist: Follow an artist for a missing user":      {http.MethodPost, &missingUser, artists["Slipknot"].ID, true},
+		"FollowArtist: Follow a missing artist":                  {http.MethodPost, &user, artists["Disturbed"].ID, true},
+		"UnfollowArtist: Unfollow a followed artist":             {http.MethodDelete, &user, artists["Sum 41"].ID, false},
+		"UnfollowArtist: Unfollow an artist that isn't followed": {http.MethodDelete, &user, artists["Slipknot"].ID, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(user); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", user.Username, err)
+		}
+		db.addSongsToTestDB(t, songs)
+		if err := db.FollowArtist(user.ID, artists["Sum 41"].ID); err != nil {
+			t.Fatalf("Failed to start test due to failure of following artist: %s\n", err)
+		}
+		server := testServer(db, addDBToArray(missingSongs, db))
+		token, err := general.CreateToken(test.credentials.ID, test.credentials.Username, test.credentials.Role)
+		if err != nil {
+			t.Errorf("%v: Failed to send token with request: %s\n", name, err)
+			continue
+		}
+		response := general.TestRequest(t, server, test.method, "/api/follow", token, general.NewPreference(test.artistID, "artist"))
+		if response.Code != http.StatusOK {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, http.StatusOK, response.Code)
+			continue
+		}
+		if _, ok := db.follows[test.credentials.ID][test.artistID]; ok != test.expectedFollowed {
+			t.Errorf("%v: Expects artist #%v to be followed: %v but got: %v\n", name, test.artistID, test.expectedFollowed, ok)
+		}
+	}
+}
+
+func TestGetFollowedArtists_response(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	userWithNoFollows := general.NewCredentials(2, "NoFollows", "user")
+	artists := []general.Artist{general.NewArtist(1, "Sum 41", ""), general.NewArtist(2, "Slipknot", ""), general.NewArtist(3, "ZZ Top", ""), general.NewArtist(4, "Disturbed", "")}
+	cases := map[string]struct {
+		path                  string
+		credentials           *general.Credentials
+		expectedStatusCode    int
+		expectedAmountResults int
+		expectedHasNext       bool
+	}{
+		"User follows artists":                                 {"/api/follow", &user, http.StatusOK, 4, false},
+		"User follows no artists":                              {"/api/follow", &userWithNoFollows, http.StatusNotFound, 0, false},
+		"No token is given":                                    {"/api/follow", nil, http.StatusUnauthorized, 0, false},
+		"Offset is bigger than amount of results":              {"/api/follow?offset=100", &user, http.StatusNotFound, 0, false},
+		"Skip results given by offset":                         {"/api/follow?offset=1", &user, http.StatusOK, 3, false},
+		"Amount is capped by max":                              {"/api/follow?max=3", &user, http.StatusOK, 3, true},
+		"Sum offset and max is smaller than amount of results": {"/api/follow?offset=1&max=2", &user, http.StatusOK, 2, true},
+		"Sum offset and max is equal to amount of results":     {"/api/follow?offset=2&max=2", &user, http.StatusOK, 2, false},
+		"Sum offset and max is bigger than amount of results":  {"/api/follow?offset=2&max=3", &user, http.StatusOK, 2, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(user); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", user.Username, err)
+		}
+		if err := db.AddUser(userWithNoFollows); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", userWithNoFollows.Username, err)
+		}
+		for _, artist := range artists {
+			db.AddArtist(artist)
+			if err := db.FollowArtist(user.ID, artist.ID); err != nil {
+				t.Fatalf("Failed to start test due to failure of following artist %v: %s\n", artist.Name, err)
+			}
+		}
+		server := testServer(db, nil)
+		token := ""
+		if test.credentials != nil {
+			var err error
+			token, err = general.CreateToken(test.credentials.ID, test.credentials.Username, test.credentials.Role)
+			if err != nil {
+				t.Errorf("%v: Failed to send token with request: %s\n", name, err)
+				continue
+			}
+		}
+		response := general.TestRequest(t, server, http.MethodGet, test.path, token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var results general.MultipleArtists
+		if err := general.ReadFromJSON(&results, response.Body); err != nil {
+			t.Errorf("%v: Failed to deserialize results: %s\n", name, err)
+			continue
+		}
+		if len(results.Data) != test.expectedAmountResults {
+			t.Errorf("%v: Expects %v results but got: %v\n", name, test.expectedAmountResults, len(results.Data))
+		}
+		if results.HasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects hasNext to be %v but got: %v\n", name, test.expectedHasNext, results.HasNext)
+		}
+	}
+}
+
+func TestGetFollowersOfArtist_response(t *testing.T) {
+	internal, err := general.CreateTokenInternalRequests("testServer")
+	if err != nil {
+		t.Fatalf("Failed to create internal token: %s\n", err)
+	}
+	userToken, err := general.CreateToken(1, "Test", "user")
+	if err != nil {
+		t.Fatalf("Failed to create user token: %s\n", err)
+	}
+	users := []general.Credentials{general.NewCredentials(1, "Test", "user"), general.NewCredentials(2, "Other", "user"), general.NewCredentials(3, "NoFollows", "user")}
+	artists := []general.Artist{general.NewArtist(1, "Sum 41", ""), general.NewArtist(2, "Slipknot", ""), general.NewArtist(3, "ZZ Top", "")}
+	cases := map[string]struct {
+		path, token        string
+		expectedStatusCode int
+		expectedFollowers  []int
+	}{
+		"Artist with multiple followers": {"/intern/followers/1", internal, http.StatusOK, []int{1, 2}},
+		"Artist with a single follower":  {"/intern/followers/2", internal, http.StatusOK, []int{2}},
+		"Artist without followers":       {"/intern/followers/3", internal, http.StatusOK, []int{}},
+		"Non-existing artist":            {"/intern/followers/404", internal, http.StatusOK, []int{}},
+		"Invalid artist id":              {"/intern/followers/Sum%2041", internal, http.StatusBadRequest, nil},
+		"User token is not authorized":   {"/intern/followers/1", userToken, http.StatusUnauthorized, nil},
+		"No token is send":               {"/intern/followers/1", "", http.StatusUnauthorized, nil},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		for _, user := range users {
+			db.AddUser(user)
+		}
+		for _, artist := range artists {
+			db.AddArtist(artist)
+		}
+		db.FollowArtist(1, 1)
+		db.FollowArtist(2, 1)
+		db.FollowArtist(2, 2)
+		server := testServer(db, nil)
+		response := general.TestRequest(t, server, http.MethodGet, test.path, test.token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var followers []int
+		if err := general.ReadFromJSONNoValidation(&followers, response.Body); err != nil {
+			t.Errorf("%v: Failed to deserialize results: %s\n", name, err)
+			continue
+		}
+		if len(followers) != len(test.expectedFollowers) {
+			t.Errorf("%v: Expects followers %v but got: %v\n", name, test.expectedFollowers, followers)
+			continue
+		}
+		for index, follower := range followers {
+			if follower != test.expectedFollowers[index] {
+				t.Errorf("%v: Expects followers %v but got: %v\n", name, test.expectedFollowers, followers)
+				break
+			}
+		}
+	}
+}
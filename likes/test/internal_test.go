@@ -1,6 +1,7 @@
 package test
 
 import (
+	"fmt"
 	"general"
 	"net/http"
 	"testing"
@@ -43,7 +44,7 @@ func TestInternal_response(t *testing.T) {
 		"GetPreferenceOfArtist: Likes gets the like tag":               {"/intern/preference/1/Sum%2041", internal, 11, http.StatusOK, "like"},
 		"GetPreferenceOfArtist: Dislikes gets the dislike tag":         {"/intern/preference/1/ZZ%20Top", internal, 21, http.StatusOK, "dislike"},
 		"GetPreferenceOfArtist: Non-preference songs are excluded":     {"/intern/preference/1/Sum%2041", internal, 404, http.StatusOK, ""},
-		"GetPreferenceOfArtist: Songs from other artists are excluded": {"/intern/preference/1/Disturbed", internal, 11, http.StatusOK, ""},
+		"GetPreferenceOfArtist: Songs from other artists are excluded": {"/intern/preference/1/Disturbed", internal, 11, http.StatusNotFound, ""},
 	}
 	for name, test := range cases {
 		db := newTestDB()
@@ -71,3 +72,60 @@ func TestInternal_response(t *testing.T) {
 		}
 	}
 }
+
+func TestExportPreferences_response(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	internal, err := general.CreateTokenInternalRequests("testServer")
+	if err != nil {
+		t.Fatalf("Failed to create internal token: %s\n", err)
+	}
+	userToken, err := general.CreateToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		t.Fatalf("Failed to create user token: %s\n", err)
+	}
+	artist := general.NewArtist(1, "Sum 41", "")
+	// More likes than a single batch ensures that all batches are exported
+	likedSongs := make([]general.Song, 0, 150)
+	for i := 1; i <= 150; i++ {
+		likedSongs = append(likedSongs, general.NewSong(i, []general.Artist{artist}, fmt.Sprintf("Song %03d", i)))
+	}
+	dislikedSongs := []general.Song{general.NewSong(201, []general.Artist{artist}, "Fat Lip")}
+	cases := map[string]struct {
+		path, token                                              string
+		expectedStatusCode                                       int
+		expectedLikes, expectedDislikes, expectedFollowedArtists int
+	}{
+		"Valid token and existing user":            {"/intern/export/1", internal, http.StatusOK, 150, 1, 1},
+		"User without preferences":                 {"/intern/export/2", internal, http.StatusOK, 0, 0, 0},
+		"User token is not authorized":             {"/intern/export/1", userToken, http.StatusUnauthorized, 0, 0, 0},
+		"No token is send":                         {"/intern/export/1", "", http.StatusUnauthorized, 0, 0, 0},
+		"Invalid user id results in a bad request": {"/intern/export/test", internal, http.StatusBadRequest, 0, 0, 0},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(user); err != nil {
+			t.Fatalf("Failed to start test due to failure of adding user %v: %s\n", user.Username, err)
+		}
+		db.addPreferencesToTestDB(t, user.ID, likedSongs, db.AddLike)
+		db.addPreferencesToTestDB(t, user.ID, dislikedSongs, db.AddDislike)
+		if err := db.FollowArtist(user.ID, artist.ID); err != nil {
+			t.Fatalf("Failed to start test due to failure of following artist %v: %s\n", artist.Name, err)
+		}
+		server := testServer(db, addDBToArray(make([]general.Song, 0), db))
+		response := general.TestRequest(t, server, http.MethodGet, test.path, test.token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var preferences general.UserPreferences
+		if err := general.ReadFromJSONNoValidation(&preferences, response.Body); err != nil {
+			t.Errorf("[ERROR] %v: Decoding response: %s\n", name, err)
+			continue
+		}
+		if len(preferences.Likes) != test.expectedLikes || len(preferences.Dislikes) != test.expectedDislikes || len(preferences.FollowedArtists) != test.expectedFollowedArtists {
+			t.Errorf("%v: Expects %v likes, %v dislikes and %v followed artists but got: %v, %v and %v\n", name, test.expectedLikes, test.expectedDislikes, test.expectedFollowedArtists, len(preferences.Likes), len(preferences.Dislikes), len(preferences.FollowedArtists))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"general"
+	"likes/handlers"
 	"testing"
 )
 
@@ -20,7 +21,7 @@ func TestAddUser_saveInDB(t *testing.T) {
 		db := newTestDB()
 		handler := testLikesHandler(db, nil)
 		creds := general.NewCredentials(test.id, test.username, test.role)
-		credsString, err := general.ToJSONBytes(creds)
+		credsString, err := general.EncodeEvent("newUser", creds)
 		if err != nil {
 			t.Errorf("%v: Can't serialize %v due to: %s\n", name, creds, err)
 			continue
@@ -32,6 +33,57 @@ func TestAddUser_saveInDB(t *testing.T) {
 	}
 }
 
+func TestDeletedUser_removeFromDB(t *testing.T) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	songs := []general.Song{general.NewSong(11, []general.Artist{artist}, "In Too Deep"), general.NewSong(12, []general.Artist{artist}, "Fat Lip")}
+	cases := map[string]struct {
+		deletedUser        general.Credentials
+		expectedRemovedIDs []int
+	}{
+		"Deleted user is removed":           {general.NewCredentials(1, "Test", "user"), []int{1}},
+		"Other users are not removed":       {general.NewCredentials(2, "Other", "user"), []int{2}},
+		"Unknown user doesn't remove users": {general.NewCredentials(3, "Unknown", "user"), []int{}},
+		"Invalid message is ignored":        {general.NewCredentials(0, "", "user"), []int{}},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.addSongsToTestDB(t, songs)
+		for _, user := range []general.Credentials{general.NewCredentials(1, "Test", "user"), general.NewCredentials(2, "Other", "user")} {
+			if err := db.AddUser(user); err != nil {
+				t.Fatalf("Failed to add user %v: %s\n", user.Username, err)
+			}
+			if err := db.AddLike(user.ID, songs[0].ID); err != nil {
+				t.Fatalf("Failed to add like of user %v: %s\n", user.Username, err)
+			}
+			if err := db.AddDislike(user.ID, songs[1].ID); err != nil {
+				t.Fatalf("Failed to add dislike of user %v: %s\n", user.Username, err)
+			}
+		}
+		handler := testLikesHandler(db, nil)
+		message, err := general.EncodeEvent("userDeleted", test.deletedUser)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.deletedUser, err)
+			continue
+		}
+		handler.ConsumeDeletedUser(message)
+		removed := make(map[int]bool)
+		for _, id := range test.expectedRemovedIDs {
+			removed[id] = true
+		}
+		for _, id := range []int{1, 2} {
+			_, userInDB := db.users[id]
+			likes, _ := db.GetLikes(id, 0, 10)
+			dislikes, _ := db.GetDislikes(id, 0, 10)
+			if removed[id] && (userInDB || len(likes) != 0 || len(dislikes) != 0) {
+				t.Errorf("%v: Expects user #%v to be removed together with its preferences but got user: %v, likes: %v and dislikes: %v\n", name, id, userInDB, likes, dislikes)
+			}
+			if !removed[id] && (!userInDB || len(likes) != 1 || len(dislikes) != 1) {
+				t.Errorf("%v: Expects user #%v with its preferences to be kept but got user: %v, likes: %v and dislikes: %v\n", name, id, userInDB, likes, dislikes)
+			}
+		}
+	}
+}
+
 func TestAddArtist_saveInDB(t *testing.T) {
 	cases := map[string]struct {
 		id                int
@@ -47,7 +99,7 @@ func TestAddArtist_saveInDB(t *testing.T) {
 		db := newTestDB()
 		handler := testLikesHandler(db, nil)
 		artist := general.NewArtist(test.id, test.name, test.prefix)
-		artistString, err := general.ToJSONBytes(artist)
+		artistString, err := general.EncodeEvent("newArtist", artist)
 		if err != nil {
 			t.Errorf("%v: Can't serialize %v due to: %s\n", name, artist, err)
 			continue
@@ -77,14 +129,14 @@ func TestAddSong_saveInDB(t *testing.T) {
 	for name, test := range cases {
 		db := newTestDB()
 		handler := testLikesHandler(db, nil)
-		existingArtistString, err := general.ToJSONBytes(existingArtist)
+		existingArtistString, err := general.EncodeEvent("newArtist", existingArtist)
 		handler.ConsumeNewArtist(existingArtistString)
 		if err != nil {
 			t.Errorf("%v: Can't serialize %v due to: %s\n", name, existingArtist, err)
 			continue
 		}
 		song := general.NewSong(test.id, test.artists, test.name)
-		songString, err := general.ToJSONBytes(song)
+		songString, err := general.EncodeEvent("newSong", song)
 		if err != nil {
 			t.Errorf("%v: Can't serialize %v due to: %s\n", name, song, err)
 			continue
@@ -95,3 +147,197 @@ func TestAddSong_saveInDB(t *testing.T) {
 		}
 	}
 }
+
+func TestAddAlbum_saveInDB(t *testing.T) {
+	existingArtist := general.NewArtist(1, "Lost Frequencies", "")
+	existingSong := general.NewSong(11, []general.Artist{existingArtist}, "Reality")
+	newArtist := general.NewArtist(2, "Miike Snow", "")
+	cases := map[string]struct {
+		id                int
+		name              string
+		artist            general.Artist
+		songs             []general.Song
+		expectedSavedInDB bool
+	}{
+		"No valid id":                      {0, "Less Is More", existingArtist, []general.Song{existingSong}, false},
+		"No name":                          {1, "", existingArtist, []general.Song{existingSong}, false},
+		"No songs":                         {1, "Less Is More", existingArtist, []general.Song{}, false},
+		"Complete data with existing data": {1, "Less Is More", existingArtist, []general.Song{existingSong}, true},
+		"Complete data with new song":      {1, "Less Is More", existingArtist, []general.Song{existingSong, general.NewSong(12, []general.Artist{existingArtist}, "Are You With Me")}, true},
+		"Complete data with new artist":    {1, "Happy to You", newArtist, []general.Song{general.NewSong(21, []general.Artist{newArtist}, "Pretender")}, true},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler := testLikesHandler(db, nil)
+		db.addSongsToTestDB(t, []general.Song{existingSong})
+		album := general.NewAlbum(test.id, test.name, test.artist, test.songs)
+		albumString, err := general.EncodeEvent("newAlbum", album)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, album, err)
+			continue
+		}
+		handler.ConsumeNewAlbum(albumString)
+		if _, ok := db.albums[test.id]; ok != test.expectedSavedInDB {
+			t.Errorf("%v: Expects to be saved: %v but got %v\n", name, test.expectedSavedInDB, ok)
+		}
+		if !test.expectedSavedInDB {
+			continue
+		}
+		for _, song := range test.songs {
+			if _, ok := db.songs[song.ID]; !ok {
+				t.Errorf("%v: Expects song #%v of the album to be saved\n", name, song.ID)
+			}
+		}
+	}
+}
+
+func TestTagSong_saveInDB(t *testing.T) {
+	artist := general.NewArtist(1, "Foo Fighters", "")
+	song := general.NewSong(11, []general.Artist{artist}, "Everlong")
+	existingGenre, taggedGenre := general.NewGenre(1, "Rock"), general.NewGenre(2, "Alternative")
+	cases := map[string]struct {
+		consume        func(*handlers.LikesHandler, []byte) error
+		tag            general.GenreTag
+		expectedTagged bool
+	}{
+		"Tag song with existing genre":    {(*handlers.LikesHandler).ConsumeTagSong, general.NewGenreTag(song.ID, existingGenre), true},
+		"Tag song with new genre":         {(*handlers.LikesHandler).ConsumeTagSong, general.NewGenreTag(song.ID, general.NewGenre(3, "Grunge")), true},
+		"Tag song that doesn't exist":     {(*handlers.LikesHandler).ConsumeTagSong, general.NewGenreTag(404, existingGenre), false},
+		"Tag song without id":             {(*handlers.LikesHandler).ConsumeTagSong, general.NewGenreTag(0, existingGenre), false},
+		"Tag song that is already tagged": {(*handlers.LikesHandler).ConsumeTagSong, general.NewGenreTag(song.ID, taggedGenre), true},
+		"Remove tag from song":            {(*handlers.LikesHandler).ConsumeUntagSong, general.NewGenreTag(song.ID, taggedGenre), false},
+		"Remove tag that doesn't exist":   {(*handlers.LikesHandler).ConsumeUntagSong, general.NewGenreTag(song.ID, existingGenre), false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler := testLikesHandler(db, nil)
+		db.addSongsToTestDB(t, []general.Song{song})
+		for _, genre := range []general.Genre{existingGenre, taggedGenre} {
+			if err := db.AddGenre(genre); err != nil {
+				t.Fatalf("%v: Failed to add genre %v due to: %s\n", name, genre.Name, err)
+			}
+		}
+		if err := db.AddGenreToSong(general.NewGenreTag(song.ID, taggedGenre)); err != nil {
+			t.Fatalf("%v: Failed to tag song due to: %s\n", name, err)
+		}
+		tagString, err := general.ToJSONBytes(test.tag)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.tag, err)
+			continue
+		}
+		test.consume(handler, tagString)
+		if _, ok := db.tags[test.tag.SongID][test.tag.Genre.ID]; ok != test.expectedTagged {
+			t.Errorf("%v: Expects song #%v to be tagged with %v: %v but got %v\n", name, test.tag.SongID, test.tag.Genre.Name, test.expectedTagged, ok)
+		}
+	}
+}
+
+func TestConsumers_errors(t *testing.T) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	existingUser := general.NewCredentials(1, "Test", "user")
+	cases := map[string]struct {
+		consume           func(*handlers.LikesHandler, []byte) error
+		message           interface{}
+		expectedError     bool
+		expectedPermanent bool
+	}{
+		"Valid user":                      {(*handlers.LikesHandler).ConsumeNewUser, general.NewCredentials(2, "New", "user"), false, false},
+		"Duplicate user":                  {(*handlers.LikesHandler).ConsumeNewUser, existingUser, false, false},
+		"User without id":                 {(*handlers.LikesHandler).ConsumeNewUser, general.NewCredentials(0, "New", "user"), true, true},
+		"Invalid JSON for user":           {(*handlers.LikesHandler).ConsumeNewUser, "no user", true, true},
+		"Valid artist":                    {(*handlers.LikesHandler).ConsumeNewArtist, artist, false, false},
+		"Artist without name":             {(*handlers.LikesHandler).ConsumeNewArtist, general.NewArtist(2, "", ""), true, true},
+		"Song of new artist":              {(*handlers.LikesHandler).ConsumeNewSong, general.NewSong(11, []general.Artist{artist}, "In Too Deep"), false, false},
+		"Song without artists":            {(*handlers.LikesHandler).ConsumeNewSong, general.NewSong(12, []general.Artist{}, "Fat Lip"), true, true},
+		"Album without songs":             {(*handlers.LikesHandler).ConsumeNewAlbum, general.NewAlbum(1, "All Killer No Filler", artist, nil), true, true},
+		"Album with song without artists": {(*handlers.LikesHandler).ConsumeNewAlbum, general.NewAlbum(1, "All Killer No Filler", general.NewArtist(2, "Blink-182", ""), []general.Song{general.NewSong(21, []general.Artist{}, "Dammit")}), true, true},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if err := db.AddUser(existingUser); err != nil {
+			t.Fatalf("Failed to add user %v: %s\n", existingUser.Username, err)
+		}
+		handler := testLikesHandler(db, nil)
+		message, err := general.ToJSONBytes(test.message)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.message, err)
+			continue
+		}
+		err = test.consume(handler, message)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+			continue
+		}
+		if err != nil && general.IsPermanent(err) != test.expectedPermanent {
+			t.Errorf("%v: Expects permanent error: %v but got: %v\n", name, test.expectedPermanent, general.IsPermanent(err))
+		}
+	}
+}
+
+func TestConsumers_events(t *testing.T) {
+	artist := general.NewArtist(1, "Foo Fighters", "")
+	event, err := general.NewEvent("newArtist", artist)
+	if err != nil {
+		t.Fatalf("Can't create event: %s\n", err)
+	}
+	newerEvent := event
+	newerEvent.Version = general.EventSchemaVersion + 1
+	cases := map[string]struct {
+		message           interface{}
+		expectedSavedInDB bool
+	}{
+		"Event with artist":                  {event, true},
+		"Payload without envelope":           {artist, true},
+		"Event with a newer version":         {newerEvent, false},
+		"Event without payload is a payload": {general.Event{ID: "1", Type: "newArtist", Version: 1}, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler := testLikesHandler(db, nil)
+		message, err := general.ToJSONBytes(test.message)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.message, err)
+			continue
+		}
+		err = handler.ConsumeNewArtist(message)
+		if _, ok := db.artists[artist.Name]; ok != test.expectedSavedInDB {
+			t.Errorf("%v: Expects to be saved: %v but got %v\n", name, test.expectedSavedInDB, ok)
+		}
+		if (err == nil) != test.expectedSavedInDB {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, !test.expectedSavedInDB, err)
+		}
+	}
+}
+
+func TestEventDeduplicator(t *testing.T) {
+	first, _ := general.EncodeEvent("newUser", general.NewCredentials(1, "Test", "user"))
+	second, _ := general.EncodeEvent("newUser", general.NewCredentials(1, "Test", "user"))
+	legacy, _ := general.ToJSONBytes(general.NewCredentials(1, "Test", "user"))
+	cases := map[string]struct {
+		messages          [][]byte
+		failFirst         bool
+		expectedProcessed int
+	}{
+		"Same event is processed once":               {[][]byte{first, first}, false, 1},
+		"Different events are processed":             {[][]byte{first, second}, false, 2},
+		"Messages without envelope are processed":    {[][]byte{legacy, legacy}, false, 2},
+		"Failed event is processed again":            {[][]byte{first, first}, true, 2},
+		"Event after failed event is processed once": {[][]byte{first, first, first}, true, 2},
+	}
+	for name, test := range cases {
+		processed := 0
+		deduplicate := general.NewEventDeduplicator(10).Deduplicate(func([]byte) error {
+			processed++
+			if test.failFirst && processed == 1 {
+				return general.GetDBError("Failed", general.UnknownError)
+			}
+			return nil
+		})
+		for _, message := range test.messages {
+			deduplicate(message)
+		}
+		if processed != test.expectedProcessed {
+			t.Errorf("%v: Expects %v processed messages but got: %v\n", name, test.expectedProcessed, processed)
+		}
+	}
+}
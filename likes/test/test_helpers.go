@@ -3,6 +3,7 @@ package test
 import (
 	"fmt"
 	"general"
+	"general/config"
 	"io"
 	"likes/database"
 	"likes/handlers"
@@ -17,28 +18,46 @@ import (
 )
 
 func testServer(db database.Database, existingSongs []general.Song) *http.Server {
-	server, _ := handlers.NewLikesServer(testLikesHandler(db, existingSongs), nil, "likes_test", "")
+	server, _ := handlers.NewLikesServer(testLikesHandler(db, existingSongs), nil, config.Config{Servername: "likes_test"})
 	return server
 }
 
 func testLikesHandler(db database.Database, existingSongs []general.Song) *handlers.LikesHandler {
-	return handlers.NewLikesHandler(general.TestEmptyLogger(), db, testGetRequest(existingSongs))
+	handler, _ := testLikesHandlerWithMessages(db, existingSongs)
+	return handler
+}
+
+func testLikesHandlerWithMessages(db database.Database, existingSongs []general.Song) (*handlers.LikesHandler, chan general.Message) {
+	sendMessage, channel := general.TestSendMessage()
+	return handlers.NewLikesHandler(general.TestEmptyLogger(), db, sendMessage, testGetRequest(existingSongs)), channel
 }
 
 func testGetRequest(existingSongs []general.Song) func(string) (*http.Response, error) {
 	songDB := make(map[int]general.Song)
+	artistDB := make(map[int]general.Artist)
 	for _, song := range existingSongs {
 		songDB[song.ID] = song
+		for _, artist := range song.Artists {
+			artistDB[artist.ID] = artist
+		}
 	}
 	return func(address string) (*http.Response, error) {
 		indexLastSlash := strings.LastIndex(address, "/")
 		if indexLastSlash == -1 {
 			return convertMessageInResponse(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		}
-		songID, err := strconv.Atoi(address[indexLastSlash+1:])
+		id, err := strconv.Atoi(address[indexLastSlash+1:])
 		if err != nil {
 			return convertMessageInResponse(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		}
+		if strings.Contains(address, "/intern/artist/") {
+			artist, ok := artistDB[id]
+			if !ok {
+				return convertMessageInResponse(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			}
+			return convertMessageInResponse(http.StatusOK, artist)
+		}
+		songID := id
 		song, ok := songDB[songID]
 		if !ok {
 			return convertMessageInResponse(http.StatusNotFound, http.StatusText(http.StatusNotFound))
@@ -82,17 +101,25 @@ type testDB struct {
 	users    map[int]general.Credentials
 	artists  map[string]general.Artist
 	songs    map[int]general.Song
+	albums   map[int]general.Album
+	genres   map[int]general.Genre
+	tags     map[int]map[int]general.Genre
 	likes    map[int]map[int]general.Song
 	dislikes map[int]map[int]general.Song
+	follows  map[int]map[int]general.Artist
 }
 
 func newTestDB() testDB {
 	users := make(map[int]general.Credentials)
 	artists := make(map[string]general.Artist)
 	songs := make(map[int]general.Song)
+	albums := make(map[int]general.Album)
+	genres := make(map[int]general.Genre)
+	tags := make(map[int]map[int]general.Genre)
 	likes := make(map[int]map[int]general.Song)
 	dislikes := make(map[int]map[int]general.Song)
-	return testDB{users: users, artists: artists, songs: songs, likes: likes, dislikes: dislikes}
+	follows := make(map[int]map[int]general.Artist)
+	return testDB{users: users, artists: artists, songs: songs, albums: albums, genres: genres, tags: tags, likes: likes, dislikes: dislikes, follows: follows}
 }
 
 func (fake testDB) addPreferencesToTestDB(t *testing.T, userID int, songs []general.Song, prefFunction func(int, int) error) {
@@ -123,6 +150,15 @@ func (fake testDB) AddUser(user general.Credentials) error {
 	fake.users[user.ID] = user
 	fake.likes[user.ID] = make(map[int]general.Song)
 	fake.dislikes[user.ID] = make(map[int]general.Song)
+	fake.follows[user.ID] = make(map[int]general.Artist)
+	return nil
+}
+
+func (fake testDB) RemoveUser(userID int) error {
+	delete(fake.users, userID)
+	delete(fake.likes, userID)
+	delete(fake.dislikes, userID)
+	delete(fake.follows, userID)
 	return nil
 }
 
@@ -147,6 +183,52 @@ func (fake testDB) AddSong(song general.Song) error {
 	return nil
 
 }
+func (fake testDB) AddAlbum(album general.Album) error {
+	if _, ok := fake.artists[album.Artist.Name]; !ok {
+		return general.GetDBError("Missing foreign key", general.MissingForeignKey)
+	}
+	for _, song := range album.Songs {
+		if _, ok := fake.songs[song.ID]; !ok {
+			return general.GetDBError("Missing foreign key", general.MissingForeignKey)
+		}
+	}
+	if _, ok := fake.albums[album.ID]; ok {
+		return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+	}
+	fake.albums[album.ID] = album
+	return nil
+}
+
+func (fake testDB) AddGenre(genre general.Genre) error {
+	if _, ok := fake.genres[genre.ID]; ok {
+		return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+	}
+	fake.genres[genre.ID] = genre
+	return nil
+}
+
+func (fake testDB) AddGenreToSong(tag general.GenreTag) error {
+	if _, ok := fake.songs[tag.SongID]; !ok {
+		return general.GetDBError("Missing foreign key", general.MissingForeignKey)
+	}
+	if _, ok := fake.genres[tag.Genre.ID]; !ok {
+		return general.GetDBError("Missing foreign key", general.MissingForeignKey)
+	}
+	if _, ok := fake.tags[tag.SongID]; !ok {
+		fake.tags[tag.SongID] = make(map[int]general.Genre)
+	}
+	if _, ok := fake.tags[tag.SongID][tag.Genre.ID]; ok {
+		return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+	}
+	fake.tags[tag.SongID][tag.Genre.ID] = tag.Genre
+	return nil
+}
+
+func (fake testDB) RemoveGenreFromSong(tag general.GenreTag) error {
+	delete(fake.tags[tag.SongID], tag.Genre.ID)
+	return nil
+}
+
 func (fake testDB) AddLike(userID, songID int) error {
 	if _, ok := fake.users[userID]; !ok {
 		return general.GetDBError("Missing key", general.MissingForeignKey)
@@ -270,6 +352,60 @@ func (fake testDB) GetDislikesIDFromArtistName(logger *log.Logger, userID int, n
 	wg.Done()
 }
 
+func (fake testDB) findArtistByID(artistID int) (general.Artist, bool) {
+	for _, artist := range fake.artists {
+		if artist.ID == artistID {
+			return artist, true
+		}
+	}
+	return general.Artist{}, false
+}
+
+func (fake testDB) FollowArtist(userID, artistID int) error {
+	if _, ok := fake.users[userID]; !ok {
+		return general.GetDBError("Missing key", general.MissingForeignKey)
+	}
+	artist, ok := fake.findArtistByID(artistID)
+	if !ok {
+		return general.GetDBError("Missing key", general.MissingForeignKey)
+	}
+	if _, ok := fake.follows[userID][artistID]; ok {
+		return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+	}
+	fake.follows[userID][artistID] = artist
+	return nil
+}
+
+func (fake testDB) UnfollowArtist(userID, artistID int) error {
+	delete(fake.follows[userID], artistID)
+	return nil
+}
+
+func (fake testDB) GetFollowedArtists(userID, offset, max int) ([]general.Artist, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	followedArtists := make([]general.Artist, 0, len(fake.follows[userID]))
+	for _, artist := range fake.follows[userID] {
+		followedArtists = append(followedArtists, artist)
+	}
+	sort.SliceStable(followedArtists, func(i, j int) bool {
+		return followedArtists[i].Name < followedArtists[j].Name
+	})
+	return followedArtists[int(math.Min(float64(offset), float64(len(followedArtists)))):int(math.Min(float64(offset+max), float64(len(followedArtists))))], nil
+}
+
+func (fake testDB) GetFollowersOfArtist(artistID int) ([]int, error) {
+	followers := make([]int, 0)
+	for userID, followedArtists := range fake.follows {
+		if _, ok := followedArtists[artistID]; ok {
+			followers = append(followers, userID)
+		}
+	}
+	sort.Ints(followers)
+	return followers, nil
+}
+
 func seperatePrefix(name string) (artist, prefix string) {
 	if len(name) < 4 {
 		artist = name
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"database/sql"
+	"general"
+)
+
+// Database is an interface for the notifications database
+type Database interface {
+	AddNotifications(release general.Notification, userIDs []int) (map[int]int, error)
+	GetNotifications(userID, offset, max int, onlyUnread bool) ([]general.Notification, error)
+	MarkAsRead(userID, notificationID int) error
+	MarkAllAsRead(userID int) error
+}
+
+// NotificationsDB is a database
+type NotificationsDB struct {
+	database *sql.DB
+}
+
+// NewNotificationsDB returns a NotificationsDB
+func NewNotificationsDB(db *sql.DB) *NotificationsDB {
+	return &NotificationsDB{database: db}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"general"
+)
+
+// AddNotifications saves the release and adds a notification about this release for every given user.
+// Releases and notifications that are already saved will be ignored. It returns the ids of the notifications by user.
+func (db *NotificationsDB) AddNotifications(release general.Notification, userIDs []int) (map[int]int, error) {
+	if len(release.Artists) == 0 {
+		return nil, general.GetDBError("No artists is given for adding a release", general.InvalidInput)
+	}
+	_, err := db.database.Exec("INSERT IGNORE INTO releases (type, music_id, name) VALUES (?,?,?);", release.Type, release.MusicID, release.Name)
+	if err != nil {
+		return nil, general.MySQLErrorToDBError(err)
+	}
+	var releaseID int
+	if err = db.database.QueryRow("SELECT id FROM releases WHERE type=? AND music_id=?;", release.Type, release.MusicID).Scan(&releaseID); err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	for _, artist := range release.Artists {
+		_, err = db.database.Exec("INSERT IGNORE INTO release_artists (release_id, artist_id, name_artist, prefix) VALUES (?,?,?,?);", releaseID, artist.ID, artist.Name, artist.Prefix)
+		if err != nil {
+			return nil, general.MySQLErrorToDBError(err)
+		}
+	}
+	ids := make(map[int]int, len(userIDs))
+	for _, userID := range userIDs {
+		_, err = db.database.Exec("INSERT IGNORE INTO notifications (user_id, release_id) VALUES (?,?);", userID, releaseID)
+		if err != nil {
+			return nil, general.MySQLErrorToDBError(err)
+		}
+		var id int
+		if err = db.database.QueryRow("SELECT id FROM notifications WHERE user_id=? AND release_id=?;", userID, releaseID).Scan(&id); err != nil {
+			return nil, general.ErrorToUnknownDBError(err)
+		}
+		ids[userID] = id
+	}
+	return ids, nil
+}
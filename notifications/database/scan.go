@@ -0,0 +1,30 @@
+package database
+
+import (
+	"database/sql"
+	"general"
+)
+
+// scanNotifications combines the rows of the same notification with their artists into one notification.
+// It expects that the rows of the same notification are consecutive.
+func scanNotifications(results *sql.Rows) ([]general.Notification, error) {
+	notifications := make([]general.Notification, 0, 20)
+	for results.Next() {
+		notification := general.NewNotification(0, "", 0, "", nil)
+		var artist general.Artist
+		err := results.Scan(&notification.ID, &notification.Type, &notification.MusicID, &notification.Name, &notification.Created, &notification.Read, &artist.ID, &artist.Name, &artist.Prefix)
+		if err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		if last := len(notifications) - 1; last >= 0 && notifications[last].ID == notification.ID {
+			notifications[last].Artists = append(notifications[last].Artists, artist)
+			continue
+		}
+		notification.Artists = append(notification.Artists, artist)
+		notifications = append(notifications, notification)
+	}
+	if len(notifications) == 0 {
+		return nil, general.GetDBError("Not found", general.NotFoundError)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"general"
+)
+
+// GetNotifications finds the notifications of the given user with the newest notifications first.
+// If onlyUnread is true, then notifications that are already read are left out.
+func (db *NotificationsDB) GetNotifications(userID, offset, max int, onlyUnread bool) ([]general.Notification, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	readCondition := ""
+	if onlyUnread {
+		readCondition = " AND is_read=FALSE"
+	}
+	// This query is a cross join between release_artists and a subquery that selects the notifications of an user
+	results, err := db.database.Query("SELECT user_notifications.id, type, music_id, name, created, is_read, artist_id, name_artist, prefix FROM release_artists CROSS JOIN (SELECT notifications.id, release_id, type, music_id, name, created, is_read FROM notifications, releases WHERE user_id=? AND release_id=releases.id"+readCondition+" ORDER BY created DESC, notifications.id DESC LIMIT ?,?) AS user_notifications ON release_artists.release_id=user_notifications.release_id ORDER BY created DESC, user_notifications.id DESC, release_artists.id;", userID, offset, max)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	return scanNotifications(results)
+}
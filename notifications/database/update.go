@@ -0,0 +1,28 @@
+package database
+
+import (
+	"general"
+)
+
+// MarkAsRead marks the given notification of the user as read
+func (db *NotificationsDB) MarkAsRead(userID, notificationID int) error {
+	var exists bool
+	if err := db.database.QueryRow("SELECT EXISTS(SELECT id FROM notifications WHERE id=? AND user_id=?);", notificationID, userID).Scan(&exists); err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	if !exists {
+		return general.GetDBError("Not found", general.NotFoundError)
+	}
+	if _, err := db.database.Exec("UPDATE notifications SET is_read=TRUE WHERE id=? AND user_id=?;", notificationID, userID); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
+// MarkAllAsRead marks all the notifications of the user as read
+func (db *NotificationsDB) MarkAllAsRead(userID int) error {
+	if _, err := db.database.Exec("UPDATE notifications SET is_read=TRUE WHERE user_id=?;", userID); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
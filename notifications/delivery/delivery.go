@@ -0,0 +1,25 @@
+package delivery
+
+import (
+	"general"
+)
+
+// Deliverer delivers a notification to an user outside of the application
+type Deliverer interface {
+	Deliver(userID int, notification general.Notification) error
+}
+
+// describe returns a short description of the new music in the notification
+func describe(notification general.Notification) string {
+	artists := ""
+	for index, artist := range notification.Artists {
+		if index > 0 {
+			artists += " & "
+		}
+		if artist.Prefix != "" {
+			artists += artist.Prefix + " "
+		}
+		artists += artist.Name
+	}
+	return "New " + notification.Type + " of " + artists + ": " + notification.Name
+}
@@ -0,0 +1,31 @@
+package delivery
+
+import (
+	"fmt"
+	"general"
+	"net/smtp"
+)
+
+// SMTPDeliverer delivers notifications by sending a mail via a SMTP server
+type SMTPDeliverer struct {
+	address, from string
+	auth          smtp.Auth
+	recipient     func(userID int) (string, error)
+}
+
+// NewSMTPDeliverer returns a SMTPDeliverer that sends mails via the SMTP server on the given address.
+// The function recipient returns the mail address of an user. Auth can be nil if the server doesn't need authentication
+func NewSMTPDeliverer(address, from string, auth smtp.Auth, recipient func(userID int) (string, error)) *SMTPDeliverer {
+	return &SMTPDeliverer{address: address, from: from, auth: auth, recipient: recipient}
+}
+
+// Deliver sends a mail about the notification to the user
+func (deliverer *SMTPDeliverer) Deliver(userID int, notification general.Notification) error {
+	to, err := deliverer.recipient(userID)
+	if err != nil {
+		return err
+	}
+	description := describe(notification)
+	message := fmt.Sprintf("From: %v\r\nTo: %v\r\nSubject: %v\r\n\r\n%v\r\n", deliverer.from, to, description, description)
+	return smtp.SendMail(deliverer.address, deliverer.auth, deliverer.from, []string{to}, []byte(message))
+}
@@ -0,0 +1,47 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"general"
+	"net/http"
+)
+
+// WebhookMessage is the body that is posted to a webhook for every notification
+type WebhookMessage struct {
+	UserID       int                  `json:"userID"`
+	Description  string               `json:"description"`
+	Notification general.Notification `json:"notification"`
+}
+
+// WebhookDeliverer delivers notifications by posting them to an url
+type WebhookDeliverer struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookDeliverer returns a WebhookDeliverer that posts to the given url.
+// If client is nil, then http.DefaultClient will be used
+func NewWebhookDeliverer(url string, client *http.Client) *WebhookDeliverer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookDeliverer{url: url, client: client}
+}
+
+// Deliver posts the notification of the user to the webhook
+func (webhook *WebhookDeliverer) Deliver(userID int, notification general.Notification) error {
+	body, err := general.ToJSONBytes(WebhookMessage{UserID: userID, Description: describe(notification), Notification: notification})
+	if err != nil {
+		return err
+	}
+	resp, err := webhook.client.Post(webhook.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with statuscode %v", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"general"
+	"general/config"
+	"log"
+	"net/http"
+	"notifications/database"
+	"notifications/delivery"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const servername string = "notifications"
+
+var addressLikes string
+
+// NewNotificationsServer returns a new server for notifications and a function that starts up the server.
+// If bus is nil, then there will be no messages consumed.
+func NewNotificationsServer(handler *NotificationsHandler, bus general.Bus, cfg config.Config) (server *http.Server, start func()) {
+	var startConsumer func(*general.ConsumerGroup)
+	if bus != nil {
+		startConsumer = handler.StartConsuming
+	}
+	s, channel, startServer := general.NewServer(cfg, initRoutes(handler), bus, startConsumer, handler.Logger)
+	server = s
+	start = func() {
+		go func() {
+			for service := range channel {
+				if service.Name == "likes" {
+					addressLikes = service.Address
+				}
+			}
+		}()
+		startServer()
+	}
+	return
+}
+
+// initRoutes returns a router which can handle all the requests for this microservice
+func initRoutes(handler *NotificationsHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.Handle("/metrics", promhttp.Handler())
+
+	clientR := router.PathPrefix("/api/notifications").Subrouter()
+	clientR.Use(general.GetValidateTokenMiddleWare(handler.Logger))
+
+	getR := clientR.Methods(http.MethodGet).Subrouter()
+	getR.Use(general.GetOffsetMaxMiddleware(handler.Logger))
+	getR.Path("").HandlerFunc(handler.GetNotifications)
+
+	putR := clientR.Methods(http.MethodPut).Subrouter()
+	putR.Path("").HandlerFunc(handler.MarkAllAsRead)
+	putR.Path("/{id}").HandlerFunc(handler.MarkAsRead)
+	return router
+}
+
+// NotificationsHandler consists of a logger, a database and the deliverers that send notifications outside of the application
+type NotificationsHandler struct {
+	Logger     *log.Logger
+	db         database.Database
+	GETRequest func(string) (*http.Response, error)
+	deliverers []delivery.Deliverer
+}
+
+// NewNotificationsHandler returns a NotificationsHandler.
+// If get is nil, then DefaultGETRequest will be used with the default servername
+func NewNotificationsHandler(logger *log.Logger, db database.Database, get func(string) (*http.Response, error), deliverers ...delivery.Deliverer) *NotificationsHandler {
+	if get == nil {
+		var err error
+		get, err = general.GetInternalGETRequest(servername)
+		if err != nil {
+			logger.Fatalf("Can't create a client for sending get requests: %s\n", err)
+		}
+	}
+	return &NotificationsHandler{Logger: logger, db: db, GETRequest: get, deliverers: deliverers}
+}
+
+var (
+	badRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_badRequests_total",
+		Help: "The total number of bad requests send to the notifications server",
+	})
+)
+
+var (
+	failureGetRequest = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_failed_get_request_total",
+		Help: "The total number of failed requests to find notifications of an user",
+	})
+)
+
+var (
+	failedNotification = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_failed_new_notification_total",
+		Help: "The total number of releases for which the notifications couldn't be created",
+	})
+)
+
+var (
+	failedDelivery = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_failed_delivery_total",
+		Help: "The total number of notifications that couldn't be delivered outside of the application",
+	})
+)
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"general"
+	"net/http"
+)
+
+// GetNotifications get the notifications of an user bounded by the given offset and max in the request. The newest notifications are send first.
+// If the query parameter unread is true, then only the unread notifications are send.
+func (handler *NotificationsHandler) GetNotifications(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	onlyUnread := request.URL.Query().Get("unread") == "true"
+	handler.Logger.Printf("Received call for notifications of user %v (only unread: %v) and limit %v,%v\n", user.Username, onlyUnread, offset, max)
+	results, errorSearch := handler.db.GetNotifications(user.ID, offset, max+1, onlyUnread)
+	if errorSearch != nil {
+		errorcode := errorSearch.(general.DBError).ErrorCode
+		if errorcode == general.InvalidOffsetMax {
+			badRequests.Inc()
+			handler.Logger.Printf("Request with invalid  values for query parameters: %v,%v", offset, max)
+			general.SendError(response, http.StatusBadRequest)
+			return
+		}
+		if errorcode == general.NotFoundError {
+			handler.Logger.Printf("Request with no results for user %v: %v,%v", user.Username, offset, max)
+			general.SendError(response, http.StatusNotFound)
+			return
+		}
+		failureGetRequest.Inc()
+		handler.Logger.Printf("[Error] Can't find notifications of user %v and limit %v,%v due to: %s\n", user.Username, offset, max, errorSearch)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	if len(results) == 0 {
+		handler.Logger.Printf("Failed to find notifications of user %v and limit %v,%v\n", user.Username, offset, max)
+		failureGetRequest.Inc()
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	handler.Logger.Printf("Succesfully found %v notifications of user %v and limit %v,%v\n", len(results), user.Username, offset, max)
+	hasNext := (len(results) > max)
+	if hasNext {
+		results = results[0:max]
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err := general.WriteToJSON(&general.MultipleNotifications{Data: results, HasNext: hasNext}, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
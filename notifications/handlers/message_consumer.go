@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"general"
+	"net/http"
+)
+
+// StartConsuming will start all the consumers that belongs to the notifications service
+func (handler *NotificationsHandler) StartConsuming(consumers *general.ConsumerGroup) {
+	// A release that is delivered twice would notify the followers twice
+	deduplicator := general.NewEventDeduplicator(general.DefaultDeduplicationWindow)
+	consumers.ConsumeNew("newSong", deduplicator.Deduplicate(handler.ConsumeNewSong))
+	consumers.ConsumeNew("newAlbum", deduplicator.Deduplicate(handler.ConsumeNewAlbum))
+}
+
+// ConsumeNewSong consumes a message and notifies the followers of the artists of the new song
+func (handler *NotificationsHandler) ConsumeNewSong(message []byte) error {
+	var song general.Song
+	if _, err := general.DecodeEvent(message, &song); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	return handler.notifyFollowers(general.NewNotification(0, "song", song.ID, song.Name, song.Artists))
+}
+
+// ConsumeNewAlbum consumes a message and notifies the followers of the artist of the new album
+func (handler *NotificationsHandler) ConsumeNewAlbum(message []byte) error {
+	var album general.Album
+	if _, err := general.DecodeEvent(message, &album); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	return handler.notifyFollowers(general.NewNotification(0, "album", album.ID, album.Name, []general.Artist{album.Artist}))
+}
+
+// notifyFollowers saves a notification for every user that follows at least one of the artists of the release and delivers these notifications.
+// It returns an error if the notifications aren't saved, a failed delivery doesn't result in an error.
+func (handler *NotificationsHandler) notifyFollowers(release general.Notification) error {
+	followers := make([]int, 0)
+	alreadyFound := make(map[int]bool)
+	for _, artist := range release.Artists {
+		followersArtist, err := handler.getFollowers(artist.ID)
+		if err != nil {
+			failedNotification.Inc()
+			handler.Logger.Printf("[ERROR] Failed to obtain followers of artist %v for %v %v: %s\n", artist.Name, release.Type, release.Name, err)
+			return err
+		}
+		for _, userID := range followersArtist {
+			if !alreadyFound[userID] {
+				alreadyFound[userID] = true
+				followers = append(followers, userID)
+			}
+		}
+	}
+	if len(followers) == 0 {
+		handler.Logger.Printf("Nobody follows the artists of %v %v\n", release.Type, release.Name)
+		return nil
+	}
+	ids, err := handler.db.AddNotifications(release, followers)
+	if err != nil {
+		failedNotification.Inc()
+		handler.Logger.Printf("[ERROR] Failed to save notifications about %v %v: %s\n", release.Type, release.Name, err)
+		return err
+	}
+	handler.Logger.Printf("Succesfully notified %v followers about %v %v\n", len(followers), release.Type, release.Name)
+	for _, deliverer := range handler.deliverers {
+		for _, userID := range followers {
+			notification := release
+			notification.ID = ids[userID]
+			if err := deliverer.Deliver(userID, notification); err != nil {
+				failedDelivery.Inc()
+				handler.Logger.Printf("[ERROR] Failed to deliver notification about %v %v to user #%v: %s\n", release.Type, release.Name, userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// getFollowers returns the ids of the users that follow the given artist
+func (handler *NotificationsHandler) getFollowers(artistID int) ([]int, error) {
+	resp, err := handler.GETRequest(fmt.Sprintf("%v/intern/followers/%v", addressLikes, artistID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Likes service responded with statuscode %v", resp.StatusCode)
+	}
+	var followers []int
+	if err = general.ReadFromJSONNoValidation(&followers, resp.Body); err != nil {
+		return nil, err
+	}
+	return followers, nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"general"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// MarkAsRead marks the notification with the id given in the path as read
+func (handler *NotificationsHandler) MarkAsRead(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	notificationIDstring := mux.Vars(request)["id"]
+	notificationID, err := strconv.Atoi(notificationIDstring)
+	if err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Received request with invalid id %v results in: %s\n", notificationIDstring, err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for marking notification #%v of user #%v as read\n", notificationID, user.ID)
+	if err = handler.db.MarkAsRead(user.ID, notificationID); err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			handler.Logger.Printf("User #%v has no notification #%v\n", user.ID, notificationID)
+			general.SendError(response, http.StatusNotFound)
+			return
+		}
+		handler.Logger.Printf("[ERROR] Failed to mark notification #%v of user #%v as read: %s\n", notificationID, user.ID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// MarkAllAsRead marks all the notifications of the user as read
+func (handler *NotificationsHandler) MarkAllAsRead(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	handler.Logger.Printf("Received call for marking all notifications of user #%v as read\n", user.ID)
+	if err := handler.db.MarkAllAsRead(user.ID); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to mark all notifications of user #%v as read: %s\n", user.ID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
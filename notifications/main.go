@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"general"
+	"general/config"
+	"log"
+	"os"
+
+	"notifications/database"
+	"notifications/delivery"
+	"notifications/handlers"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var configPath = flag.String("config", "config.yaml", "Path to the configuration file of this service")
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[ERROR] Can't load configuration due to: %s\n", err)
+	}
+	logger := log.New(os.Stdout, cfg.Servername, log.LstdFlags|log.Lshortfile)
+	if err = general.ConfigureKeys(logger, cfg.Keys); err != nil {
+		logger.Fatalf("[ERROR] Can't load keys due to: %s\n", err)
+	}
+	go general.WatchKeys(logger, *configPath)
+	db, err := general.ConnectToMYSQL(logger, cfg)
+	if err != nil {
+		logger.Printf("Stop starting server")
+		return
+	}
+	defer db.Close()
+	bus, closeBus := general.ConnectToBus(logger, cfg)
+	defer closeBus()
+	handler := handlers.NewNotificationsHandler(logger, database.NewNotificationsDB(db), nil, deliverers(logger)...)
+	_, startServer := handlers.NewNotificationsServer(handler, bus, cfg)
+	startServer()
+}
+
+// deliverers returns the deliverers that are enabled by the environment variables
+func deliverers(logger *log.Logger) []delivery.Deliverer {
+	deliverers := make([]delivery.Deliverer, 0, 2)
+	if url := os.Getenv("NOTIFICATIONS_WEBHOOK"); url != "" {
+		logger.Printf("Notifications will be delivered to webhook %v\n", url)
+		deliverers = append(deliverers, delivery.NewWebhookDeliverer(url, nil))
+	}
+	// The recipient is a format string that contains the id of the user, e.g. user%v@example.com
+	if address, recipient := os.Getenv("NOTIFICATIONS_SMTP"), os.Getenv("NOTIFICATIONS_SMTP_RECIPIENT"); address != "" && recipient != "" {
+		logger.Printf("Notifications will be delivered via SMTP server %v\n", address)
+		deliverers = append(deliverers, delivery.NewSMTPDeliverer(address, os.Getenv("NOTIFICATIONS_SMTP_FROM"), nil, func(userID int) (string, error) {
+			return fmt.Sprintf(recipient, userID), nil
+		}))
+	}
+	return deliverers
+}
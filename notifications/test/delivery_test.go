@@ -0,0 +1,137 @@
+package test
+
+import (
+	"bufio"
+	"errors"
+	"general"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"notifications/delivery"
+	"strings"
+	"testing"
+)
+
+func TestWebhookDeliverer_deliver(t *testing.T) {
+	notification := general.NewNotification(1, "song", 11, "In Too Deep", []general.Artist{general.NewArtist(1, "Sum 41", "")})
+	cases := map[string]struct {
+		statusCode    int
+		expectedError bool
+	}{
+		"Webhook accepts the notification": {http.StatusOK, false},
+		"Webhook responds with no content": {http.StatusNoContent, false},
+		"Webhook fails":                    {http.StatusInternalServerError, true},
+	}
+	for name, test := range cases {
+		var received delivery.WebhookMessage
+		webhook := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if err := general.ReadFromJSONNoValidation(&received, request.Body); err != nil {
+				t.Errorf("%v: Webhook can't deserialize the body due to: %s\n", name, err)
+			}
+			response.WriteHeader(test.statusCode)
+		}))
+		err := delivery.NewWebhookDeliverer(webhook.URL, nil).Deliver(2, notification)
+		webhook.Close()
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+		if received.UserID != 2 || received.Notification.MusicID != notification.MusicID {
+			t.Errorf("%v: Expects notification about song #%v for user #2 but got: %v\n", name, notification.MusicID, received)
+		}
+		if received.Description != "New song of Sum 41: In Too Deep" {
+			t.Errorf("%v: Expects a description of the notification but got: %v\n", name, received.Description)
+		}
+	}
+}
+
+func TestSMTPDeliverer_deliver(t *testing.T) {
+	notification := general.NewNotification(1, "album", 21, "Is This It", []general.Artist{general.NewArtist(3, "Strokes", "The")})
+	cases := map[string]struct {
+		recipientErr  error
+		expectedError bool
+		expectedMail  bool
+	}{
+		"Mail is delivered":      {nil, false, true},
+		"Recipient is not found": {errors.New("Unknown user"), true, false},
+	}
+	for name, test := range cases {
+		address, mails := startTestSMTPServer(t)
+		deliverer := delivery.NewSMTPDeliverer(address, "notifications@musicapp.test", nil, func(userID int) (string, error) {
+			if test.recipientErr != nil {
+				return "", test.recipientErr
+			}
+			return "user2@musicapp.test", nil
+		})
+		err := deliverer.Deliver(2, notification)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+		if !test.expectedMail {
+			continue
+		}
+		mail := <-mails
+		if !strings.Contains(mail, "To: user2@musicapp.test") {
+			t.Errorf("%v: Expects mail to user2@musicapp.test but got: %v\n", name, mail)
+		}
+		if !strings.Contains(mail, "Subject: New album of The Strokes: Is This It") {
+			t.Errorf("%v: Expects subject about the new album but got: %v\n", name, mail)
+		}
+	}
+}
+
+// startTestSMTPServer starts a SMTP server that accepts a single mail and sends the data of this mail to the returned channel
+func startTestSMTPServer(t *testing.T) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't start SMTP server due to: %s\n", err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	mails := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			conn.Write([]byte(line + "\r\n"))
+		}
+		reply("220 localhost")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var mail strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					mail.WriteString(dataLine)
+				}
+				mails <- mail.String()
+				reply("250 OK")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().String(), mails
+}
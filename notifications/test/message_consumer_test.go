@@ -0,0 +1,102 @@
+package test
+
+import (
+	"errors"
+	"general"
+	"testing"
+)
+
+func TestNewMusic_notifyFollowers(t *testing.T) {
+	artists := map[string]general.Artist{
+		"Sum 41":   general.NewArtist(1, "Sum 41", ""),
+		"Slipknot": general.NewArtist(2, "Slipknot", ""),
+		"Strokes":  general.NewArtist(3, "Strokes", "The"),
+	}
+	followers := map[int][]int{artists["Sum 41"].ID: {1, 2}, artists["Slipknot"].ID: {2, 3}}
+	cases := map[string]struct {
+		topic             string
+		message           interface{}
+		expectedNotified  []int
+		expectedType      string
+		expectedMusicID   int
+		expectedNoneSaved bool
+	}{
+		"NewSong: Followers get notified":                {"newSong", general.NewSong(11, []general.Artist{artists["Sum 41"]}, "In Too Deep"), []int{1, 2}, "song", 11, false},
+		"NewSong: Followers of all artists get notified": {"newSong", general.NewSong(12, []general.Artist{artists["Sum 41"], artists["Slipknot"]}, "Collab"), []int{1, 2, 3}, "song", 12, false},
+		"NewSong: Artist has no followers":               {"newSong", general.NewSong(13, []general.Artist{artists["Strokes"]}, "Reptilia"), []int{}, "", 0, true},
+		"NewSong: Invalid message":                       {"newSong", general.NewArtist(1, "Sum 41", ""), []int{}, "", 0, true},
+		"NewAlbum: Followers get notified":               {"newAlbum", general.NewAlbum(21, "Iowa", artists["Slipknot"], nil), []int{2, 3}, "album", 21, false},
+		"NewAlbum: Artist has no followers":              {"newAlbum", general.NewAlbum(22, "Is This It", artists["Strokes"], nil), []int{}, "", 0, true},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		deliverer := newTestDeliverer(nil)
+		handler := testNotificationsHandler(db, followers, deliverer)
+		message, err := general.EncodeEvent(test.topic, test.message)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.message, err)
+			continue
+		}
+		if test.topic == "newSong" {
+			handler.ConsumeNewSong(message)
+		} else {
+			handler.ConsumeNewAlbum(message)
+		}
+		if test.expectedNoneSaved && len(db.notifications) != 0 {
+			t.Errorf("%v: Expects no notifications but got: %v\n", name, db.notifications)
+		}
+		if len(db.notifications) != len(test.expectedNotified) {
+			t.Errorf("%v: Expects %v notified users but got: %v\n", name, len(test.expectedNotified), len(db.notifications))
+		}
+		for _, userID := range test.expectedNotified {
+			notifications := db.notifications[userID]
+			if len(notifications) != 1 {
+				t.Errorf("%v: Expects exactly one notification for user #%v but got: %v\n", name, userID, notifications)
+				continue
+			}
+			if notifications[0].Type != test.expectedType || notifications[0].MusicID != test.expectedMusicID {
+				t.Errorf("%v: Expects notification about %v #%v but got: %v #%v\n", name, test.expectedType, test.expectedMusicID, notifications[0].Type, notifications[0].MusicID)
+			}
+			if len(deliverer.delivered[userID]) != 1 {
+				t.Errorf("%v: Expects one delivered notification for user #%v but got: %v\n", name, userID, deliverer.delivered[userID])
+				continue
+			}
+			if delivered := deliverer.delivered[userID][0]; delivered.ID != notifications[0].ID {
+				t.Errorf("%v: Expects the delivered notification to have id %v but got %v\n", name, notifications[0].ID, delivered.ID)
+			}
+		}
+	}
+}
+
+func TestNewSong_failingDelivery(t *testing.T) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	db := newTestDB()
+	failing, working := newTestDeliverer(errors.New("Delivery failed")), newTestDeliverer(nil)
+	handler := testNotificationsHandler(db, map[int][]int{artist.ID: {1}}, failing, working)
+	message, err := general.EncodeEvent("newSong", general.NewSong(11, []general.Artist{artist}, "In Too Deep"))
+	if err != nil {
+		t.Fatalf("Can't serialize song due to: %s\n", err)
+	}
+	handler.ConsumeNewSong(message)
+	if len(db.notifications[1]) != 1 {
+		t.Errorf("Expects the notification to be saved despite the failing delivery but got: %v\n", db.notifications[1])
+	}
+	if len(working.delivered[1]) != 1 {
+		t.Errorf("Expects the other deliverer to deliver the notification but got: %v\n", working.delivered[1])
+	}
+}
+
+func TestNewSong_duplicateMessage(t *testing.T) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	db := newTestDB()
+	handler := testNotificationsHandler(db, map[int][]int{artist.ID: {1}})
+	message, err := general.EncodeEvent("newSong", general.NewSong(11, []general.Artist{artist}, "In Too Deep"))
+	if err != nil {
+		t.Fatalf("Can't serialize song due to: %s\n", err)
+	}
+	handler.ConsumeNewSong(message)
+	handler.ConsumeNewSong(message)
+	if len(db.notifications[1]) != 1 {
+		t.Errorf("Expects one notification after receiving the same song twice but got: %v\n", db.notifications[1])
+	}
+}
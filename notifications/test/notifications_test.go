@@ -0,0 +1,128 @@
+package test
+
+import (
+	"general"
+	"net/http"
+	"testing"
+)
+
+func addNotificationsToTestDB(t *testing.T, db *testDB, userID int, amount, amountRead int) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	for index := 1; index <= amount; index++ {
+		if _, err := db.AddNotifications(general.NewNotification(0, "song", index, "Song", []general.Artist{artist}), []int{userID}); err != nil {
+			t.Fatalf("Failed to add notification due to: %s\n", err)
+		}
+	}
+	for index := 0; index < amountRead; index++ {
+		db.notifications[userID][index].Read = true
+	}
+}
+
+func TestGetNotifications_response(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	userWithoutNotifications := general.NewCredentials(2, "Nothing", "user")
+	cases := map[string]struct {
+		path                  string
+		credentials           *general.Credentials
+		expectedStatusCode    int
+		expectedAmountResults int
+		expectedHasNext       bool
+	}{
+		"User has notifications":                               {"/api/notifications", &user, http.StatusOK, 4, false},
+		"User has no notifications":                            {"/api/notifications", &userWithoutNotifications, http.StatusNotFound, 0, false},
+		"No token is given":                                    {"/api/notifications", nil, http.StatusUnauthorized, 0, false},
+		"Only unread notifications":                            {"/api/notifications?unread=true", &user, http.StatusOK, 3, false},
+		"Only unread notifications with max":                   {"/api/notifications?unread=true&max=2", &user, http.StatusOK, 2, true},
+		"Offset is bigger than amount of results":              {"/api/notifications?offset=100", &user, http.StatusNotFound, 0, false},
+		"Skip results given by offset":                         {"/api/notifications?offset=1", &user, http.StatusOK, 3, false},
+		"Amount is capped by max":                              {"/api/notifications?max=3", &user, http.StatusOK, 3, true},
+		"Sum offset and max is smaller than amount of results": {"/api/notifications?offset=1&max=2", &user, http.StatusOK, 2, true},
+		"Sum offset and max is equal to amount of results":     {"/api/notifications?offset=2&max=2", &user, http.StatusOK, 2, false},
+		"Sum offset and max is bigger than amount of results":  {"/api/notifications?offset=2&max=3", &user, http.StatusOK, 2, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		addNotificationsToTestDB(t, db, user.ID, 4, 1)
+		server := testServer(db)
+		token := ""
+		if test.credentials != nil {
+			var err error
+			token, err = general.CreateToken(test.credentials.ID, test.credentials.Username, test.credentials.Role)
+			if err != nil {
+				t.Errorf("%v: Failed to send token with request: %s\n", name, err)
+				continue
+			}
+		}
+		response := general.TestRequest(t, server, http.MethodGet, test.path, token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var results general.MultipleNotifications
+		if err := general.ReadFromJSON(&results, response.Body); err != nil {
+			t.Errorf("%v: Failed to deserialize results: %s\n", name, err)
+			continue
+		}
+		if len(results.Data) != test.expectedAmountResults {
+			t.Errorf("%v: Expects %v results but got: %v\n", name, test.expectedAmountResults, len(results.Data))
+		}
+		if results.HasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects hasNext to be %v but got: %v\n", name, test.expectedHasNext, results.HasNext)
+		}
+		for index := 1; index < len(results.Data); index++ {
+			if results.Data[index].Created.After(results.Data[index-1].Created) {
+				t.Errorf("%v: Expects newest notifications first but got: %v\n", name, results.Data)
+				break
+			}
+		}
+	}
+}
+
+func TestMarkAsRead_response(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	otherUser := general.NewCredentials(2, "Other", "user")
+	cases := map[string]struct {
+		path                 string
+		credentials          *general.Credentials
+		expectedStatusCode   int
+		expectedAmountUnread int
+	}{
+		"Mark a notification as read":            {"/api/notifications/2", &user, http.StatusOK, 2},
+		"Mark a read notification as read":       {"/api/notifications/1", &user, http.StatusOK, 3},
+		"Mark all notifications as read":         {"/api/notifications", &user, http.StatusOK, 0},
+		"Mark a non-existing notification":       {"/api/notifications/404", &user, http.StatusNotFound, 3},
+		"Mark a notification of another user":    {"/api/notifications/2", &otherUser, http.StatusNotFound, 3},
+		"Mark all notifications of another user": {"/api/notifications", &otherUser, http.StatusOK, 3},
+		"Invalid id":                             {"/api/notifications/first", &user, http.StatusBadRequest, 3},
+		"No token is given":                      {"/api/notifications/2", nil, http.StatusUnauthorized, 3},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		addNotificationsToTestDB(t, db, user.ID, 4, 1)
+		server := testServer(db)
+		token := ""
+		if test.credentials != nil {
+			var err error
+			token, err = general.CreateToken(test.credentials.ID, test.credentials.Username, test.credentials.Role)
+			if err != nil {
+				t.Errorf("%v: Failed to send token with request: %s\n", name, err)
+				continue
+			}
+		}
+		response := general.TestRequest(t, server, http.MethodPut, test.path, token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		unread := 0
+		for _, notification := range db.notifications[user.ID] {
+			if !notification.Read {
+				unread++
+			}
+		}
+		if unread != test.expectedAmountUnread {
+			t.Errorf("%v: Expects %v unread notifications but got: %v\n", name, test.expectedAmountUnread, unread)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"fmt"
+	"general"
+	"general/config"
+	"io"
+	"math"
+	"net/http"
+	"notifications/delivery"
+	"notifications/handlers"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func testServer(db *testDB) *http.Server {
+	server, _ := handlers.NewNotificationsServer(testNotificationsHandler(db, nil), nil, config.Config{Servername: "notifications_test"})
+	return server
+}
+
+func testNotificationsHandler(db *testDB, followers map[int][]int, deliverers ...delivery.Deliverer) *handlers.NotificationsHandler {
+	return handlers.NewNotificationsHandler(general.TestEmptyLogger(), db, testGetRequest(followers), deliverers...)
+}
+
+// testGetRequest returns a function that responds to requests for followers of an artist with the followers in the given map
+func testGetRequest(followers map[int][]int) func(string) (*http.Response, error) {
+	return func(address string) (*http.Response, error) {
+		indexLastSlash := strings.LastIndex(address, "/")
+		if indexLastSlash == -1 || !strings.Contains(address, "/intern/followers/") {
+			return convertMessageInResponse(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		artistID, err := strconv.Atoi(address[indexLastSlash+1:])
+		if err != nil {
+			return convertMessageInResponse(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		followersArtist, ok := followers[artistID]
+		if !ok {
+			followersArtist = []int{}
+		}
+		return convertMessageInResponse(http.StatusOK, followersArtist)
+	}
+}
+
+func convertMessageInResponse(status int, body interface{}) (*http.Response, error) {
+	var resp http.Response
+	var err error
+	resp.StatusCode = status
+	bodyRequest, writer := io.Pipe()
+	go func() {
+		err = general.WriteToJSON(body, writer)
+		writer.Close()
+	}()
+	resp.Body = bodyRequest
+	if err != nil {
+		return nil, fmt.Errorf("Error in test helper: %s", err)
+	}
+	return &resp, nil
+}
+
+type testDB struct {
+	notifications map[int][]general.Notification
+	lastID        int
+	created       time.Time
+}
+
+func newTestDB() *testDB {
+	return &testDB{notifications: make(map[int][]general.Notification), created: time.Now()}
+}
+
+func (fake *testDB) AddNotifications(release general.Notification, userIDs []int) (map[int]int, error) {
+	if len(release.Artists) == 0 {
+		return nil, general.GetDBError("No artists is given for adding a release", general.InvalidInput)
+	}
+	ids := make(map[int]int, len(userIDs))
+	for _, userID := range userIDs {
+		for _, notification := range fake.notifications[userID] {
+			if notification.Type == release.Type && notification.MusicID == release.MusicID {
+				ids[userID] = notification.ID
+			}
+		}
+		if _, alreadyNotified := ids[userID]; alreadyNotified {
+			continue
+		}
+		fake.lastID++
+		// Every notification is a second newer than the previous one
+		fake.created = fake.created.Add(time.Second)
+		notification := release
+		notification.ID, notification.Read, notification.Created = fake.lastID, false, fake.created
+		fake.notifications[userID] = append(fake.notifications[userID], notification)
+		ids[userID] = notification.ID
+	}
+	return ids, nil
+}
+
+func (fake *testDB) GetNotifications(userID, offset, max int, onlyUnread bool) ([]general.Notification, error) {
+	if max <= 0 || offset < 0 {
+		return nil, general.GetDBError("Can not search with negative offset or non-positive max", general.InvalidOffsetMax)
+	}
+	notifications := make([]general.Notification, 0, len(fake.notifications[userID]))
+	for _, notification := range fake.notifications[userID] {
+		if !onlyUnread || !notification.Read {
+			notifications = append(notifications, notification)
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].Created.After(notifications[j].Created)
+	})
+	notifications = notifications[int(math.Min(float64(offset), float64(len(notifications)))):int(math.Min(float64(offset+max), float64(len(notifications))))]
+	if len(notifications) == 0 {
+		return nil, general.GetDBError("Not found", general.NotFoundError)
+	}
+	return notifications, nil
+}
+
+func (fake *testDB) MarkAsRead(userID, notificationID int) error {
+	for index, notification := range fake.notifications[userID] {
+		if notification.ID == notificationID {
+			fake.notifications[userID][index].Read = true
+			return nil
+		}
+	}
+	return general.GetDBError("Not found", general.NotFoundError)
+}
+
+func (fake *testDB) MarkAllAsRead(userID int) error {
+	for index := range fake.notifications[userID] {
+		fake.notifications[userID][index].Read = true
+	}
+	return nil
+}
+
+// testDeliverer saves all the notifications that it has to deliver
+type testDeliverer struct {
+	lock      sync.Mutex
+	delivered map[int][]general.Notification
+	err       error
+}
+
+func newTestDeliverer(err error) *testDeliverer {
+	return &testDeliverer{delivered: make(map[int][]general.Notification), err: err}
+}
+
+func (fake *testDeliverer) Deliver(userID int, notification general.Notification) error {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	if fake.err != nil {
+		return fake.err
+	}
+	fake.delivered[userID] = append(fake.delivered[userID], notification)
+	return nil
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"database/sql"
+	"general"
+)
+
+// Database is an interface for the suggestions database
+type Database interface {
+	AddSong(song general.Song) error
+	FindSongsByID(songIDs []int) ([]general.Song, error)
+	SetPreference(change general.PreferenceChange) error
+	GetAllPreferences() ([]general.PreferenceChange, error)
+}
+
+// SuggestionsDB is a database
+type SuggestionsDB struct {
+	database *sql.DB
+}
+
+// NewSuggestionsDB returns a SuggestionsDB
+func NewSuggestionsDB(db *sql.DB) *SuggestionsDB {
+	return &SuggestionsDB{database: db}
+}
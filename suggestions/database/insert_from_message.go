@@ -0,0 +1,42 @@
+package database
+
+import (
+	"general"
+)
+
+// AddSong adds a new song together with its artists to the database. Artists that already exists will be ignored.
+func (db *SuggestionsDB) AddSong(song general.Song) error {
+	if len(song.Artists) == 0 {
+		return general.GetDBError("No artists is given for adding a song", general.InvalidInput)
+	}
+	for _, artist := range song.Artists {
+		if _, err := db.database.Exec("INSERT IGNORE INTO artists (id, name_artist, prefix) VALUES (?,?,?);", artist.ID, artist.Name, artist.Prefix); err != nil {
+			return general.MySQLErrorToDBError(err)
+		}
+	}
+	if _, err := db.database.Exec("INSERT INTO songs (id, name_song) VALUES (?,?);", song.ID, song.Name); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	for _, artist := range song.Artists {
+		if _, err := db.database.Exec("INSERT INTO discography (artist_id, song_id) VALUES (?,?);", artist.ID, song.ID); err != nil {
+			// Revert changes on failure. The discography will be deleted by the cascade.
+			db.database.Exec("DELETE FROM songs WHERE id=?;", song.ID)
+			return general.MySQLErrorToDBError(err)
+		}
+	}
+	return nil
+}
+
+// SetPreference saves the change of a preference. A removed preference will only be deleted if it is the current preference of the user.
+func (db *SuggestionsDB) SetPreference(change general.PreferenceChange) error {
+	var err error
+	if change.Removed {
+		_, err = db.database.Exec("DELETE FROM preferences WHERE user_id=? AND song_id=? AND preference=?;", change.UserID, change.SongID, change.Preference)
+	} else {
+		_, err = db.database.Exec("INSERT INTO preferences (user_id, song_id, preference) VALUES (?,?,?) ON DUPLICATE KEY UPDATE preference=?;", change.UserID, change.SongID, change.Preference, change.Preference)
+	}
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql"
+	"general"
+)
+
+// scanSongs combines the rows of artists and songs into songs. It expects that rows of the same song are next to each other.
+func scanSongs(results *sql.Rows) ([]general.Song, error) {
+	returningResults := make([]general.Song, 0, 20)
+	for results.Next() {
+		song := general.NewSong(0, nil, "")
+		var artist general.Artist
+		if err := results.Scan(&artist.ID, &artist.Name, &artist.Prefix, &song.ID, &song.Name); err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		last := len(returningResults) - 1
+		if last >= 0 && returningResults[last].ID == song.ID {
+			returningResults[last].Artists = append(returningResults[last].Artists, artist)
+			continue
+		}
+		song.Artists = append(song.Artists, artist)
+		returningResults = append(returningResults, song)
+	}
+	return returningResults, nil
+}
+
+func scanPreferences(results *sql.Rows) ([]general.PreferenceChange, error) {
+	returningResults := make([]general.PreferenceChange, 0, 20)
+	for results.Next() {
+		var preference general.PreferenceChange
+		if err := results.Scan(&preference.UserID, &preference.SongID, &preference.Preference); err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		returningResults = append(returningResults, preference)
+	}
+	return returningResults, nil
+}
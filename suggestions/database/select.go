@@ -0,0 +1,34 @@
+package database
+
+import (
+	"general"
+	"strings"
+)
+
+// FindSongsByID returns the songs that belong to the given IDs. Songs that don't exist are left out.
+func (db *SuggestionsDB) FindSongsByID(songIDs []int) ([]general.Song, error) {
+	if len(songIDs) == 0 {
+		return []general.Song{}, nil
+	}
+	args := make([]interface{}, 0, len(songIDs))
+	for _, songID := range songIDs {
+		args = append(args, songID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	results, err := db.database.Query("SELECT artists.id, name_artist, prefix, songs.id, name_song FROM artists, discography, songs WHERE artists.id=artist_id AND songs.id=song_id AND songs.id IN ("+placeholders+") ORDER BY songs.id;", args...)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	return scanSongs(results)
+}
+
+// GetAllPreferences returns all the preferences that are saved in the database
+func (db *SuggestionsDB) GetAllPreferences() ([]general.PreferenceChange, error) {
+	results, err := db.database.Query("SELECT user_id, song_id, preference FROM preferences;")
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer results.Close()
+	return scanPreferences(results)
+}
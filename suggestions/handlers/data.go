@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"general"
+	"general/config"
+	"log"
+	"net/http"
+	"suggestions/database"
+	"suggestions/model"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewSuggestionsServer returns a new server for suggestions and a function that starts up the server.
+// If bus is nil, then there will be no messages consumed.
+func NewSuggestionsServer(handler *SuggestionsHandler, bus general.Bus, cfg config.Config) (server *http.Server, start func()) {
+	var startConsumer func(*general.ConsumerGroup)
+	if bus != nil {
+		startConsumer = handler.StartConsuming
+	}
+	s, channel, startServer := general.NewServer(cfg, initRoutes(handler), bus, startConsumer, handler.Logger)
+	server = s
+	start = func() {
+		go func() {
+			// This service doesn't send requests to other services
+			for range channel {
+			}
+		}()
+		startServer()
+	}
+	return
+}
+
+// initRoutes returns a router which can handle all the requests for this microservice
+func initRoutes(handler *SuggestionsHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.Handle("/metrics", promhttp.Handler())
+
+	getR := router.PathPrefix("/api").Methods(http.MethodGet).Subrouter()
+	getR.Use(general.GetValidateTokenMiddleWare(handler.Logger))
+	getR.Use(general.GetOffsetMaxMiddleware(handler.Logger))
+	getR.Path("/suggestions").HandlerFunc(handler.GetSuggestions)
+	return router
+}
+
+// SuggestionsHandler consists of a logger, a database and a model of the similarities between users
+type SuggestionsHandler struct {
+	Logger *log.Logger
+	db     database.Database
+	model  *model.Model
+}
+
+// NewSuggestionsHandler returns a SuggestionsHandler with a model that contains all preferences from the database
+func NewSuggestionsHandler(logger *log.Logger, db database.Database) (*SuggestionsHandler, error) {
+	preferences, err := db.GetAllPreferences()
+	if err != nil {
+		return nil, err
+	}
+	similarityModel := model.NewModel()
+	for _, preference := range preferences {
+		similarityModel.SetPreference(preference)
+	}
+	logger.Printf("Loaded %v preferences in the model\n", len(preferences))
+	return &SuggestionsHandler{Logger: logger, db: db, model: similarityModel}, nil
+}
+
+var (
+	badRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "suggestions_badRequests_total",
+		Help: "The total number of bad requests send to the suggestions server",
+	})
+)
+
+var (
+	failureGetRequest = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "suggestions_failed_get_request_total",
+		Help: "The total number of failed requests to find suggestions for an user",
+	})
+)
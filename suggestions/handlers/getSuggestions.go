@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"general"
+	"math"
+	"net/http"
+)
+
+// GetSuggestions responds with songs that the user may like, ordered by the score of the suggestion
+func (handler *SuggestionsHandler) GetSuggestions(response http.ResponseWriter, request *http.Request) {
+	user := request.Context().Value(general.Credentials{}).(general.Credentials)
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	handler.Logger.Printf("Received call for suggestions of user #%v and limit %v,%v\n", user.ID, offset, max)
+	suggestions := handler.model.Suggestions(user.ID)
+	if len(suggestions) <= offset {
+		handler.Logger.Printf("Failed to find suggestions of user #%v and limit %v,%v\n", user.ID, offset, max)
+		failureGetRequest.Inc()
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	hasNext := (len(suggestions) > offset+max)
+	suggestions = suggestions[offset:int(math.Min(float64(offset+max), float64(len(suggestions))))]
+	songIDs := make([]int, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		songIDs = append(songIDs, suggestion.SongID)
+	}
+	songs, err := handler.db.FindSongsByID(songIDs)
+	if err != nil {
+		failureGetRequest.Inc()
+		handler.Logger.Printf("[ERROR] Can't find the suggested songs of user #%v due to: %s\n", user.ID, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	songsByID := make(map[int]general.Song)
+	for _, song := range songs {
+		songsByID[song.ID] = song
+	}
+	results := make([]general.Suggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		song, ok := songsByID[suggestion.SongID]
+		if !ok {
+			handler.Logger.Printf("Suggested song #%v for user #%v is missing in the database\n", suggestion.SongID, user.ID)
+			continue
+		}
+		results = append(results, general.NewSuggestion(song, suggestion.Score))
+	}
+	handler.Logger.Printf("Succesfully found %v suggestions of user #%v and limit %v,%v\n", len(results), user.ID, offset, max)
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	err = general.WriteToJSON(&general.MultipleSuggestions{Data: results, HasNext: hasNext}, response)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"general"
+)
+
+// StartConsuming will start all the consumers that belongs to the suggestions service. Events that are delivered more than once are only processed once.
+func (handler *SuggestionsHandler) StartConsuming(consumers *general.ConsumerGroup) {
+	deduplicator := general.NewEventDeduplicator(general.DefaultDeduplicationWindow)
+	consumers.Consume("newSong", deduplicator.Deduplicate(handler.ConsumeNewSong))
+	consumers.Consume("changePreference", deduplicator.Deduplicate(handler.ConsumeChangePreference))
+}
+
+// ConsumeNewSong consumes a message and adds a new song to the database
+func (handler *SuggestionsHandler) ConsumeNewSong(message []byte) error {
+	var song general.Song
+	if _, err := general.DecodeEvent(message, &song); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if err := handler.db.AddSong(song); err != nil {
+		if err.(general.DBError).ErrorCode != general.DuplicateEntry {
+			handler.Logger.Printf("[ERROR] Failed to add new song %v - %v to DB: %s\n", song.Artists, song.Name, err)
+			return err
+		}
+		handler.Logger.Printf("Adding song %v - %v results in duplicate error.\n", song.Artists, song.Name)
+	}
+	handler.Logger.Printf("Succesfully added new song %v - %v\n", song.Artists, song.Name)
+	return nil
+}
+
+// ConsumeChangePreference consumes a message and processes the change of a preference in the database and the model
+func (handler *SuggestionsHandler) ConsumeChangePreference(message []byte) error {
+	var change general.PreferenceChange
+	if _, err := general.DecodeEvent(message, &change); err != nil {
+		handler.Logger.Printf("Failed to deserialize message: %v due to: %s\n", string(message), err)
+		return general.PermanentError(err)
+	}
+	if change.Preference != "like" && change.Preference != "dislike" {
+		handler.Logger.Printf("Received change with unknown preference %v for user #%v and song #%v\n", change.Preference, change.UserID, change.SongID)
+		return general.PermanentError(general.GetDBError("Unknown preference "+change.Preference, general.InvalidInput))
+	}
+	if err := handler.db.SetPreference(change); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to save %v of user #%v and song #%v: %s\n", change.Preference, change.UserID, change.SongID, err)
+		return err
+	}
+	handler.model.SetPreference(change)
+	handler.Logger.Printf("Succesfully processed %v of user #%v and song #%v (removed: %v)\n", change.Preference, change.UserID, change.SongID, change.Removed)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"general"
+	"general/config"
+	"log"
+	"os"
+
+	"suggestions/database"
+	"suggestions/handlers"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var configPath = flag.String("config", "config.yaml", "Path to the configuration file of this service")
+
+func main() {
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[ERROR] Can't load configuration due to: %s\n", err)
+	}
+	logger := log.New(os.Stdout, cfg.Servername, log.LstdFlags|log.Lshortfile)
+	if err = general.ConfigureKeys(logger, cfg.Keys); err != nil {
+		logger.Fatalf("[ERROR] Can't load keys due to: %s\n", err)
+	}
+	go general.WatchKeys(logger, *configPath)
+	db, err := general.ConnectToMYSQL(logger, cfg)
+	if err != nil {
+		logger.Printf("Stop starting server")
+		return
+	}
+	defer db.Close()
+	bus, closeBus := general.ConnectToBus(logger, cfg)
+	defer closeBus()
+	handler, err := handlers.NewSuggestionsHandler(logger, database.NewSuggestionsDB(db))
+	if err != nil {
+		logger.Fatalf("[ERROR] Can't create handler due to: %s\n", err)
+	}
+	_, startServer := handlers.NewSuggestionsServer(handler, bus, cfg)
+	startServer()
+}
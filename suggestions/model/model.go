@@ -0,0 +1,134 @@
+package model
+
+import (
+	"general"
+	"sort"
+	"sync"
+)
+
+// Model keeps the preferences of all users together with the similarity between every pair of users.
+// The similarity of two users is the amount of songs on which they agree minus the amount of songs on which they disagree.
+// The model is safe for concurrent use.
+type Model struct {
+	mutex sync.RWMutex
+	// preferences combines an user with the preferences of this user where a like is 1 and a dislike is -1
+	preferences map[int]map[int]int
+	// ratings contains the same preferences as preferences, but then grouped by song
+	ratings      map[int]map[int]int
+	similarities map[int]map[int]int
+}
+
+// NewModel returns an empty Model
+func NewModel() *Model {
+	return &Model{preferences: make(map[int]map[int]int), ratings: make(map[int]map[int]int), similarities: make(map[int]map[int]int)}
+}
+
+// Suggestion contains the id of a suggested song together with its score
+type Suggestion struct {
+	SongID int
+	Score  int
+}
+
+// SetPreference updates the model with the change of a preference.
+// A removed preference will only be processed if it is the current preference of the user.
+func (model *Model) SetPreference(change general.PreferenceChange) {
+	value := preferenceValue(change.Preference)
+	if value == 0 {
+		return
+	}
+	model.mutex.Lock()
+	defer model.mutex.Unlock()
+	oldValue := model.preferences[change.UserID][change.SongID]
+	newValue := value
+	if change.Removed {
+		if oldValue != value {
+			return
+		}
+		newValue = 0
+	}
+	if oldValue == newValue {
+		return
+	}
+	for otherUser, otherValue := range model.ratings[change.SongID] {
+		if otherUser == change.UserID {
+			continue
+		}
+		model.addSimilarity(change.UserID, otherUser, (newValue-oldValue)*otherValue)
+	}
+	if newValue == 0 {
+		delete(model.preferences[change.UserID], change.SongID)
+		delete(model.ratings[change.SongID], change.UserID)
+		return
+	}
+	if _, ok := model.preferences[change.UserID]; !ok {
+		model.preferences[change.UserID] = make(map[int]int)
+	}
+	if _, ok := model.ratings[change.SongID]; !ok {
+		model.ratings[change.SongID] = make(map[int]int)
+	}
+	model.preferences[change.UserID][change.SongID] = newValue
+	model.ratings[change.SongID][change.UserID] = newValue
+}
+
+// Similarity returns the similarity between both users
+func (model *Model) Similarity(userID, otherUserID int) int {
+	model.mutex.RLock()
+	defer model.mutex.RUnlock()
+	return model.similarities[userID][otherUserID]
+}
+
+// Suggestions returns the songs without a preference of the user ordered by score.
+// The score of a song is the sum of the preferences of other users weighted by their similarity with the user.
+// Only users with a positive similarity are taken into account and only songs with a positive score are returned.
+func (model *Model) Suggestions(userID int) []Suggestion {
+	model.mutex.RLock()
+	scores := make(map[int]int)
+	for otherUser, similarity := range model.similarities[userID] {
+		if similarity <= 0 {
+			continue
+		}
+		for songID, value := range model.preferences[otherUser] {
+			if _, ok := model.preferences[userID][songID]; ok {
+				continue
+			}
+			scores[songID] += similarity * value
+		}
+	}
+	model.mutex.RUnlock()
+	suggestions := make([]Suggestion, 0, len(scores))
+	for songID, score := range scores {
+		if score > 0 {
+			suggestions = append(suggestions, Suggestion{SongID: songID, Score: score})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].SongID < suggestions[j].SongID
+	})
+	return suggestions
+}
+
+func (model *Model) addSimilarity(userID, otherUserID, delta int) {
+	for _, pair := range [][2]int{{userID, otherUserID}, {otherUserID, userID}} {
+		if _, ok := model.similarities[pair[0]]; !ok {
+			model.similarities[pair[0]] = make(map[int]int)
+		}
+		model.similarities[pair[0]][pair[1]] += delta
+		if model.similarities[pair[0]][pair[1]] == 0 {
+			delete(model.similarities[pair[0]], pair[1])
+		}
+	}
+}
+
+func preferenceValue(preference string) int {
+	switch preference {
+	case "like":
+		return 1
+	case "dislike":
+		return -1
+	default:
+		return 0
+	}
+}
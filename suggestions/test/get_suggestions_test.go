@@ -0,0 +1,102 @@
+package test
+
+import (
+	"general"
+	"net/http"
+	"testing"
+)
+
+func TestGetSuggestions_response(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	userWithNoSuggestions := general.NewCredentials(4, "Lonely", "user")
+	artist := general.NewArtist(1, "Sum 41", "")
+	songs := []general.Song{general.NewSong(11, []general.Artist{artist}, "In Too Deep"), general.NewSong(12, []general.Artist{artist}, "Walking Disaster"), general.NewSong(13, []general.Artist{artist}, "Fat Lip"), general.NewSong(14, []general.Artist{artist}, "Still Waiting"), general.NewSong(15, []general.Artist{artist}, "Pieces")}
+	cases := map[string]struct {
+		path                  string
+		credentials           *general.Credentials
+		expectedStatusCode    int
+		expectedAmountResults int
+		expectedHasNext       bool
+	}{
+		"User has suggestions":                                 {"/api/suggestions", &user, http.StatusOK, 3, false},
+		"User has no suggestions":                              {"/api/suggestions", &userWithNoSuggestions, http.StatusNotFound, 0, false},
+		"No token is given":                                    {"/api/suggestions", nil, http.StatusUnauthorized, 0, false},
+		"Offset is bigger than amount of results":              {"/api/suggestions?offset=100", &user, http.StatusNotFound, 0, false},
+		"Skip results given by offset":                         {"/api/suggestions?offset=1", &user, http.StatusOK, 2, false},
+		"Amount is capped by max":                              {"/api/suggestions?max=2", &user, http.StatusOK, 2, true},
+		"Sum offset and max is equal to amount of results":     {"/api/suggestions?offset=1&max=2", &user, http.StatusOK, 2, false},
+		"Sum offset and max is bigger than amount of results":  {"/api/suggestions?offset=2&max=3", &user, http.StatusOK, 1, false},
+		"Sum offset and max is smaller than amount of results": {"/api/suggestions?offset=0&max=1", &user, http.StatusOK, 1, true},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.addSongsToTestDB(t, songs)
+		db.addPreferencesToTestDB(user.ID, []int{11}, nil)
+		db.addPreferencesToTestDB(2, []int{11, 12, 13}, nil)
+		db.addPreferencesToTestDB(3, []int{11, 13, 14}, []int{15})
+		server := testServer(t, db)
+		token := ""
+		if test.credentials != nil {
+			var err error
+			token, err = general.CreateToken(test.credentials.ID, test.credentials.Username, test.credentials.Role)
+			if err != nil {
+				t.Errorf("%v: Failed to send token with request: %s\n", name, err)
+				continue
+			}
+		}
+		response := general.TestRequest(t, server, http.MethodGet, test.path, token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var results general.MultipleSuggestions
+		if err := general.ReadFromJSON(&results, response.Body); err != nil {
+			t.Errorf("%v: Failed to deserialize results: %s\n", name, err)
+			continue
+		}
+		if len(results.Data) != test.expectedAmountResults {
+			t.Errorf("%v: Expects %v results but got: %v\n", name, test.expectedAmountResults, len(results.Data))
+		}
+		if results.HasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects hasNext to be %v but got: %v\n", name, test.expectedHasNext, results.HasNext)
+		}
+	}
+}
+
+func TestGetSuggestions_order(t *testing.T) {
+	user := general.NewCredentials(1, "Test", "user")
+	artist := general.NewArtist(1, "Sum 41", "")
+	songs := []general.Song{general.NewSong(11, []general.Artist{artist}, "In Too Deep"), general.NewSong(12, []general.Artist{artist}, "Walking Disaster"), general.NewSong(13, []general.Artist{artist}, "Fat Lip"), general.NewSong(14, []general.Artist{artist}, "Still Waiting")}
+	db := newTestDB()
+	db.addSongsToTestDB(t, songs)
+	db.addPreferencesToTestDB(user.ID, []int{11}, nil)
+	db.addPreferencesToTestDB(2, []int{11, 12, 13}, nil)
+	db.addPreferencesToTestDB(3, []int{11, 13, 14}, nil)
+	server := testServer(t, db)
+	token, err := general.CreateToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		t.Fatalf("Failed to send token with request: %s\n", err)
+	}
+	response := general.TestRequest(t, server, http.MethodGet, "/api/suggestions", token, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expects statuscode: %v but got: %v\n", http.StatusOK, response.Code)
+	}
+	var results general.MultipleSuggestions
+	if err := general.ReadFromJSON(&results, response.Body); err != nil {
+		t.Fatalf("Failed to deserialize results: %s\n", err)
+	}
+	expectedOrder := []int{13, 12, 14}
+	if len(results.Data) != len(expectedOrder) {
+		t.Fatalf("Expects %v results but got: %v\n", len(expectedOrder), results.Data)
+	}
+	for index, suggestion := range results.Data {
+		if suggestion.Song.ID != expectedOrder[index] {
+			t.Errorf("Expects song #%v at position %v but got: %v\n", expectedOrder[index], index, suggestion.Song.ID)
+		}
+		if index > 0 && suggestion.Score > results.Data[index-1].Score {
+			t.Errorf("Expects scores in descending order but got %v after %v\n", suggestion.Score, results.Data[index-1].Score)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"general"
+	"testing"
+)
+
+func TestAddSong_saveInDB(t *testing.T) {
+	artist := general.NewArtist(1, "Sum 41", "")
+	cases := map[string]struct {
+		song              general.Song
+		expectedSavedInDB bool
+	}{
+		"No artists":    {general.NewSong(11, []general.Artist{}, "In Too Deep"), false},
+		"Complete data": {general.NewSong(11, []general.Artist{artist}, "In Too Deep"), true},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler := testSuggestionsHandler(t, db)
+		songString, err := general.EncodeEvent("newSong", test.song)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.song, err)
+			continue
+		}
+		handler.ConsumeNewSong(songString)
+		if _, ok := db.songs[test.song.ID]; ok != test.expectedSavedInDB {
+			t.Errorf("%v: Expects to be saved: %v but got %v\n", name, test.expectedSavedInDB, ok)
+		}
+	}
+}
+
+func TestChangePreference_saveInDB(t *testing.T) {
+	cases := map[string]struct {
+		existing           string
+		change             general.PreferenceChange
+		expectedPreference string
+	}{
+		"Add like":                     {"", general.NewPreferenceChange(1, 11, "like", false), "like"},
+		"Add dislike":                  {"", general.NewPreferenceChange(1, 11, "dislike", false), "dislike"},
+		"Unknown preference":           {"", general.NewPreferenceChange(1, 11, "love", false), ""},
+		"Change like into dislike":     {"like", general.NewPreferenceChange(1, 11, "dislike", false), "dislike"},
+		"Remove like":                  {"like", general.NewPreferenceChange(1, 11, "like", true), ""},
+		"Remove dislike that is liked": {"like", general.NewPreferenceChange(1, 11, "dislike", true), "like"},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		if test.existing != "" {
+			db.SetPreference(general.NewPreferenceChange(test.change.UserID, test.change.SongID, test.existing, false))
+		}
+		handler := testSuggestionsHandler(t, db)
+		changeString, err := general.EncodeEvent("changePreference", test.change)
+		if err != nil {
+			t.Errorf("%v: Can't serialize %v due to: %s\n", name, test.change, err)
+			continue
+		}
+		handler.ConsumeChangePreference(changeString)
+		if preference := db.preferences[test.change.UserID][test.change.SongID]; preference != test.expectedPreference {
+			t.Errorf("%v: Expects preference %q but got: %q\n", name, test.expectedPreference, preference)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"general"
+	"suggestions/model"
+	"testing"
+)
+
+func TestModel_similarity(t *testing.T) {
+	cases := map[string]struct {
+		changes            []general.PreferenceChange
+		expectedSimilarity int
+	}{
+		"No common songs":              {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 12, "like", false)}, 0},
+		"Both like the same song":      {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false)}, 1},
+		"Both dislike the same song":   {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "dislike", false), general.NewPreferenceChange(2, 11, "dislike", false)}, 1},
+		"Disagree about a song":        {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "dislike", false)}, -1},
+		"Agree about multiple songs":   {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false), general.NewPreferenceChange(1, 12, "dislike", false), general.NewPreferenceChange(2, 12, "dislike", false)}, 2},
+		"Change like into dislike":     {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false), general.NewPreferenceChange(2, 11, "dislike", false)}, -1},
+		"Remove like":                  {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false), general.NewPreferenceChange(2, 11, "like", true)}, 0},
+		"Remove dislike that is liked": {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false), general.NewPreferenceChange(2, 11, "dislike", true)}, 1},
+		"Same like twice":              {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false), general.NewPreferenceChange(2, 11, "like", false)}, 1},
+		"Unknown preference":           {[]general.PreferenceChange{general.NewPreferenceChange(1, 11, "like", false), general.NewPreferenceChange(2, 11, "love", false)}, 0},
+	}
+	for name, test := range cases {
+		similarityModel := model.NewModel()
+		for _, change := range test.changes {
+			similarityModel.SetPreference(change)
+		}
+		if similarity := similarityModel.Similarity(1, 2); similarity != test.expectedSimilarity {
+			t.Errorf("%v: Expects similarity %v but got: %v\n", name, test.expectedSimilarity, similarity)
+		}
+		if similarity := similarityModel.Similarity(2, 1); similarity != test.expectedSimilarity {
+			t.Errorf("%v: Expects symmetric similarity %v but got: %v\n", name, test.expectedSimilarity, similarity)
+		}
+	}
+}
+
+func TestModel_suggestions(t *testing.T) {
+	user := 1
+	cases := map[string]struct {
+		preferences       map[int][2][]int
+		expectedSuggested []int
+	}{
+		"No other users":                          {map[int][2][]int{user: {{11}, nil}}, []int{}},
+		"Song liked by similar user":              {map[int][2][]int{user: {{11}, nil}, 2: {{11, 12}, nil}}, []int{12}},
+		"Song disliked by similar user":           {map[int][2][]int{user: {{11}, nil}, 2: {{11}, {12}}}, []int{}},
+		"Songs of dissimilar users are ignored":   {map[int][2][]int{user: {{11}, nil}, 2: {nil, {11}}, 3: {{12}, nil}}, []int{}},
+		"Songs with preference are not suggested": {map[int][2][]int{user: {{11}, {12}}, 2: {{11, 12}, nil}}, []int{}},
+		"More similar users weigh more":           {map[int][2][]int{user: {{11, 12}, nil}, 2: {{11, 12, 13}, nil}, 3: {{11, 14}, nil}}, []int{13, 14}},
+		"Songs liked by more users rank higher":   {map[int][2][]int{user: {{11}, nil}, 2: {{11, 13}, nil}, 3: {{11, 12, 13}, nil}}, []int{13, 12}},
+	}
+	for name, test := range cases {
+		similarityModel := model.NewModel()
+		for userID, preferences := range test.preferences {
+			for _, songID := range preferences[0] {
+				similarityModel.SetPreference(general.NewPreferenceChange(userID, songID, "like", false))
+			}
+			for _, songID := range preferences[1] {
+				similarityModel.SetPreference(general.NewPreferenceChange(userID, songID, "dislike", false))
+			}
+		}
+		suggestions := similarityModel.Suggestions(user)
+		if len(suggestions) != len(test.expectedSuggested) {
+			t.Errorf("%v: Expects %v suggestions but got: %v\n", name, len(test.expectedSuggested), suggestions)
+			continue
+		}
+		for index, suggestion := range suggestions {
+			if suggestion.SongID != test.expectedSuggested[index] {
+				t.Errorf("%v: Expects song #%v at position %v but got: %v\n", name, test.expectedSuggested[index], index, suggestion.SongID)
+			}
+			if suggestion.Score <= 0 {
+				t.Errorf("%v: Expects positive score but got: %v\n", name, suggestion.Score)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"general"
+	"general/config"
+	"net/http"
+	"suggestions/database"
+	"suggestions/handlers"
+	"testing"
+)
+
+func testServer(t *testing.T, db database.Database) *http.Server {
+	server, _ := handlers.NewSuggestionsServer(testSuggestionsHandler(t, db), nil, config.Config{Servername: "suggestions_test"})
+	return server
+}
+
+func testSuggestionsHandler(t *testing.T, db database.Database) *handlers.SuggestionsHandler {
+	handler, err := handlers.NewSuggestionsHandler(general.TestEmptyLogger(), db)
+	if err != nil {
+		t.Fatalf("Failed to create a handler due to: %s\n", err)
+	}
+	return handler
+}
+
+type testDB struct {
+	songs       map[int]general.Song
+	preferences map[int]map[int]string
+}
+
+func newTestDB() testDB {
+	return testDB{songs: make(map[int]general.Song), preferences: make(map[int]map[int]string)}
+}
+
+func (fake testDB) addSongsToTestDB(t *testing.T, songs []general.Song) {
+	for _, song := range songs {
+		if err := fake.AddSong(song); err != nil {
+			t.Fatalf("Failed to add song %v due to: %s\n", song.Name, err)
+		}
+	}
+}
+
+func (fake testDB) AddSong(song general.Song) error {
+	if len(song.Artists) == 0 {
+		return general.GetDBError("No artists is given for adding a song", general.InvalidInput)
+	}
+	if _, ok := fake.songs[song.ID]; ok {
+		return general.GetDBError("Duplicate entry", general.DuplicateEntry)
+	}
+	fake.songs[song.ID] = song
+	return nil
+}
+
+func (fake testDB) FindSongsByID(songIDs []int) ([]general.Song, error) {
+	songs := make([]general.Song, 0, len(songIDs))
+	for _, songID := range songIDs {
+		if song, ok := fake.songs[songID]; ok {
+			songs = append(songs, song)
+		}
+	}
+	return songs, nil
+}
+
+func (fake testDB) SetPreference(change general.PreferenceChange) error {
+	if _, ok := fake.preferences[change.UserID]; !ok {
+		fake.preferences[change.UserID] = make(map[int]string)
+	}
+	if !change.Removed {
+		fake.preferences[change.UserID][change.SongID] = change.Preference
+		return nil
+	}
+	if fake.preferences[change.UserID][change.SongID] == change.Preference {
+		delete(fake.preferences[change.UserID], change.SongID)
+	}
+	return nil
+}
+
+func (fake testDB) GetAllPreferences() ([]general.PreferenceChange, error) {
+	preferences := make([]general.PreferenceChange, 0)
+	for userID, preferencesUser := range fake.preferences {
+		for songID, preference := range preferencesUser {
+			preferences = append(preferences, general.NewPreferenceChange(userID, songID, preference, false))
+		}
+	}
+	return preferences, nil
+}
+
+// addPreferencesToTestDB adds the likes and dislikes of the given user to the test database
+func (fake testDB) addPreferencesToTestDB(userID int, likes, dislikes []int) {
+	for _, songID := range likes {
+		fake.SetPreference(general.NewPreferenceChange(userID, songID, "like", false))
+	}
+	for _, songID := range dislikes {
+		fake.SetPreference(general.NewPreferenceChange(userID, songID, "dislike", false))
+	}
+}
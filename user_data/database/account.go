@@ -0,0 +1,42 @@
+package database
+
+import (
+	"database/sql"
+	"general"
+)
+
+// ChangePassword replaces the password of the user if the old password matches the password from the database
+func (db *UserDB) ChangePassword(username, oldPassword, newPassword string) error {
+	user, err := db.Login(username, oldPassword)
+	if err != nil {
+		return err
+	}
+	return db.updatePassword(user.ID, newPassword)
+}
+
+// DeleteUser removes the user from the database. The refresh tokens of the user are removed as well.
+// A message to topic userDeleted is added to the outbox in the same transaction.
+func (db *UserDB) DeleteUser(userID int) error {
+	tx, err := db.database.Begin()
+	if err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	defer tx.Rollback()
+	var user general.Credentials
+	if err = tx.QueryRow("SELECT id, username, role FROM users WHERE id=? FOR UPDATE", userID).Scan(&user.ID, &user.Username, &user.Role); err != nil {
+		if err != sql.ErrNoRows {
+			return general.ErrorToUnknownDBError(err)
+		}
+		return general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	if _, err = tx.Exec("DELETE FROM users WHERE id=?", userID); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	if err = general.AddToOutbox(tx, "userDeleted", user); err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	return nil
+}
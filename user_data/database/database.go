@@ -2,39 +2,57 @@ package database
 
 import (
 	"crypto/hmac"
-	"crypto/rand"
 	"crypto/sha512"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/base64"
 	"general"
 	"io"
 )
 
-// This key will be exported to a file
-const key string = "WlAye5L1uzZq61p41A6PyhpBxsnklABk6FPAOeOXUwqWuouUEvTG8Apqkqo1uloZ"
-
 // Database will be used to extract dependencies on db
 type Database interface {
 	SignUp(username, password string) (int, error)
 	Login(username, password string) (general.Credentials, error)
 	FindUser(username string) (general.Credentials, error)
+	GetTokenVersion(userID int) (int, error)
+	AddRefreshToken(userID int) (string, error)
+	UseRefreshToken(refreshToken string) (general.Credentials, error)
+	RemoveRefreshToken(userID int, refreshToken string) error
+	RevokeTokens(userID int, removeRefreshTokens bool) (int, error)
+	ChangePassword(username, oldPassword, newPassword string) error
+	DeleteUser(userID int) error
+	ListUsers(offset, max int) ([]general.Credentials, error)
+	SetRole(userID int, role string) (int, error)
+	CountUsersWithRole(role string) (int, error)
 }
 
 // UserDB is a sql database
 type UserDB struct {
 	database *sql.DB
+	params   Argon2Parameters
+	pepper   []byte
 }
 
-// NewUserDB returns a UserDB
-func NewUserDB(db *sql.DB) *UserDB {
-	return &UserDB{database: db}
+// NewUserDB returns a UserDB that hashes passwords with argon2id and the given parameters.
+// The pepper is the secret key of the legacy HMAC-SHA512 hashes, these hashes are replaced by argon2id hashes as soon as the user logs in.
+func NewUserDB(db *sql.DB, params Argon2Parameters, pepper string) *UserDB {
+	return &UserDB{database: db, params: params, pepper: []byte(pepper)}
 }
 
-// SignUp adds a new user to the database and returns the newly added id
+// SignUp adds a new user to the database and returns the newly added id. A message to topic newUser is added to the outbox in the same transaction.
 func (db *UserDB) SignUp(username, password string) (int, error) {
-	salt := generateSalt()
-	hash := hashPass(password, string(salt))
-	result, err := db.database.Exec("INSERT INTO users(username, password,salt, role) VALUES ( ?, ?,?, ?)", username, hash, salt, "user")
+	hash, err := HashPassword(password, db.params)
+	if err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	tx, err := db.database.Begin()
+	if err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	defer tx.Rollback()
+	// The salt is part of the encoded hash, the column salt is only used by legacy hashes
+	result, err := tx.Exec("INSERT INTO users(username, password,salt, role) VALUES ( ?, ?,?, ?)", username, hash, []byte{}, general.RoleUser)
 	if err != nil {
 		return 0, general.MySQLErrorToDBError(err)
 	}
@@ -42,25 +60,24 @@ func (db *UserDB) SignUp(username, password string) (int, error) {
 	if errorID != nil {
 		return 0, general.ErrorToUnknownDBError(errorID)
 	}
-	return int(userID), nil
-}
-
-func generateSalt() []byte {
-	randomBytes := make([]byte, 64)
-	_, err := rand.Read(randomBytes)
-	if err != nil {
-		return nil
+	if err = general.AddToOutbox(tx, "newUser", general.Credentials{ID: int(userID), Username: username}); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
 	}
-	return randomBytes
+	if err = tx.Commit(); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	return int(userID), nil
 }
 
-func hashPass(password string, salt string) string {
-	hash := hmac.New(sha512.New, []byte(key))
+// legacyHashPass returns the HMAC-SHA512 hash that was used before argon2id
+func (db *UserDB) legacyHashPass(password string, salt string) string {
+	hash := hmac.New(sha512.New, db.pepper)
 	io.WriteString(hash, password+salt)
 	return base64.URLEncoding.EncodeToString(hash.Sum(nil))
 }
 
 // Login compares the given password with the password from the database.
+// A legacy hash or a hash with outdated parameters is replaced by a new hash of the password.
 func (db *UserDB) Login(username, password string) (general.Credentials, error) {
 	var userdata general.Credentials
 	var passwordDB, saltDB string
@@ -71,13 +88,38 @@ func (db *UserDB) Login(username, password string) (general.Credentials, error)
 		}
 		return general.Credentials{}, general.GetDBError("The credentials do not match", general.InvalidInput)
 	}
-	hash := hashPass(password, string(saltDB))
-	if hash != passwordDB {
+	var match, rehash bool
+	if isArgon2Hash(passwordDB) {
+		if match, rehash, err = VerifyPassword(password, passwordDB, db.params); err != nil {
+			return general.Credentials{}, general.ErrorToUnknownDBError(err)
+		}
+	} else if len(db.pepper) != 0 {
+		legacyHash := db.legacyHashPass(password, saltDB)
+		match = subtle.ConstantTimeCompare([]byte(legacyHash), []byte(passwordDB)) == 1
+		rehash = match
+	}
+	if !match {
 		return general.Credentials{}, general.GetDBError("The credentials do not match", general.InvalidInput)
 	}
+	if rehash {
+		if err = db.updatePassword(userdata.ID, password); err != nil {
+			return general.Credentials{}, err
+		}
+	}
 	return userdata, nil
 }
 
+func (db *UserDB) updatePassword(userID int, password string) error {
+	hash, err := HashPassword(password, db.params)
+	if err != nil {
+		return general.ErrorToUnknownDBError(err)
+	}
+	if _, err = db.database.Exec("UPDATE users SET password=?, salt=? WHERE id=?", hash, []byte{}, userID); err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	return nil
+}
+
 // FindUser searches the database for an user with the given username
 func (db *UserDB) FindUser(username string) (general.Credentials, error) {
 	var userdata general.Credentials
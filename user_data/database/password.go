@@ -0,0 +1,69 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Parameters are the cost parameters that are used for hashing passwords with argon2id. Memory is given in KiB.
+type Argon2Parameters struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// DefaultArgon2Parameters are the parameters that are recommended for argon2id
+var DefaultArgon2Parameters = Argon2Parameters{Time: 3, Memory: 64 * 1024, Threads: 2}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	argon2Prefix     = "$argon2id$"
+)
+
+var errInvalidHash = errors.New("The encoded hash is not a valid argon2id hash")
+
+// HashPassword hashes the password with argon2id and a random salt. The result is encoded as $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+func HashPassword(password string, params Argon2Parameters) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, argon2KeyLength)
+	return fmt.Sprintf("%vv=%d$m=%d,t=%d,p=%d$%v$%v", argon2Prefix, argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// VerifyPassword checks if the password matches the encoded hash. Rehash is true if the hash is created with other parameters than the given parameters.
+func VerifyPassword(password, encoded string, params Argon2Parameters) (match, rehash bool, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, false, errInvalidHash
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errInvalidHash
+	}
+	var hashParams Argon2Parameters
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hashParams.Memory, &hashParams.Time, &hashParams.Threads); err != nil {
+		return false, false, errInvalidHash
+	}
+	salt, errSalt := base64.RawStdEncoding.DecodeString(parts[4])
+	hash, errHash := base64.RawStdEncoding.DecodeString(parts[5])
+	if errSalt != nil || errHash != nil || len(hash) == 0 {
+		return false, false, errInvalidHash
+	}
+	result := argon2.IDKey([]byte(password), salt, hashParams.Time, hashParams.Memory, hashParams.Threads, uint32(len(hash)))
+	match = subtle.ConstantTimeCompare(result, hash) == 1
+	return match, match && (hashParams != params || len(hash) != argon2KeyLength), nil
+}
+
+func isArgon2Hash(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2Prefix)
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"general"
+	"strconv"
+)
+
+// ListUsers returns the users ordered by username. It returns a NotFoundError if there are no users with the given offset.
+func (db *UserDB) ListUsers(offset, max int) ([]general.Credentials, error) {
+	rows, err := db.database.Query("SELECT id, username, role FROM users ORDER BY username LIMIT ?, ?", offset, max)
+	if err != nil {
+		return nil, general.ErrorToUnknownDBError(err)
+	}
+	defer rows.Close()
+	users := make([]general.Credentials, 0, max)
+	for rows.Next() {
+		var user general.Credentials
+		var role sql.NullString
+		if err := rows.Scan(&user.ID, &user.Username, &role); err != nil {
+			return nil, general.GetDBError(err.Error(), general.ScannerError)
+		}
+		user.Role = role.String
+		users = append(users, user)
+	}
+	if len(users) == 0 {
+		return nil, general.GetDBError("No users found", general.NotFoundError)
+	}
+	return users, nil
+}
+
+// SetRole changes the role of the user and increases the token version of the user, since the tokens of the user contain the old role. It returns the new token version.
+// Messages to the topics roleChanged and revokeTokens are added to the outbox in the same transaction, the revocation has the id of the user as key.
+func (db *UserDB) SetRole(userID int, role string) (int, error) {
+	tx, err := db.database.Begin()
+	if err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec("UPDATE users SET role=?, token_version=token_version+1 WHERE id=?", role, userID)
+	if err != nil {
+		return 0, general.MySQLErrorToDBError(err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return 0, general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	version, err := tokenVersion(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err = general.AddToOutbox(tx, "roleChanged", general.NewRoleChange(userID, role, version)); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	if err = general.AddToOutboxWithKey(tx, "revokeTokens", strconv.Itoa(userID), general.NewTokenRevocation(userID, version)); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	return version, nil
+}
+
+// CountUsersWithRole returns the number of users with the given role
+func (db *UserDB) CountUsersWithRole(role string) (int, error) {
+	var count int
+	if err := db.database.QueryRow("SELECT COUNT(*) FROM users WHERE role=?", role).Scan(&count); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	return count, nil
+}
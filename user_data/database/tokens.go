@@ -0,0 +1,124 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"general"
+	"strconv"
+)
+
+// refreshTokenDays is the number of days that a refresh token can be used
+const refreshTokenDays int = 30
+
+// GetTokenVersion returns the current token version of the user
+func (db *UserDB) GetTokenVersion(userID int) (int, error) {
+	var version int
+	if err := db.database.QueryRow("SELECT token_version FROM users WHERE id=?", userID).Scan(&version); err != nil {
+		if err != sql.ErrNoRows {
+			return 0, general.ErrorToUnknownDBError(err)
+		}
+		return 0, general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	return version, nil
+}
+
+// AddRefreshToken creates a new refresh token for the user. Only the hash of the refresh token is saved.
+func (db *UserDB) AddRefreshToken(userID int) (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", general.ErrorToUnknownDBError(err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(randomBytes)
+	_, err := db.database.Exec("INSERT INTO refresh_tokens(user_id, token_hash, expires) VALUES (?, ?, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))", userID, hashRefreshToken(refreshToken), refreshTokenDays)
+	if err != nil {
+		return "", general.MySQLErrorToDBError(err)
+	}
+	return refreshToken, nil
+}
+
+// UseRefreshToken removes the refresh token and returns the credentials of the user to which it belongs. It returns a NotFoundError if the refresh token is unknown or expired.
+func (db *UserDB) UseRefreshToken(refreshToken string) (general.Credentials, error) {
+	var userdata general.Credentials
+	hash := hashRefreshToken(refreshToken)
+	err := db.database.QueryRow("SELECT users.id, users.username, users.role FROM refresh_tokens JOIN users ON refresh_tokens.user_id=users.id WHERE refresh_tokens.token_hash=? AND refresh_tokens.expires > UTC_TIMESTAMP()", hash).Scan(&userdata.ID, &userdata.Username, &userdata.Role)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return general.Credentials{}, general.ErrorToUnknownDBError(err)
+		}
+		return general.Credentials{}, general.GetDBError("Unknown refresh token", general.NotFoundError)
+	}
+	result, err := db.database.Exec("DELETE FROM refresh_tokens WHERE token_hash=?", hash)
+	if err != nil {
+		return general.Credentials{}, general.MySQLErrorToDBError(err)
+	}
+	// The refresh token is used by another request in the meantime
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return general.Credentials{}, general.GetDBError("Unknown refresh token", general.NotFoundError)
+	}
+	return userdata, nil
+}
+
+// RemoveRefreshToken removes the refresh token of the user. It returns a NotFoundError if the user has no such refresh token.
+func (db *UserDB) RemoveRefreshToken(userID int, refreshToken string) error {
+	result, err := db.database.Exec("DELETE FROM refresh_tokens WHERE user_id=? AND token_hash=?", userID, hashRefreshToken(refreshToken))
+	if err != nil {
+		return general.MySQLErrorToDBError(err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return general.GetDBError("Unknown refresh token", general.NotFoundError)
+	}
+	return nil
+}
+
+// RevokeTokens increases the token version of the user and returns the new version. All refresh tokens of the user are removed if removeRefreshTokens is true.
+// A message to topic revokeTokens with the id of the user as key is added to the outbox in the same transaction.
+func (db *UserDB) RevokeTokens(userID int, removeRefreshTokens bool) (int, error) {
+	tx, err := db.database.Begin()
+	if err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec("UPDATE users SET token_version=token_version+1 WHERE id=?", userID)
+	if err != nil {
+		return 0, general.MySQLErrorToDBError(err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return 0, general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	if removeRefreshTokens {
+		if _, err = tx.Exec("DELETE FROM refresh_tokens WHERE user_id=?", userID); err != nil {
+			return 0, general.MySQLErrorToDBError(err)
+		}
+	}
+	version, err := tokenVersion(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err = general.AddToOutboxWithKey(tx, "revokeTokens", strconv.Itoa(userID), general.NewTokenRevocation(userID, version)); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, general.ErrorToUnknownDBError(err)
+	}
+	return version, nil
+}
+
+// tokenVersion returns the token version of the user within the transaction
+func tokenVersion(tx *sql.Tx, userID int) (int, error) {
+	var version int
+	if err := tx.QueryRow("SELECT token_version FROM users WHERE id=?", userID).Scan(&version); err != nil {
+		if err != sql.ErrNoRows {
+			return 0, general.ErrorToUnknownDBError(err)
+		}
+		return 0, general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	return version, nil
+}
+
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
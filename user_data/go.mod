@@ -8,6 +8,7 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/optiopay/kafka/v2 v2.1.1
 	github.com/prometheus/client_golang v1.5.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 )
 
 replace general => ../general
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"general"
+	"net/http"
+	"time"
+)
+
+// PasswordChange contains the current password and the new password of an user
+type PasswordChange struct {
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+// NewPasswordChange returns a PasswordChange with the given data
+func NewPasswordChange(oldPassword, newPassword string) PasswordChange {
+	return PasswordChange{OldPassword: oldPassword, NewPassword: newPassword}
+}
+
+// AccountDeletion contains the password that confirms the deletion of an account
+type AccountDeletion struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// NewAccountDeletion returns an AccountDeletion with the given password
+func NewAccountDeletion(password string) AccountDeletion {
+	return AccountDeletion{Password: password}
+}
+
+// AccountExport contains all the data that is stored about an user
+type AccountExport struct {
+	User        general.Credentials     `json:"user"`
+	Preferences general.UserPreferences `json:"preferences"`
+	Exported    time.Time               `json:"exported"`
+}
+
+// ChangePassword replaces the password of the user if the old password in the body is correct and the new password satisfies the signup policy.
+// All tokens and refresh tokens of the user are revoked, the response contains a new token and refresh token.
+func (handler *UserHandler) ChangePassword(response http.ResponseWriter, request *http.Request) {
+	creds := request.Context().Value(general.Credentials{}).(general.Credentials)
+	var change PasswordChange
+	if err := general.ReadFromJSON(&change, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request for changing a password: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for changing the password of user %v\n", creds.Username)
+	if violations := handler.SignupPolicy.CheckPassword(creds.Username, change.NewPassword); len(violations) != 0 {
+		badRequests.Inc()
+		handler.Logger.Printf("New password of user %v violates %v rules of the policy\n", creds.Username, len(violations))
+		handler.sendPolicyViolations(response, violations)
+		return
+	}
+	if err := handler.db.ChangePassword(creds.Username, change.OldPassword, change.NewPassword); err != nil {
+		if err.(general.DBError).ErrorCode != general.InvalidInput {
+			handler.Logger.Printf("[ERROR] Failed to change the password of user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("User %v sends an incorrect password for changing the password\n", creds.Username)
+		http.Error(response, "The old password is incorrect.", http.StatusForbidden)
+		return
+	}
+	// Sessions that are started with the old password have to log in again
+	if !handler.revokeAllTokens(response, creds) {
+		return
+	}
+	passwordChanges.Inc()
+	handler.Logger.Printf("Succesfully changed the password of user %v\n", creds.Username)
+	handler.sendToken(creds, response)
+}
+
+// DeleteAccount removes the user if the password in the body is correct. Other services are notified with an userDeleted message.
+func (handler *UserHandler) DeleteAccount(response http.ResponseWriter, request *http.Request) {
+	creds := request.Context().Value(general.Credentials{}).(general.Credentials)
+	var deletion AccountDeletion
+	if err := general.ReadFromJSON(&deletion, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request for deleting an account: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.Logger.Printf("Received call for deleting the account of user %v\n", creds.Username)
+	if _, err := handler.db.Login(creds.Username, deletion.Password); err != nil {
+		if err.(general.DBError).ErrorCode != general.InvalidInput {
+			handler.Logger.Printf("[ERROR] Failed to verify the password of user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("User %v sends an incorrect password for deleting the account\n", creds.Username)
+		http.Error(response, "The password is incorrect.", http.StatusForbidden)
+		return
+	}
+	if !handler.revokeAllTokens(response, creds) {
+		return
+	}
+	if err := handler.db.DeleteUser(creds.ID); err != nil {
+		if err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to delete user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("[ERROR] Can't find user %v from token in database users\n", creds.Username)
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	deletedAccounts.Inc()
+	handler.Logger.Printf("Succesfully deleted user %v\n", creds.Username)
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// ExportAccount responds with a JSON archive containing the credentials of the user and its preferences from the likes service
+func (handler *UserHandler) ExportAccount(response http.ResponseWriter, request *http.Request) {
+	creds := request.Context().Value(general.Credentials{}).(general.Credentials)
+	handler.Logger.Printf("Received call for exporting the data of user %v\n", creds.Username)
+	user, err := handler.db.FindUser(creds.Username)
+	if err != nil {
+		if err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to find user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("[ERROR] Can't find user %v from token in database users\n", creds.Username)
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	resp, err := handler.GETRequest(fmt.Sprintf("%v/intern/export/%v", addressLikes, user.ID))
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to obtain preferences of user %v from likes: %v\n", creds.Username, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		handler.Logger.Printf("[ERROR] Failed to obtain preferences of user %v from likes due to statuscode: %v\n", creds.Username, resp.StatusCode)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	var preferences general.UserPreferences
+	if err = general.ReadFromJSONNoValidation(&preferences, resp.Body); err != nil {
+		handler.Logger.Printf("[ERROR] Failed to deserialize preferences of user %v: %v\n", creds.Username, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	handler.Logger.Printf("Succesfully exported the data of user %v\n", creds.Username)
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"musicapp-%v.json\"", user.Username))
+	response.WriteHeader(http.StatusOK)
+	export := AccountExport{User: user, Preferences: preferences, Exported: time.Now().UTC()}
+	if err = general.WriteToJSON(&export, response); err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// revokeAllTokens revokes all tokens and refresh tokens of the user. It returns false if an error is send to the response
+func (handler *UserHandler) revokeAllTokens(response http.ResponseWriter, creds general.Credentials) bool {
+	version, err := handler.db.RevokeTokens(creds.ID, true)
+	if err != nil {
+		if err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to revoke tokens of user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return false
+		}
+		handler.Logger.Printf("[ERROR] Can't find user %v from token in database users\n", creds.Username)
+		general.SendError(response, http.StatusNotFound)
+		return false
+	}
+	// The other services receive the revocation from the outbox
+	general.RevokeTokens(general.NewTokenRevocation(creds.ID, version))
+	return true
+}
@@ -3,12 +3,12 @@ package handlers
 import (
 	"errors"
 	"general"
+	"general/config"
 	"log"
 	"net/http"
 	"user_data/database"
 
 	"github.com/gorilla/mux"
-	"github.com/optiopay/kafka/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -16,9 +16,22 @@ import (
 
 const servername string = "users"
 
+var addressLikes string
+
 // NewUserServer returns a new server for userdata and a function that starts up the server
-func NewUserServer(handler *UserHandler, broker *kafka.Broker, servername, port string) (server *http.Server, start func()) {
-	server, _, start = general.NewServer(servername, port, initRoutes(handler), broker, nil, handler.Logger)
+func NewUserServer(handler *UserHandler, bus general.Bus, cfg config.Config) (server *http.Server, start func()) {
+	s, channel, startServer := general.NewServer(cfg, initRoutes(handler), bus, nil, handler.Logger)
+	server = s
+	start = func() {
+		go func() {
+			for service := range channel {
+				if service.Name == "likes" {
+					addressLikes = service.Address
+				}
+			}
+		}()
+		startServer()
+	}
 	return
 }
 
@@ -30,6 +43,22 @@ func initRoutes(users *UserHandler) *mux.Router {
 	postRouter := router.Methods(http.MethodPost).Subrouter()
 	postRouter.HandleFunc("/signup", users.SignUp)
 	postRouter.HandleFunc("/login", users.Login)
+	postRouter.HandleFunc("/refresh", users.Refresh)
+	postRouter.Handle("/logout", general.GetValidateTokenMiddleWare(users.Logger)(http.HandlerFunc(users.Logout)))
+
+	router.Methods(http.MethodGet).Path("/.well-known/jwks.json").HandlerFunc(users.GetJWKS)
+
+	adminRouter := router.PathPrefix("/admin/users").Subrouter()
+	adminRouter.Use(general.GetIsAdminMiddleware(users.Logger))
+	adminRouter.Methods(http.MethodGet).Path("").Handler(general.GetOffsetMaxMiddleware(users.Logger)(http.HandlerFunc(users.GetUsers)))
+	adminRouter.Methods(http.MethodPut).Path("/{id}/role").HandlerFunc(users.GrantRole)
+	adminRouter.Methods(http.MethodDelete).Path("/{id}/role").HandlerFunc(users.RevokeRole)
+
+	accountRouter := router.PathPrefix("/account").Subrouter()
+	accountRouter.Use(general.GetValidateTokenMiddleWare(users.Logger))
+	accountRouter.Methods(http.MethodPut).Path("/password").HandlerFunc(users.ChangePassword)
+	accountRouter.Methods(http.MethodDelete).Path("").HandlerFunc(users.DeleteAccount)
+	accountRouter.Methods(http.MethodGet).Path("/export").HandlerFunc(users.ExportAccount)
 
 	validateRouter := router.PathPrefix("/validate").Subrouter()
 	validateRouter.HandleFunc("/", users.GetRole)
@@ -39,17 +68,39 @@ func initRoutes(users *UserHandler) *mux.Router {
 
 // UserHandler consists of a logger and a database
 type UserHandler struct {
-	Logger      *log.Logger
-	db          database.Database
-	SendMessage func(string, []byte)
+	Logger         *log.Logger
+	db             database.Database
+	SendMessage    func(string, []byte)
+	GETRequest     func(string) (*http.Response, error)
+	LoginThrottle  *LoginThrottle
+	SignupPolicy   *SignupPolicy
+	TrustedProxies *TrustedProxies
 }
 
-//NewUserHandler returns a UserHandler. It returns an error if sendMessage is nil.
-func NewUserHandler(logger *log.Logger, db database.Database, sendMessage func(string, []byte) error) (*UserHandler, error) {
+// NewUserHandler returns a UserHandler. It returns an error if sendMessage is nil.
+// If get is nil, then DefaultGETRequest will be used with the default servername.
+// Failed logins are throttled with DefaultUsernameThrottling and DefaultIPThrottling and new credentials are checked with DefaultSignup.
+// The X-Forwarded-For header is trusted for requests from DefaultTrustedProxies.
+func NewUserHandler(logger *log.Logger, db database.Database, sendMessage func(string, []byte) error, get func(string) (*http.Response, error)) (*UserHandler, error) {
 	if sendMessage == nil {
 		return nil, errors.New("sendMessage can't be nil")
 	}
-	return &UserHandler{Logger: logger, db: db, SendMessage: func(topic string, message []byte) {
+	if get == nil {
+		var err error
+		if get, err = general.GetInternalGETRequest(servername); err != nil {
+			return nil, err
+		}
+	}
+	throttle := NewLoginThrottle(DefaultUsernameThrottling, DefaultIPThrottling)
+	policy, err := NewSignupPolicy(DefaultSignup)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := NewTrustedProxies(DefaultTrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &UserHandler{Logger: logger, db: db, GETRequest: get, LoginThrottle: throttle, SignupPolicy: policy, TrustedProxies: proxies, SendMessage: func(topic string, message []byte) {
 		if err := sendMessage(topic, message); err != nil {
 			logger.Printf("Topic %v: Can't send message %s: %v\n", topic, message, err)
 			return
@@ -71,15 +122,28 @@ func NewClientCredentials(username, password string) ClientCredentials {
 
 func (handler *UserHandler) sendToken(creds general.Credentials, response http.ResponseWriter) {
 	if creds.Role == "" {
-		creds.Role = "user"
+		creds.Role = general.RoleUser
+	}
+	version, err := handler.db.GetTokenVersion(creds.ID)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to obtain token version of user %v: %v\n", creds.Username, err.Error())
+		http.Error(response, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
-	token, err := general.CreateToken(creds.ID, creds.Username, creds.Role)
+	token, err := general.CreateTokenWithVersion(creds.ID, creds.Username, creds.Role, version)
 	if err != nil {
 		handler.Logger.Printf("[ERROR] Failed to create valid jwt: %v\n", err.Error())
 		http.Error(response, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	refreshToken, err := handler.db.AddRefreshToken(creds.ID)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to create refresh token for user %v: %v\n", creds.Username, err.Error())
+		http.Error(response, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
 	handler.Logger.Printf("Succesfully created token for user: %v\n", creds.Username)
+	response.Header().Set("Refresh-Token", refreshToken)
 	response.Header().Set("Content-Type", "text/plain;charset=utf-8")
 	response.WriteHeader(http.StatusOK)
 	response.Write([]byte(token))
@@ -113,6 +177,55 @@ var (
 	})
 )
 
+var (
+	succesRefresh = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_refresh_total",
+		Help: "The total number of refreshed tokens",
+	})
+)
+
+var (
+	roleChanges = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_role_changes_total",
+		Help: "The total number of changed roles of users",
+	})
+)
+
+var (
+	passwordChanges = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_password_changes_total",
+		Help: "The total number of changed passwords",
+	})
+)
+
+var (
+	deletedAccounts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_deleted_accounts_total",
+		Help: "The total number of deleted accounts",
+	})
+)
+
+var (
+	failedLogins = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_login_failed_total",
+		Help: "The total number of login attempts with incorrect credentials",
+	})
+)
+
+var (
+	throttledLogins = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_login_throttled_total",
+		Help: "The total number of login attempts that are rejected due to earlier failed attempts",
+	})
+)
+
+var (
+	lockouts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_login_lockouts_total",
+		Help: "The total number of lockouts after too many failed login attempts",
+	})
+)
+
 var (
 	failServerLogin = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "users_login_server_error_total",
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"general"
+	"net/http"
+)
+
+// GetJWKS returns the public keys that can be used by other services for validating tokens
+func (handler *UserHandler) GetJWKS(response http.ResponseWriter, request *http.Request) {
+	jwks, err := general.GetJWKS()
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to obtain the public keys: %s\n", err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	general.WriteToJSON(jwks, response)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"fmt"
 	"general"
+	"math"
 	"net/http"
 )
 
@@ -14,22 +16,48 @@ func (handler *UserHandler) Login(response http.ResponseWriter, request *http.Re
 		general.SendError(response, http.StatusBadRequest)
 		return
 	}
-	handler.Logger.Printf("Received login call for user: %v\n", creds.Username)
+	ip := handler.TrustedProxies.ClientIP(request)
+	handler.Logger.Printf("Received login call for user: %v from %v\n", creds.Username, ip)
+	// The password isn't checked during a backoff or lockout, otherwise passwords can still be guessed
+	attempt, wait, ok := handler.LoginThrottle.Check(creds.Username, ip)
+	if !ok {
+		throttledLogins.Inc()
+		handler.Logger.Printf("Login attempt for user %v from %v is throttled for %v\n", creds.Username, ip, wait)
+		response.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		http.Error(response, "Too many failed login attempts, try again later.", http.StatusTooManyRequests)
+		return
+	}
 	result, err := handler.db.Login(creds.Username, creds.Password)
 	if err != nil {
 		if err.(general.DBError).ErrorCode != general.InvalidInput {
+			attempt.Release()
 			failServerLogin.Inc()
 			handler.Logger.Printf("[ERROR] Failed to retrieve credentials from database: %v\n", err.Error())
 			general.SendError(response, http.StatusInternalServerError)
 			return
 		}
+		attempts, locked := attempt.Failed()
+		failedLogins.Inc()
+		if locked {
+			lockouts.Inc()
+			handler.Logger.Printf("User %v is locked out after %v failed attempts from %v\n", creds.Username, attempts, ip)
+		}
+		go func(failure general.LoginFailure) {
+			msg, err := general.EncodeEvent("loginFailed", failure)
+			if err != nil {
+				handler.Logger.Printf("[ERROR] Failed to convert failed login of user %v to bytes: %v\n", failure.Username, err)
+				return
+			}
+			handler.SendMessage("loginFailed", msg)
+		}(general.NewLoginFailure(creds.Username, ip, attempts, locked))
 		handler.Logger.Printf("User %v sends incorrect credentials\n", creds.Username)
 		http.Error(response, "Username and password do not match.", http.StatusUnauthorized)
 		return
 	}
+	attempt.Succeeded()
 	handler.Logger.Printf("User %v succesfully logged in\n", creds.Username)
 	go func(user general.Credentials) {
-		msg, _ := general.ToJSONBytes(user)
+		msg, err := general.EncodeEvent("login", user)
 		if err != nil {
 			handler.Logger.Printf("[ERROR] Failed to convert user with ID:%v, username: %v to bytes: %v\n", user.ID, user.Username, err)
 			return
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"general/config"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultSignup contains the policy for usernames and passwords that is used for values that aren't configured
+var DefaultSignup = config.Signup{
+	UsernameMinLength:  3,
+	UsernameMaxLength:  64,
+	UsernamePattern:    "^[A-Za-z0-9_.-]+$",
+	PasswordMinLength:  8,
+	PasswordMaxLength:  128,
+	PasswordMinClasses: 2,
+}
+
+// PolicyViolation describes a rule of the signup policy that isn't satisfied by a field
+type PolicyViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyViolations contains all violations of the signup policy
+type PolicyViolations struct {
+	Errors []PolicyViolation `json:"errors"`
+}
+
+// SignupPolicy checks usernames and passwords of new users
+type SignupPolicy struct {
+	rules           config.Signup
+	usernamePattern *regexp.Regexp
+	breached        map[string]struct{}
+}
+
+// NewSignupPolicy returns a SignupPolicy with the given rules. Rules that are zero are replaced by the rules of DefaultSignup.
+// The breached passwords are read from the configured file, no passwords are blocked if no file is configured.
+func NewSignupPolicy(rules config.Signup) (*SignupPolicy, error) {
+	if rules.UsernameMinLength == 0 {
+		rules.UsernameMinLength = DefaultSignup.UsernameMinLength
+	}
+	if rules.UsernameMaxLength == 0 {
+		rules.UsernameMaxLength = DefaultSignup.UsernameMaxLength
+	}
+	if rules.UsernamePattern == "" {
+		rules.UsernamePattern = DefaultSignup.UsernamePattern
+	}
+	if rules.PasswordMinLength == 0 {
+		rules.PasswordMinLength = DefaultSignup.PasswordMinLength
+	}
+	if rules.PasswordMaxLength == 0 {
+		rules.PasswordMaxLength = DefaultSignup.PasswordMaxLength
+	}
+	if rules.PasswordMinClasses == 0 {
+		rules.PasswordMinClasses = DefaultSignup.PasswordMinClasses
+	}
+	if rules.UsernameMinLength > rules.UsernameMaxLength || rules.PasswordMinLength > rules.PasswordMaxLength {
+		return nil, fmt.Errorf("The minimum length of usernames and passwords can't be larger than the maximum length")
+	}
+	pattern, err := regexp.Compile(rules.UsernamePattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pattern for usernames %v: %s", rules.UsernamePattern, err)
+	}
+	breached := make(map[string]struct{})
+	if rules.BreachedPasswords != "" {
+		if breached, err = readBreachedPasswords(rules.BreachedPasswords); err != nil {
+			return nil, err
+		}
+	}
+	return &SignupPolicy{rules: rules, usernamePattern: pattern, breached: breached}, nil
+}
+
+// readBreachedPasswords reads the file with one password per line. Empty lines and lines starting with # are skipped.
+func readBreachedPasswords(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open file with breached passwords %v: %s", path, err)
+	}
+	defer file.Close()
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[line] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Can't read file with breached passwords %v: %s", path, err)
+	}
+	return breached, nil
+}
+
+// Check returns all violations of the policy by the credentials
+func (policy *SignupPolicy) Check(creds ClientCredentials) []PolicyViolation {
+	return append(policy.checkUsername(creds.Username), policy.CheckPassword(creds.Username, creds.Password)...)
+}
+
+func (policy *SignupPolicy) checkUsername(username string) []PolicyViolation {
+	rules := policy.rules
+	if username == "" {
+		return []PolicyViolation{{Field: "username", Rule: "required", Message: "A username is required"}}
+	}
+	violations := make([]PolicyViolation, 0)
+	if length := utf8.RuneCountInString(username); length < rules.UsernameMinLength {
+		violations = append(violations, PolicyViolation{Field: "username", Rule: "minLength", Message: fmt.Sprintf("The username should contain at least %v characters", rules.UsernameMinLength)})
+	} else if length > rules.UsernameMaxLength {
+		violations = append(violations, PolicyViolation{Field: "username", Rule: "maxLength", Message: fmt.Sprintf("The username can contain at most %v characters", rules.UsernameMaxLength)})
+	}
+	if !policy.usernamePattern.MatchString(username) {
+		violations = append(violations, PolicyViolation{Field: "username", Rule: "pattern", Message: fmt.Sprintf("The username should match %v", rules.UsernamePattern)})
+	}
+	return violations
+}
+
+// CheckPassword returns all violations of the policy by the password of the user with the given username
+func (policy *SignupPolicy) CheckPassword(username, password string) []PolicyViolation {
+	rules := policy.rules
+	if password == "" {
+		return []PolicyViolation{{Field: "password", Rule: "required", Message: "A password is required"}}
+	}
+	violations := make([]PolicyViolation, 0)
+	if length := utf8.RuneCountInString(password); length < rules.PasswordMinLength {
+		violations = append(violations, PolicyViolation{Field: "password", Rule: "minLength", Message: fmt.Sprintf("The password should contain at least %v characters", rules.PasswordMinLength)})
+	} else if length > rules.PasswordMaxLength {
+		violations = append(violations, PolicyViolation{Field: "password", Rule: "maxLength", Message: fmt.Sprintf("The password can contain at most %v characters", rules.PasswordMaxLength)})
+	}
+	if characterClasses(password) < rules.PasswordMinClasses {
+		violations = append(violations, PolicyViolation{Field: "password", Rule: "strength", Message: fmt.Sprintf("The password should contain at least %v of: lowercase letters, uppercase letters, digits and other characters", rules.PasswordMinClasses)})
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, PolicyViolation{Field: "password", Rule: "username", Message: "The password can't contain the username"})
+	}
+	if _, ok := policy.breached[password]; ok {
+		violations = append(violations, PolicyViolation{Field: "password", Rule: "breached", Message: "The password is known from data breaches"})
+	}
+	return violations
+}
+
+// characterClasses returns how many of lowercase letters, uppercase letters, digits and other characters are used in the password
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, char := range password {
+		switch {
+		case unicode.IsLower(char):
+			lower = 1
+		case unicode.IsUpper(char):
+			upper = 1
+		case unicode.IsDigit(char):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
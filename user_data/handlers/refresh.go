@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"general"
+	"net/http"
+)
+
+// Refresh returns a new token and a new refresh token in exchange for the refresh token in the Refresh-Token header. A refresh token can only be used once.
+func (handler *UserHandler) Refresh(response http.ResponseWriter, request *http.Request) {
+	refreshToken := request.Header.Get("Refresh-Token")
+	if refreshToken == "" {
+		badRequests.Inc()
+		handler.Logger.Printf("Got refresh request without refresh token\n")
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	creds, err := handler.db.UseRefreshToken(refreshToken)
+	if err != nil {
+		if err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to use refresh token: %v\n", err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("Got refresh request with unknown or expired refresh token\n")
+		http.Error(response, "Invalid refresh token.", http.StatusUnauthorized)
+		return
+	}
+	handler.Logger.Printf("Succesfully refreshed token of user: %v\n", creds.Username)
+	succesRefresh.Inc()
+	handler.sendToken(creds, response)
+}
+
+// Logout revokes all tokens of the user and removes the refresh token in the Refresh-Token header.
+// Other sessions of the user can obtain a new token with their refresh token, unless the query contains all=true.
+func (handler *UserHandler) Logout(response http.ResponseWriter, request *http.Request) {
+	creds := request.Context().Value(general.Credentials{}).(general.Credentials)
+	if refreshToken := request.Header.Get("Refresh-Token"); refreshToken != "" {
+		if err := handler.db.RemoveRefreshToken(creds.ID, refreshToken); err != nil && err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to remove refresh token of user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+	}
+	version, err := handler.db.RevokeTokens(creds.ID, request.URL.Query().Get("all") == "true")
+	if err != nil {
+		if err.(general.DBError).ErrorCode != general.NotFoundError {
+			handler.Logger.Printf("[ERROR] Failed to revoke tokens of user %v: %v\n", creds.Username, err.Error())
+			general.SendError(response, http.StatusInternalServerError)
+			return
+		}
+		handler.Logger.Printf("[ERROR] Can't find user %v from token in database users\n", creds.Username)
+		general.SendError(response, http.StatusNotFound)
+		return
+	}
+	// The other services receive the revocation from the outbox
+	general.RevokeTokens(general.NewTokenRevocation(creds.ID, version))
+	handler.Logger.Printf("User %v succesfully logged out\n", creds.Username)
+	response.WriteHeader(http.StatusOK)
+}
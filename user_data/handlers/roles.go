@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"general"
+	"net/http"
+	"strconv"
+	"user_data/database"
+
+	"github.com/gorilla/mux"
+)
+
+// Role contains a role that is granted to an user
+type Role struct {
+	Role string `json:"role" validate:"required,oneof=admin curator user"`
+}
+
+// NewRole returns a Role with the given role
+func NewRole(role string) Role {
+	return Role{Role: role}
+}
+
+// GetUsers returns the users bounded by the given offset and max in the request. The results are ordered by username
+func (handler *UserHandler) GetUsers(response http.ResponseWriter, request *http.Request) {
+	offsetMax := request.Context().Value(general.OffsetMax{}).(general.OffsetMax)
+	offset, max := offsetMax.Offset, offsetMax.Max
+	handler.Logger.Printf("Received call for users with limit %v,%v\n", offset, max)
+	results, err := handler.db.ListUsers(offset, max+1)
+	if err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			handler.Logger.Printf("Request with no results for users: %v,%v\n", offset, max)
+			general.SendError(response, http.StatusNotFound)
+			return
+		}
+		handler.Logger.Printf("[ERROR] Can't find users with limit %v,%v due to: %s\n", offset, max, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	handler.Logger.Printf("Succesfully found %v users with limit %v,%v\n", len(results), offset, max)
+	hasNext := (len(results) > max)
+	if hasNext {
+		results = results[0:max]
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	if err = general.WriteToJSON(&general.MultipleCredentials{Data: results, HasNext: hasNext}, response); err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// GrantRole gives the user in the path the role from the body of the request
+func (handler *UserHandler) GrantRole(response http.ResponseWriter, request *http.Request) {
+	var role Role
+	if err := general.ReadFromJSON(&role, request.Body); err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Got invalid request for granting a role: %v\n", err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	handler.changeRole(response, request, role.Role)
+}
+
+// RevokeRole sets the role of the user in the path back to user
+func (handler *UserHandler) RevokeRole(response http.ResponseWriter, request *http.Request) {
+	handler.changeRole(response, request, general.RoleUser)
+}
+
+func (handler *UserHandler) changeRole(response http.ResponseWriter, request *http.Request, role string) {
+	admin := request.Context().Value(general.Credentials{}).(general.Credentials)
+	userIDstring := mux.Vars(request)["id"]
+	userID, err := strconv.Atoi(userIDstring)
+	if err != nil {
+		badRequests.Inc()
+		handler.Logger.Printf("Received request with invalid id %v results in: %s\n", userIDstring, err)
+		general.SendError(response, http.StatusBadRequest)
+		return
+	}
+	// This ensures that there is always at least one admin
+	if userID == admin.ID {
+		handler.Logger.Printf("Admin %v tries to change its own role\n", admin.Username)
+		http.Error(response, "Admins can't change their own role.", http.StatusForbidden)
+		return
+	}
+	handler.Logger.Printf("Received call from admin %v for changing the role of user #%v to %v\n", admin.Username, userID, role)
+	version, err := handler.db.SetRole(userID, role)
+	if err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			handler.Logger.Printf("Can't find user #%v for changing the role\n", userID)
+			general.SendError(response, http.StatusNotFound)
+			return
+		}
+		handler.Logger.Printf("[ERROR] Failed to change the role of user #%v to %v: %s\n", userID, role, err)
+		general.SendError(response, http.StatusInternalServerError)
+		return
+	}
+	// The tokens of the user contain the old role, so they are revoked. The user can obtain a token with the new role by using a refresh token.
+	// The other services receive the role change and the revocation from the outbox.
+	general.RevokeTokens(general.NewTokenRevocation(userID, version))
+	roleChanges.Inc()
+	handler.Logger.Printf("Succesfully changed the role of user #%v to %v\n", userID, role)
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// BootstrapAdmin makes the user with the given username an admin. This is only allowed if there is no admin yet, other admins are added by an admin.
+func BootstrapAdmin(db database.Database, username string) error {
+	if username == "" {
+		return errors.New("No username is given")
+	}
+	admins, err := db.CountUsersWithRole(general.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins != 0 {
+		return errors.New("There is already an admin, other admins have to be added by an admin")
+	}
+	user, err := db.FindUser(username)
+	if err != nil {
+		if err.(general.DBError).ErrorCode == general.NotFoundError {
+			return fmt.Errorf("Can't find user %v, the user has to sign up first", username)
+		}
+		return err
+	}
+	_, err = db.SetRole(user.ID, general.RoleAdmin)
+	return err
+}
@@ -8,12 +8,18 @@ import (
 // SignUp handles the request to add a new user to the database.
 func (handler *UserHandler) SignUp(response http.ResponseWriter, request *http.Request) {
 	var creds ClientCredentials
-	if err := general.ReadFromJSON(&creds, request.Body); err != nil {
+	if err := general.ReadFromJSONNoValidation(&creds, request.Body); err != nil {
 		badRequests.Inc()
 		handler.Logger.Printf("Got invalid signup request: %v\n", err)
 		general.SendError(response, http.StatusBadRequest)
 		return
 	}
+	if violations := handler.SignupPolicy.Check(creds); len(violations) != 0 {
+		badRequests.Inc()
+		handler.Logger.Printf("Signup request for user %v violates %v rules of the policy\n", creds.Username, len(violations))
+		handler.sendPolicyViolations(response, violations)
+		return
+	}
 	handler.Logger.Printf("Received call for new user: %v\n", creds.Username)
 	userID, err := handler.db.SignUp(creds.Username, creds.Password)
 	if err != nil {
@@ -28,15 +34,17 @@ func (handler *UserHandler) SignUp(response http.ResponseWriter, request *http.R
 		return
 	}
 	handler.Logger.Printf("Succesfully added new user: %v\n", creds.Username)
+	// The message to topic newUser is send from the outbox of the database
 	newUser := general.Credentials{ID: userID, Username: creds.Username}
-	go func(credentials general.Credentials) {
-		msg, _ := general.ToJSONBytes(&newUser)
-		if err != nil {
-			handler.Logger.Printf("[ERROR] Failed to convert user with ID:%v, username: %v to bytes: %v\n", credentials.ID, credentials.Username, err)
-			return
-		}
-		handler.SendMessage("newUser", msg)
-	}(newUser)
 	succesSignUps.Inc()
 	handler.sendToken(newUser, response)
 }
+
+// sendPolicyViolations responds with a bad request that lists all the violations of the signup policy
+func (handler *UserHandler) sendPolicyViolations(response http.ResponseWriter, violations []PolicyViolation) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusBadRequest)
+	if err := general.WriteToJSON(&PolicyViolations{Errors: violations}, response); err != nil {
+		handler.Logger.Printf("[ERROR] %s\n", err)
+	}
+}
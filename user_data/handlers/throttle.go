@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleParameters determine how failed login attempts are throttled.
+// After FreeAttempts failures every next attempt has to wait BaseDelay, doubled for every further failure and bounded by MaxDelay.
+// After LockoutAttempts failures no attempts are allowed during LockoutDuration. Failures are forgotten ResetAfter the last failure.
+type ThrottleParameters struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+// DefaultUsernameThrottling are the parameters for throttling failed login attempts for a single username
+var DefaultUsernameThrottling = ThrottleParameters{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, LockoutAttempts: 10, LockoutDuration: 15 * time.Minute, ResetAfter: time.Hour}
+
+// DefaultIPThrottling are the parameters for throttling failed login attempts from a single IP address.
+// These are less strict, since multiple users can share an IP address.
+var DefaultIPThrottling = ThrottleParameters{FreeAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Minute, LockoutAttempts: 50, LockoutDuration: 15 * time.Minute, ResetAfter: time.Hour}
+
+// LoginThrottle keeps track of failed login attempts per username and per IP address
+type LoginThrottle struct {
+	mutex     sync.Mutex
+	usernames *attempts
+	ips       *attempts
+}
+
+// NewLoginThrottle returns a LoginThrottle that throttles usernames and IP addresses with the given parameters
+func NewLoginThrottle(perUsername, perIP ThrottleParameters) *LoginThrottle {
+	return &LoginThrottle{usernames: newAttempts(perUsername), ips: newAttempts(perIP)}
+}
+
+// Check reserves a login attempt for the username from the IP address. It returns false together with the time the client has to wait if the attempt isn't allowed yet.
+// A reserved attempt is pending until it failed or succeeded. An attempt isn't allowed if the pending attempts would exceed the free attempts when they fail,
+// so concurrent guesses can't get past the throttling.
+func (throttle *LoginThrottle) Check(username, ip string) (*LoginAttempt, time.Duration, bool) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	now := time.Now()
+	waitUsername := throttle.usernames.wait(username, now)
+	waitIP := throttle.ips.wait(ip, now)
+	if waitIP > waitUsername {
+		return nil, waitIP, false
+	}
+	if waitUsername > 0 {
+		return nil, waitUsername, false
+	}
+	throttle.usernames.reserve(username)
+	throttle.ips.reserve(ip)
+	return &LoginAttempt{throttle: throttle, username: username, ip: ip}, 0, true
+}
+
+// Failed registers a failed login attempt that isn't reserved by Check. It returns the number of failures of the username and whether the username or the IP address is locked out.
+func (throttle *LoginThrottle) Failed(username, ip string) (int, bool) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	now := time.Now()
+	failures, lockedUsername := throttle.usernames.fail(username, now)
+	_, lockedIP := throttle.ips.fail(ip, now)
+	return failures, lockedUsername || lockedIP
+}
+
+// Succeeded forgets the failed login attempts of the username. Failures of the IP address are kept, otherwise an attacker could reset them with its own account.
+func (throttle *LoginThrottle) Succeeded(username string) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	throttle.usernames.reset(username)
+}
+
+// LoginAttempt is a login attempt that is reserved by LoginThrottle.Check. Exactly one of Failed, Succeeded and Release has to be called for it.
+type LoginAttempt struct {
+	throttle     *LoginThrottle
+	username, ip string
+}
+
+// Failed registers the attempt as failed. It returns the number of failures of the username and whether the username or the IP address is locked out.
+func (attempt *LoginAttempt) Failed() (int, bool) {
+	throttle := attempt.throttle
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	now := time.Now()
+	throttle.usernames.release(attempt.username)
+	throttle.ips.release(attempt.ip)
+	failures, lockedUsername := throttle.usernames.fail(attempt.username, now)
+	_, lockedIP := throttle.ips.fail(attempt.ip, now)
+	return failures, lockedUsername || lockedIP
+}
+
+// Succeeded forgets the failed login attempts of the username like LoginThrottle.Succeeded
+func (attempt *LoginAttempt) Succeeded() {
+	throttle := attempt.throttle
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	throttle.ips.release(attempt.ip)
+	throttle.usernames.reset(attempt.username)
+}
+
+// Release gives up the attempt without counting it, e.g. if the password couldn't be checked
+func (attempt *LoginAttempt) Release() {
+	throttle := attempt.throttle
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	throttle.usernames.release(attempt.username)
+	throttle.ips.release(attempt.ip)
+}
+
+type attemptEntry struct {
+	failures     int
+	pending      int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// attempts keeps track of failed and pending attempts per key, the caller has to hold the mutex of the LoginThrottle
+type attempts struct {
+	params    ThrottleParameters
+	entries   map[string]*attemptEntry
+	lastPrune time.Time
+}
+
+func newAttempts(params ThrottleParameters) *attempts {
+	return &attempts{params: params, entries: make(map[string]*attemptEntry)}
+}
+
+// wait returns the time until the next attempt for the key is allowed.
+// While attempts are pending, another attempt is only allowed if the key isn't throttled when all pending attempts fail.
+func (attempts *attempts) wait(key string, now time.Time) time.Duration {
+	entry, ok := attempts.entries[key]
+	if !ok {
+		return 0
+	}
+	if now.Before(entry.blockedUntil) {
+		return entry.blockedUntil.Sub(now)
+	}
+	if entry.pending == 0 {
+		return 0
+	}
+	failures := entry.failures
+	if attempts.expired(entry, now) {
+		failures = 0
+	}
+	return attempts.delay(failures + entry.pending)
+}
+
+// reserve adds a pending attempt for the key
+func (attempts *attempts) reserve(key string) {
+	entry, ok := attempts.entries[key]
+	if !ok {
+		entry = &attemptEntry{}
+		attempts.entries[key] = entry
+	}
+	entry.pending++
+}
+
+// release removes a pending attempt of the key, the entry is already removed if the key is reset in the meantime
+func (attempts *attempts) release(key string) {
+	if entry, ok := attempts.entries[key]; ok && entry.pending > 0 {
+		entry.pending--
+	}
+}
+
+// fail registers a failed attempt for the key and returns the number of failures and whether the key is locked out
+func (attempts *attempts) fail(key string, now time.Time) (int, bool) {
+	attempts.prune(now)
+	entry, ok := attempts.entries[key]
+	if !ok {
+		entry = &attemptEntry{}
+		attempts.entries[key] = entry
+	}
+	if attempts.expired(entry, now) {
+		*entry = attemptEntry{pending: entry.pending}
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if delay := attempts.delay(entry.failures); delay > 0 {
+		entry.blockedUntil = now.Add(delay)
+	}
+	return entry.failures, entry.failures >= attempts.params.LockoutAttempts
+}
+
+// delay returns the time that the next attempt has to wait after the given number of failures
+func (attempts *attempts) delay(failures int) time.Duration {
+	params := attempts.params
+	if failures >= params.LockoutAttempts {
+		return params.LockoutDuration
+	}
+	if failures <= params.FreeAttempts {
+		return 0
+	}
+	// Bounding the shift avoids an overflow of the delay
+	if shift := failures - params.FreeAttempts - 1; shift < 32 && params.BaseDelay<<shift < params.MaxDelay {
+		return params.BaseDelay << shift
+	}
+	return params.MaxDelay
+}
+
+// expired returns true if the failures of the entry are forgotten
+func (attempts *attempts) expired(entry *attemptEntry, now time.Time) bool {
+	return now.Sub(entry.lastFailure) >= attempts.params.ResetAfter && !now.Before(entry.blockedUntil)
+}
+
+func (attempts *attempts) reset(key string) {
+	delete(attempts.entries, key)
+}
+
+// prune removes the entries that are forgotten and don't have pending attempts. The entries are checked at most once per ResetAfter.
+func (attempts *attempts) prune(now time.Time) {
+	if now.Sub(attempts.lastPrune) < attempts.params.ResetAfter {
+		return
+	}
+	attempts.lastPrune = now
+	for key, entry := range attempts.entries {
+		if entry.pending == 0 && attempts.expired(entry, now) {
+			delete(attempts.entries, key)
+		}
+	}
+}
+
+// DefaultTrustedProxies are the loopback networks, the gateway runs on the same host as the users service by default
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// TrustedProxies are the networks of the proxies, like the gateway, that add the address of the client to the X-Forwarded-For header
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies returns TrustedProxies for the given IP addresses and networks in CIDR notation
+func NewTrustedProxies(proxies []string) (*TrustedProxies, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid address of a trusted proxy: %v", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid network of trusted proxies %v: %s", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	return &TrustedProxies{networks: networks}, nil
+}
+
+// ClientIP returns the IP address of the client. The X-Forwarded-For header is only used for requests from a trusted proxy,
+// the client is the last address in the header that isn't a trusted proxy. The header of other requests can be set by anyone, so it is ignored.
+func (proxies *TrustedProxies) ClientIP(request *http.Request) string {
+	ip, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		ip = request.RemoteAddr
+	}
+	if !proxies.trusts(ip) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(request.Header.Values("X-Forwarded-For"), ","), ",")
+	for index := len(forwarded) - 1; index >= 0; index-- {
+		address := strings.TrimSpace(forwarded[index])
+		if address == "" {
+			continue
+		}
+		if net.ParseIP(address) == nil {
+			// Addresses in front of an invalid address can't be trusted
+			break
+		}
+		ip = address
+		if !proxies.trusts(address) {
+			break
+		}
+	}
+	return ip
+}
+
+func (proxies *TrustedProxies) trusts(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,38 +1,89 @@
 package main
 
 import (
+	"flag"
 	"general"
+	"general/config"
 	"log"
 	"os"
 	"user_data/database"
 	"user_data/handlers"
 
-	"github.com/optiopay/kafka/v2"
-
 	_ "github.com/go-sql-driver/mysql"
 )
 
-// These configurations will be exported to a file
-const port string = ":9001"
-const servername string = "users"
+var configPath = flag.String("config", "config.yaml", "Path to the configuration file of this service")
 
 func main() {
-	logger := log.New(os.Stdout, servername, log.LstdFlags|log.Lshortfile)
-	db, err := general.ConnectToMYSQL(logger, servername, "credentialsMusicApp:validate@tcp(127.0.0.1:3306)/userdata")
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("[ERROR] Can't load configuration due to: %s\n", err)
+	}
+	logger := log.New(os.Stdout, cfg.Servername, log.LstdFlags|log.Lshortfile)
+	if err = general.ConfigureKeys(logger, cfg.Keys); err != nil {
+		logger.Fatalf("[ERROR] Can't load keys due to: %s\n", err)
+	}
+	go general.WatchKeys(logger, *configPath)
+	db, err := general.ConnectToMYSQL(logger, cfg)
 	if err != nil {
 		logger.Printf("Stop starting server")
 		return
 	}
 	defer db.Close()
-	broker, closeBroker := general.ConnectToKafka(logger, servername)
-	defer closeBroker()
-	if topicErr := general.CreateTopics(broker, logger, "newUser", "login"); topicErr != nil {
+	// The pepper is only needed for verifying the legacy hashes of users that didn't log in since the switch to argon2id
+	pepper := cfg.Passwords.Pepper
+	if pepper == "" {
+		logger.Printf("[WARNING] No password pepper is configured in passwords.pepper or %v, users with a legacy hash can't log in\n", config.EnvPepper)
+	}
+	params := argon2Parameters(cfg.Argon2)
+	// The first admin is added with the command: bootstrap-admin <username>
+	if flag.Arg(0) == "bootstrap-admin" {
+		if err = handlers.BootstrapAdmin(database.NewUserDB(db, params, pepper), flag.Arg(1)); err != nil {
+			logger.Fatalf("[ERROR] Can't bootstrap admin: %s\n", err)
+		}
+		logger.Printf("Succesfully made %v an admin\n", flag.Arg(1))
+		return
+	}
+	bus, closeBus := general.ConnectToBus(logger, cfg)
+	defer closeBus()
+	if topicErr := bus.CreateTopics("newUser", "login", "roleChanged", "userDeleted", "loginFailed"); topicErr != nil {
+		logger.Fatalf("[ERROR] Failed to create topics due to: %s\n", topicErr)
+	}
+	// Only the newest revocation of every user is needed, the revocations are keyed by the id of the user
+	if topicErr := bus.CreateCompactedTopics("revokeTokens"); topicErr != nil {
 		logger.Fatalf("[ERROR] Failed to create topics due to: %s\n", topicErr)
 	}
-	handler, err := handlers.NewUserHandler(logger, database.NewUserDB(db), general.GetSendMessage(broker.Producer(kafka.NewProducerConf())))
+	// New users are send from the outbox that is written together with the user
+	stopRelay := general.NewOutboxRelay(logger, db, bus).Start()
+	defer stopRelay()
+	handler, err := handlers.NewUserHandler(logger, database.NewUserDB(db, params, pepper), bus.Publish, nil)
 	if err != nil {
 		logger.Fatalf("[ERROR] Can't create handler due to: %s\n", err)
 	}
-	_, startServer := handlers.NewUserServer(handler, broker, servername, port)
+	if handler.SignupPolicy, err = handlers.NewSignupPolicy(cfg.Signup); err != nil {
+		logger.Fatalf("[ERROR] Invalid signup policy: %s\n", err)
+	}
+	if len(cfg.TrustedProxies) > 0 {
+		if handler.TrustedProxies, err = handlers.NewTrustedProxies(cfg.TrustedProxies); err != nil {
+			logger.Fatalf("[ERROR] Invalid trusted proxies: %s\n", err)
+		}
+	}
+	_, startServer := handlers.NewUserServer(handler, bus, cfg)
 	startServer()
 }
+
+// argon2Parameters returns the default parameters for hashing passwords overridden by the values that are configured
+func argon2Parameters(configured config.Argon2) database.Argon2Parameters {
+	params := database.DefaultArgon2Parameters
+	if configured.Time != 0 {
+		params.Time = configured.Time
+	}
+	if configured.Memory != 0 {
+		params.Memory = configured.Memory
+	}
+	if configured.Threads != 0 {
+		params.Threads = configured.Threads
+	}
+	return params
+}
@@ -0,0 +1,176 @@
+package test
+
+import (
+	"general"
+	"net/http"
+	"testing"
+	"user_data/handlers"
+)
+
+func TestChangePassword_response(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	cases := map[string]struct {
+		change             interface{}
+		sendToken          bool
+		expectedStatusCode int
+		expectedPassword   string
+	}{
+		"Correct old password":   {handlers.NewPasswordChange(user.Password, "NewPassword1"), true, http.StatusOK, "NewPassword1"},
+		"Incorrect old password": {handlers.NewPasswordChange("Wrong", "NewPassword1"), true, http.StatusForbidden, user.Password},
+		"Missing new password":   {handlers.NewPasswordChange(user.Password, ""), true, http.StatusBadRequest, user.Password},
+		"Too short new password": {handlers.NewPasswordChange(user.Password, "Short1"), true, http.StatusBadRequest, user.Password},
+		"No token is send":       {handlers.NewPasswordChange(user.Password, "NewPassword1"), false, http.StatusUnauthorized, user.Password},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.db[user.Username] = testCredentials{id: testNewUserID(), username: user.Username, password: user.Password}
+		server, channel := testServerWithPreferences(t, db, nil)
+		go func() {
+			for range channel {
+			}
+		}()
+		login := general.TestRequest(t, server, http.MethodPost, "/login", "", user)
+		token, refreshToken := login.Body.String(), login.Header().Get("Refresh-Token")
+		if !test.sendToken {
+			token = ""
+		}
+		response := general.TestRequest(t, server, http.MethodPut, "/account/password", token, test.change)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if password := db.db[user.Username].password; password != test.expectedPassword {
+			t.Errorf("%v: Expects password: %v but got: %v\n", name, test.expectedPassword, password)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		if testValidateToken(token) {
+			t.Errorf("%v: Expects the token from before the password change to be revoked\n", name)
+		}
+		if refresh := testRequestWithHeaders(server, http.MethodPost, "/refresh", map[string]string{"Refresh-Token": refreshToken}); refresh.Code != http.StatusUnauthorized {
+			t.Errorf("%v: Expects the refresh token from before the password change to be revoked but got statuscode: %v\n", name, refresh.Code)
+		}
+		if !testValidateToken(response.Body.String()) || response.Header().Get("Refresh-Token") == "" {
+			t.Errorf("%v: Expects a new valid token and refresh token\n", name)
+		}
+	}
+}
+
+func TestDeleteAccount_response(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	cases := map[string]struct {
+		deletion           interface{}
+		sendToken          bool
+		expectedStatusCode int
+		expectedDeleted    bool
+	}{
+		"Correct password":   {handlers.NewAccountDeletion(user.Password), true, http.StatusOK, true},
+		"Incorrect password": {handlers.NewAccountDeletion("Wrong"), true, http.StatusForbidden, false},
+		"Missing password":   {handlers.NewAccountDeletion(""), true, http.StatusBadRequest, false},
+		"No token is send":   {handlers.NewAccountDeletion(user.Password), false, http.StatusUnauthorized, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.db[user.Username] = testCredentials{id: testNewUserID(), username: user.Username, password: user.Password}
+		server, channel := testServerWithPreferences(t, db, nil)
+		go func() {
+			for range channel {
+			}
+		}()
+		login := general.TestRequest(t, server, http.MethodPost, "/login", "", user)
+		token, refreshToken := login.Body.String(), login.Header().Get("Refresh-Token")
+		if !test.sendToken {
+			token = ""
+		}
+		response := general.TestRequest(t, server, http.MethodDelete, "/account", token, test.deletion)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if _, ok := db.db[user.Username]; ok == test.expectedDeleted {
+			t.Errorf("%v: Expects user to be deleted: %v but got: %v\n", name, test.expectedDeleted, !ok)
+		}
+		if !test.expectedDeleted {
+			continue
+		}
+		if testValidateToken(token) {
+			t.Errorf("%v: Expects the token of the deleted user to be revoked\n", name)
+		}
+		if refresh := testRequestWithHeaders(server, http.MethodPost, "/refresh", map[string]string{"Refresh-Token": refreshToken}); refresh.Code != http.StatusUnauthorized {
+			t.Errorf("%v: Expects the refresh token of the deleted user to be invalid but got statuscode: %v\n", name, refresh.Code)
+		}
+	}
+}
+
+func TestDeleteAccount_sendMessage(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	db := newTestDB()
+	userID := testNewUserID()
+	db.db[user.Username] = testCredentials{id: userID, username: user.Username, password: user.Password, role: "user"}
+	server, channel := testServerWithPreferences(t, db, nil)
+	token, _ := general.CreateTokenWithVersion(userID, user.Username, "user", 0)
+	general.TestRequest(t, server, http.MethodDelete, "/account", token, handlers.NewAccountDeletion(user.Password))
+	messages := make(map[string][]byte)
+	for _, message := range testMessages(db, channel) {
+		messages[message.Topic] = message.Message
+	}
+	var deleted general.Credentials
+	if _, err := general.DecodeEvent(messages["userDeleted"], &deleted); err != nil {
+		t.Fatalf("Expects to send a message containing the deleted user but deserializing results in: %v\n", err)
+	}
+	if expected := general.NewCredentials(userID, user.Username, "user"); deleted != expected {
+		t.Errorf("Expects deleted user %v but got: %v\n", expected, deleted)
+	}
+	var revocation general.TokenRevocation
+	if _, err := general.DecodeEvent(messages["revokeTokens"], &revocation); err != nil {
+		t.Fatalf("Expects to send a message containing a revocation but deserializing results in: %v\n", err)
+	}
+	if expected := general.NewTokenRevocation(userID, 1); revocation != expected {
+		t.Errorf("Expects revocation %v but got: %v\n", expected, revocation)
+	}
+}
+
+func TestExportAccount_response(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	artist := general.NewArtist(1, "Sum 41", "")
+	cases := map[string]struct {
+		preferences        map[int]general.UserPreferences
+		sendToken          bool
+		expectedStatusCode int
+		expectedLikes      int
+	}{
+		"User with preferences":    {map[int]general.UserPreferences{1: general.NewUserPreferences([]general.Song{general.NewSong(11, []general.Artist{artist}, "In Too Deep")}, nil, []general.Artist{artist})}, true, http.StatusOK, 1},
+		"User without preferences": {map[int]general.UserPreferences{}, true, http.StatusOK, 0},
+		"Likes is unavailable":     {nil, true, http.StatusInternalServerError, 0},
+		"No token is send":         {map[int]general.UserPreferences{}, false, http.StatusUnauthorized, 0},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.db[user.Username] = testCredentials{id: 1, username: user.Username, password: user.Password, role: "user"}
+		server, _ := testServerWithPreferences(t, db, test.preferences)
+		token := ""
+		if test.sendToken {
+			token, _ = general.CreateToken(1, user.Username, "user")
+		}
+		response := general.TestRequest(t, server, http.MethodGet, "/account/export", token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var export handlers.AccountExport
+		if err := general.ReadFromJSONNoValidation(&export, response.Body); err != nil {
+			t.Errorf("%v: Failed to decode response: %s\n", name, err)
+			continue
+		}
+		if expected := general.NewCredentials(1, user.Username, "user"); export.User != expected {
+			t.Errorf("%v: Expects user %v in the export but got: %v\n", name, expected, export.User)
+		}
+		if len(export.Preferences.Likes) != test.expectedLikes {
+			t.Errorf("%v: Expects %v likes in the export but got: %v\n", name, test.expectedLikes, export.Preferences.Likes)
+		}
+		if export.Exported.IsZero() {
+			t.Errorf("%v: Expects the time of the export\n", name)
+		}
+	}
+}
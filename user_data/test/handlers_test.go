@@ -4,7 +4,6 @@ import (
 	"general"
 	"net/http"
 	"testing"
-	"time"
 	"user_data/handlers"
 )
 
@@ -63,20 +62,21 @@ func TestHandlers_sendMessage(t *testing.T) {
 			t.Errorf("%v: Failed to run test due to failing signup existing user: %s\n", name, err)
 			continue
 		}
+		db.clearOutbox()
 		server, channel := testServer(t, db)
 		general.TestRequest(t, server, http.MethodPost, test.path, "", handlers.NewClientCredentials(test.username, test.password))
-		go func() {
-			time.Sleep(time.Millisecond)
-			close(channel)
-		}()
 		foundTopic := false
-		for message := range channel {
+		for _, message := range testMessages(db, channel) {
+			// Failed logins are tested in TestLogin_sendLoginFailed
+			if message.Topic == "loginFailed" {
+				continue
+			}
 			if message.Topic != test.topic {
 				t.Errorf("%v: Expects topic %v but got: %v\n", name, test.topic, message.Topic)
 			} else {
 				foundTopic = true
 				var result general.Credentials
-				if err := general.FromJSONBytes(&result, []byte(message.Message)); err != nil {
+				if _, err := general.DecodeEvent(message.Message, &result); err != nil {
 					t.Errorf("%v: Expects to send a message containing an user but deserializing results in: %v\n", name, err)
 				}
 				if result.ID == 0 {
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"general"
+	"general/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestJWKS_response(t *testing.T) {
+	dir := t.TempDir()
+	signingKey := testWriteKey(t, dir, "signing.pem", testECDSAKey(t), false)
+	oldKey := testWriteKey(t, dir, "old.pem", testRSAKey(t), true)
+	keys := config.Keys{ID: "users-2", SigningKey: signingKey, VerificationKeys: []config.VerificationKey{{ID: "users-1", Path: oldKey}}}
+	if err := general.ConfigureKeys(general.TestEmptyLogger(), keys); err != nil {
+		t.Fatalf("Failed to configure keys: %s\n", err)
+	}
+	server, _ := testServer(t, newTestDB())
+	response := general.TestRequest(t, server, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expects statuscode: %v but got: %v\n", http.StatusOK, response.Code)
+	}
+	var jwks general.JWKS
+	if err := general.ReadFromJSONNoValidation(&jwks, response.Body); err != nil {
+		t.Fatalf("Failed to decode response: %s\n", err)
+	}
+	// Keys that were used for signing tokens in other tests are rotated out and can also be in the JWKS
+	expected := map[string]string{"users-2": "ES256", "users-1": "RS256"}
+	for _, jwk := range jwks.Keys {
+		if _, err := jwk.PublicKey(); err != nil {
+			t.Errorf("Can't obtain public key from %v: %s\n", jwk.Kid, err)
+		}
+		if alg, ok := expected[jwk.Kid]; ok {
+			if jwk.Alg != alg {
+				t.Errorf("Expects key %v with algorithm %v but got: %v\n", jwk.Kid, alg, jwk.Alg)
+			}
+			delete(expected, jwk.Kid)
+		}
+	}
+	if len(expected) != 0 {
+		t.Errorf("Expects keys %v in the JWKS but got: %v\n", expected, jwks.Keys)
+	}
+	token, err := general.CreateToken(1, "Test", "user")
+	if err != nil {
+		t.Fatalf("Failed to create token: %s\n", err)
+	}
+	if !testValidateToken(token) {
+		t.Errorf("Expects token signed by the configured key to be valid\n")
+	}
+}
+
+func TestKeySet_validateWithJWKS(t *testing.T) {
+	cases := map[string]struct {
+		private crypto.Signer
+	}{
+		"ES256": {testECDSAKey(t)},
+		"RS256": {testRSAKey(t)},
+	}
+	for name, test := range cases {
+		users := general.NewKeySet()
+		signingKey, err := general.NewSigningKey("users-"+name, test.private)
+		if err != nil {
+			t.Errorf("%v: Failed to create signing key: %s\n", name, err)
+			continue
+		}
+		users.SetSigningKey(signingKey)
+		jwks := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			keys, _ := users.JWKS()
+			general.WriteToJSON(keys, response)
+		}))
+		other := general.NewKeySet()
+		other.UseJWKS(jwks.URL, jwks.Client())
+		token, err := users.CreateToken(3, "Test", "user", 0)
+		if err != nil {
+			t.Errorf("%v: Failed to create token: %s\n", name, err)
+		}
+		creds, err := other.ValidateToken(token)
+		if err != nil {
+			t.Errorf("%v: Expects token to be valid but got: %s\n", name, err)
+		}
+		if expected := general.NewCredentials(3, "Test", "user"); creds != expected {
+			t.Errorf("%v: Expects credentials %v but got: %v\n", name, expected, creds)
+		}
+		unknown, _ := general.NewKeySet().CreateToken(3, "Test", "admin", 0)
+		if _, err := other.ValidateToken(unknown); err == nil {
+			t.Errorf("%v: Expects token signed by an unknown key to be invalid\n", name)
+		}
+		jwks.Close()
+	}
+}
+
+func TestKeySet_rotation(t *testing.T) {
+	keys := general.NewKeySet()
+	oldKey, _ := general.NewSigningKey("old", testECDSAKey(t))
+	newKey, _ := general.NewSigningKey("new", testRSAKey(t))
+	keys.SetSigningKey(oldKey)
+	oldToken, _ := keys.CreateToken(1, "Old", "user", 0)
+	keys.SetSigningKey(newKey)
+	newToken, _ := keys.CreateToken(2, "New", "user", 0)
+	for name, token := range map[string]string{"Token signed by the rotated out key": oldToken, "Token signed by the new key": newToken} {
+		if _, err := keys.ValidateToken(token); err != nil {
+			t.Errorf("%v: Expects token to be valid but got: %s\n", name, err)
+		}
+	}
+	jwks, err := keys.JWKS()
+	if err != nil {
+		t.Fatalf("Failed to obtain JWKS: %s\n", err)
+	}
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[1].Kid != "old" {
+		t.Errorf("Expects the new and the rotated out key in the JWKS but got: %v\n", jwks.Keys)
+	}
+}
+
+func TestKeySet_roles(t *testing.T) {
+	private := testECDSAKey(t)
+	allRoles := []string{general.RoleInternal, general.RoleUser, general.RoleCurator, general.RoleAdmin}
+	cases := map[string]struct {
+		trustedRoles  []string
+		role          string
+		expectedValid bool
+	}{
+		"Internal token of a service":    {[]string{general.RoleInternal}, general.RoleInternal, true},
+		"Admin token of a service":       {[]string{general.RoleInternal}, general.RoleAdmin, false},
+		"User token of a service":        {[]string{general.RoleInternal}, general.RoleUser, false},
+		"Admin token of users service":   {allRoles, general.RoleAdmin, true},
+		"Curator token of users service": {allRoles, general.RoleCurator, true},
+		"Key without roles":              {nil, general.RoleAdmin, true},
+	}
+	for name, test := range cases {
+		// The private key is used without restrictions, like by a service that is compromised
+		signer := general.NewKeySet()
+		signingKey, _ := general.NewSigningKey("likes-1", private)
+		signer.SetSigningKey(signingKey)
+		token, err := signer.CreateToken(1, "Test", test.role, 0)
+		if err != nil {
+			t.Errorf("%v: Failed to create token: %s\n", name, err)
+			continue
+		}
+		// The users service trusts the key for the roles and publishes the roles on its JWKS endpoint
+		users := general.NewKeySet()
+		if err = users.SetVerificationKeys(map[string]general.TrustedKey{"likes-1": {Public: private.Public(), Roles: test.trustedRoles}}); err != nil {
+			t.Errorf("%v: Failed to set verification keys: %s\n", name, err)
+			continue
+		}
+		jwks := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			keys, _ := users.JWKS()
+			general.WriteToJSON(keys, response)
+		}))
+		other := general.NewKeySet()
+		other.UseJWKS(jwks.URL, jwks.Client())
+		for source, keys := range map[string]*general.KeySet{"Verification key": users, "Key from JWKS": other} {
+			if _, err := keys.ValidateToken(token); (err == nil) != test.expectedValid {
+				t.Errorf("%v: %v: Expects token to be valid: %v but got: %v\n", name, source, test.expectedValid, err)
+			}
+		}
+		jwks.Close()
+	}
+
+	// A signing key doesn't sign tokens of roles that it isn't trusted for
+	keys := general.NewKeySet()
+	signingKey, _ := general.NewSigningKey("likes-1", private)
+	signingKey.Roles = []string{general.RoleInternal}
+	keys.SetSigningKey(signingKey)
+	if _, err := keys.CreateToken(1, "Test", general.RoleAdmin, 0); err == nil {
+		t.Errorf("Expects an error when a key of a service signs an admin token\n")
+	}
+	token, err := keys.CreateToken(-1, "likes", general.RoleInternal, 0)
+	if err != nil {
+		t.Fatalf("Failed to create internal token: %s\n", err)
+	}
+	if _, err = keys.ValidateToken(token); err != nil {
+		t.Errorf("Expects internal token to be valid but got: %s\n", err)
+	}
+}
+
+func testECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s\n", err)
+	}
+	return key
+}
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s\n", err)
+	}
+	return key
+}
+
+// testWriteKey writes the private key or its public key PEM encoded to the file with the given name and returns its path
+func testWriteKey(t *testing.T, dir, name string, key crypto.Signer, public bool) string {
+	var block *pem.Block
+	if public {
+		bytes, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatalf("Failed to encode public key: %s\n", err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: bytes}
+	} else {
+		bytes, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Failed to encode private key: %s\n", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: bytes}
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write key: %s\n", err)
+	}
+	return path
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"user_data/database"
+)
+
+var testArgon2Parameters = database.Argon2Parameters{Time: 1, Memory: 1024, Threads: 1}
+
+func TestHashPassword_encoding(t *testing.T) {
+	hash, err := database.HashPassword("Password", testArgon2Parameters)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %s\n", err)
+	}
+	if prefix := "$argon2id$v=19$m=1024,t=1,p=1$"; !strings.HasPrefix(hash, prefix) {
+		t.Errorf("Expects hash with prefix %v but got: %v\n", prefix, hash)
+	}
+	if other, _ := database.HashPassword("Password", testArgon2Parameters); other == hash {
+		t.Errorf("Expects different hashes for the same password due to the salt but got twice: %v\n", hash)
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := database.HashPassword("Password", testArgon2Parameters)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %s\n", err)
+	}
+	cases := map[string]struct {
+		password, encoded     string
+		params                database.Argon2Parameters
+		expectedMatch, rehash bool
+		expectedError         bool
+	}{
+		"Correct password":                 {"Password", hash, testArgon2Parameters, true, false, false},
+		"Wrong password":                   {"password", hash, testArgon2Parameters, false, false, false},
+		"Correct password with new params": {"Password", hash, database.Argon2Parameters{Time: 2, Memory: 1024, Threads: 1}, true, true, false},
+		"Wrong password with new params":   {"password", hash, database.Argon2Parameters{Time: 2, Memory: 1024, Threads: 1}, false, false, false},
+		"Legacy hash":                      {"Password", "oF3Lq1sdTtBJB3hU0Ws3pTOfkPHy_mBLBXk2I0hMWjk=", testArgon2Parameters, false, false, true},
+		"Hash with invalid parameters":     {"Password", "$argon2id$v=19$m=a,t=1,p=1$c2FsdA$aGFzaA", testArgon2Parameters, false, false, true},
+		"Hash of other version":            {"Password", strings.Replace(hash, "v=19", "v=16", 1), testArgon2Parameters, false, false, true},
+	}
+	for name, test := range cases {
+		match, rehash, err := database.VerifyPassword(test.password, test.encoded, test.params)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+		if match != test.expectedMatch {
+			t.Errorf("%v: Expects match: %v but got: %v\n", name, test.expectedMatch, match)
+		}
+		if rehash != test.rehash {
+			t.Errorf("%v: Expects rehash: %v but got: %v\n", name, test.rehash, rehash)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"general"
+	"general/config"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"user_data/handlers"
+)
+
+func TestSignUp_policyViolations(t *testing.T) {
+	cases := map[string]struct {
+		username, password string
+		expectedRules      []string
+	}{
+		"Valid credentials":                   {"TestPolicy", "Password1", []string{}},
+		"Missing username and password":       {"", "", []string{"username:required", "password:required"}},
+		"Too short username":                  {"ab", "Password1", []string{"username:minLength"}},
+		"Too long username":                   {strings.Repeat("a", 65), "Password1", []string{"username:maxLength"}},
+		"Username with invalid characters":    {"Test user!", "Password1", []string{"username:pattern"}},
+		"Too short password":                  {"TestPolicy", "Pass1", []string{"password:minLength"}},
+		"Too long password":                   {"TestPolicy", strings.Repeat("Pa1", 50), []string{"password:maxLength"}},
+		"Weak password":                       {"TestPolicy", "password", []string{"password:strength"}},
+		"Password containing the username":    {"TestPolicy", "testpolicy1", []string{"password:username"}},
+		"Breached password":                   {"TestPolicy", "Welcome123", []string{"password:breached"}},
+		"Violations of username and password": {"a", "a", []string{"username:minLength", "password:minLength", "password:strength", "password:username"}},
+	}
+	breached := filepath.Join(t.TempDir(), "breached.txt")
+	if err := ioutil.WriteFile(breached, []byte("# Passwords from data breaches\nWelcome123\n\nQwerty123\n"), 0600); err != nil {
+		t.Fatalf("Failed to write breached passwords: %s\n", err)
+	}
+	policy, err := handlers.NewSignupPolicy(config.Signup{BreachedPasswords: breached})
+	if err != nil {
+		t.Fatalf("Failed to create signup policy: %s\n", err)
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		handler, channel := testUserHandler(t, db, nil)
+		handler.SignupPolicy = policy
+		server, _ := handlers.NewUserServer(handler, nil, config.Config{Servername: "user_data_test"})
+		go func() {
+			for range channel {
+			}
+		}()
+		response := general.TestRequest(t, server, http.MethodPost, "/signup", "", handlers.NewClientCredentials(test.username, test.password))
+		if len(test.expectedRules) == 0 {
+			if response.Code != http.StatusOK {
+				t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, http.StatusOK, response.Code)
+			}
+			continue
+		}
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, http.StatusBadRequest, response.Code)
+			continue
+		}
+		if _, ok := db.db[test.username]; ok {
+			t.Errorf("%v: Expects user %v not to be saved\n", name, test.username)
+		}
+		var violations handlers.PolicyViolations
+		if err := general.ReadFromJSONNoValidation(&violations, response.Body); err != nil {
+			t.Errorf("%v: Failed to decode response: %s\n", name, err)
+			continue
+		}
+		rules := make([]string, 0, len(violations.Errors))
+		for _, violation := range violations.Errors {
+			if violation.Message == "" {
+				t.Errorf("%v: Expects a message for violation of rule %v\n", name, violation.Rule)
+			}
+			rules = append(rules, violation.Field+":"+violation.Rule)
+		}
+		if !reflect.DeepEqual(rules, test.expectedRules) {
+			t.Errorf("%v: Expects violations of %v but got: %v\n", name, test.expectedRules, rules)
+		}
+	}
+}
+
+func TestNewSignupPolicy_invalidRules(t *testing.T) {
+	cases := map[string]config.Signup{
+		"Invalid username pattern":               {UsernamePattern: "["},
+		"Minimum length larger than the maximum": {PasswordMinLength: 20, PasswordMaxLength: 10},
+		"Missing file with breached passwords":   {BreachedPasswords: filepath.Join(t.TempDir(), "missing.txt")},
+	}
+	for name, rules := range cases {
+		if _, err := handlers.NewSignupPolicy(rules); err == nil {
+			t.Errorf("%v: Expects an error for rules %v\n", name, rules)
+		}
+	}
+}
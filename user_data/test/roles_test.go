@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"general"
+	"net/http"
+	"testing"
+	"user_data/handlers"
+)
+
+func TestGetUsers_response(t *testing.T) {
+	cases := map[string]struct {
+		role, query        string
+		expectedStatusCode int
+		expectedUsers      []string
+		expectedHasNext    bool
+	}{
+		"Admin":                     {general.RoleAdmin, "", http.StatusOK, []string{"Admin", "Alice", "Bob", "Carol"}, false},
+		"Admin with max":            {general.RoleAdmin, "?max=2", http.StatusOK, []string{"Admin", "Alice"}, true},
+		"Admin with offset":         {general.RoleAdmin, "?offset=2&max=2", http.StatusOK, []string{"Bob", "Carol"}, false},
+		"Admin with too big offset": {general.RoleAdmin, "?offset=4", http.StatusNotFound, nil, false},
+		"Curator":                   {general.RoleCurator, "", http.StatusUnauthorized, nil, false},
+		"User":                      {general.RoleUser, "", http.StatusUnauthorized, nil, false},
+	}
+	for name, test := range cases {
+		db, adminID := testRolesDB()
+		server, _ := testServer(t, db)
+		token, _ := general.CreateToken(adminID, "Admin", test.role)
+		response := general.TestRequest(t, server, http.MethodGet, "/admin/users"+test.query, token, nil)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusOK {
+			continue
+		}
+		var result general.MultipleCredentials
+		if err := general.ReadFromJSONNoValidation(&result, response.Body); err != nil {
+			t.Errorf("%v: Failed to decode response: %s\n", name, err)
+			continue
+		}
+		if result.HasNext != test.expectedHasNext {
+			t.Errorf("%v: Expects hasNext: %v but got: %v\n", name, test.expectedHasNext, result.HasNext)
+		}
+		if len(result.Data) != len(test.expectedUsers) {
+			t.Errorf("%v: Expects users %v but got: %v\n", name, test.expectedUsers, result.Data)
+			continue
+		}
+		for index, user := range result.Data {
+			if user.Username != test.expectedUsers[index] {
+				t.Errorf("%v: Expects user %v on index %v but got: %v\n", name, test.expectedUsers[index], index, user.Username)
+			}
+		}
+	}
+}
+
+func TestChangeRole_response(t *testing.T) {
+	cases := map[string]struct {
+		method, role, username string
+		body                   string
+		expectedStatusCode     int
+		expectedRole           string
+	}{
+		"Grant curator":              {http.MethodPut, general.RoleAdmin, "Alice", general.RoleCurator, http.StatusOK, general.RoleCurator},
+		"Grant admin":                {http.MethodPut, general.RoleAdmin, "Alice", general.RoleAdmin, http.StatusOK, general.RoleAdmin},
+		"Grant unknown role":         {http.MethodPut, general.RoleAdmin, "Alice", "superuser", http.StatusBadRequest, general.RoleUser},
+		"Grant role to unknown user": {http.MethodPut, general.RoleAdmin, "Unknown", general.RoleCurator, http.StatusNotFound, ""},
+		"Grant role to yourself":     {http.MethodPut, general.RoleAdmin, "Admin", general.RoleCurator, http.StatusForbidden, general.RoleAdmin},
+		"Grant role as curator":      {http.MethodPut, general.RoleCurator, "Alice", general.RoleCurator, http.StatusUnauthorized, general.RoleUser},
+		"Revoke role":                {http.MethodDelete, general.RoleAdmin, "Bob", "", http.StatusOK, general.RoleUser},
+		"Revoke role as user":        {http.MethodDelete, general.RoleUser, "Bob", "", http.StatusUnauthorized, general.RoleCurator},
+	}
+	for name, test := range cases {
+		db, adminID := testRolesDB()
+		server, channel := testServer(t, db)
+		go func() {
+			for range channel {
+			}
+		}()
+		userID := -1
+		if user, ok := db.db[test.username]; ok {
+			userID = user.id
+		}
+		token, _ := general.CreateToken(adminID, "Admin", test.role)
+		response := general.TestRequest(t, server, test.method, fmt.Sprintf("/admin/users/%v/role", userID), token, handlers.NewRole(test.body))
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if role := db.db[test.username].role; role != test.expectedRole {
+			t.Errorf("%v: Expects role %v in DB but got: %v\n", name, test.expectedRole, role)
+		}
+	}
+}
+
+func TestChangeRole_sendMessage(t *testing.T) {
+	db, adminID := testRolesDB()
+	server, channel := testServer(t, db)
+	user := db.db["Alice"]
+	userToken, _ := general.CreateTokenWithVersion(user.id, user.username, general.RoleUser, 0)
+	token, _ := general.CreateToken(adminID, "Admin", general.RoleAdmin)
+	general.TestRequest(t, server, http.MethodPut, fmt.Sprintf("/admin/users/%v/role", user.id), token, handlers.NewRole(general.RoleCurator))
+	expectedTopics := map[string]bool{"roleChanged": true, "revokeTokens": true}
+	for _, message := range testMessages(db, channel) {
+		if !expectedTopics[message.Topic] {
+			t.Fatalf("Expects message on topics %v but got: %v\n", expectedTopics, message.Topic)
+		}
+		delete(expectedTopics, message.Topic)
+		if message.Topic != "roleChanged" {
+			continue
+		}
+		var roleChange general.RoleChange
+		if _, err := general.DecodeEvent(message.Message, &roleChange); err != nil {
+			t.Fatalf("Expects to send a message containing a role change but deserializing results in: %v\n", err)
+		}
+		if expected := general.NewRoleChange(user.id, general.RoleCurator, 1); roleChange != expected {
+			t.Errorf("Expects role change %v but got: %v\n", expected, roleChange)
+		}
+	}
+	if len(expectedTopics) != 0 {
+		t.Errorf("Expects messages on topics %v in the outbox\n", expectedTopics)
+	}
+	if testValidateToken(userToken) {
+		t.Errorf("Expects the token with the old role to be revoked\n")
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	cases := map[string]struct {
+		username      string
+		existingAdmin bool
+		expectedError bool
+	}{
+		"Existing user":               {"Alice", false, false},
+		"Existing user with an admin": {"Alice", true, true},
+		"Unknown user":                {"Unknown", false, true},
+		"Missing username":            {"", false, true},
+	}
+	for name, test := range cases {
+		db, _ := testRolesDB()
+		if !test.existingAdmin {
+			admin := db.db["Admin"]
+			admin.role = general.RoleUser
+			db.db["Admin"] = admin
+		}
+		err := handlers.BootstrapAdmin(db, test.username)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+		if expected := !test.expectedError; (db.db[test.username].role == general.RoleAdmin) != expected {
+			t.Errorf("%v: Expects user %v to be admin: %v but got role: %v\n", name, test.username, expected, db.db[test.username].role)
+		}
+	}
+}
+
+// testRolesDB returns a testDB with an admin, a curator and two users and the id of the admin.
+// Changing a role revokes the tokens of the user, so every user gets a new id.
+func testRolesDB() (testDB, int) {
+	db := newTestDB()
+	roles := map[string]string{"Admin": general.RoleAdmin, "Alice": general.RoleUser, "Bob": general.RoleCurator, "Carol": general.RoleUser}
+	for username, role := range roles {
+		db.db[username] = testCredentials{id: testNewUserID(), username: username, password: "Password", role: role}
+	}
+	return db, db.db["Admin"].id
+}
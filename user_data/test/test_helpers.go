@@ -1,35 +1,104 @@
 package test
 
 import (
+	"fmt"
 	"general"
+	"general/config"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 	"user_data/database"
 	"user_data/handlers"
 )
 
 func testServer(t *testing.T, db database.Database) (*http.Server, chan general.Message) {
+	return testServerWithPreferences(t, db, nil)
+}
+
+// testServerWithPreferences returns a server that obtains the given preferences from the likes service.
+// The likes service is unavailable if preferences is nil.
+func testServerWithPreferences(t *testing.T, db database.Database, preferences map[int]general.UserPreferences) (*http.Server, chan general.Message) {
+	handler, channel := testUserHandler(t, db, preferences)
+	server, _ := handlers.NewUserServer(handler, nil, config.Config{Servername: "user_data_test"})
+	return server, channel
+}
+
+// testServerWithThrottle returns a server that throttles failed logins with the given throttle
+func testServerWithThrottle(t *testing.T, db database.Database, throttle *handlers.LoginThrottle) (*http.Server, chan general.Message) {
+	handler, channel := testUserHandler(t, db, nil)
+	handler.LoginThrottle = throttle
+	server, _ := handlers.NewUserServer(handler, nil, config.Config{Servername: "user_data_test"})
+	return server, channel
+}
+
+func testUserHandler(t *testing.T, db database.Database, preferences map[int]general.UserPreferences) (*handlers.UserHandler, chan general.Message) {
 	sendMessage, channel := general.TestSendMessage()
-	handler, err := handlers.NewUserHandler(general.TestEmptyLogger(), db, sendMessage)
+	handler, err := handlers.NewUserHandler(general.TestEmptyLogger(), db, sendMessage, testGetRequest(preferences))
 	if err != nil {
 		t.Fatalf("Failed to create a testServer due to: %s\n", err)
 	}
-	server, _ := handlers.NewUserServer(handler, nil, "user_data_test", "")
-	return server, channel
+	return handler, channel
+}
+
+func testGetRequest(preferences map[int]general.UserPreferences) func(string) (*http.Response, error) {
+	return func(address string) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		if preferences == nil {
+			general.SendError(recorder, http.StatusServiceUnavailable)
+			return recorder.Result(), nil
+		}
+		userID, err := strconv.Atoi(address[strings.LastIndex(address, "/")+1:])
+		if err != nil || !strings.Contains(address, "/intern/export/") {
+			general.SendError(recorder, http.StatusBadRequest)
+			return recorder.Result(), nil
+		}
+		preferencesUser, ok := preferences[userID]
+		if !ok {
+			preferencesUser = general.NewUserPreferences(nil, nil, nil)
+		}
+		recorder.WriteHeader(http.StatusOK)
+		general.WriteToJSON(&preferencesUser, recorder)
+		return recorder.Result(), nil
+	}
 }
 
 type testCredentials struct {
-	id                 int
-	username, password string
+	id                       int
+	username, password, role string
 }
 
 type testDB struct {
-	db map[string]testCredentials
+	db            map[string]testCredentials
+	versions      map[int]int
+	refreshTokens map[string]int
+	outbox        *[]general.Message
 }
 
 func newTestDB() testDB {
-	return testDB{db: make(map[string]testCredentials)}
+	return testDB{db: make(map[string]testCredentials), versions: make(map[int]int), refreshTokens: make(map[string]int), outbox: &[]general.Message{}}
+}
+
+func (fake testDB) clearOutbox() {
+	*fake.outbox = (*fake.outbox)[:0]
+}
+
+// testMessages returns the messages that the handler sends within a millisecond followed by the messages in the outbox of the database
+func testMessages(db testDB, channel chan general.Message) []general.Message {
+	messages := make([]general.Message, 0)
+	timeout := time.After(time.Millisecond)
+	for {
+		select {
+		case message := <-channel:
+			messages = append(messages, message)
+		case <-timeout:
+			return append(messages, *db.outbox...)
+		}
+	}
 }
 
 func (fake testDB) SignUp(username, password string) (int, error) {
@@ -39,6 +108,8 @@ func (fake testDB) SignUp(username, password string) (int, error) {
 	}
 	id := len(fake.db) + 1
 	fake.db[username] = testCredentials{id: id, username: username, password: password}
+	msg, _ := general.EncodeEvent("newUser", general.Credentials{ID: id, Username: username})
+	*fake.outbox = append(*fake.outbox, general.Message{Topic: "newUser", Message: msg})
 	return id, nil
 }
 func (fake testDB) Login(username, password string) (general.Credentials, error) {
@@ -46,14 +117,131 @@ func (fake testDB) Login(username, password string) (general.Credentials, error)
 	if !ok || entry.password != password {
 		return general.Credentials{}, general.GetDBError("The credentials do not match", general.InvalidInput)
 	}
-	return general.NewCredentials(entry.id, entry.username, ""), nil
+	return general.NewCredentials(entry.id, entry.username, entry.role), nil
 }
 func (fake testDB) FindUser(username string) (general.Credentials, error) {
 	user, ok := fake.db[username]
 	if !ok {
 		return general.Credentials{}, general.GetDBError("Can't find user", general.NotFoundError)
 	}
-	return general.NewCredentials(user.id, user.username, ""), nil
+	return general.NewCredentials(user.id, user.username, user.role), nil
+}
+
+func (fake testDB) findUserByID(userID int) (general.Credentials, bool) {
+	for _, user := range fake.db {
+		if user.id == userID {
+			return general.NewCredentials(user.id, user.username, user.role), true
+		}
+	}
+	return general.Credentials{}, false
+}
+func (fake testDB) GetTokenVersion(userID int) (int, error) {
+	if _, ok := fake.findUserByID(userID); !ok {
+		return 0, general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	return fake.versions[userID], nil
+}
+func (fake testDB) AddRefreshToken(userID int) (string, error) {
+	refreshToken := fmt.Sprintf("refresh%v-%v", userID, rand.Int63())
+	fake.refreshTokens[refreshToken] = userID
+	return refreshToken, nil
+}
+func (fake testDB) UseRefreshToken(refreshToken string) (general.Credentials, error) {
+	userID, ok := fake.refreshTokens[refreshToken]
+	if !ok {
+		return general.Credentials{}, general.GetDBError("Unknown refresh token", general.NotFoundError)
+	}
+	delete(fake.refreshTokens, refreshToken)
+	user, _ := fake.findUserByID(userID)
+	return user, nil
+}
+func (fake testDB) RemoveRefreshToken(userID int, refreshToken string) error {
+	if owner, ok := fake.refreshTokens[refreshToken]; !ok || owner != userID {
+		return general.GetDBError("Unknown refresh token", general.NotFoundError)
+	}
+	delete(fake.refreshTokens, refreshToken)
+	return nil
+}
+func (fake testDB) RevokeTokens(userID int, removeRefreshTokens bool) (int, error) {
+	if _, ok := fake.findUserByID(userID); !ok {
+		return 0, general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	fake.versions[userID]++
+	if removeRefreshTokens {
+		for refreshToken, owner := range fake.refreshTokens {
+			if owner == userID {
+				delete(fake.refreshTokens, refreshToken)
+			}
+		}
+	}
+	msg, _ := general.EncodeEvent("revokeTokens", general.NewTokenRevocation(userID, fake.versions[userID]))
+	*fake.outbox = append(*fake.outbox, general.Message{Topic: "revokeTokens", Message: msg})
+	return fake.versions[userID], nil
+}
+
+func (fake testDB) ListUsers(offset, max int) ([]general.Credentials, error) {
+	usernames := make([]string, 0, len(fake.db))
+	for username := range fake.db {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	users := make([]general.Credentials, 0, max)
+	for index := offset; index < len(usernames) && index < offset+max; index++ {
+		user := fake.db[usernames[index]]
+		users = append(users, general.NewCredentials(user.id, user.username, user.role))
+	}
+	if len(users) == 0 {
+		return nil, general.GetDBError("No users found", general.NotFoundError)
+	}
+	return users, nil
+}
+func (fake testDB) SetRole(userID int, role string) (int, error) {
+	for username, user := range fake.db {
+		if user.id == userID {
+			user.role = role
+			fake.db[username] = user
+			fake.versions[userID]++
+			msg, _ := general.EncodeEvent("roleChanged", general.NewRoleChange(userID, role, fake.versions[userID]))
+			*fake.outbox = append(*fake.outbox, general.Message{Topic: "roleChanged", Message: msg})
+			msg, _ = general.EncodeEvent("revokeTokens", general.NewTokenRevocation(userID, fake.versions[userID]))
+			*fake.outbox = append(*fake.outbox, general.Message{Topic: "revokeTokens", Message: msg})
+			return fake.versions[userID], nil
+		}
+	}
+	return 0, general.GetDBError("Can't find user", general.NotFoundError)
+}
+func (fake testDB) CountUsersWithRole(role string) (int, error) {
+	count := 0
+	for _, user := range fake.db {
+		if user.role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+func (fake testDB) ChangePassword(username, oldPassword, newPassword string) error {
+	user, ok := fake.db[username]
+	if !ok || user.password != oldPassword {
+		return general.GetDBError("The credentials do not match", general.InvalidInput)
+	}
+	user.password = newPassword
+	fake.db[username] = user
+	return nil
+}
+func (fake testDB) DeleteUser(userID int) error {
+	user, ok := fake.findUserByID(userID)
+	if !ok {
+		return general.GetDBError("Can't find user", general.NotFoundError)
+	}
+	delete(fake.db, user.Username)
+	for refreshToken, owner := range fake.refreshTokens {
+		if owner == userID {
+			delete(fake.refreshTokens, refreshToken)
+		}
+	}
+	msg, _ := general.EncodeEvent("userDeleted", user)
+	*fake.outbox = append(*fake.outbox, general.Message{Topic: "userDeleted", Message: msg})
+	return nil
 }
 
 type testHandler struct{}
@@ -70,3 +258,14 @@ func testValidateToken(token string) bool {
 	tokenValidator(handler).ServeHTTP(recorder, request)
 	return recorder.Code == http.StatusOK
 }
+
+// testRequestWithHeaders sends a request without body and with the given headers to the server
+func testRequestWithHeaders(server *http.Server, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "http://localhost"+path, nil)
+	for header, value := range headers {
+		request.Header.Add(header, value)
+	}
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -0,0 +1,303 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"general"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"user_data/handlers"
+)
+
+// testIP is the address of requests from httptest
+const testIP = "192.0.2.1"
+
+func testLoginThrottle() *handlers.LoginThrottle {
+	perUsername := handlers.ThrottleParameters{FreeAttempts: 2, BaseDelay: time.Hour, MaxDelay: 2 * time.Hour, LockoutAttempts: 5, LockoutDuration: 3 * time.Hour, ResetAfter: 24 * time.Hour}
+	perIP := handlers.ThrottleParameters{FreeAttempts: 4, BaseDelay: time.Hour, MaxDelay: 2 * time.Hour, LockoutAttempts: 8, LockoutDuration: 3 * time.Hour, ResetAfter: 24 * time.Hour}
+	return handlers.NewLoginThrottle(perUsername, perIP)
+}
+
+func TestLogin_throttling(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	cases := map[string]struct {
+		username, ip       string
+		failures           int
+		expectedStatusCode int
+		expectedRetryAfter int
+	}{
+		"No failed attempts":                       {user.Username, testIP, 0, http.StatusOK, 0},
+		"Failures below the free attempts":         {user.Username, testIP, 2, http.StatusOK, 0},
+		"Backoff after the free attempts":          {user.Username, "198.51.100.1", 3, http.StatusTooManyRequests, 3600},
+		"Backoff is doubled for every failure":     {user.Username, "198.51.100.1", 4, http.StatusTooManyRequests, 7200},
+		"Lockout after too many failures":          {user.Username, "198.51.100.1", 5, http.StatusTooManyRequests, 3 * 3600},
+		"Failures of other usernames":              {"Other", "198.51.100.1", 5, http.StatusOK, 0},
+		"Failures from the same IP address":        {"Other", testIP, 5, http.StatusTooManyRequests, 3600},
+		"Failures from the same IP below the free": {"Other", testIP, 4, http.StatusOK, 0},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.SignUp(user.Username, user.Password)
+		throttle := testLoginThrottle()
+		for i := 0; i < test.failures; i++ {
+			throttle.Failed(test.username, test.ip)
+		}
+		server, channel := testServerWithThrottle(t, db, throttle)
+		go func() {
+			for range channel {
+			}
+		}()
+		response := general.TestRequest(t, server, http.MethodPost, "/login", "", user)
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if response.Code != http.StatusTooManyRequests {
+			continue
+		}
+		retryAfter, err := strconv.Atoi(response.Header().Get("Retry-After"))
+		if err != nil || retryAfter < test.expectedRetryAfter-1 || retryAfter > test.expectedRetryAfter {
+			t.Errorf("%v: Expects to retry after %v seconds but got: %v\n", name, test.expectedRetryAfter, response.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestLogin_failuresAreCounted(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	db := newTestDB()
+	db.SignUp(user.Username, user.Password)
+	server, channel := testServerWithThrottle(t, db, testLoginThrottle())
+	go func() {
+		for range channel {
+		}
+	}()
+	expected := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for attempt, expectedStatusCode := range expected {
+		response := general.TestRequest(t, server, http.MethodPost, "/login", "", handlers.NewClientCredentials(user.Username, "NOTsimilar"))
+		if response.Code != expectedStatusCode {
+			t.Errorf("Attempt %v: Expects statuscode: %v but got: %v\n", attempt+1, expectedStatusCode, response.Code)
+		}
+	}
+	// The correct password is also rejected during the backoff
+	if response := general.TestRequest(t, server, http.MethodPost, "/login", "", user); response.Code != http.StatusTooManyRequests {
+		t.Errorf("Expects a login with the correct password to be throttled but got statuscode: %v\n", response.Code)
+	}
+}
+
+func TestLoginThrottle_succesResetsUsername(t *testing.T) {
+	throttle := testLoginThrottle()
+	for i := 0; i < 2; i++ {
+		throttle.Failed("Test", testIP)
+	}
+	throttle.Succeeded("Test")
+	for i := 0; i < 2; i++ {
+		throttle.Failed("Test", "198.51.100.1")
+	}
+	attempt, wait, ok := throttle.Check("Test", "198.51.100.1")
+	if !ok {
+		t.Fatalf("Expects failures before a succesful login to be forgotten but got a wait of %v\n", wait)
+	}
+	attempt.Release()
+	// The IP address has 2 failures left before it is throttled
+	throttle.Failed("Other", testIP)
+	throttle.Failed("Other", testIP)
+	throttle.Succeeded("Other")
+	attempt, _, ok = throttle.Check("Test", testIP)
+	if !ok {
+		t.Fatalf("Expects the IP address to be allowed with 4 failures\n")
+	}
+	attempt.Release()
+	throttle.Failed("Other", testIP)
+	if _, _, ok := throttle.Check("Test", testIP); ok {
+		t.Errorf("Expects failures of an IP address to be kept after a succesful login\n")
+	}
+}
+
+func TestLoginThrottle_pendingAttempts(t *testing.T) {
+	cases := map[string]struct {
+		failures      int
+		pending       int
+		finish        func(*handlers.LoginAttempt)
+		expectedAllow bool
+	}{
+		"Pending attempts within the free attempts":        {0, 2, nil, true},
+		"Pending attempts that reach the free attempts":    {0, 3, nil, false},
+		"Pending attempt after the free attempts":          {2, 1, nil, false},
+		"Released attempt after the free attempts":         {2, 1, func(attempt *handlers.LoginAttempt) { attempt.Release() }, true},
+		"Succeeded attempt after the free attempts":        {2, 1, func(attempt *handlers.LoginAttempt) { attempt.Succeeded() }, true},
+		"Failed attempt after the free attempts":           {2, 1, func(attempt *handlers.LoginAttempt) { attempt.Failed() }, false},
+		"Released attempts that reached the free attempts": {0, 3, func(attempt *handlers.LoginAttempt) { attempt.Release() }, true},
+	}
+	for name, test := range cases {
+		throttle := testLoginThrottle()
+		for i := 0; i < test.failures; i++ {
+			throttle.Failed("Test", testIP)
+		}
+		pending := make([]*handlers.LoginAttempt, 0, test.pending)
+		for i := 0; i < test.pending; i++ {
+			attempt, wait, ok := throttle.Check("Test", testIP)
+			if !ok {
+				t.Fatalf("%v: Expects pending attempt %v to be allowed but got a wait of %v\n", name, i+1, wait)
+			}
+			pending = append(pending, attempt)
+		}
+		if test.finish != nil {
+			for _, attempt := range pending {
+				test.finish(attempt)
+			}
+		}
+		if _, _, ok := throttle.Check("Test", testIP); ok != test.expectedAllow {
+			t.Errorf("%v: Expects next attempt to be allowed: %v but got: %v\n", name, test.expectedAllow, ok)
+		}
+	}
+}
+
+func TestLogin_concurrentFailures(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	db := newTestDB()
+	db.SignUp(user.Username, user.Password)
+	server, channel := testServerWithThrottle(t, db, testLoginThrottle())
+	go func() {
+		for range channel {
+		}
+	}()
+	const guesses = 20
+	codes := make(chan int, guesses)
+	var wait sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wait.Add(1)
+		go func(guess int) {
+			defer wait.Done()
+			codes <- general.TestRequest(t, server, http.MethodPost, "/login", "", handlers.NewClientCredentials(user.Username, fmt.Sprintf("Guess%v", guess))).Code
+		}(i)
+	}
+	wait.Wait()
+	close(codes)
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	// Like sequential guesses, only the free attempts and the one after them check the password
+	if counts[http.StatusUnauthorized] != 3 || counts[http.StatusTooManyRequests] != guesses-3 {
+		t.Errorf("Expects 3 checked passwords and %v throttled guesses but got statuscodes: %v\n", guesses-3, counts)
+	}
+}
+
+func TestLogin_sendLoginFailed(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	cases := map[string]struct {
+		failures         int
+		expectedAttempts int
+		expectedLocked   bool
+	}{
+		"First failed attempt":        {0, 1, false},
+		"Failed attempt with lockout": {2, 3, true},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.SignUp(user.Username, user.Password)
+		// Without a backoff the lockout is the only throttling
+		lockoutOnly := handlers.ThrottleParameters{LockoutAttempts: 3, LockoutDuration: time.Hour, ResetAfter: time.Hour}
+		throttle := handlers.NewLoginThrottle(lockoutOnly, handlers.DefaultIPThrottling)
+		for i := 0; i < test.failures; i++ {
+			throttle.Failed(user.Username, testIP)
+		}
+		server, channel := testServerWithThrottle(t, db, throttle)
+		general.TestRequest(t, server, http.MethodPost, "/login", "", handlers.NewClientCredentials(user.Username, "NOTsimilar"))
+		message := <-channel
+		if message.Topic != "loginFailed" {
+			t.Errorf("%v: Expects message on topic loginFailed but got: %v\n", name, message.Topic)
+			continue
+		}
+		var failure general.LoginFailure
+		if _, err := general.DecodeEvent(message.Message, &failure); err != nil {
+			t.Errorf("%v: Expects to send a message containing a failed login but deserializing results in: %v\n", name, err)
+			continue
+		}
+		if failure.Username != user.Username || failure.IP != testIP {
+			t.Errorf("%v: Expects failed login of %v from %v but got: %v from %v\n", name, user.Username, testIP, failure.Username, failure.IP)
+		}
+		if failure.Attempts != test.expectedAttempts || failure.Locked != test.expectedLocked {
+			t.Errorf("%v: Expects %v attempts and locked: %v but got: %v and %v\n", name, test.expectedAttempts, test.expectedLocked, failure.Attempts, failure.Locked)
+		}
+	}
+}
+
+func TestTrustedProxies_clientIP(t *testing.T) {
+	cases := map[string]struct {
+		remoteAddr, forwarded string
+		expectedIP            string
+	}{
+		"Request without proxy":                    {testIP + ":1234", "", testIP},
+		"Untrusted request with forwarded address": {testIP + ":1234", "203.0.113.9", testIP},
+		"Request from the gateway":                 {"127.0.0.1:1234", "203.0.113.9", "203.0.113.9"},
+		"Request from the gateway over IPv6":       {"[::1]:1234", "203.0.113.9", "203.0.113.9"},
+		"Request from a trusted network":           {"10.1.2.3:1234", "203.0.113.9", "203.0.113.9"},
+		"Spoofed address in front of the client":   {"127.0.0.1:1234", "198.51.100.7, 203.0.113.9", "203.0.113.9"},
+		"Chain of trusted proxies":                 {"127.0.0.1:1234", "203.0.113.9, 10.0.0.8", "203.0.113.9"},
+		"Only trusted proxies":                     {"127.0.0.1:1234", "10.0.0.8", "10.0.0.8"},
+		"Request from the gateway without header":  {"127.0.0.1:1234", "", "127.0.0.1"},
+		"Invalid forwarded address":                {"127.0.0.1:1234", "unknown", "127.0.0.1"},
+	}
+	proxies, err := handlers.NewTrustedProxies(append([]string{"10.0.0.0/8"}, handlers.DefaultTrustedProxies...))
+	if err != nil {
+		t.Fatalf("Can't create trusted proxies: %s\n", err)
+	}
+	for name, test := range cases {
+		request := httptest.NewRequest(http.MethodPost, "/login", nil)
+		request.RemoteAddr = test.remoteAddr
+		if test.forwarded != "" {
+			request.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if ip := proxies.ClientIP(request); ip != test.expectedIP {
+			t.Errorf("%v: Expects client %v but got: %v\n", name, test.expectedIP, ip)
+		}
+	}
+}
+
+func TestNewTrustedProxies(t *testing.T) {
+	cases := map[string]struct {
+		proxies       []string
+		expectedError bool
+	}{
+		"Addresses":       {[]string{"127.0.0.1", "::1"}, false},
+		"Networks":        {[]string{"10.0.0.0/8", "fd00::/8"}, false},
+		"Invalid address": {[]string{"gateway"}, true},
+		"Invalid network": {[]string{"10.0.0.0/33"}, true},
+	}
+	for name, test := range cases {
+		if _, err := handlers.NewTrustedProxies(test.proxies); (err != nil) != test.expectedError {
+			t.Errorf("%v: Expects error: %v but got: %v\n", name, test.expectedError, err)
+		}
+	}
+}
+
+func TestLogin_spoofedForwardedFor(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	db := newTestDB()
+	db.SignUp(user.Username, user.Password)
+	throttle := testLoginThrottle()
+	for i := 0; i < 5; i++ {
+		throttle.Failed("Other", testIP)
+	}
+	server, channel := testServerWithThrottle(t, db, throttle)
+	go func() {
+		for range channel {
+		}
+	}()
+	// The client isn't a trusted proxy, so it can't escape the throttling of its address with another X-Forwarded-For header
+	body, err := general.ToJSONBytes(user)
+	if err != nil {
+		t.Fatalf("Can't serialize credentials: %s\n", err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	request.Header.Set("X-Forwarded-For", "203.0.113.9")
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	if response.Code != http.StatusTooManyRequests {
+		t.Errorf("Expects statuscode: %v but got: %v\n", http.StatusTooManyRequests, response.Code)
+	}
+}
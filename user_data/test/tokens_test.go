@@ -0,0 +1,125 @@
+package test
+
+import (
+	"general"
+	"net/http"
+	"testing"
+	"user_data/handlers"
+)
+
+func TestRefresh_response(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	cases := map[string]struct {
+		useRefreshToken, useTwice bool
+		refreshToken              string
+		expectedStatusCode        int
+		expectedValidToken        bool
+	}{
+		"Valid refresh token":        {true, false, "", http.StatusOK, true},
+		"Refresh token that is used": {true, true, "", http.StatusUnauthorized, false},
+		"Unknown refresh token":      {false, false, "unknown", http.StatusUnauthorized, false},
+		"Missing refresh token":      {false, false, "", http.StatusBadRequest, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.SignUp(user.Username, user.Password)
+		server, _ := testServer(t, db)
+		login := general.TestRequest(t, server, http.MethodPost, "/login", "", user)
+		refreshToken := test.refreshToken
+		if test.useRefreshToken {
+			refreshToken = login.Header().Get("Refresh-Token")
+		}
+		if test.useTwice {
+			testRequestWithHeaders(server, http.MethodPost, "/refresh", map[string]string{"Refresh-Token": refreshToken})
+		}
+		response := testRequestWithHeaders(server, http.MethodPost, "/refresh", map[string]string{"Refresh-Token": refreshToken})
+		if response.Code != test.expectedStatusCode {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, test.expectedStatusCode, response.Code)
+		}
+		if result := testValidateToken(response.Body.String()); result != test.expectedValidToken {
+			t.Errorf("%v: Expects valid token: %v but got: %v\n", name, test.expectedValidToken, result)
+		}
+		newRefreshToken := response.Header().Get("Refresh-Token")
+		if test.expectedValidToken && (newRefreshToken == "" || newRefreshToken == refreshToken) {
+			t.Errorf("%v: Expects a new refresh token but got: %v\n", name, newRefreshToken)
+		}
+	}
+}
+
+// Revocations are kept by the service, so every test of a logout uses a new user
+var lastTestUserID = 1000
+
+func testNewUserID() int {
+	lastTestUserID++
+	return lastTestUserID
+}
+
+func TestLogout_revocation(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	cases := map[string]struct {
+		query                          string
+		sendRefreshToken               bool
+		expectedValidRefreshToken      bool
+		expectedValidOtherRefreshToken bool
+	}{
+		"Logout with refresh token":            {"", true, false, true},
+		"Logout without refresh token":         {"", false, true, true},
+		"Logout with refresh token everywhere": {"?all=true", true, false, false},
+	}
+	for name, test := range cases {
+		db := newTestDB()
+		db.db[user.Username] = testCredentials{id: testNewUserID(), username: user.Username, password: user.Password}
+		server, channel := testServer(t, db)
+		go func() {
+			for range channel {
+			}
+		}()
+		login := general.TestRequest(t, server, http.MethodPost, "/login", "", user)
+		otherLogin := general.TestRequest(t, server, http.MethodPost, "/login", "", user)
+		token, refreshToken := login.Body.String(), login.Header().Get("Refresh-Token")
+		headers := map[string]string{"Token": token}
+		if test.sendRefreshToken {
+			headers["Refresh-Token"] = refreshToken
+		}
+		response := testRequestWithHeaders(server, http.MethodPost, "/logout"+test.query, headers)
+		if response.Code != http.StatusOK {
+			t.Errorf("%v: Expects statuscode: %v but got: %v\n", name, http.StatusOK, response.Code)
+		}
+		if testValidateToken(token) || testValidateToken(otherLogin.Body.String()) {
+			t.Errorf("%v: Expects all tokens of the user to be revoked\n", name)
+		}
+		refresh := testRequestWithHeaders(server, http.MethodPost, "/refresh", map[string]string{"Refresh-Token": refreshToken})
+		if result := refresh.Code == http.StatusOK; result != test.expectedValidRefreshToken {
+			t.Errorf("%v: Expects valid refresh token: %v but got: %v\n", name, test.expectedValidRefreshToken, result)
+		}
+		otherRefresh := testRequestWithHeaders(server, http.MethodPost, "/refresh", map[string]string{"Refresh-Token": otherLogin.Header().Get("Refresh-Token")})
+		if result := otherRefresh.Code == http.StatusOK; result != test.expectedValidOtherRefreshToken {
+			t.Errorf("%v: Expects valid refresh token of other session: %v but got: %v\n", name, test.expectedValidOtherRefreshToken, result)
+		}
+		if test.expectedValidOtherRefreshToken && !testValidateToken(otherRefresh.Body.String()) {
+			t.Errorf("%v: Expects the refreshed token of the other session to be valid\n", name)
+		}
+	}
+}
+
+func TestLogout_sendMessage(t *testing.T) {
+	user := handlers.NewClientCredentials("ExistTest", "Passexist")
+	db := newTestDB()
+	userID := testNewUserID()
+	db.db[user.Username] = testCredentials{id: userID, username: user.Username, password: user.Password}
+	server, channel := testServer(t, db)
+	token, _ := general.CreateTokenWithVersion(userID, user.Username, "user", 0)
+	testRequestWithHeaders(server, http.MethodPost, "/logout", map[string]string{"Token": token})
+	messages := testMessages(db, channel)
+	if len(messages) != 1 || messages[0].Topic != "revokeTokens" {
+		t.Fatalf("Expects one message on topic revokeTokens in the outbox but got: %v\n", messages)
+	}
+	message := messages[0]
+	var revocation general.TokenRevocation
+	if _, err := general.DecodeEvent(message.Message, &revocation); err != nil {
+		t.Fatalf("Expects to send a message containing a revocation but deserializing results in: %v\n", err)
+	}
+	if expected := general.NewTokenRevocation(userID, 1); revocation != expected {
+		t.Errorf("Expects revocation %v but got: %v\n", expected, revocation)
+	}
+}